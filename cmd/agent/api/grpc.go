@@ -12,11 +12,13 @@ package api
 
 import (
 	"context"
+	"fmt"
 
 	pb "github.com/DataDog/datadog-agent/cmd/agent/api/pb"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
 	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	hostutil "github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -57,3 +59,85 @@ func (s *serverSecure) GetTags(ctx context.Context, in *pb.TagRequest) (*pb.TagR
 	return nil, status.Errorf(codes.PermissionDenied,
 		"This is an experimental endpoint and has been disabled in this build")
 }
+
+// TaggerStreamEntities subscribes to the entities of the tagger and streams
+// their changes to the client, starting with every entity already known.
+func (s *serverSecure) TaggerStreamEntities(in *pb.StreamTagsRequest, out pb.AgentSecure_TaggerStreamEntitiesServer) error {
+	cardinality, err := pbToTagCardinality(in.GetCardinality())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ch := tagger.Subscribe(cardinality)
+	defer tagger.Unsubscribe(ch)
+
+	for {
+		select {
+		case events, ok := <-ch:
+			if !ok {
+				// the subscription was dropped for lagging behind, the
+				// client has to subscribe again to get a consistent state
+				return status.Error(codes.Aborted, "subscription dropped because it was lagging behind")
+			}
+
+			response := &pb.StreamTagsResponse{
+				Events: make([]*pb.StreamTagsEvent, 0, len(events)),
+			}
+			for _, event := range events {
+				e, err := tagEventToPB(event)
+				if err != nil {
+					log.Warnf("Can't convert tagger event: %s", err)
+					continue
+				}
+				response.Events = append(response.Events, e)
+			}
+
+			// the first response is sent even when empty, it tells the
+			// client that it received every known entity
+			if err := out.Send(response); err != nil {
+				log.Warnf("Error sending tagger events: %s", err)
+				return err
+			}
+		case <-out.Context().Done():
+			return nil
+		}
+	}
+}
+
+func pbToTagCardinality(cardinality pb.TagCardinality) (collectors.TagCardinality, error) {
+	switch cardinality {
+	case pb.TagCardinality_LOW:
+		return collectors.LowCardinality, nil
+	case pb.TagCardinality_ORCHESTRATOR:
+		return collectors.OrchestratorCardinality, nil
+	case pb.TagCardinality_HIGH:
+		return collectors.HighCardinality, nil
+	}
+	return 0, fmt.Errorf("invalid cardinality %d", cardinality)
+}
+
+func tagEventToPB(event tagger.EntityEvent) (*pb.StreamTagsEvent, error) {
+	var eventType pb.EventType
+	switch event.EventType {
+	case tagger.EventTypeAdded:
+		eventType = pb.EventType_ADDED
+	case tagger.EventTypeModified:
+		eventType = pb.EventType_MODIFIED
+	case tagger.EventTypeDeleted:
+		eventType = pb.EventType_DELETED
+	default:
+		return nil, fmt.Errorf("invalid event type %d", event.EventType)
+	}
+
+	return &pb.StreamTagsEvent{
+		Type: eventType,
+		Entity: &pb.Entity{
+			Id:                          event.Entity.ID,
+			Hash:                        event.Entity.Hash,
+			LowCardinalityTags:          event.Entity.LowCardinalityTags,
+			OrchestratorCardinalityTags: event.Entity.OrchestratorCardinalityTags,
+			HighCardinalityTags:         event.Entity.HighCardinalityTags,
+			StandardTags:                event.Entity.StandardTags,
+		},
+	}, nil
+}
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/input"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cardinalityStatsFilePath string
+	cardinalityStatsTop      int
+)
+
+func init() {
+	AgentCmd.AddCommand(cardinalityStatsCmd)
+	cardinalityStatsCmd.Flags().BoolVarP(&jsonStatus, "json", "j", false, "print out raw json")
+	cardinalityStatsCmd.Flags().BoolVarP(&prettyPrintJSON, "pretty-json", "p", false, "pretty print JSON")
+	cardinalityStatsCmd.Flags().StringVarP(&cardinalityStatsFilePath, "file", "o", "", "Output the cardinality-stats command to a file")
+	cardinalityStatsCmd.Flags().IntVarP(&cardinalityStatsTop, "top", "t", 20, "Number of metrics and origins to list, 0 lists all of them")
+}
+
+var cardinalityStatsCmd = &cobra.Command{
+	Use:   "cardinality-stats",
+	Short: "Print the metrics and origins with the most contexts in the aggregator",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if flagNoColor {
+			color.NoColor = true
+		}
+
+		err := common.SetupConfigWithoutSecrets(confFilePath, "")
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+
+		err = config.SetupLogger(loggerName, config.GetEnv("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+		if err != nil {
+			fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+			return err
+		}
+
+		return requestCardinalityStats()
+	},
+}
+
+func requestCardinalityStats() error {
+	fmt.Printf("Getting the cardinality stats from the agent.\n\n")
+	var e error
+	var s string
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	ipcAddress, err := config.GetIPCAddress()
+	if err != nil {
+		return err
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/cardinality-stats?top=%d", ipcAddress, config.Datadog.GetInt("cmd_port"), cardinalityStatsTop)
+
+	// Set session token
+	e = util.SetAuthToken()
+	if e != nil {
+		return e
+	}
+
+	r, e := util.DoGet(c, urlstr)
+	if e != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(r, &errMap) //nolint:errcheck
+		// If the error has been marshalled into a json object, check it and return it properly
+		if err, found := errMap["error"]; found {
+			e = fmt.Errorf(err)
+		}
+
+		fmt.Printf("Could not reach agent: %v \nMake sure the agent is running before requesting the cardinality stats and contact support if you continue having issues. \n", e)
+
+		return e
+	}
+
+	// The rendering is done in the client so that the agent has less work to do
+	if prettyPrintJSON {
+		var prettyJSON bytes.Buffer
+		json.Indent(&prettyJSON, r, "", "  ") //nolint:errcheck
+		s = prettyJSON.String()
+	} else if jsonStatus {
+		s = string(r)
+	} else {
+		s, e = aggregator.FormatContextStats(r)
+		if e != nil {
+			fmt.Printf("Could not format the statistics, the data must be inconsistent. You may want to try the JSON output. Contact the support if you continue having issues.\n")
+			return nil
+		}
+	}
+
+	if cardinalityStatsFilePath == "" {
+		fmt.Println(s)
+		return nil
+	}
+
+	// if the file is already existing, ask for a confirmation.
+	if _, err := os.Stat(cardinalityStatsFilePath); err == nil {
+		if !input.AskForConfirmation(fmt.Sprintf("'%s' already exists, do you want to overwrite it? [y/N]", cardinalityStatsFilePath)) {
+			fmt.Println("Canceling.")
+			return nil
+		}
+	}
+
+	if err := ioutil.WriteFile(cardinalityStatsFilePath, []byte(s), 0644); err != nil {
+		fmt.Println("Error while writing the file (is the location writable by the dd-agent user?):", err)
+	} else {
+		fmt.Println("Cardinality stats written in:", cardinalityStatsFilePath)
+	}
+
+	return nil
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metadata"
 	"github.com/DataDog/datadog-agent/pkg/metadata/host"
 	"github.com/DataDog/datadog-agent/pkg/pidfile"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
@@ -51,6 +52,7 @@ import (
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/ebpf"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/embed"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/net"
+	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/openmetrics"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system/gpu/nvidia/jetson"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/systemd"
@@ -262,11 +264,27 @@ func StartAgent() error {
 	if err != nil {
 		log.Error("Misconfiguration of agent endpoints: ", err)
 	}
-	common.Forwarder = forwarder.NewDefaultForwarder(forwarder.NewOptions(keysPerDomain))
+	f := forwarder.NewDefaultForwarder(forwarder.NewOptions(keysPerDomain))
+	common.Forwarder = f
 	log.Debugf("Starting forwarder")
 	common.Forwarder.Start() //nolint:errcheck
 	log.Debugf("Forwarder started")
 
+	// pick up the API keys rotated in the secret backend
+	secrets.RegisterRefreshCallback(func(origins []string) {
+		for _, origin := range origins {
+			if origin != "datadog.yaml" {
+				continue
+			}
+			keysPerDomain, err := config.GetMultipleEndpoints()
+			if err != nil {
+				log.Errorf("Could not update the forwarder API keys: %s", err)
+				return
+			}
+			f.UpdateAPIKeys(keysPerDomain)
+		}
+	})
+
 	// setup the aggregator
 	s := serializer.NewSerializer(common.Forwarder)
 	agg := aggregator.InitAggregator(s, hostname)
@@ -302,7 +320,8 @@ func StartAgent() error {
 		if config.Datadog.GetBool("log_enabled") {
 			log.Warn(`"log_enabled" is deprecated, use "logs_enabled" instead`)
 		}
-		if err := logs.Start(func() *autodiscovery.AutoConfig { return common.AC }); err != nil {
+		metricsIn, _, _ := agg.GetChannels()
+		if err := logs.Start(func() *autodiscovery.AutoConfig { return common.AC }, metricsIn); err != nil {
 			log.Error("Could not start logs-agent: ", err)
 		}
 	} else {
@@ -15,6 +15,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	lsched "github.com/DataDog/datadog-agent/pkg/logs/scheduler"
 	lstatus "github.com/DataDog/datadog-agent/pkg/logs/status"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
@@ -44,6 +45,9 @@ func SetupAutoConfig(confdPath string) {
 	// create the Autoconfig instance
 	AC = autodiscovery.NewAutoConfig(metaScheduler)
 
+	// reschedule the checks using a secret whose value changed
+	secrets.RegisterRefreshCallback(AC.ProcessRefreshedSecrets)
+
 	// Add the configuration providers
 	// File Provider is hardocded and always enabled
 	confSearchPaths := []string{
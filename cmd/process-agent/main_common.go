@@ -110,7 +110,11 @@ func runAgent(exit chan struct{}) {
 	log.Infof("running version: %s", versionString(", "))
 
 	// Tagger must be initialized after agent config has been setup
-	tagger.Init()
+	if ddconfig.Datadog.GetBool("process_config.remote_tagger") {
+		tagger.InitRemote()
+	} else {
+		tagger.Init()
+	}
 	defer tagger.Stop() //nolint:errcheck
 
 	err = initInfo(cfg)
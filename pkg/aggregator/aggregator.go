@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator/rules"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -103,6 +104,7 @@ var (
 	aggregatorServiceCheck                     = expvar.Int{}
 	aggregatorEvent                            = expvar.Int{}
 	aggregatorHostnameUpdate                   = expvar.Int{}
+	aggregatorContextsLimited                  = expvar.Int{}
 
 	tlmFlush = telemetry.NewCounter("aggregator", "flush",
 		[]string{"data_type", "state"}, "Number of metrics/service checks/events flushed")
@@ -110,6 +112,10 @@ var (
 		[]string{"data_type"}, "Amount of metrics/services_checks/events processed by the aggregator")
 	tlmHostnameUpdate = telemetry.NewCounter("aggregator", "hostname_update",
 		nil, "Count of hostname update")
+	tlmContexts = telemetry.NewGauge("aggregator", "contexts",
+		nil, "Number of contexts tracked by the aggregator")
+	tlmContextsLimited = telemetry.NewCounter("aggregator", "contexts_limited",
+		[]string{"limit", "action"}, "Count of new contexts over the limits per metric or per origin")
 
 	// Hold series to be added to aggregated series on each flush
 	recurrentSeries     metrics.Series
@@ -145,6 +151,8 @@ func init() {
 	aggregatorExpvars.Set("ServiceCheck", &aggregatorServiceCheck)
 	aggregatorExpvars.Set("Event", &aggregatorEvent)
 	aggregatorExpvars.Set("HostnameUpdate", &aggregatorHostnameUpdate)
+	aggregatorExpvars.Set("ContextsLimited", &aggregatorContextsLimited)
+	aggregatorExpvars.Set("MetricRuleHits", &rules.Hits)
 }
 
 // InitAggregator returns the Singleton instance
@@ -197,6 +205,8 @@ type BufferedAggregator struct {
 
 	statsdSampler      TimeSampler
 	checkSamplers      map[check.ID]*CheckSampler
+	contextLimiter     *contextLimiter // counts and limits the contexts of all the samplers
+	metricRules        *rules.Engine   // applied to the metric samples before their aggregation, nil if none
 	serviceChecks      metrics.ServiceChecks
 	events             metrics.Events
 	flushInterval      time.Duration
@@ -225,6 +235,17 @@ func NewBufferedAggregator(s serializer.MetricSerializer, hostname string, flush
 		agentName = flavor.HerokuAgent
 	}
 
+	limiter := newContextLimiterFromConfig()
+	statsdSampler := NewTimeSampler(bucketSize)
+	statsdSampler.contextResolver.limiter = limiter
+
+	var metricRules *rules.Engine
+	if configRules, err := config.GetMetricRules(); err == nil && len(configRules) > 0 {
+		if metricRules, err = rules.NewEngine(configRules); err != nil {
+			log.Errorf("Could not create the metric rules, no rule will be applied: %v", err)
+		}
+	}
+
 	aggregator := &BufferedAggregator{
 		bufferedMetricIn:       make(chan []metrics.MetricSample, bufferSize),
 		bufferedServiceCheckIn: make(chan []*metrics.ServiceCheck, bufferSize),
@@ -239,8 +260,10 @@ func NewBufferedAggregator(s serializer.MetricSerializer, hostname string, flush
 
 		MetricSamplePool: metrics.NewMetricSamplePool(MetricSamplePoolBatchSize),
 
-		statsdSampler:      *NewTimeSampler(bucketSize),
+		statsdSampler:      *statsdSampler,
 		checkSamplers:      make(map[check.ID]*CheckSampler),
+		contextLimiter:     limiter,
+		metricRules:        metricRules,
 		flushInterval:      flushInterval,
 		serializer:         s,
 		hostname:           hostname,
@@ -317,13 +340,19 @@ func (agg *BufferedAggregator) registerSender(id check.ID) error {
 	if _, ok := agg.checkSamplers[id]; ok {
 		return fmt.Errorf("Sender with ID '%s' has already been registered, will use existing sampler", id)
 	}
-	agg.checkSamplers[id] = newCheckSampler()
+	checkSampler := newCheckSampler()
+	checkSampler.origin = string(id)
+	checkSampler.contextResolver.limiter = agg.contextLimiter
+	agg.checkSamplers[id] = checkSampler
 	return nil
 }
 
 func (agg *BufferedAggregator) deregisterSender(id check.ID) {
 	agg.mu.Lock()
-	delete(agg.checkSamplers, id)
+	if checkSampler, ok := agg.checkSamplers[id]; ok {
+		checkSampler.contextResolver.releaseContexts()
+		delete(agg.checkSamplers, id)
+	}
 	agg.mu.Unlock()
 }
 
@@ -335,6 +364,9 @@ func (agg *BufferedAggregator) handleSenderSample(ss senderMetricSample) {
 		if ss.commit {
 			checkSampler.commit(timeNowNano())
 		} else {
+			if agg.metricRules != nil && !agg.metricRules.ApplySample(ss.metricSample) {
+				return
+			}
 			ss.metricSample.Tags = util.SortUniqInPlace(ss.metricSample.Tags)
 			checkSampler.addSample(ss.metricSample)
 		}
@@ -348,6 +380,9 @@ func (agg *BufferedAggregator) handleSenderBucket(checkBucket senderHistogramBuc
 	defer agg.mu.Unlock()
 
 	if checkSampler, ok := agg.checkSamplers[checkBucket.id]; ok {
+		if agg.metricRules != nil && !agg.metricRules.ApplyBucket(checkBucket.bucket) {
+			return
+		}
 		checkBucket.bucket.Tags = util.SortUniqInPlace(checkBucket.bucket.Tags)
 		checkSampler.addBucket(checkBucket.bucket)
 	} else {
@@ -377,6 +412,9 @@ func (agg *BufferedAggregator) addEvent(e metrics.Event) {
 
 // addSample adds the metric sample
 func (agg *BufferedAggregator) addSample(metricSample *metrics.MetricSample, timestamp float64) {
+	if agg.metricRules != nil && !agg.metricRules.ApplySample(metricSample) {
+		return
+	}
 	metricSample.Tags = util.SortUniqInPlace(metricSample.Tags)
 	agg.statsdSampler.addSample(metricSample, timestamp)
 }
@@ -18,6 +18,7 @@ const checksSourceTypeName = "System"
 
 // CheckSampler aggregates metrics from one Check instance
 type CheckSampler struct {
+	origin          string // the ID of the check, used to limit its contexts
 	series          []*metrics.Serie
 	sketches        []metrics.SketchSeries
 	contextResolver *ContextResolver
@@ -43,7 +44,10 @@ func newCheckSampler() *CheckSampler {
 }
 
 func (cs *CheckSampler) addSample(metricSample *metrics.MetricSample) {
-	contextKey := cs.contextResolver.trackContext(metricSample, metricSample.Timestamp)
+	contextKey, ok := cs.contextResolver.trackContext(metricSample, metricSample.Timestamp, cs.origin)
+	if !ok {
+		return
+	}
 
 	if err := cs.metrics.AddSample(contextKey, metricSample, metricSample.Timestamp, 1); err != nil {
 		log.Debug("Ignoring sample '%s' on host '%s' and tags '%s': %s", metricSample.Name, metricSample.Host, metricSample.Tags, err)
@@ -83,7 +87,10 @@ func (cs *CheckSampler) addBucket(bucket *metrics.HistogramBucket) {
 		return
 	}
 
-	contextKey := cs.contextResolver.trackContext(bucket, bucket.Timestamp)
+	contextKey, ok := cs.contextResolver.trackContext(bucket, bucket.Timestamp, cs.origin)
+	if !ok {
+		return
+	}
 
 	// if the bucket is monotonic and we have already seen the bucket we only send the delta
 	if bucket.Monotonic {
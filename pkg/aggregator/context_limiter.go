@@ -0,0 +1,269 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// contextLimitActionAggregate tracks the contexts over the limits without their tags,
+	// except the ones listed in aggregator_context_limit_keep_tags
+	contextLimitActionAggregate = "aggregate"
+	// contextLimitActionDrop drops the samples of the contexts over the limits
+	contextLimitActionDrop = "drop"
+
+	// dogstatsdNoOrigin is the origin of the dogstatsd samples whose origin is unknown
+	dogstatsdNoOrigin = "dogstatsd"
+)
+
+// contextLimiter counts the contexts tracked by the aggregator per metric name
+// and per origin (a check ID or a dogstatsd client), and bounds them when limits
+// are configured. It is shared by all the samplers of the aggregator.
+type contextLimiter struct {
+	mu sync.Mutex
+
+	maxPerMetric int // 0 means no limit
+	maxPerOrigin int // 0 means no limit
+	action       string
+	keepTags     []string // prefixes of the tags kept by the aggregate action, e.g. "env:"
+
+	contexts        int
+	byMetric        map[string]*contextCount
+	byOrigin        map[string]*contextCount
+	rejectedMetrics uint64
+	rejectedOrigins uint64
+}
+
+// contextCount holds the number of live and rejected contexts of a metric or an origin.
+type contextCount struct {
+	contexts int
+	rejected uint64
+}
+
+func newContextLimiter(maxPerMetric, maxPerOrigin int, action string, keepTags []string) *contextLimiter {
+	if action != contextLimitActionAggregate && action != contextLimitActionDrop {
+		log.Warnf("Unknown aggregator_context_limit_action %q, using %q", action, contextLimitActionAggregate)
+		action = contextLimitActionAggregate
+	}
+	prefixes := make([]string, 0, len(keepTags))
+	for _, key := range keepTags {
+		prefixes = append(prefixes, strings.TrimSuffix(key, ":")+":")
+	}
+	return &contextLimiter{
+		maxPerMetric: maxPerMetric,
+		maxPerOrigin: maxPerOrigin,
+		action:       action,
+		keepTags:     prefixes,
+		byMetric:     make(map[string]*contextCount),
+		byOrigin:     make(map[string]*contextCount),
+	}
+}
+
+// newContextLimiterFromConfig returns a contextLimiter configured from the agent configuration.
+func newContextLimiterFromConfig() *contextLimiter {
+	return newContextLimiter(
+		config.Datadog.GetInt("aggregator_max_contexts_per_metric"),
+		config.Datadog.GetInt("aggregator_max_contexts_per_origin"),
+		config.Datadog.GetString("aggregator_context_limit_action"),
+		config.Datadog.GetStringSlice("aggregator_context_limit_keep_tags"),
+	)
+}
+
+// track counts a new context of the given metric and origin. It returns false,
+// without counting it, if the context is over one of the limits.
+func (l *contextLimiter) track(name, origin string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	metric, orig := l.byMetric[name], l.byOrigin[origin]
+	if l.maxPerMetric > 0 && metric != nil && metric.contexts >= l.maxPerMetric {
+		metric.rejected++
+		l.rejectedMetrics++
+		tlmContextsLimited.Inc("metric", l.action)
+		aggregatorContextsLimited.Add(1)
+		return false
+	}
+	if l.maxPerOrigin > 0 && orig != nil && orig.contexts >= l.maxPerOrigin {
+		orig.rejected++
+		l.rejectedOrigins++
+		tlmContextsLimited.Inc("origin", l.action)
+		aggregatorContextsLimited.Add(1)
+		return false
+	}
+
+	if metric == nil {
+		metric = &contextCount{}
+		l.byMetric[name] = metric
+	}
+	if orig == nil {
+		orig = &contextCount{}
+		l.byOrigin[origin] = orig
+	}
+	metric.contexts++
+	orig.contexts++
+	l.contexts++
+	tlmContexts.Set(float64(l.contexts))
+	return true
+}
+
+// release uncounts the given contexts, once they are expired.
+func (l *contextLimiter) release(contexts []*Context) {
+	if len(contexts) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, ctx := range contexts {
+		if !ctx.counted {
+			continue
+		}
+		if metric := l.byMetric[ctx.Name]; metric != nil {
+			metric.contexts--
+			if metric.contexts <= 0 && metric.rejected == 0 {
+				delete(l.byMetric, ctx.Name)
+			}
+		}
+		if orig := l.byOrigin[ctx.origin]; orig != nil {
+			orig.contexts--
+			if orig.contexts <= 0 && orig.rejected == 0 {
+				delete(l.byOrigin, ctx.origin)
+			}
+		}
+		l.contexts--
+	}
+	tlmContexts.Set(float64(l.contexts))
+}
+
+// aggregatedTags returns the tags of a context over the limits with the
+// aggregate action: only the tags listed in keepTags are kept.
+func (l *contextLimiter) aggregatedTags(tags []string) []string {
+	kept := make([]string, 0, len(l.keepTags))
+	for _, tag := range tags {
+		for _, prefix := range l.keepTags {
+			if strings.HasPrefix(tag, prefix) {
+				kept = append(kept, tag)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// ContextStats holds the number of contexts tracked by the aggregator per metric
+// name and per origin, sorted by decreasing number of contexts.
+type ContextStats struct {
+	Contexts         int            `json:"contexts"`
+	MaxPerMetric     int            `json:"max_contexts_per_metric"`
+	MaxPerOrigin     int            `json:"max_contexts_per_origin"`
+	Action           string         `json:"action"`
+	RejectedByMetric uint64         `json:"rejected_by_metric_limit"`
+	RejectedByOrigin uint64         `json:"rejected_by_origin_limit"`
+	Metrics          []ContextCount `json:"metrics"`
+	Origins          []ContextCount `json:"origins"`
+}
+
+// ContextCount holds the number of live and rejected contexts of a metric name or an origin.
+type ContextCount struct {
+	Name     string `json:"name"`
+	Contexts int    `json:"contexts"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// stats returns the top metric names and origins by number of contexts. top <= 0 returns all of them.
+func (l *contextLimiter) stats(top int) ContextStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return ContextStats{
+		Contexts:         l.contexts,
+		MaxPerMetric:     l.maxPerMetric,
+		MaxPerOrigin:     l.maxPerOrigin,
+		Action:           l.action,
+		RejectedByMetric: l.rejectedMetrics,
+		RejectedByOrigin: l.rejectedOrigins,
+		Metrics:          topContextCounts(l.byMetric, top),
+		Origins:          topContextCounts(l.byOrigin, top),
+	}
+}
+
+func topContextCounts(counts map[string]*contextCount, top int) []ContextCount {
+	list := make([]ContextCount, 0, len(counts))
+	for name, c := range counts {
+		list = append(list, ContextCount{Name: name, Contexts: c.contexts, Rejected: c.rejected})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Contexts != list[j].Contexts {
+			return list[i].Contexts > list[j].Contexts
+		}
+		if list[i].Rejected != list[j].Rejected {
+			return list[i].Rejected > list[j].Rejected
+		}
+		return list[i].Name < list[j].Name
+	})
+	if top > 0 && len(list) > top {
+		list = list[:top]
+	}
+	return list
+}
+
+// GetContextStats returns the top metric names and origins by number of
+// contexts tracked by the aggregator. top <= 0 returns all of them.
+func GetContextStats(top int) ContextStats {
+	if aggregatorInstance == nil || aggregatorInstance.contextLimiter == nil {
+		return ContextStats{}
+	}
+	return aggregatorInstance.contextLimiter.stats(top)
+}
+
+// FormatContextStats renders the JSON output of GetContextStats as tables.
+func FormatContextStats(data []byte) (string, error) {
+	var stats ContextStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return "", err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	limit := func(max int) string {
+		if max <= 0 {
+			return "none"
+		}
+		return fmt.Sprint(max)
+	}
+	fmt.Fprintf(buf, "Contexts: %d\n", stats.Contexts)
+	fmt.Fprintf(buf, "Limits: %s per metric, %s per origin (action: %s)\n", limit(stats.MaxPerMetric), limit(stats.MaxPerOrigin), stats.Action)
+	fmt.Fprintf(buf, "Rejected contexts: %d by the metric limit, %d by the origin limit\n", stats.RejectedByMetric, stats.RejectedByOrigin)
+
+	for _, table := range []struct {
+		title  string
+		counts []ContextCount
+	}{
+		{"Metric", stats.Metrics},
+		{"Origin", stats.Origins},
+	} {
+		buf.WriteString("\n")
+		header := fmt.Sprintf("%-60s | %-10s | %-10s\n", table.title, "Contexts", "Rejected")
+		buf.WriteString(header)
+		buf.WriteString(strings.Repeat("-", len(header)) + "\n")
+		for _, c := range table.counts {
+			fmt.Fprintf(buf, "%-60s | %-10d | %-10d\n", c.Name, c.Contexts, c.Rejected)
+		}
+		if len(table.counts) == 0 {
+			buf.WriteString("No contexts tracked yet.\n")
+		}
+	}
+
+	return buf.String(), nil
+}
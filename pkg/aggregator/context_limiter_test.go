@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package aggregator
+
+import (
+	// stdlib
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	// 3p
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func newLimitedContextResolver(maxPerMetric, maxPerOrigin int, action string, keepTags []string) *ContextResolver {
+	cr := newContextResolver()
+	cr.limiter = newContextLimiter(maxPerMetric, maxPerOrigin, action, keepTags)
+	return cr
+}
+
+func TestContextLimiterUnknownAction(t *testing.T) {
+	l := newContextLimiter(1, 0, "unknown", []string{"env", "service:"})
+	assert.Equal(t, contextLimitActionAggregate, l.action)
+	assert.Equal(t, []string{"env:", "service:"}, l.keepTags)
+}
+
+func TestContextLimiterPerMetric(t *testing.T) {
+	cr := newLimitedContextResolver(2, 0, contextLimitActionDrop, nil)
+
+	for i, tags := range [][]string{{"a"}, {"b"}, {"c"}} {
+		mSample := metrics.MetricSample{Name: "my.metric", Tags: tags}
+		_, ok := cr.trackContext(&mSample, float64(i), "check:1")
+		assert.Equal(t, i < 2, ok)
+	}
+	// other metrics are not limited
+	_, ok := cr.trackContext(&metrics.MetricSample{Name: "other.metric", Tags: []string{"c"}}, 3, "check:1")
+	assert.True(t, ok)
+	// tracked contexts are still accepted
+	_, ok = cr.trackContext(&metrics.MetricSample{Name: "my.metric", Tags: []string{"a"}}, 4, "check:1")
+	assert.True(t, ok)
+	// rejected contexts are rejected again, without being tracked
+	_, ok = cr.trackContext(&metrics.MetricSample{Name: "my.metric", Tags: []string{"c"}}, 4, "check:1")
+	assert.False(t, ok)
+
+	assert.Len(t, cr.contextsByKey, 3)
+	stats := cr.limiter.stats(0)
+	assert.Equal(t, 3, stats.Contexts)
+	assert.Equal(t, uint64(2), stats.RejectedByMetric)
+	assert.Equal(t, uint64(0), stats.RejectedByOrigin)
+	assert.Equal(t, []ContextCount{
+		{Name: "my.metric", Contexts: 2, Rejected: 2},
+		{Name: "other.metric", Contexts: 1},
+	}, stats.Metrics)
+	assert.Equal(t, []ContextCount{{Name: "check:1", Contexts: 3}}, stats.Origins)
+
+	// expiring contexts makes room for new ones
+	cr.expireContexts(3)
+	stats = cr.limiter.stats(0)
+	assert.Equal(t, 2, stats.Contexts)
+	_, ok = cr.trackContext(&metrics.MetricSample{Name: "my.metric", Tags: []string{"c"}}, 5, "check:1")
+	assert.True(t, ok)
+}
+
+func TestContextLimiterPerOrigin(t *testing.T) {
+	cr := newLimitedContextResolver(0, 1, contextLimitActionDrop, nil)
+
+	_, ok := cr.trackContext(&metrics.MetricSample{Name: "my.metric"}, 0, "client:1")
+	assert.True(t, ok)
+	_, ok = cr.trackContext(&metrics.MetricSample{Name: "other.metric"}, 0, "client:1")
+	assert.False(t, ok)
+	_, ok = cr.trackContext(&metrics.MetricSample{Name: "other.metric"}, 0, "client:2")
+	assert.True(t, ok)
+
+	stats := cr.limiter.stats(1)
+	assert.Equal(t, uint64(1), stats.RejectedByOrigin)
+	assert.Equal(t, []ContextCount{{Name: "client:1", Contexts: 1, Rejected: 1}}, stats.Origins)
+
+	cr.releaseContexts()
+	stats = cr.limiter.stats(0)
+	assert.Equal(t, 0, stats.Contexts)
+	// the origins with rejected contexts are kept for the stats
+	assert.Equal(t, []ContextCount{{Name: "client:1", Rejected: 1}}, stats.Origins)
+}
+
+func TestContextLimiterAggregate(t *testing.T) {
+	cr := newLimitedContextResolver(1, 0, contextLimitActionAggregate, []string{"env"})
+
+	key1, ok := cr.trackContext(&metrics.MetricSample{Name: "my.metric", Tags: []string{"env:prod", "user:1"}}, 0, "")
+	assert.True(t, ok)
+	key2, ok := cr.trackContext(&metrics.MetricSample{Name: "my.metric", Tags: []string{"env:prod", "user:2"}}, 0, "")
+	assert.True(t, ok)
+	key3, ok := cr.trackContext(&metrics.MetricSample{Name: "my.metric", Tags: []string{"user:3", "env:prod"}}, 0, "")
+	assert.True(t, ok)
+
+	assert.NotEqual(t, key1, key2)
+	assert.Equal(t, key2, key3)
+	assert.Equal(t, []string{"env:prod"}, cr.contextsByKey[key2].Tags)
+	assert.False(t, cr.contextsByKey[key2].counted)
+	assert.Len(t, cr.contextsByKey, 2)
+
+	// the samples of a rejected context are aggregated into the same context
+	key4, ok := cr.trackContext(&metrics.MetricSample{Name: "my.metric", Tags: []string{"env:prod", "user:2"}}, 0, "")
+	assert.True(t, ok)
+	assert.Equal(t, key2, key4)
+	assert.Equal(t, uint64(3), cr.limiter.stats(0).RejectedByMetric)
+
+	// aggregated contexts are not counted
+	cr.expireContexts(1)
+	assert.Equal(t, 0, cr.limiter.stats(0).Contexts)
+}
+
+func TestContextLimiterBoundsMemory(t *testing.T) {
+	for _, action := range []string{contextLimitActionDrop, contextLimitActionAggregate} {
+		t.Run(action, func(t *testing.T) {
+			cr := newLimitedContextResolver(10, 0, action, nil)
+			for i := 0; i < 10000; i++ {
+				cr.trackContext(&metrics.MetricSample{Name: "my.metric", Tags: []string{fmt.Sprintf("user:%d", i)}}, 0, "")
+			}
+			// the rejected contexts are not tracked, nor remembered
+			assert.LessOrEqual(t, len(cr.contextsByKey), 11)
+			assert.LessOrEqual(t, len(cr.lastSeenByKey), 11)
+			assert.Equal(t, uint64(9990), cr.limiter.stats(0).RejectedByMetric)
+		})
+	}
+}
+
+func TestFormatContextStats(t *testing.T) {
+	data, err := json.Marshal(ContextStats{
+		Contexts:         3,
+		MaxPerMetric:     2,
+		Action:           contextLimitActionDrop,
+		RejectedByMetric: 1,
+		Metrics:          []ContextCount{{Name: "my.metric", Contexts: 2, Rejected: 1}},
+		Origins:          []ContextCount{},
+	})
+	require.NoError(t, err)
+
+	out, err := FormatContextStats(data)
+	require.NoError(t, err)
+	assert.Contains(t, out, "Contexts: 3\n")
+	assert.Contains(t, out, "Limits: 2 per metric, none per origin (action: drop)\n")
+	assert.Contains(t, out, "Rejected contexts: 1 by the metric limit, 0 by the origin limit\n")
+	assert.Regexp(t, `my\.metric +\| 2 +\| 1 +\n`, out)
+	assert.Contains(t, out, "No contexts tracked yet.\n")
+
+	_, err = FormatContextStats([]byte("{"))
+	assert.Error(t, err)
+}
@@ -17,6 +17,9 @@ type Context struct {
 	Name string
 	Tags []string
 	Host string
+
+	origin  string
+	counted bool // whether the context is counted by the contextLimiter
 }
 
 // ContextResolver allows tracking and expiring contexts
@@ -24,6 +27,7 @@ type ContextResolver struct {
 	contextsByKey map[ckey.ContextKey]*Context
 	lastSeenByKey map[ckey.ContextKey]float64
 	keyGenerator  *ckey.KeyGenerator
+	limiter       *contextLimiter
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -39,19 +43,37 @@ func newContextResolver() *ContextResolver {
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *ContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp float64) ckey.ContextKey {
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// When the resolver has a limiter, the new contexts over its limits are either tracked without their tags or
+// dropped, in which case trackContext returns false.
+func (cr *ContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp float64, origin string) (ckey.ContextKey, bool) {
 	contextKey := cr.generateContextKey(metricSampleContext)
 	if _, ok := cr.contextsByKey[contextKey]; !ok {
-		cr.contextsByKey[contextKey] = &Context{
-			Name: metricSampleContext.GetName(),
-			Tags: metricSampleContext.GetTags(),
-			Host: metricSampleContext.GetHost(),
+		name, tags, host := metricSampleContext.GetName(), metricSampleContext.GetTags(), metricSampleContext.GetHost()
+		counted := false
+		if cr.limiter != nil {
+			counted = cr.limiter.track(name, origin)
+			if !counted {
+				if cr.limiter.action == contextLimitActionDrop {
+					return contextKey, false
+				}
+				tags = cr.limiter.aggregatedTags(tags)
+				contextKey = cr.keyGenerator.Generate(name, host, tags)
+			}
+		}
+		if _, ok := cr.contextsByKey[contextKey]; !ok {
+			cr.contextsByKey[contextKey] = &Context{
+				Name:    name,
+				Tags:    tags,
+				Host:    host,
+				origin:  origin,
+				counted: counted,
+			}
 		}
 	}
 	cr.lastSeenByKey[contextKey] = currentTimestamp
 
-	return contextKey
+	return contextKey, true
 }
 
 // updateTrackedContext updates the last seen timestamp on a given context key
@@ -78,10 +100,27 @@ func (cr *ContextResolver) expireContexts(expireTimestamp float64) []ckey.Contex
 	}
 
 	// Delete expired context keys
+	expiredContexts := make([]*Context, 0, len(expiredContextKeys))
 	for _, expiredContextKey := range expiredContextKeys {
+		expiredContexts = append(expiredContexts, cr.contextsByKey[expiredContextKey])
 		delete(cr.contextsByKey, expiredContextKey)
 		delete(cr.lastSeenByKey, expiredContextKey)
 	}
+	if cr.limiter != nil {
+		cr.limiter.release(expiredContexts)
+	}
 
 	return expiredContextKeys
 }
+
+// releaseContexts uncounts all the tracked contexts from the limiter, when the resolver is discarded
+func (cr *ContextResolver) releaseContexts() {
+	if cr.limiter == nil {
+		return
+	}
+	contexts := make([]*Context, 0, len(cr.contextsByKey))
+	for _, ctx := range cr.contextsByKey {
+		contexts = append(contexts, ctx)
+	}
+	cr.limiter.release(contexts)
+}
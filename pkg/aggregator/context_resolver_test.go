@@ -69,9 +69,9 @@ func TestTrackContext(t *testing.T) {
 	contextResolver := newContextResolver()
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 1)
-	contextKey2 := contextResolver.trackContext(&mSample2, 1)
-	contextKey3 := contextResolver.trackContext(&mSample3, 1)
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 1, "")
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 1, "")
+	contextKey3, _ := contextResolver.trackContext(&mSample3, 1, "")
 
 	// When we look up the 2 keys, they return the correct contexts
 	context1 := contextResolver.contextsByKey[contextKey1]
@@ -106,8 +106,8 @@ func TestExpireContexts(t *testing.T) {
 	contextResolver := newContextResolver()
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 4)
-	contextKey2 := contextResolver.trackContext(&mSample2, 6)
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 4, "")
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 6, "")
 
 	// With an expireTimestap of 3, both contexts are still valid
 	assert.Len(t, contextResolver.expireContexts(3), 0)
@@ -0,0 +1,255 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package rules
+
+import (
+	"expvar"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+const (
+	matchTypeWildcard = "wildcard"
+	matchTypeRegex    = "regex"
+)
+
+var (
+	// Hits counts the metric samples matched by each rule, by rule name
+	Hits = expvar.Map{}
+
+	tlmHits = telemetry.NewCounter("aggregator", "metric_rule_hits",
+		[]string{"rule"}, "Count of metric samples matched by each metric rule")
+)
+
+// Engine applies an ordered list of rules to the metric samples. The rules are
+// applied in order, each one to the result of the previous ones, until a rule
+// drops the sample.
+type Engine struct {
+	rules []*rule
+}
+
+type rule struct {
+	name      string
+	match     *regexp.Regexp // nil matches every metric name
+	matchTags []*regexp.Regexp
+
+	drop         bool
+	rename       string
+	renameTags   map[string]string
+	removeTags   []*regexp.Regexp
+	stripTagKeys map[string]struct{}
+	addTags      []string
+
+	hits uint64
+}
+
+// NewEngine validates the configured rules and returns an Engine applying them.
+func NewEngine(configRules []config.MetricRule) (*Engine, error) {
+	e := &Engine{}
+	names := make(map[string]struct{}, len(configRules))
+	for i, c := range configRules {
+		if c.Name == "" {
+			return nil, fmt.Errorf("metric rule num %d: name is required", i)
+		}
+		if _, found := names[c.Name]; found {
+			return nil, fmt.Errorf("metric rule %s: duplicate name", c.Name)
+		}
+		names[c.Name] = struct{}{}
+		if c.Match == "" && len(c.MatchTags) == 0 {
+			return nil, fmt.Errorf("metric rule %s: match or match_tags is required", c.Name)
+		}
+		if !c.Drop && c.Rename == "" && len(c.RenameTags) == 0 && len(c.RemoveTags) == 0 && len(c.StripTagKeys) == 0 && len(c.AddTags) == 0 {
+			return nil, fmt.Errorf("metric rule %s: no action, set one of drop, rename, rename_tags, remove_tags, strip_tag_keys or add_tags", c.Name)
+		}
+
+		r := &rule{
+			name:       c.Name,
+			drop:       c.Drop,
+			rename:     c.Rename,
+			renameTags: c.RenameTags,
+			addTags:    c.AddTags,
+		}
+		if c.Match != "" {
+			matchType := c.MatchType
+			if matchType == "" {
+				matchType = matchTypeWildcard
+			}
+			var err error
+			switch matchType {
+			case matchTypeWildcard:
+				r.match, err = compileWildcard(c.Match)
+			case matchTypeRegex:
+				r.match, err = regexp.Compile("^(?:" + c.Match + ")$")
+			default:
+				return nil, fmt.Errorf("metric rule %s: invalid match type, must be `wildcard` or `regex`", c.Name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("metric rule %s: invalid match `%s`: %v", c.Name, c.Match, err)
+			}
+		}
+		for _, pattern := range c.MatchTags {
+			re, err := compileWildcard(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("metric rule %s: invalid match_tags `%s`: %v", c.Name, pattern, err)
+			}
+			r.matchTags = append(r.matchTags, re)
+		}
+		for _, pattern := range c.RemoveTags {
+			re, err := compileWildcard(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("metric rule %s: invalid remove_tags `%s`: %v", c.Name, pattern, err)
+			}
+			r.removeTags = append(r.removeTags, re)
+		}
+		if len(c.StripTagKeys) > 0 {
+			r.stripTagKeys = make(map[string]struct{}, len(c.StripTagKeys))
+			for _, key := range c.StripTagKeys {
+				r.stripTagKeys[key] = struct{}{}
+			}
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// compileWildcard compiles a pattern where `*` matches any sequence of characters,
+// including dots, e.g. `myapp.*` or `pod_name:*`. Each `*` is a capture group.
+func compileWildcard(pattern string) (*regexp.Regexp, error) {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.Compile("^" + strings.Join(parts, "(.*)") + "$")
+}
+
+// ApplySample applies the rules to a metric sample, updating its name and tags.
+// It returns false when the sample is dropped.
+func (e *Engine) ApplySample(sample *metrics.MetricSample) bool {
+	var keep bool
+	sample.Name, sample.Tags, keep = e.apply(sample.Name, sample.Tags)
+	return keep
+}
+
+// ApplyBucket applies the rules to a histogram bucket, updating its name and tags.
+// It returns false when the bucket is dropped.
+func (e *Engine) ApplyBucket(bucket *metrics.HistogramBucket) bool {
+	var keep bool
+	bucket.Name, bucket.Tags, keep = e.apply(bucket.Name, bucket.Tags)
+	return keep
+}
+
+// RuleHits returns the number of samples matched by each rule, by rule name.
+func (e *Engine) RuleHits() map[string]uint64 {
+	hits := make(map[string]uint64, len(e.rules))
+	for _, r := range e.rules {
+		hits[r.name] = atomic.LoadUint64(&r.hits)
+	}
+	return hits
+}
+
+func (e *Engine) apply(name string, tags []string) (string, []string, bool) {
+	// the tags of the samples can be shared with their sender, they are copied
+	// before their first modification
+	copied := false
+	for _, r := range e.rules {
+		var submatches []int
+		if r.match != nil {
+			if submatches = r.match.FindStringSubmatchIndex(name); submatches == nil {
+				continue
+			}
+		}
+		if !r.matchesTags(tags) {
+			continue
+		}
+
+		atomic.AddUint64(&r.hits, 1)
+		Hits.Add(r.name, 1)
+		tlmHits.Inc(r.name)
+
+		if r.drop {
+			return name, tags, false
+		}
+		// the submatches are indexes in the name matched by the rule
+		matchedName := name
+		if r.rename != "" {
+			if submatches != nil {
+				name = string(r.match.ExpandString(nil, r.rename, name, submatches))
+			} else {
+				name = r.rename
+			}
+		}
+		if len(r.renameTags) > 0 || len(r.removeTags) > 0 || len(r.stripTagKeys) > 0 || len(r.addTags) > 0 {
+			if !copied {
+				tags = append(make([]string, 0, len(tags)+len(r.addTags)), tags...)
+				copied = true
+			}
+			tags = r.applyTags(tags, matchedName, submatches)
+		}
+	}
+	return name, tags, true
+}
+
+// matchesTags returns whether each of the match_tags patterns matches one of the tags.
+func (r *rule) matchesTags(tags []string) bool {
+	for _, re := range r.matchTags {
+		found := false
+		for _, tag := range tags {
+			if re.MatchString(tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// applyTags renames, removes and adds the tags in place. The added tags are
+// expanded with the elements captured by the match pattern.
+func (r *rule) applyTags(tags []string, name string, submatches []int) []string {
+	kept := tags[:0]
+	for _, tag := range tags {
+		key, value := tag, ""
+		if i := strings.IndexByte(tag, ':'); i >= 0 {
+			key, value = tag[:i], tag[i:]
+		}
+		if newKey, found := r.renameTags[key]; found {
+			key = newKey
+			tag = newKey + value
+		}
+		if _, found := r.stripTagKeys[key]; found {
+			continue
+		}
+		if r.removesTag(tag) {
+			continue
+		}
+		kept = append(kept, tag)
+	}
+	for _, tag := range r.addTags {
+		if submatches != nil {
+			tag = string(r.match.ExpandString(nil, tag, name, submatches))
+		}
+		kept = append(kept, tag)
+	}
+	return kept
+}
+
+func (r *rule) removesTag(tag string) bool {
+	for _, re := range r.removeTags {
+		if re.MatchString(tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestNewEngineErrors(t *testing.T) {
+	for name, rules := range map[string][]config.MetricRule{
+		"no-name":        {{Match: "foo", Drop: true}},
+		"duplicate-name": {{Name: "a", Match: "foo", Drop: true}, {Name: "a", Match: "bar", Drop: true}},
+		"no-match":       {{Name: "a", Drop: true}},
+		"no-action":      {{Name: "a", Match: "foo"}},
+		"match-type":     {{Name: "a", Match: "foo", MatchType: "glob", Drop: true}},
+		"invalid-regex":  {{Name: "a", Match: "(", MatchType: "regex", Drop: true}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewEngine(rules)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplySample(t *testing.T) {
+	e, err := NewEngine([]config.MetricRule{
+		{Name: "drop_debug", Match: "myapp.debug.*", Drop: true},
+		{Name: "drop_dev", MatchTags: []string{"env:dev*"}, Drop: true},
+		{Name: "rename_requests", Match: `myapp\.requests\.(\w+)`, MatchType: "regex", Rename: "myapp.$1.requests"},
+		{
+			Name:         "tags",
+			Match:        "myapp.*",
+			MatchTags:    []string{"env:prod"},
+			RenameTags:   map[string]string{"host_name": "hostname"},
+			RemoveTags:   []string{"debug", "pod_name:*"},
+			StripTagKeys: []string{"user_id"},
+			AddTags:      []string{"team:web"},
+		},
+	})
+	require.NoError(t, err)
+
+	for name, tt := range map[string]struct {
+		sample       metrics.MetricSample
+		dropped      bool
+		expectedName string
+		expectedTags []string
+	}{
+		"drop-name": {
+			sample:  metrics.MetricSample{Name: "myapp.debug.foo.bar", Tags: []string{"env:prod"}},
+			dropped: true,
+		},
+		"drop-tags": {
+			sample:  metrics.MetricSample{Name: "other.metric", Tags: []string{"env:dev2"}},
+			dropped: true,
+		},
+		"no-match": {
+			sample:       metrics.MetricSample{Name: "other.metric", Tags: []string{"env:prod", "user_id:1"}},
+			expectedName: "other.metric",
+			expectedTags: []string{"env:prod", "user_id:1"},
+		},
+		"rename-and-tags": {
+			sample:       metrics.MetricSample{Name: "myapp.requests.get", Tags: []string{"env:prod", "host_name:a", "debug", "pod_name:b", "user_id:1", "user_id"}},
+			expectedName: "myapp.get.requests",
+			expectedTags: []string{"env:prod", "hostname:a", "team:web"},
+		},
+		"tags-not-matched": {
+			sample:       metrics.MetricSample{Name: "myapp.requests.get", Tags: []string{"env:staging", "user_id:1"}},
+			expectedName: "myapp.get.requests",
+			expectedTags: []string{"env:staging", "user_id:1"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tags := append([]string(nil), tt.sample.Tags...)
+			sample := tt.sample
+			keep := e.ApplySample(&sample)
+			assert.Equal(t, !tt.dropped, keep)
+			if !tt.dropped {
+				assert.Equal(t, tt.expectedName, sample.Name)
+				assert.Equal(t, tt.expectedTags, sample.Tags)
+			}
+			// the tags of the sample are not modified in place
+			assert.Equal(t, tags, tt.sample.Tags)
+		})
+	}
+
+	hits := e.RuleHits()
+	assert.Equal(t, uint64(1), hits["drop_debug"])
+	assert.Equal(t, uint64(1), hits["drop_dev"])
+	assert.Equal(t, uint64(2), hits["rename_requests"])
+	assert.Equal(t, uint64(1), hits["tags"])
+}
+
+func TestApplyBucket(t *testing.T) {
+	e, err := NewEngine([]config.MetricRule{
+		{Name: "rename", Match: "*.bucket", Rename: "renamed.$1", AddTags: []string{"origin:$1"}},
+		{Name: "drop", Match: "drop.*", Drop: true},
+	})
+	require.NoError(t, err)
+
+	bucket := metrics.HistogramBucket{Name: "my.bucket", Tags: []string{"foo:bar"}}
+	assert.True(t, e.ApplyBucket(&bucket))
+	assert.Equal(t, "renamed.my", bucket.Name)
+	assert.Equal(t, []string{"foo:bar", "origin:my"}, bucket.Tags)
+
+	bucket = metrics.HistogramBucket{Name: "drop.me"}
+	assert.False(t, e.ApplyBucket(&bucket))
+}
@@ -57,7 +57,14 @@ func (s *TimeSampler) isBucketStillOpen(bucketStartTimestamp, timestamp int64) b
 // Add the metricSample to the correct bucket
 func (s *TimeSampler) addSample(metricSample *metrics.MetricSample, timestamp float64) {
 	// Keep track of the context
-	contextKey := s.contextResolver.trackContext(metricSample, timestamp)
+	origin := metricSample.OriginID
+	if origin == "" {
+		origin = dogstatsdNoOrigin
+	}
+	contextKey, ok := s.contextResolver.trackContext(metricSample, timestamp, origin)
+	if !ok {
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
 	switch metricSample.Mtype {
@@ -242,7 +242,7 @@ func (ac *AutoConfig) GetAllConfigs() []integration.Config {
 			cfgs = goodConfs
 		}
 		// Store all raw configs in the provider
-		pd.configs = cfgs
+		pd.setConfigs(cfgs)
 
 		// resolve configs if needed
 		for _, config := range cfgs {
@@ -451,6 +451,44 @@ func decryptConfig(conf integration.Config) (integration.Config, error) {
 	return conf, nil
 }
 
+// ProcessRefreshedSecrets reschedules the configurations referencing secrets
+// whose value changed, origins being the names of these configurations. They
+// are decrypted again from the configurations collected by the providers.
+func (ac *AutoConfig) ProcessRefreshedSecrets(origins []string) {
+	names := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		names[origin] = true
+	}
+
+	// unschedule the configurations decrypted with the previous values,
+	// the ones resolved from templates are handled with their template
+	var outdated []integration.Config
+	for _, c := range ac.store.getLoadedConfigs() {
+		if names[c.Name] && !c.IsTemplate() {
+			outdated = append(outdated, c)
+		}
+	}
+	ac.processRemovedConfigs(outdated)
+
+	ac.m.RLock()
+	pollers := append([]*configPoller{}, ac.providers...)
+	ac.m.RUnlock()
+
+	for _, pd := range pollers {
+		for _, config := range pd.getConfigs() {
+			if !names[config.Name] {
+				continue
+			}
+			log.Infof("Rescheduling %s configuration from %v provider after a secret refresh", config.Name, pd.provider)
+			if config.IsTemplate() {
+				ac.removeConfigTemplates([]integration.Config{config})
+			}
+			config.Provider = pd.provider.String()
+			ac.schedule(ac.processNewConfig(config))
+		}
+	}
+}
+
 func (ac *AutoConfig) processRemovedConfigs(configs []integration.Config) {
 	ac.unschedule(configs)
 	for _, c := range configs {
@@ -384,3 +384,51 @@ func TestCheckOverride(t *testing.T) {
 	})
 	assert.Len(t, ac.resolveTemplate(tpl), 1)
 }
+
+type mockScheduler struct {
+	scheduled   []integration.Config
+	unscheduled []integration.Config
+}
+
+func (s *mockScheduler) Schedule(configs []integration.Config) {
+	s.scheduled = append(s.scheduled, configs...)
+}
+
+func (s *mockScheduler) Unschedule(configs []integration.Config) {
+	s.unscheduled = append(s.unscheduled, configs...)
+}
+
+func (s *mockScheduler) Stop() {}
+
+func TestProcessRefreshedSecrets(t *testing.T) {
+	ac := NewAutoConfig(scheduler.NewMetaScheduler())
+	sch := &mockScheduler{}
+	ac.AddScheduler("mock", sch, false)
+
+	static := integration.Config{Name: "postgres", Instances: []integration.Data{integration.Data("password: ENC[pass]")}}
+	other := integration.Config{Name: "memory"}
+	tpl := integration.Config{Name: "postgres", ADIdentifiers: []string{"postgres"}}
+	pd := newConfigPoller(&MockProvider{}, false, 0)
+	pd.setConfigs([]integration.Config{static, other, tpl})
+	ac.providers = append(ac.providers, pd)
+
+	ac.processNewService(&dummyService{
+		ID:            "a5901276aed16ae9ea11660a41fecd674da47e8f5d8d5bce0080a611feed2be9",
+		ADIdentifiers: []string{"postgres"},
+	})
+	for _, c := range pd.getConfigs() {
+		ac.schedule(ac.processNewConfig(c))
+	}
+	require.Len(t, ac.GetLoadedConfigs(), 3)
+	sch.scheduled = nil
+
+	ac.ProcessRefreshedSecrets([]string{"postgres"})
+
+	// the static and the resolved configurations of postgres are rescheduled
+	require.Len(t, sch.unscheduled, 2)
+	require.Len(t, sch.scheduled, 2)
+	for _, c := range append(sch.unscheduled, sch.scheduled...) {
+		assert.Equal(t, "postgres", c.Name)
+	}
+	assert.Len(t, ac.GetLoadedConfigs(), 3)
+}
@@ -7,6 +7,7 @@ package autodiscovery
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
@@ -20,6 +21,7 @@ import (
 type configPoller struct {
 	provider     providers.ConfigProvider
 	configs      []integration.Config
+	configsMu    sync.RWMutex // guards configs, which are also read outside of the polling goroutine
 	canPoll      bool
 	isPolling    bool
 	pollInterval time.Duration
@@ -38,6 +40,8 @@ func newConfigPoller(provider providers.ConfigProvider, canPoll bool, interval t
 
 // contains checks if the providerDescriptor contains the Config passed
 func (pd *configPoller) contains(c *integration.Config) bool {
+	pd.configsMu.RLock()
+	defer pd.configsMu.RUnlock()
 	for _, config := range pd.configs {
 		if config.Equal(c) {
 			return true
@@ -46,6 +50,20 @@ func (pd *configPoller) contains(c *integration.Config) bool {
 	return false
 }
 
+// getConfigs returns a snapshot of the configurations collected from the provider
+func (pd *configPoller) getConfigs() []integration.Config {
+	pd.configsMu.RLock()
+	defer pd.configsMu.RUnlock()
+	return append([]integration.Config{}, pd.configs...)
+}
+
+// setConfigs replaces the configurations collected from the provider
+func (pd *configPoller) setConfigs(configs []integration.Config) {
+	pd.configsMu.Lock()
+	defer pd.configsMu.Unlock()
+	pd.configs = configs
+}
+
 // stop stops the provider descriptor if it's polling
 func (pd *configPoller) stop() {
 	if !pd.canPoll || pd.isPolling {
@@ -116,7 +134,7 @@ func (pd *configPoller) poll(ac *AutoConfig) {
 func (pd *configPoller) collect() ([]integration.Config, []integration.Config) {
 	var newConf []integration.Config
 	var removedConf []integration.Config
-	old := pd.configs
+	old := pd.getConfigs()
 
 	fetched, err := pd.provider.Collect()
 	if err != nil {
@@ -130,7 +148,7 @@ func (pd *configPoller) collect() ([]integration.Config, []integration.Config) {
 		}
 	}
 
-	pd.configs = fetched
+	pd.setConfigs(fetched)
 	for _, c := range old {
 		if !pd.contains(&c) {
 			removedConf = append(removedConf, c)
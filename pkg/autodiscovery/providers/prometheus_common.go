@@ -14,6 +14,7 @@ import (
 const (
 	// Default openmetrics check configuration values
 	openmetricsCheckName   = "openmetrics"
+	openmetricsCoreCheck   = "openmetrics_core"
 	openmetricsInitConfig  = "{}"
 	openmetricsURLPrefix   = "http://%%host%%:"
 	openmetricsDefaultPort = "%%port%%"
@@ -19,8 +19,9 @@ import (
 
 // PrometheusPodsConfigProvider implements the ConfigProvider interface for prometheus pods.
 type PrometheusPodsConfigProvider struct {
-	kubelet kubelet.KubeUtilInterface
-	checks  []*PrometheusCheck
+	kubelet   kubelet.KubeUtilInterface
+	checks    []*PrometheusCheck
+	checkName string
 }
 
 // NewPrometheusPodsConfigProvider returns a new Prometheus ConfigProvider connected to kubelet.
@@ -33,7 +34,13 @@ func NewPrometheusPodsConfigProvider(config config.ConfigurationProviders) (Conf
 
 // setupConfigs reads and initializes the checks from the configuration
 // It defines a default openmetrics instances with default AD if the checks configuration is empty
+// The instances are scheduled on the openmetrics_core Go check if 'prometheus_scrape.use_core_check' is set
 func (p *PrometheusPodsConfigProvider) setupConfigs() error {
+	p.checkName = openmetricsCheckName
+	if config.Datadog.GetBool("prometheus_scrape.use_core_check") {
+		p.checkName = openmetricsCoreCheck
+	}
+
 	checks := []*PrometheusCheck{}
 	if err := config.Datadog.UnmarshalKey("prometheus_scrape.checks", &checks); err != nil {
 		return err
@@ -89,14 +96,14 @@ func (p *PrometheusPodsConfigProvider) parsePodlist(podlist []*kubelet.Pod) []in
 	var configs []integration.Config
 	for _, pod := range podlist {
 		for _, check := range p.checks {
-			configs = append(configs, check.configsForPod(pod)...)
+			configs = append(configs, check.configsForPod(pod, p.checkName)...)
 		}
 	}
 	return configs
 }
 
-// configsForPod returns the openmetrics configurations for a given pod if it matches the AD configuration
-func (pc *PrometheusCheck) configsForPod(pod *kubelet.Pod) []integration.Config {
+// configsForPod returns the configurations of the given openmetrics check for a given pod if it matches the AD configuration
+func (pc *PrometheusCheck) configsForPod(pod *kubelet.Pod, checkName string) []integration.Config {
 	var configs []integration.Config
 	for k, v := range pc.AD.KubeAnnotations.Excl {
 		if pod.Metadata.Annotations[k] == v {
@@ -128,7 +135,7 @@ func (pc *PrometheusCheck) configsForPod(pod *kubelet.Pod) []integration.Config
 					continue
 				}
 				configs = append(configs, integration.Config{
-					Name:          openmetricsCheckName,
+					Name:          checkName,
 					InitConfig:    integration.Data(openmetricsInitConfig),
 					Instances:     instances,
 					Provider:      names.Prometheus,
@@ -461,7 +461,7 @@ func TestConfigsForPod(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.check.init()
-			configs := tt.check.configsForPod(tt.pod)
+			configs := tt.check.configsForPod(tt.pod, openmetricsCheckName)
 			assert.ElementsMatch(t, configs, tt.want)
 		})
 	}
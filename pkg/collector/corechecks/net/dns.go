@@ -0,0 +1,237 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package net
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	dnsCheckName = "dns_check_core"
+
+	defaultDNSTimeout    = 5
+	defaultDNSPort       = 53
+	defaultDNSRecordType = "A"
+	// nxdomainRecordType checks that the hostname does not exist
+	nxdomainRecordType = "NXDOMAIN"
+)
+
+// for testing purpose
+var resolvConfPath = "/etc/resolv.conf"
+
+// DNSCheck resolves a hostname and checks the answers of the nameserver. Its
+// metrics and service checks are the ones of the dns_check integration.
+type DNSCheck struct {
+	core.CheckBase
+	cfg        *dnsInstanceConfig
+	client     *dns.Client
+	qtype      uint16
+	nameserver string
+	resolvesAs []string
+	tags       []string
+}
+
+type dnsInstanceConfig struct {
+	Name           string `yaml:"name"`
+	Hostname       string `yaml:"hostname"`
+	Nameserver     string `yaml:"nameserver"`
+	NameserverPort int    `yaml:"nameserver_port"`
+	Timeout        int    `yaml:"timeout"`
+	RecordType     string `yaml:"record_type"`
+	// ResolvesAs is a comma-separated list of the expected answers
+	ResolvesAs string `yaml:"resolves_as"`
+}
+
+func (c *dnsInstanceConfig) parse(data []byte) error {
+	*c = dnsInstanceConfig{
+		NameserverPort: defaultDNSPort,
+		Timeout:        defaultDNSTimeout,
+		RecordType:     defaultDNSRecordType,
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.Hostname == "" {
+		return errors.New("hostname is required")
+	}
+	c.RecordType = strings.ToUpper(c.RecordType)
+	if c.Timeout <= 0 {
+		c.Timeout = defaultDNSTimeout
+	}
+	return nil
+}
+
+// Configure parses the check configuration and init the check
+func (c *DNSCheck) Configure(data integration.Data, initConfig integration.Data, source string) error {
+	cfg := new(dnsInstanceConfig)
+	if err := cfg.parse(data); err != nil {
+		return fmt.Errorf("invalid %s configuration: %v", dnsCheckName, err)
+	}
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data, source); err != nil {
+		return err
+	}
+
+	qtype := dns.TypeA
+	if cfg.RecordType != nxdomainRecordType {
+		var ok bool
+		if qtype, ok = dns.StringToType[cfg.RecordType]; !ok {
+			return fmt.Errorf("unknown record_type %q", cfg.RecordType)
+		}
+	}
+
+	nameserver := cfg.Nameserver
+	if nameserver == "" {
+		conf, err := dns.ClientConfigFromFile(resolvConfPath)
+		if err != nil || len(conf.Servers) == 0 {
+			return fmt.Errorf("no nameserver configured and none found in %s: %v", resolvConfPath, err)
+		}
+		nameserver = conf.Servers[0]
+	}
+
+	var resolvesAs []string
+	for _, answer := range strings.Split(cfg.ResolvesAs, ",") {
+		if answer = normalizeDNSAnswer(answer); answer != "" {
+			resolvesAs = append(resolvesAs, answer)
+		}
+	}
+	sort.Strings(resolvesAs)
+
+	c.cfg = cfg
+	c.client = &dns.Client{Timeout: time.Duration(cfg.Timeout) * time.Second}
+	c.qtype = qtype
+	c.nameserver = net.JoinHostPort(nameserver, strconv.Itoa(cfg.NameserverPort))
+	c.resolvesAs = resolvesAs
+	c.tags = []string{
+		"instance:" + cfg.Name,
+		"resolved_hostname:" + cfg.Hostname,
+		"nameserver:" + nameserver,
+		"record_type:" + cfg.RecordType,
+	}
+	return nil
+}
+
+// Run executes the check
+func (c *DNSCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(c.cfg.Hostname), c.qtype)
+	start := time.Now()
+	resp, _, err := c.client.Exchange(msg, c.nameserver)
+	elapsed := time.Since(start)
+	if err != nil {
+		sender.ServiceCheck("dns.can_resolve", metrics.ServiceCheckCritical, "", c.tags, err.Error())
+		sender.Commit()
+		return nil
+	}
+
+	if err := c.checkResponse(resp); err != nil {
+		sender.ServiceCheck("dns.can_resolve", metrics.ServiceCheckCritical, "", c.tags, err.Error())
+		sender.Commit()
+		return nil
+	}
+
+	sender.Gauge("dns.response_time", elapsed.Seconds(), "", c.tags)
+	sender.ServiceCheck("dns.can_resolve", metrics.ServiceCheckOK, "", c.tags, "")
+	sender.Commit()
+	return nil
+}
+
+// checkResponse checks the response code of the nameserver and, when
+// resolves_as is set, that the answers are the expected ones.
+func (c *DNSCheck) checkResponse(resp *dns.Msg) error {
+	if c.cfg.RecordType == nxdomainRecordType {
+		if resp.Rcode != dns.RcodeNameError {
+			return fmt.Errorf("expected NXDOMAIN for %s, got %s", c.cfg.Hostname, dns.RcodeToString[resp.Rcode])
+		}
+		return nil
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("unable to resolve %s: %s", c.cfg.Hostname, dns.RcodeToString[resp.Rcode])
+	}
+
+	var answers []string
+	for _, rr := range resp.Answer {
+		if rr.Header().Rrtype != c.qtype {
+			// e.g. the CNAME records of an A query
+			continue
+		}
+		answers = append(answers, normalizeDNSAnswer(dnsAnswerValue(rr)))
+	}
+	if len(answers) == 0 {
+		return fmt.Errorf("no %s record found for %s", c.cfg.RecordType, c.cfg.Hostname)
+	}
+	if len(c.resolvesAs) == 0 {
+		return nil
+	}
+
+	sort.Strings(answers)
+	if strings.Join(answers, ",") != strings.Join(c.resolvesAs, ",") {
+		return fmt.Errorf("%s resolved as %s, expected %s", c.cfg.Hostname, strings.Join(answers, ","), strings.Join(c.resolvesAs, ","))
+	}
+	return nil
+}
+
+// dnsAnswerValue returns the value of a record, like the address of an A record.
+func dnsAnswerValue(rr dns.RR) string {
+	switch r := rr.(type) {
+	case *dns.A:
+		return r.A.String()
+	case *dns.AAAA:
+		return r.AAAA.String()
+	case *dns.CNAME:
+		return r.Target
+	case *dns.MX:
+		return r.Mx
+	case *dns.NS:
+		return r.Ns
+	case *dns.PTR:
+		return r.Ptr
+	case *dns.TXT:
+		return strings.Join(r.Txt, "")
+	}
+	// the value is the last field of the text representation of the record
+	fields := strings.Fields(rr.String())
+	return fields[len(fields)-1]
+}
+
+// normalizeDNSAnswer lowercases an answer and removes the trailing dot of fully
+// qualified names, so that answers can be compared to the resolves_as values.
+func normalizeDNSAnswer(answer string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(answer), "."))
+}
+
+func dnsFactory() check.Check {
+	return &DNSCheck{
+		CheckBase: core.NewCheckBase(dnsCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(dnsCheckName, dnsFactory)
+}
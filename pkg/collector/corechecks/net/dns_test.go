@@ -0,0 +1,168 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package net
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// startDNSServer starts a nameserver answering for the example.com zone and
+// returns its port.
+func startDNSServer(t *testing.T) (int, func()) {
+	records := map[uint16][]string{
+		dns.TypeA:     {"example.com. 60 IN A 10.0.0.1", "example.com. 60 IN A 10.0.0.2"},
+		dns.TypeCNAME: {"www.example.com. 60 IN CNAME example.com."},
+		dns.TypeMX:    {"example.com. 60 IN MX 10 mail.example.com."},
+	}
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		if q.Name != "example.com." && q.Name != "www.example.com." {
+			m.Rcode = dns.RcodeNameError
+			w.WriteMsg(m)
+			return
+		}
+		for _, s := range records[q.Qtype] {
+			rr, err := dns.NewRR(s)
+			if err == nil && rr.Header().Name == q.Name {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+		w.WriteMsg(m)
+	})
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	return pc.LocalAddr().(*net.UDPAddr).Port, func() { server.Shutdown() }
+}
+
+func runDNSCheck(t *testing.T, config string) *mocksender.MockSender {
+	check := dnsFactory().(*DNSCheck)
+	require.NoError(t, check.Configure([]byte(config), []byte(""), "test"))
+	sender := mocksender.NewMockSender(check.ID())
+	sender.SetupAcceptAll()
+	require.NoError(t, check.Run())
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+	return sender
+}
+
+func TestDNSConfigure(t *testing.T) {
+	for name, tt := range map[string]struct {
+		config string
+		err    bool
+	}{
+		"valid":          {config: "name: test\nhostname: example.com\nnameserver: 127.0.0.1"},
+		"no-name":        {config: "hostname: example.com\nnameserver: 127.0.0.1", err: true},
+		"no-hostname":    {config: "name: test\nnameserver: 127.0.0.1", err: true},
+		"invalid-record": {config: "name: test\nhostname: example.com\nnameserver: 127.0.0.1\nrecord_type: FOO", err: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := dnsFactory().Configure([]byte(tt.config), []byte(""), "test")
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDNSDefaultNameserver(t *testing.T) {
+	f, err := ioutil.TempFile("", "resolv.conf")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("nameserver 10.0.0.53\n")
+	require.NoError(t, err)
+	f.Close()
+
+	resolvConfPath = f.Name()
+	defer func() { resolvConfPath = "/etc/resolv.conf" }()
+
+	check := dnsFactory().(*DNSCheck)
+	require.NoError(t, check.Configure([]byte("name: test\nhostname: example.com"), []byte(""), "test"))
+	assert.Equal(t, "10.0.0.53:53", check.nameserver)
+	assert.Contains(t, check.tags, "nameserver:10.0.0.53")
+}
+
+func TestDNSCheck(t *testing.T) {
+	port, stop := startDNSServer(t)
+	defer stop()
+
+	for name, tt := range map[string]struct {
+		config  string
+		status  metrics.ServiceCheckStatus
+		message string
+	}{
+		"a": {
+			config: "hostname: example.com",
+			status: metrics.ServiceCheckOK,
+		},
+		"a-resolves-as": {
+			config: "hostname: example.com\nresolves_as: 10.0.0.2, 10.0.0.1",
+			status: metrics.ServiceCheckOK,
+		},
+		"a-unexpected": {
+			config:  "hostname: example.com\nresolves_as: 10.0.0.1",
+			status:  metrics.ServiceCheckCritical,
+			message: "example.com resolved as 10.0.0.1,10.0.0.2, expected 10.0.0.1",
+		},
+		"cname": {
+			config: "hostname: www.example.com\nrecord_type: CNAME\nresolves_as: example.com",
+			status: metrics.ServiceCheckOK,
+		},
+		"mx": {
+			config: "hostname: example.com\nrecord_type: mx\nresolves_as: mail.example.com.",
+			status: metrics.ServiceCheckOK,
+		},
+		"no-record": {
+			config:  "hostname: example.com\nrecord_type: TXT",
+			status:  metrics.ServiceCheckCritical,
+			message: "no TXT record found for example.com",
+		},
+		"unknown": {
+			config:  "hostname: unknown.com",
+			status:  metrics.ServiceCheckCritical,
+			message: "unable to resolve unknown.com: NXDOMAIN",
+		},
+		"nxdomain": {
+			config: "hostname: unknown.com\nrecord_type: NXDOMAIN",
+			status: metrics.ServiceCheckOK,
+		},
+		"nxdomain-exists": {
+			config:  "hostname: example.com\nrecord_type: NXDOMAIN",
+			status:  metrics.ServiceCheckCritical,
+			message: "expected NXDOMAIN for example.com, got NOERROR",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sender := runDNSCheck(t, fmt.Sprintf("name: test\nnameserver: 127.0.0.1\nnameserver_port: %d\n%s", port, tt.config))
+			tags := []string{"instance:test", "nameserver:127.0.0.1"}
+			sender.AssertServiceCheck(t, "dns.can_resolve", tt.status, "", tags, tt.message)
+			if tt.status == metrics.ServiceCheckOK {
+				sender.AssertMetricInRange(t, "Gauge", "dns.response_time", 0, 5, "", tags)
+			} else {
+				sender.AssertNotCalled(t, "Gauge", "dns.response_time", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
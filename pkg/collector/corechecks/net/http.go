@@ -0,0 +1,323 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package net
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+)
+
+const (
+	httpCheckName = "http_check_core"
+
+	defaultHTTPTimeout     = 10
+	defaultHTTPStatusCodes = `(1|2|3)\d\d`
+	defaultDaysWarning     = 14
+	defaultDaysCritical    = 7
+
+	// maxHTTPBodySize is the size of the beginning of the response body
+	// which is read, included in the response time and matched against
+	// content_match, the rest of the body is ignored.
+	maxHTTPBodySize = 1024 * 1024
+)
+
+// HTTPCheck probes an HTTP endpoint: it checks the status code and the content
+// of the response, the expiration of the TLS certificate, and reports the
+// response time. Its metrics and service checks are the ones of the http_check
+// integration.
+type HTTPCheck struct {
+	core.CheckBase
+	cfg          *httpInstanceConfig
+	client       *http.Client
+	statusCodes  *regexp.Regexp
+	contentMatch *regexp.Regexp
+	tags         []string
+}
+
+type httpInstanceConfig struct {
+	Name                string            `yaml:"name"`
+	URL                 string            `yaml:"url"`
+	Method              string            `yaml:"method"`
+	Data                string            `yaml:"data"`
+	Headers             map[string]string `yaml:"headers"`
+	Username            string            `yaml:"username"`
+	Password            string            `yaml:"password"`
+	Timeout             int               `yaml:"timeout"`
+	StatusCode          string            `yaml:"http_response_status_code"`
+	ContentMatch        string            `yaml:"content_match"`
+	ReverseContentMatch bool              `yaml:"reverse_content_match"`
+	AllowRedirects      bool              `yaml:"allow_redirects"`
+	TLSVerify           bool              `yaml:"tls_verify"`
+	TLSCACert           string            `yaml:"tls_ca_cert"`
+	CheckCertExpiration bool              `yaml:"check_certificate_expiration"`
+	DaysWarning         int               `yaml:"days_warning"`
+	DaysCritical        int               `yaml:"days_critical"`
+	SecondsWarning      int               `yaml:"seconds_warning"`
+	SecondsCritical     int               `yaml:"seconds_critical"`
+	CollectResponseTime bool              `yaml:"collect_response_time"`
+}
+
+func (c *httpInstanceConfig) parse(data []byte) error {
+	*c = httpInstanceConfig{
+		Method:              http.MethodGet,
+		Timeout:             defaultHTTPTimeout,
+		StatusCode:          defaultHTTPStatusCodes,
+		AllowRedirects:      true,
+		TLSVerify:           true,
+		CheckCertExpiration: true,
+		DaysWarning:         defaultDaysWarning,
+		DaysCritical:        defaultDaysCritical,
+		CollectResponseTime: true,
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.URL == "" {
+		return errors.New("url is required")
+	}
+	c.Method = strings.ToUpper(c.Method)
+	if c.Timeout <= 0 {
+		c.Timeout = defaultHTTPTimeout
+	}
+	// the thresholds in seconds take precedence over the ones in days
+	if c.SecondsWarning == 0 {
+		c.SecondsWarning = c.DaysWarning * 24 * 3600
+	}
+	if c.SecondsCritical == 0 {
+		c.SecondsCritical = c.DaysCritical * 24 * 3600
+	}
+	return nil
+}
+
+// httpTimings holds the latency breakdown of a request.
+type httpTimings struct {
+	dnsStart, dnsDone       time.Time
+	connectStart, connected time.Time
+	tlsStart, tlsDone       time.Time
+	firstByte               time.Time
+}
+
+func (t *httpTimings) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { t.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { t.connected = time.Now() },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tlsDone = time.Now() },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+	}
+}
+
+// Configure parses the check configuration and init the check
+func (c *HTTPCheck) Configure(data integration.Data, initConfig integration.Data, source string) error {
+	cfg := new(httpInstanceConfig)
+	if err := cfg.parse(data); err != nil {
+		return fmt.Errorf("invalid %s configuration: %v", httpCheckName, err)
+	}
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data, source); err != nil {
+		return err
+	}
+
+	statusCodes, err := regexp.Compile("^(?:" + cfg.StatusCode + ")")
+	if err != nil {
+		return fmt.Errorf("invalid http_response_status_code %q: %v", cfg.StatusCode, err)
+	}
+	var contentMatch *regexp.Regexp
+	if cfg.ContentMatch != "" {
+		if contentMatch, err = regexp.Compile(cfg.ContentMatch); err != nil {
+			return fmt.Errorf("invalid content_match %q: %v", cfg.ContentMatch, err)
+		}
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: !cfg.TLSVerify}
+	if cfg.TLSCACert != "" {
+		cert, err := ioutil.ReadFile(cfg.TLSCACert)
+		if err != nil {
+			return fmt.Errorf("unable to read tls_ca_cert: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(cert) {
+			return fmt.Errorf("no certificate found in tls_ca_cert %q", cfg.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := httputils.CreateHTTPTransport()
+	transport.TLSClientConfig = tlsConfig
+	// each run measures a new connection
+	transport.DisableKeepAlives = true
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+	}
+	if !cfg.AllowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	c.cfg = cfg
+	c.client = client
+	c.statusCodes = statusCodes
+	c.contentMatch = contentMatch
+	c.tags = []string{"url:" + cfg.URL, "instance:" + cfg.Name}
+	return nil
+}
+
+// Run executes the check
+func (c *HTTPCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	timings := &httpTimings{}
+	start := time.Now()
+	resp, body, err := c.request(timings)
+	elapsed := time.Since(start)
+
+	status, message := metrics.ServiceCheckOK, ""
+	switch {
+	case err != nil:
+		status, message = metrics.ServiceCheckCritical, err.Error()
+	case !c.statusCodes.MatchString(fmt.Sprint(resp.StatusCode)):
+		status = metrics.ServiceCheckCritical
+		message = fmt.Sprintf("Incorrect HTTP return code for url %s. Expected %s, got %d.", c.cfg.URL, c.cfg.StatusCode, resp.StatusCode)
+	case c.contentMatch != nil:
+		found := c.contentMatch.Match(body)
+		if found && c.cfg.ReverseContentMatch {
+			status = metrics.ServiceCheckCritical
+			message = fmt.Sprintf("Content %q found in the response.", c.cfg.ContentMatch)
+		} else if !found && !c.cfg.ReverseContentMatch {
+			status = metrics.ServiceCheckCritical
+			message = fmt.Sprintf("Content %q not found in the response.", c.cfg.ContentMatch)
+		}
+	}
+
+	if err == nil && c.cfg.CollectResponseTime {
+		sender.Gauge("network.http.response_time", elapsed.Seconds(), "", c.tags)
+		c.submitTimings(sender, start, timings)
+	}
+	if status == metrics.ServiceCheckOK {
+		sender.Gauge("network.http.can_connect", 1, "", c.tags)
+		sender.Gauge("network.http.cant_connect", 0, "", c.tags)
+	} else {
+		sender.Gauge("network.http.can_connect", 0, "", c.tags)
+		sender.Gauge("network.http.cant_connect", 1, "", c.tags)
+	}
+	sender.ServiceCheck("http.can_connect", status, "", c.tags, message)
+
+	if c.cfg.CheckCertExpiration && strings.HasPrefix(strings.ToLower(c.cfg.URL), "https://") {
+		c.checkCertificate(sender, resp, err)
+	}
+
+	sender.Commit()
+	return nil
+}
+
+// request sends the request of the instance and reads the beginning of the
+// response body, so that the response time includes its download.
+func (c *HTTPCheck) request(timings *httpTimings) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(c.cfg.Method, c.cfg.URL, bytes.NewBufferString(c.cfg.Data))
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timings.clientTrace()))
+	for k, v := range c.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHTTPBodySize))
+	if err != nil {
+		return resp, nil, err
+	}
+	return resp, body, nil
+}
+
+// submitTimings submits the latency breakdown of the request, skipping the
+// phases that did not happen, like the DNS lookup of an IP address.
+func (c *HTTPCheck) submitTimings(sender aggregator.Sender, start time.Time, t *httpTimings) {
+	submit := func(name string, from, to time.Time) {
+		if from.IsZero() || to.IsZero() {
+			return
+		}
+		sender.Gauge(name, to.Sub(from).Seconds(), "", c.tags)
+	}
+	submit("network.http.dns_lookup_time", t.dnsStart, t.dnsDone)
+	submit("network.http.connect_time", t.connectStart, t.connected)
+	submit("network.http.tls_handshake_time", t.tlsStart, t.tlsDone)
+	submit("network.http.time_to_first_byte", start, t.firstByte)
+}
+
+// checkCertificate reports the time left before the expiration of the
+// certificate presented by the server.
+func (c *HTTPCheck) checkCertificate(sender aggregator.Sender, resp *http.Response, err error) {
+	if resp == nil || resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		message := "no certificate presented by the server"
+		if err != nil {
+			message = err.Error()
+		}
+		sender.ServiceCheck("http.ssl_cert", metrics.ServiceCheckCritical, "", c.tags, message)
+		return
+	}
+
+	expiration := resp.TLS.PeerCertificates[0].NotAfter
+	secondsLeft := time.Until(expiration).Seconds()
+	sender.Gauge("http.ssl.days_left", secondsLeft/(24*3600), "", c.tags)
+	sender.Gauge("http.ssl.seconds_left", secondsLeft, "", c.tags)
+
+	status, message := metrics.ServiceCheckOK, ""
+	switch {
+	case secondsLeft < 0:
+		status, message = metrics.ServiceCheckCritical, fmt.Sprintf("Certificate expired on %s", expiration.UTC())
+	case secondsLeft < float64(c.cfg.SecondsCritical):
+		status, message = metrics.ServiceCheckCritical, fmt.Sprintf("Certificate expires in %.0f seconds", secondsLeft)
+	case secondsLeft < float64(c.cfg.SecondsWarning):
+		status, message = metrics.ServiceCheckWarning, fmt.Sprintf("Certificate expires in %.0f seconds", secondsLeft)
+	}
+	sender.ServiceCheck("http.ssl_cert", status, "", c.tags, message)
+}
+
+func httpFactory() check.Check {
+	return &HTTPCheck{
+		CheckBase: core.NewCheckBase(httpCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(httpCheckName, httpFactory)
+}
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package net
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func newHTTPServer(tlsServer bool) *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/large":
+			// the content is past the part of the body which is read
+			w.Write(make([]byte, maxHTTPBodySize))
+		case "/slow":
+			// the body is sent after the headers
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		case "/auth":
+			if u, p, ok := r.BasicAuth(); !ok || u != "user" || p != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		fmt.Fprint(w, "status: healthy")
+	})
+	if tlsServer {
+		return httptest.NewTLSServer(handler)
+	}
+	return httptest.NewServer(handler)
+}
+
+func runHTTPCheck(t *testing.T, config string) *mocksender.MockSender {
+	check := httpFactory().(*HTTPCheck)
+	require.NoError(t, check.Configure([]byte(config), []byte(""), "test"))
+	sender := mocksender.NewMockSender(check.ID())
+	sender.SetupAcceptAll()
+	require.NoError(t, check.Run())
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+	return sender
+}
+
+func TestHTTPConfigure(t *testing.T) {
+	for name, tt := range map[string]struct {
+		config string
+		err    bool
+	}{
+		"valid":          {config: "name: test\nurl: http://localhost"},
+		"no-name":        {config: "url: http://localhost", err: true},
+		"no-url":         {config: "name: test", err: true},
+		"invalid-status": {config: "name: test\nurl: http://localhost\nhttp_response_status_code: '('", err: true},
+		"invalid-match":  {config: "name: test\nurl: http://localhost\ncontent_match: '('", err: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := httpFactory().Configure([]byte(tt.config), []byte(""), "test")
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckOK(t *testing.T) {
+	ts := newHTTPServer(false)
+	defer ts.Close()
+
+	sender := runHTTPCheck(t, fmt.Sprintf("name: test\nurl: %s/auth\nusername: user\npassword: pass\ncontent_match: 'status: (healthy|ok)'", ts.URL))
+	tags := []string{"url:" + ts.URL + "/auth", "instance:test"}
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckOK, "", tags, "")
+	sender.AssertMetric(t, "Gauge", "network.http.can_connect", 1, "", tags)
+	sender.AssertMetric(t, "Gauge", "network.http.cant_connect", 0, "", tags)
+	sender.AssertMetricInRange(t, "Gauge", "network.http.response_time", 0, 10, "", tags)
+	sender.AssertMetricInRange(t, "Gauge", "network.http.connect_time", 0, 10, "", tags)
+	sender.AssertMetricInRange(t, "Gauge", "network.http.time_to_first_byte", 0, 10, "", tags)
+	sender.AssertNotCalled(t, "ServiceCheck", "http.ssl_cert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHTTPCheckResponseTime(t *testing.T) {
+	ts := newHTTPServer(false)
+	defer ts.Close()
+
+	// the response time includes the download of the body, even without content_match
+	sender := runHTTPCheck(t, fmt.Sprintf("name: test\nurl: %s/slow", ts.URL))
+	tags := []string{"url:" + ts.URL + "/slow", "instance:test"}
+	sender.AssertMetricInRange(t, "Gauge", "network.http.response_time", 0.1, 10, "", tags)
+	sender.AssertMetricInRange(t, "Gauge", "network.http.time_to_first_byte", 0, 0.1, "", tags)
+}
+
+func TestHTTPCheckCritical(t *testing.T) {
+	ts := newHTTPServer(false)
+	defer ts.Close()
+
+	for name, tt := range map[string]struct {
+		config  string
+		message string
+	}{
+		"status": {
+			config:  fmt.Sprintf("name: test\nurl: %s/error", ts.URL),
+			message: fmt.Sprintf("Incorrect HTTP return code for url %s/error. Expected (1|2|3)\\d\\d, got 500.", ts.URL),
+		},
+		"auth": {
+			config:  fmt.Sprintf("name: test\nurl: %s/auth", ts.URL),
+			message: fmt.Sprintf("Incorrect HTTP return code for url %s/auth. Expected (1|2|3)\\d\\d, got 401.", ts.URL),
+		},
+		"content": {
+			config:  fmt.Sprintf("name: test\nurl: %s\ncontent_match: unhealthy", ts.URL),
+			message: `Content "unhealthy" not found in the response.`,
+		},
+		"content-too-far": {
+			config:  fmt.Sprintf("name: test\nurl: %s/large\ncontent_match: healthy", ts.URL),
+			message: `Content "healthy" not found in the response.`,
+		},
+		"reverse-content": {
+			config:  fmt.Sprintf("name: test\nurl: %s\ncontent_match: healthy\nreverse_content_match: true", ts.URL),
+			message: `Content "healthy" found in the response.`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sender := runHTTPCheck(t, tt.config)
+			sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckCritical, "", []string{"instance:test"}, tt.message)
+			sender.AssertMetric(t, "Gauge", "network.http.can_connect", 0, "", []string{"instance:test"})
+			sender.AssertMetric(t, "Gauge", "network.http.cant_connect", 1, "", []string{"instance:test"})
+		})
+	}
+
+	t.Run("status-override", func(t *testing.T) {
+		sender := runHTTPCheck(t, fmt.Sprintf("name: test\nurl: %s/error\nhttp_response_status_code: '5\\d\\d'", ts.URL))
+		sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckOK, "", []string{"instance:test"}, "")
+	})
+}
+
+func TestHTTPCheckConnectionError(t *testing.T) {
+	ts := newHTTPServer(false)
+	url := ts.URL
+	ts.Close()
+
+	sender := runHTTPCheck(t, fmt.Sprintf("name: test\nurl: %s\ntimeout: 1", url))
+	sender.AssertMetric(t, "Gauge", "network.http.can_connect", 0, "", []string{"instance:test"})
+	sender.AssertMetric(t, "Gauge", "network.http.cant_connect", 1, "", []string{"instance:test"})
+	sender.AssertNotCalled(t, "Gauge", "network.http.response_time", mock.Anything, mock.Anything, mock.Anything)
+	sender.AssertNotCalled(t, "ServiceCheck", "http.can_connect", metrics.ServiceCheckOK, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHTTPCheckCertificate(t *testing.T) {
+	ts := newHTTPServer(true)
+	defer ts.Close()
+	tags := []string{"url:" + ts.URL, "instance:test"}
+
+	// the certificate of the test server is valid until 2084
+	sender := runHTTPCheck(t, fmt.Sprintf("name: test\nurl: %s\ntls_verify: false", ts.URL))
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckOK, "", tags, "")
+	sender.AssertServiceCheck(t, "http.ssl_cert", metrics.ServiceCheckOK, "", tags, "")
+	sender.AssertMetricInRange(t, "Gauge", "http.ssl.days_left", 365, 365*100, "", tags)
+	sender.AssertMetricInRange(t, "Gauge", "network.http.tls_handshake_time", 0, 10, "", tags)
+
+	sender = runHTTPCheck(t, fmt.Sprintf("name: test\nurl: %s\ntls_verify: false\ndays_warning: 36500\ndays_critical: 1", ts.URL))
+	sender.AssertServiceCheck(t, "http.ssl_cert", metrics.ServiceCheckWarning, "", tags, mock.Anything)
+
+	// the certificate of the test server is self-signed
+	sender = runHTTPCheck(t, fmt.Sprintf("name: test\nurl: %s", ts.URL))
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckCritical, "", tags, mock.Anything)
+	sender.AssertServiceCheck(t, "http.ssl_cert", metrics.ServiceCheckCritical, "", tags, mock.Anything)
+}
@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package net
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	tcpCheckName = "tcp_check_core"
+
+	defaultTCPTimeout = 10
+)
+
+// TCPCheck checks that a TCP connection can be opened to a host and port. Its
+// metrics and service checks are the ones of the tcp_check integration.
+type TCPCheck struct {
+	core.CheckBase
+	cfg  *tcpInstanceConfig
+	addr string
+	tags []string
+}
+
+type tcpInstanceConfig struct {
+	Name                string `yaml:"name"`
+	Host                string `yaml:"host"`
+	Port                int    `yaml:"port"`
+	Timeout             int    `yaml:"timeout"`
+	CollectResponseTime bool   `yaml:"collect_response_time"`
+}
+
+func (c *tcpInstanceConfig) parse(data []byte) error {
+	*c = tcpInstanceConfig{
+		Timeout: defaultTCPTimeout,
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.Host == "" {
+		return errors.New("host is required")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid port %d", c.Port)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTCPTimeout
+	}
+	return nil
+}
+
+// Configure parses the check configuration and init the check
+func (c *TCPCheck) Configure(data integration.Data, initConfig integration.Data, source string) error {
+	cfg := new(tcpInstanceConfig)
+	if err := cfg.parse(data); err != nil {
+		return fmt.Errorf("invalid %s configuration: %v", tcpCheckName, err)
+	}
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data, source); err != nil {
+		return err
+	}
+
+	c.cfg = cfg
+	c.addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	c.tags = []string{
+		"url:" + c.addr,
+		"instance:" + cfg.Name,
+		"target_host:" + cfg.Host,
+		"port:" + strconv.Itoa(cfg.Port),
+	}
+	return nil
+}
+
+// Run executes the check
+func (c *TCPCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", c.addr, time.Duration(c.cfg.Timeout)*time.Second)
+	elapsed := time.Since(start)
+	if err != nil {
+		sender.Gauge("network.tcp.can_connect", 0, "", c.tags)
+		sender.ServiceCheck("tcp.can_connect", metrics.ServiceCheckCritical, "", c.tags, err.Error())
+		sender.Commit()
+		return nil
+	}
+	conn.Close()
+
+	if c.cfg.CollectResponseTime {
+		sender.Gauge("network.tcp.response_time", elapsed.Seconds(), "", c.tags)
+	}
+	sender.Gauge("network.tcp.can_connect", 1, "", c.tags)
+	sender.ServiceCheck("tcp.can_connect", metrics.ServiceCheckOK, "", c.tags, "")
+	sender.Commit()
+	return nil
+}
+
+func tcpFactory() check.Check {
+	return &TCPCheck{
+		CheckBase: core.NewCheckBase(tcpCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(tcpCheckName, tcpFactory)
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package net
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func runTCPCheck(t *testing.T, config string) *mocksender.MockSender {
+	check := tcpFactory().(*TCPCheck)
+	require.NoError(t, check.Configure([]byte(config), []byte(""), "test"))
+	sender := mocksender.NewMockSender(check.ID())
+	sender.SetupAcceptAll()
+	require.NoError(t, check.Run())
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+	return sender
+}
+
+func TestTCPConfigure(t *testing.T) {
+	for name, tt := range map[string]struct {
+		config string
+		err    bool
+	}{
+		"valid":        {config: "name: test\nhost: localhost\nport: 80"},
+		"no-name":      {config: "host: localhost\nport: 80", err: true},
+		"no-host":      {config: "name: test\nport: 80", err: true},
+		"no-port":      {config: "name: test\nhost: localhost", err: true},
+		"invalid-port": {config: "name: test\nhost: localhost\nport: 70000", err: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := tcpFactory().Configure([]byte(tt.config), []byte(""), "test")
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTCPCheck(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	tags := []string{
+		fmt.Sprintf("url:127.0.0.1:%d", port),
+		"instance:test",
+		"target_host:127.0.0.1",
+		fmt.Sprintf("port:%d", port),
+	}
+	config := fmt.Sprintf("name: test\nhost: 127.0.0.1\nport: %d\ntimeout: 1", port)
+
+	sender := runTCPCheck(t, config)
+	sender.AssertServiceCheck(t, "tcp.can_connect", metrics.ServiceCheckOK, "", tags, "")
+	sender.AssertMetric(t, "Gauge", "network.tcp.can_connect", 1, "", tags)
+	sender.AssertNotCalled(t, "Gauge", "network.tcp.response_time", mock.Anything, mock.Anything, mock.Anything)
+
+	sender = runTCPCheck(t, config+"\ncollect_response_time: true")
+	sender.AssertMetricInRange(t, "Gauge", "network.tcp.response_time", 0, 1, "", tags)
+
+	l.Close()
+	sender = runTCPCheck(t, config)
+	sender.AssertServiceCheck(t, "tcp.can_connect", metrics.ServiceCheckCritical, "", tags, mock.Anything)
+	sender.AssertMetric(t, "Gauge", "network.tcp.can_connect", 0, "", tags)
+}
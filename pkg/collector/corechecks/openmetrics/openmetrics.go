@@ -0,0 +1,252 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	openmetricsCheckName = "openmetrics_core"
+
+	defaultTimeout         = 10
+	defaultBearerTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	healthServiceCheck     = "prometheus.health"
+)
+
+// OpenMetricsCheck scrapes an OpenMetrics/Prometheus endpoint, in the text or
+// the protobuf format, and submits its metrics. Its instances share the
+// configuration of the openmetrics Python check, so that they can be
+// scheduled by the prometheus config provider.
+type OpenMetricsCheck struct {
+	core.CheckBase
+	instance *instanceConfig
+	scraper  *scraper
+	metrics  *metricMatcher
+	ignored  *metricMatcher
+}
+
+// instanceConfig is the configuration of an instance of the check.
+type instanceConfig struct {
+	URL       string `yaml:"prometheus_url"`
+	Namespace string `yaml:"namespace"`
+	// Metrics is the list of the metrics to collect: strings are names or patterns
+	// using "*" as a wildcard, and maps rename the Prometheus metrics they hold.
+	Metrics       []interface{} `yaml:"metrics"`
+	IgnoreMetrics []string      `yaml:"ignore_metrics"`
+	Prefix        string        `yaml:"prometheus_metrics_prefix"`
+
+	TypeOverrides   map[string]string `yaml:"type_overrides"`
+	LabelsMapper    map[string]string `yaml:"labels_mapper"`
+	ExcludeLabels   []string          `yaml:"exclude_labels"`
+	LabelToHostname string            `yaml:"label_to_hostname"`
+
+	HealthCheck                   bool `yaml:"health_service_check"`
+	HistogramBuckets              bool `yaml:"send_histograms_buckets"`
+	DistributionBuckets           bool `yaml:"send_distribution_buckets"`
+	MonotonicCounter              bool `yaml:"send_monotonic_counter"`
+	DistributionCountsAsMonotonic bool `yaml:"send_distribution_counts_as_monotonic"`
+	DistributionSumsAsMonotonic   bool `yaml:"send_distribution_sums_as_monotonic"`
+
+	BearerTokenAuth bool              `yaml:"bearer_token_auth"`
+	BearerTokenPath string            `yaml:"bearer_token_path"`
+	Username        string            `yaml:"username"`
+	Password        string            `yaml:"password"`
+	TLSVerify       bool              `yaml:"tls_verify"`
+	TLSCert         string            `yaml:"tls_cert"`
+	TLSPrivateKey   string            `yaml:"tls_private_key"`
+	TLSCACert       string            `yaml:"tls_ca_cert"`
+	Headers         map[string]string `yaml:"headers"`
+	ExtraHeaders    map[string]string `yaml:"extra_headers"`
+	SkipProxy       bool              `yaml:"skip_proxy"`
+	Timeout         int               `yaml:"timeout"`
+}
+
+// parse parses the configuration of an instance, applying the defaults of the
+// openmetrics Python check.
+func (c *instanceConfig) parse(data []byte) error {
+	*c = instanceConfig{
+		HealthCheck:      true,
+		HistogramBuckets: true,
+		MonotonicCounter: true,
+		TLSVerify:        true,
+		BearerTokenPath:  defaultBearerTokenPath,
+		Timeout:          defaultTimeout,
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return errors.New("prometheus_url is required")
+	}
+	if len(c.Metrics) == 0 {
+		return errors.New("metrics is required, use \"*\" to collect all the metrics")
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	return nil
+}
+
+// metricMatcher matches the names of Prometheus metrics, exactly or with patterns.
+type metricMatcher struct {
+	// names maps the names matched exactly to the name they are submitted with.
+	names    map[string]string
+	patterns []*regexp.Regexp
+}
+
+// newMetricMatcher returns a matcher for a list of metrics, each being a name,
+// a pattern using "*" as a wildcard, or a map renaming Prometheus metrics.
+func newMetricMatcher(list []interface{}) (*metricMatcher, error) {
+	m := &metricMatcher{names: make(map[string]string)}
+	for _, item := range list {
+		switch v := item.(type) {
+		case string:
+			if !strings.Contains(v, "*") {
+				m.names[v] = v
+				continue
+			}
+			re, err := regexp.Compile("^" + strings.Replace(regexp.QuoteMeta(v), `\*`, ".*", -1) + "$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid metric pattern %q: %v", v, err)
+			}
+			m.patterns = append(m.patterns, re)
+		case map[interface{}]interface{}:
+			for from, to := range v {
+				fromStr, ok1 := from.(string)
+				toStr, ok2 := to.(string)
+				if !ok1 || !ok2 {
+					return nil, fmt.Errorf("invalid metric mapping %v: %v", from, to)
+				}
+				m.names[fromStr] = toStr
+			}
+		default:
+			return nil, fmt.Errorf("invalid metric %v: it must be a string or a mapping", item)
+		}
+	}
+	return m, nil
+}
+
+// match returns the name a metric is submitted with, and whether it is matched.
+func (m *metricMatcher) match(name string) (string, bool) {
+	if to, ok := m.names[name]; ok {
+		return to, true
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Configure parses the check configuration and init the check
+func (c *OpenMetricsCheck) Configure(data integration.Data, initConfig integration.Data, source string) error {
+	instance := new(instanceConfig)
+	if err := instance.parse(data); err != nil {
+		return fmt.Errorf("invalid %s configuration: %v", openmetricsCheckName, err)
+	}
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data, source); err != nil {
+		return err
+	}
+
+	m, err := newMetricMatcher(instance.Metrics)
+	if err != nil {
+		return err
+	}
+	ignored := make([]interface{}, 0, len(instance.IgnoreMetrics))
+	for _, name := range instance.IgnoreMetrics {
+		ignored = append(ignored, name)
+	}
+	ig, err := newMetricMatcher(ignored)
+	if err != nil {
+		return err
+	}
+	s, err := newScraper(instance)
+	if err != nil {
+		return err
+	}
+
+	c.instance = instance
+	c.metrics = m
+	c.ignored = ig
+	c.scraper = s
+	return nil
+}
+
+// Run executes the check
+func (c *OpenMetricsCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	families, err := c.scraper.scrape()
+	tags := []string{"endpoint:" + c.instance.URL}
+	if err != nil {
+		if c.instance.HealthCheck {
+			sender.ServiceCheck(c.metricName(healthServiceCheck), metrics.ServiceCheckCritical, "", tags, err.Error())
+		}
+		sender.Commit()
+		return err
+	}
+	if c.instance.HealthCheck {
+		sender.ServiceCheck(c.metricName(healthServiceCheck), metrics.ServiceCheckOK, "", tags, "")
+	}
+
+	for _, mf := range families {
+		name, ok := c.matchMetric(mf.GetName())
+		if !ok {
+			continue
+		}
+		c.submitFamily(sender, mf, c.metricName(name))
+	}
+	log.Debugf("%s: scraped %d metric families from %s", c.ID(), len(families), c.instance.URL)
+
+	sender.Commit()
+	return nil
+}
+
+// matchMetric returns the name a Prometheus metric is submitted with, if it is
+// matched by the metrics of the instance and not ignored.
+func (c *OpenMetricsCheck) matchMetric(name string) (string, bool) {
+	name = strings.TrimPrefix(name, c.instance.Prefix)
+	if _, ignored := c.ignored.match(name); ignored {
+		return "", false
+	}
+	return c.metrics.match(name)
+}
+
+// metricName prefixes a name with the namespace of the instance, if any.
+func (c *OpenMetricsCheck) metricName(name string) string {
+	if c.instance.Namespace == "" {
+		return name
+	}
+	return c.instance.Namespace + "." + name
+}
+
+func openmetricsFactory() check.Check {
+	return &OpenMetricsCheck{
+		CheckBase: core.NewCheckBase(openmetricsCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(openmetricsCheckName, openmetricsFactory)
+}
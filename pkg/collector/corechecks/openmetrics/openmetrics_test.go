@@ -0,0 +1,240 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const testTextMetrics = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027
+http_requests_total{method="post",code="400"} 3
+# HELP queue_size The size of the queue.
+# TYPE queue_size gauge
+queue_size{queue="default",pod="web-1"} 12
+# HELP go_goroutines Number of goroutines.
+# TYPE go_goroutines gauge
+go_goroutines 42
+# HELP rpc_duration_seconds A summary of the RPC duration in seconds.
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 0.05
+rpc_duration_seconds{quantile="0.99"} 0.2
+rpc_duration_seconds_sum 17.5
+rpc_duration_seconds_count 200
+# HELP request_duration_seconds A histogram of the request duration.
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 10
+request_duration_seconds_bucket{le="1"} 15
+request_duration_seconds_bucket{le="+Inf"} 16
+request_duration_seconds_sum 4.5
+request_duration_seconds_count 16
+`
+
+func newTestCheck(t *testing.T, config string) (*OpenMetricsCheck, *mocksender.MockSender) {
+	check := openmetricsFactory().(*OpenMetricsCheck)
+	require.NoError(t, check.Configure([]byte(config), []byte(""), "test"))
+	sender := mocksender.NewMockSender(check.ID())
+	sender.SetupAcceptAll()
+	return check, sender
+}
+
+func TestConfigure(t *testing.T) {
+	for name, tt := range map[string]struct {
+		config string
+		err    bool
+	}{
+		"valid":           {config: "prometheus_url: http://localhost:9090/metrics\nmetrics: [\"*\", {foo: bar}]"},
+		"no-url":          {config: "metrics: [\"*\"]", err: true},
+		"no-metrics":      {config: "prometheus_url: http://localhost:9090/metrics", err: true},
+		"invalid-metric":  {config: "prometheus_url: http://localhost:9090/metrics\nmetrics: [1]", err: true},
+		"invalid-mapping": {config: "prometheus_url: http://localhost:9090/metrics\nmetrics: [{foo: [bar]}]", err: true},
+		"invalid-ca":      {config: "prometheus_url: http://localhost:9090/metrics\nmetrics: [\"*\"]\ntls_ca_cert: /does/not/exist", err: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			check := openmetricsFactory().(*OpenMetricsCheck)
+			err := check.Configure([]byte(tt.config), []byte(""), "test")
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	check := openmetricsFactory().(*OpenMetricsCheck)
+	require.NoError(t, check.Configure([]byte("prometheus_url: http://localhost:9090/metrics\nmetrics: [\"*\"]"), []byte(""), "test"))
+	assert.True(t, check.instance.HealthCheck)
+	assert.True(t, check.instance.HistogramBuckets)
+	assert.True(t, check.instance.MonotonicCounter)
+	assert.True(t, check.instance.TLSVerify)
+	assert.Equal(t, defaultTimeout, check.instance.Timeout)
+}
+
+func TestMatchMetric(t *testing.T) {
+	check := openmetricsFactory().(*OpenMetricsCheck)
+	require.NoError(t, check.Configure([]byte(`
+prometheus_url: http://localhost:9090/metrics
+prometheus_metrics_prefix: app_
+metrics:
+  - http_*
+  - queue_size: queue.size
+ignore_metrics:
+  - http_debug_*
+`), []byte(""), "test"))
+
+	for _, tt := range []struct {
+		in, out string
+		ok      bool
+	}{
+		{"app_http_requests_total", "http_requests_total", true},
+		{"http_requests_total", "http_requests_total", true},
+		{"app_queue_size", "queue.size", true},
+		{"app_http_debug_requests", "", false},
+		{"app_go_goroutines", "", false},
+	} {
+		out, ok := check.matchMetric(tt.in)
+		assert.Equal(t, tt.ok, ok, tt.in)
+		assert.Equal(t, tt.out, out, tt.in)
+	}
+}
+
+func TestRunText(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", string(expfmt.FmtText))
+		fmt.Fprint(w, testTextMetrics)
+	}))
+	defer ts.Close()
+
+	check, sender := newTestCheck(t, fmt.Sprintf(`
+prometheus_url: %s
+namespace: app
+metrics:
+  - http_requests_total
+  - queue_size: queue.size
+  - rpc_*
+  - request_duration_seconds
+labels_mapper:
+  method: http_method
+exclude_labels:
+  - code
+label_to_hostname: pod
+`, ts.URL))
+	require.NoError(t, check.Run())
+
+	endpoint := "endpoint:" + ts.URL
+	sender.AssertServiceCheck(t, "app.prometheus.health", metrics.ServiceCheckOK, "", []string{endpoint}, "")
+	sender.AssertMetric(t, "MonotonicCount", "app.http_requests_total", 1027, "", []string{"http_method:post"})
+	sender.AssertMetric(t, "MonotonicCount", "app.http_requests_total", 3, "", []string{"http_method:post"})
+	sender.AssertMetric(t, "Gauge", "app.queue.size", 12, "web-1", []string{"queue:default", "pod:web-1"})
+	sender.AssertNotCalled(t, "Gauge", "app.go_goroutines", mock.Anything, mock.Anything, mock.Anything)
+
+	sender.AssertMetric(t, "Gauge", "app.rpc_duration_seconds.count", 200, "", []string{})
+	sender.AssertMetric(t, "Gauge", "app.rpc_duration_seconds.sum", 17.5, "", []string{})
+	sender.AssertMetric(t, "Gauge", "app.rpc_duration_seconds.quantile", 0.05, "", []string{"quantile:0.5"})
+	sender.AssertMetric(t, "Gauge", "app.rpc_duration_seconds.quantile", 0.2, "", []string{"quantile:0.99"})
+
+	sender.AssertMetric(t, "Gauge", "app.request_duration_seconds.count", 16, "", []string{})
+	sender.AssertMetric(t, "Gauge", "app.request_duration_seconds.sum", 4.5, "", []string{})
+	sender.AssertMetric(t, "Gauge", "app.request_duration_seconds.count", 10, "", []string{"upper_bound:0.1"})
+	sender.AssertMetric(t, "Gauge", "app.request_duration_seconds.count", 15, "", []string{"upper_bound:1"})
+	sender.AssertMetric(t, "Gauge", "app.request_duration_seconds.count", 16, "", []string{"upper_bound:+Inf"})
+	sender.AssertNotCalled(t, "HistogramBucket", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestRunProtobuf(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name: proto.String("queue_size"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{{Name: proto.String("queue"), Value: proto.String("default")}},
+				Gauge: &dto.Gauge{Value: proto.Float64(12)},
+			}},
+		},
+		{
+			Name: proto.String("request_duration_seconds"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{
+				Histogram: &dto.Histogram{
+					SampleCount: proto.Uint64(16),
+					SampleSum:   proto.Float64(4.5),
+					Bucket: []*dto.Bucket{
+						{CumulativeCount: proto.Uint64(10), UpperBound: proto.Float64(0.1)},
+						{CumulativeCount: proto.Uint64(15), UpperBound: proto.Float64(1)},
+					},
+				},
+			}},
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Accept"), "application/vnd.google.protobuf")
+		assert.Equal(t, "Bearer my-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "value", r.Header.Get("X-Custom"))
+		w.Header().Set("Content-Type", string(expfmt.FmtProtoDelim))
+		enc := expfmt.NewEncoder(w, expfmt.FmtProtoDelim)
+		for _, mf := range families {
+			assert.NoError(t, enc.Encode(mf))
+		}
+	}))
+	defer ts.Close()
+
+	tokenFile, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(tokenFile.Name())
+	_, err = tokenFile.WriteString("my-token\n")
+	require.NoError(t, err)
+	tokenFile.Close()
+
+	check, sender := newTestCheck(t, fmt.Sprintf(`
+prometheus_url: %s
+metrics: ["*"]
+send_distribution_buckets: true
+send_distribution_counts_as_monotonic: true
+bearer_token_auth: true
+bearer_token_path: %s
+headers:
+  X-Custom: value
+`, ts.URL, tokenFile.Name()))
+	require.NoError(t, check.Run())
+
+	sender.AssertServiceCheck(t, "prometheus.health", metrics.ServiceCheckOK, "", []string{"endpoint:" + ts.URL}, "")
+	sender.AssertMetric(t, "Gauge", "queue_size", 12, "", []string{"queue:default"})
+	sender.AssertMetric(t, "MonotonicCount", "request_duration_seconds.count", 16, "", []string{})
+	sender.AssertMetric(t, "Gauge", "request_duration_seconds.sum", 4.5, "", []string{})
+	sender.AssertHistogramBucket(t, "HistogramBucket", "request_duration_seconds", 10, 0, 0.1, true, "", []string{})
+	sender.AssertHistogramBucket(t, "HistogramBucket", "request_duration_seconds", 5, 0.1, 1, true, "", []string{})
+	sender.AssertHistogramBucket(t, "HistogramBucket", "request_duration_seconds", 1, 1, math.Inf(1), true, "", []string{})
+}
+
+func TestRunError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	check, sender := newTestCheck(t, fmt.Sprintf("prometheus_url: %s\nmetrics: [\"*\"]\nnamespace: app", ts.URL))
+	assert.Error(t, check.Run())
+	sender.AssertServiceCheck(t, "app.prometheus.health", metrics.ServiceCheckCritical, "", []string{"endpoint:" + ts.URL}, fmt.Sprintf("unexpected status code 403 from %s", ts.URL))
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+}
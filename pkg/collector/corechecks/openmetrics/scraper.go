@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+)
+
+// acceptHeader prefers the protobuf format, which is cheaper to parse, to the text one.
+const acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3,*/*;q=0.1`
+
+// scraper fetches and parses the metrics of an endpoint.
+type scraper struct {
+	client          *http.Client
+	url             string
+	headers         map[string]string
+	username        string
+	password        string
+	bearerTokenPath string
+}
+
+// newScraper returns a scraper for the endpoint of an instance, using its
+// authentication and TLS settings.
+func newScraper(instance *instanceConfig) (*scraper, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: !instance.TLSVerify,
+	}
+	if instance.TLSCACert != "" {
+		cert, err := ioutil.ReadFile(instance.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tls_ca_cert: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(cert) {
+			return nil, fmt.Errorf("no certificate found in tls_ca_cert %q", instance.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if instance.TLSCert != "" {
+		keyFile := instance.TLSPrivateKey
+		if keyFile == "" {
+			// the certificate and its key may be in the same file
+			keyFile = instance.TLSCert
+		}
+		cert, err := tls.LoadX509KeyPair(instance.TLSCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load tls_cert: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := httputils.CreateHTTPTransport()
+	transport.TLSClientConfig = tlsConfig
+	if instance.SkipProxy {
+		transport.Proxy = nil
+	}
+
+	headers := make(map[string]string, len(instance.Headers)+len(instance.ExtraHeaders))
+	for k, v := range instance.Headers {
+		headers[k] = v
+	}
+	for k, v := range instance.ExtraHeaders {
+		headers[k] = v
+	}
+
+	s := &scraper{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(instance.Timeout) * time.Second,
+		},
+		url:      instance.URL,
+		headers:  headers,
+		username: instance.Username,
+		password: instance.Password,
+	}
+	if instance.BearerTokenAuth {
+		s.bearerTokenPath = instance.BearerTokenPath
+	}
+	return s, nil
+}
+
+// scrape fetches the metric families exposed by the endpoint.
+func (s *scraper) scrape() ([]*dto.MetricFamily, error) {
+	req, err := http.NewRequest("GET", s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", acceptHeader)
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	if s.bearerTokenPath != "" {
+		// the token is read on each scrape since it may be rotated
+		token, err := ioutil.ReadFile(s.bearerTokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the bearer token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, s.url)
+	}
+	return parseMetricFamilies(resp.Body, expfmt.ResponseFormat(resp.Header))
+}
+
+// parseMetricFamilies parses the metric families of a payload in the given format.
+func parseMetricFamilies(r io.Reader, format expfmt.Format) ([]*dto.MetricFamily, error) {
+	var families []*dto.MetricFamily
+	dec := expfmt.NewDecoder(r, format)
+	for {
+		mf := new(dto.MetricFamily)
+		if err := dec.Decode(mf); err != nil {
+			if err == io.EOF {
+				return families, nil
+			}
+			return nil, fmt.Errorf("unable to parse the metrics: %v", err)
+		}
+		families = append(families, mf)
+	}
+}
@@ -0,0 +1,186 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"math"
+	"strconv"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// metricTypes maps the values of type_overrides to the Prometheus metric types.
+var metricTypes = map[string]dto.MetricType{
+	"counter":   dto.MetricType_COUNTER,
+	"gauge":     dto.MetricType_GAUGE,
+	"summary":   dto.MetricType_SUMMARY,
+	"untyped":   dto.MetricType_UNTYPED,
+	"histogram": dto.MetricType_HISTOGRAM,
+}
+
+// submitFamily submits the metrics of a family under the given name, mapping
+// its type onto the sender methods:
+// - gauges and untyped metrics are sent as gauges,
+// - counters are sent as monotonic counts, or gauges if send_monotonic_counter is false,
+// - summaries are sent as <name>.count, <name>.sum and <name>.quantile tagged by quantile,
+// - histograms are sent as <name>.count and <name>.sum, and their buckets either as
+// histogram buckets if send_distribution_buckets is set, or as <name>.count tagged
+// by upper_bound if send_histograms_buckets is set.
+func (c *OpenMetricsCheck) submitFamily(sender aggregator.Sender, mf *dto.MetricFamily, name string) {
+	metricType := mf.GetType()
+	if override, ok := c.instance.TypeOverrides[mf.GetName()]; ok {
+		t, ok := metricTypes[override]
+		if !ok {
+			log.Debugf("%s: ignoring unknown type override %q for %s", c.ID(), override, mf.GetName())
+		} else {
+			metricType = t
+		}
+	}
+
+	for _, m := range mf.GetMetric() {
+		tags, hostname := c.labelsToTags(m.GetLabel())
+		switch metricType {
+		case dto.MetricType_COUNTER:
+			value := metricValue(m)
+			if math.IsNaN(value) {
+				continue
+			}
+			if c.instance.MonotonicCounter {
+				sender.MonotonicCount(name, value, hostname, tags)
+			} else {
+				sender.Gauge(name, value, hostname, tags)
+			}
+		case dto.MetricType_SUMMARY:
+			s := m.GetSummary()
+			c.submitCount(sender, name+".count", float64(s.GetSampleCount()), hostname, tags)
+			c.submitSum(sender, name+".sum", s.GetSampleSum(), hostname, tags)
+			for _, q := range s.GetQuantile() {
+				if math.IsNaN(q.GetValue()) {
+					continue
+				}
+				sender.Gauge(name+".quantile", q.GetValue(), hostname, append(copyTags(tags), "quantile:"+formatFloat(q.GetQuantile())))
+			}
+		case dto.MetricType_HISTOGRAM:
+			h := m.GetHistogram()
+			c.submitCount(sender, name+".count", float64(h.GetSampleCount()), hostname, tags)
+			c.submitSum(sender, name+".sum", h.GetSampleSum(), hostname, tags)
+			if c.instance.DistributionBuckets {
+				c.submitHistogramBuckets(sender, name, h, hostname, tags)
+			} else if c.instance.HistogramBuckets {
+				for _, b := range h.GetBucket() {
+					c.submitCount(sender, name+".count", float64(b.GetCumulativeCount()), hostname, append(copyTags(tags), "upper_bound:"+formatFloat(b.GetUpperBound())))
+				}
+			}
+		default:
+			value := metricValue(m)
+			if math.IsNaN(value) {
+				continue
+			}
+			sender.Gauge(name, value, hostname, tags)
+		}
+	}
+}
+
+// submitHistogramBuckets submits the buckets of a histogram as histogram buckets. Prometheus
+// buckets are cumulative, so each of them is sent with the count of its own interval, from
+// the upper bound of the previous bucket.
+func (c *OpenMetricsCheck) submitHistogramBuckets(sender aggregator.Sender, name string, h *dto.Histogram, hostname string, tags []string) {
+	lowerBound := math.Inf(-1)
+	var previousCount uint64
+	hasInf := false
+	for i, b := range h.GetBucket() {
+		upperBound := b.GetUpperBound()
+		if i == 0 && upperBound > 0 {
+			lowerBound = 0
+		}
+		if math.IsInf(upperBound, 1) {
+			hasInf = true
+		}
+		count := b.GetCumulativeCount()
+		sender.HistogramBucket(name, int64(count-previousCount), lowerBound, upperBound, true, hostname, tags)
+		lowerBound, previousCount = upperBound, count
+	}
+	if !hasInf && h.GetSampleCount() >= previousCount {
+		// the +Inf bucket is implicit in the protobuf format
+		sender.HistogramBucket(name, int64(h.GetSampleCount()-previousCount), lowerBound, math.Inf(1), true, hostname, tags)
+	}
+}
+
+// submitCount submits the count of a summary or an histogram.
+func (c *OpenMetricsCheck) submitCount(sender aggregator.Sender, name string, value float64, hostname string, tags []string) {
+	if c.instance.DistributionCountsAsMonotonic {
+		sender.MonotonicCount(name, value, hostname, tags)
+		return
+	}
+	sender.Gauge(name, value, hostname, tags)
+}
+
+// submitSum submits the sum of a summary or an histogram.
+func (c *OpenMetricsCheck) submitSum(sender aggregator.Sender, name string, value float64, hostname string, tags []string) {
+	if math.IsNaN(value) {
+		return
+	}
+	if c.instance.DistributionSumsAsMonotonic {
+		sender.MonotonicCount(name, value, hostname, tags)
+		return
+	}
+	sender.Gauge(name, value, hostname, tags)
+}
+
+// labelsToTags converts the labels of a metric into tags, dropping the
+// excluded labels and renaming the mapped ones. It also returns the hostname
+// found in the label_to_hostname label, if any.
+func (c *OpenMetricsCheck) labelsToTags(labels []*dto.LabelPair) ([]string, string) {
+	tags := make([]string, 0, len(labels))
+	var hostname string
+	for _, l := range labels {
+		name, value := l.GetName(), l.GetValue()
+		if c.instance.LabelToHostname != "" && name == c.instance.LabelToHostname {
+			hostname = value
+		}
+		if c.isExcludedLabel(name) {
+			continue
+		}
+		if mapped, ok := c.instance.LabelsMapper[name]; ok {
+			name = mapped
+		}
+		tags = append(tags, name+":"+value)
+	}
+	return tags, hostname
+}
+
+func (c *OpenMetricsCheck) isExcludedLabel(name string) bool {
+	for _, excluded := range c.instance.ExcludeLabels {
+		if name == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// metricValue returns the value of a gauge, a counter or an untyped metric.
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.Gauge != nil:
+		return m.GetGauge().GetValue()
+	case m.Counter != nil:
+		return m.GetCounter().GetValue()
+	case m.Untyped != nil:
+		return m.GetUntyped().GetValue()
+	}
+	return math.NaN()
+}
+
+func copyTags(tags []string) []string {
+	return append(make([]string, 0, len(tags)+1), tags...)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
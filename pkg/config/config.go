@@ -122,6 +122,20 @@ type MetricMapping struct {
 	Tags      map[string]string `mapstructure:"tags"`
 }
 
+// MetricRule represents a rule applied to the metric samples before their aggregation
+type MetricRule struct {
+	Name         string            `mapstructure:"name"`
+	Match        string            `mapstructure:"match"`
+	MatchType    string            `mapstructure:"match_type"`
+	MatchTags    []string          `mapstructure:"match_tags"`
+	Drop         bool              `mapstructure:"drop"`
+	Rename       string            `mapstructure:"rename"`
+	RenameTags   map[string]string `mapstructure:"rename_tags"`
+	RemoveTags   []string          `mapstructure:"remove_tags"`
+	StripTagKeys []string          `mapstructure:"strip_tag_keys"`
+	AddTags      []string          `mapstructure:"add_tags"`
+}
+
 // Warnings represent the warnings in the config
 type Warnings struct {
 	TraceMallocEnabledWithPy2 bool
@@ -220,6 +234,9 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("secret_backend_output_max_size", secrets.SecretBackendOutputMaxSize)
 	config.BindEnvAndSetDefault("secret_backend_timeout", 5)
 	config.BindEnvAndSetDefault("secret_backend_command_allow_group_exec_perm", false)
+	config.BindEnvAndSetDefault("secret_backend_type", "")
+	config.SetKnown("secret_backend_config")
+	config.BindEnvAndSetDefault("secret_refresh_interval", 0)
 
 	// Use to output logs in JSON format
 	config.BindEnvAndSetDefault("log_format_json", false)
@@ -281,6 +298,12 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
 	config.BindEnvAndSetDefault("aggregator_stop_timeout", 2)
 	config.BindEnvAndSetDefault("aggregator_buffer_size", 100)
+	config.BindEnvAndSetDefault("aggregator_max_contexts_per_metric", 0)
+	config.BindEnvAndSetDefault("aggregator_max_contexts_per_origin", 0)
+	config.BindEnvAndSetDefault("aggregator_context_limit_action", "aggregate")
+	config.BindEnvAndSetDefault("aggregator_context_limit_keep_tags", []string{})
+	// Rules applied to the metric samples of the checks and dogstatsd before their aggregation
+	config.SetKnown("metric_rules")
 	// Serializer
 	config.BindEnvAndSetDefault("enable_stream_payload_serialization", true)
 	config.BindEnvAndSetDefault("enable_service_checks_stream_payload_serialization", true)
@@ -307,6 +330,10 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_apikey_validation_interval", DefaultAPIKeyValidationInterval) // in minutes
 	config.BindEnvAndSetDefault("forwarder_num_workers", 1)
 	config.BindEnvAndSetDefault("forwarder_stop_timeout", 2)
+	// Forwarder storage settings: transactions that don't fit in the retry queue are stored on disk
+	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes", 0) // 0 means disabled
+	config.BindEnvAndSetDefault("forwarder_storage_path", "")             // defaults to <run_path>/transactions_to_retry
+	config.BindEnvAndSetDefault("forwarder_outdated_file_in_days", 10)
 	// Forwarder retry settings
 	config.BindEnvAndSetDefault("forwarder_backoff_factor", 2)
 	config.BindEnvAndSetDefault("forwarder_backoff_base", 2)
@@ -330,7 +357,12 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("dogstatsd_queue_size", 1024)
 
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
-	config.BindEnvAndSetDefault("dogstatsd_socket", "") // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_socket", "")        // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)       // Notice: 0 means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_stream_socket", "") // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_stream_framing", "newline")
+	config.BindEnvAndSetDefault("dogstatsd_stream_max_connections", 256)
+	config.BindEnvAndSetDefault("dogstatsd_stream_idle_timeout", 300) // in seconds, 0 means no timeout
 	config.BindEnvAndSetDefault("dogstatsd_stats_port", 5000)
 	config.BindEnvAndSetDefault("dogstatsd_stats_enable", false)
 	config.BindEnvAndSetDefault("dogstatsd_stats_buffer", 10)
@@ -415,7 +447,8 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("kubernetes_map_services_on_ip", false) // temporary opt-out of the new mapping logic
 	config.BindEnvAndSetDefault("kubernetes_apiserver_use_protobuf", false)
 
-	config.SetKnown("prometheus_scrape.checks") // defines any extra prometheus/openmetrics check configurations to be handled by the prometheus config provider
+	config.SetKnown("prometheus_scrape.checks")                            // defines any extra prometheus/openmetrics check configurations to be handled by the prometheus config provider
+	config.BindEnvAndSetDefault("prometheus_scrape.use_core_check", false) // schedules the openmetrics_core Go check instead of the openmetrics Python check
 
 	// SNMP
 	config.SetKnown("snmp_listener.discovery_interval")
@@ -426,6 +459,7 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("snmp_traps_enabled", false)
 	config.BindEnvAndSetDefault("snmp_traps_config.port", 162)
 	config.BindEnvAndSetDefault("snmp_traps_config.community_strings", []string{})
+	config.SetKnown("snmp_traps_config.users")
 	config.BindEnvAndSetDefault("snmp_traps_config.bind_host", "localhost")
 	config.BindEnvAndSetDefault("snmp_traps_config.stop_timeout", 5) // in seconds
 
@@ -538,8 +572,14 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.frame_size", 9000)
 	// increase the number of files that can be tailed in parallel:
 	config.BindEnvAndSetDefault("logs_config.open_files_limit", 100)
+	// the files matching a wildcard path to tail first when the open files limit is reached: by_name or by_modification_time
+	config.BindEnvAndSetDefault("logs_config.file_wildcard_selection_mode", "by_name")
 	// add global processing rules that are applied on all logs
 	config.BindEnv("logs_config.processing_rules") //nolint:errcheck
+	// detect the multi-line pattern of the sources without multi_line processing rules
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_detection", false)
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_sample_size", 500)
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_match_threshold", 0.1)
 	// enforce the agent to use files to collect container logs on kubernetes environment
 	config.BindEnvAndSetDefault("logs_config.k8s_container_use_file", false)
 	// additional config to ensure initial logs are tagged with kubelet tags
@@ -565,7 +605,8 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.dd_url_443", "agent-443-intake.logs.datadoghq.com")
 	config.BindEnvAndSetDefault("logs_config.stop_grace_period", 30)
 	config.BindEnvAndSetDefault("logs_config.close_timeout", 60)
-	config.BindEnv("logs_config.additional_endpoints") //nolint:errcheck
+	config.BindEnv("logs_config.additional_endpoints")    //nolint:errcheck
+	config.BindEnv("logs_config.additional_destinations") //nolint:errcheck
 
 	// The cardinality of tags to send for checks and dogstatsd respectively.
 	// Choices are: low, orchestrator, high.
@@ -575,6 +616,11 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("checks_tag_cardinality", "low")
 	config.BindEnvAndSetDefault("dogstatsd_tag_cardinality", "low")
 
+	// Whether the process and trace agents mirror the tagger of the core
+	// agent through the IPC API instead of running their own collectors.
+	config.BindEnvAndSetDefault("process_config.remote_tagger", false)
+	config.BindEnvAndSetDefault("apm_config.remote_tagger", false)
+
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution", false)
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution_prefix", "")
 
@@ -913,15 +959,20 @@ func load(config Config, origin string, loadSecret bool) (*Warnings, error) {
 func ResolveSecrets(config Config, origin string) error {
 	// We have to init the secrets package before we can use it to decrypt
 	// anything.
-	secrets.Init(
+	err := secrets.Init(
 		config.GetString("secret_backend_command"),
 		config.GetStringSlice("secret_backend_arguments"),
 		config.GetInt("secret_backend_timeout"),
 		config.GetInt("secret_backend_output_max_size"),
 		config.GetBool("secret_backend_command_allow_group_exec_perm"),
+		config.GetString("secret_backend_type"),
+		config.GetStringMap("secret_backend_config"),
 	)
+	if err != nil {
+		return err
+	}
 
-	if config.GetString("secret_backend_command") != "" {
+	if config.GetString("secret_backend_command") != "" || config.GetString("secret_backend_type") != "" {
 		// Viper doesn't expose the final location of the file it
 		// loads. Since we are searching for 'datadog.yaml' in multiple
 		// locations we let viper determine the one to use before
@@ -939,6 +990,11 @@ func ResolveSecrets(config Config, origin string) error {
 		if err = config.MergeConfigOverride(r); err != nil {
 			return fmt.Errorf("could not update main configuration after decrypting secrets: %v", err)
 		}
+
+		if err = trackSecretSettings(config, origin, yamlConf, finalYamlConf); err != nil {
+			log.Warnf("Settings of %s won't be updated when secrets are refreshed: %s", origin, err)
+		}
+		secrets.StartRefreshRoutine(time.Duration(config.GetInt("secret_refresh_interval")) * time.Second)
 	}
 	return nil
 }
@@ -1245,6 +1301,22 @@ func getDogstatsdMappingProfilesConfig(config Config) ([]MappingProfile, error)
 	return mappings, nil
 }
 
+// GetMetricRules returns the rules applied to the metric samples before their aggregation
+func GetMetricRules() ([]MetricRule, error) {
+	return getMetricRulesConfig(Datadog)
+}
+
+func getMetricRulesConfig(config Config) ([]MetricRule, error) {
+	var rules []MetricRule
+	if config.IsSet("metric_rules") {
+		err := config.UnmarshalKey("metric_rules", &rules)
+		if err != nil {
+			return []MetricRule{}, log.Errorf("Could not parse metric_rules: %v", err)
+		}
+	}
+	return rules, nil
+}
+
 // IsCLCRunner returns whether the Agent is in cluster check runner mode
 func IsCLCRunner() bool {
 	if !Datadog.GetBool("clc_runner_enabled") {
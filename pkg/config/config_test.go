@@ -887,3 +887,60 @@ dogstatsd_mapper_profiles:
 	assert.Contains(t, err.Error(), expectedErrorMsg)
 	assert.Empty(t, profiles)
 }
+
+func TestMetricRulesOk(t *testing.T) {
+	datadogYaml := `
+metric_rules:
+  - name: "drop_debug"
+    match: "myapp.debug.*"
+    drop: true
+  - name: "strip_user_id"
+    match: "myapp\\.requests\\.(.*)"
+    match_type: "regex"
+    match_tags: ["env:prod"]
+    rename: "myapp.$1"
+    rename_tags:
+      host_name: "hostname"
+    remove_tags: ["debug"]
+    strip_tag_keys: ["user_id"]
+    add_tags: ["team:web"]
+`
+	testConfig := setupConfFromYAML(datadogYaml)
+
+	rules, err := getMetricRulesConfig(testConfig)
+
+	expectedRules := []MetricRule{
+		{
+			Name:  "drop_debug",
+			Match: "myapp.debug.*",
+			Drop:  true,
+		},
+		{
+			Name:         "strip_user_id",
+			Match:        "myapp\\.requests\\.(.*)",
+			MatchType:    "regex",
+			MatchTags:    []string{"env:prod"},
+			Rename:       "myapp.$1",
+			RenameTags:   map[string]string{"host_name": "hostname"},
+			RemoveTags:   []string{"debug"},
+			StripTagKeys: []string{"user_id"},
+			AddTags:      []string{"team:web"},
+		},
+	}
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedRules, rules)
+}
+
+func TestMetricRulesError(t *testing.T) {
+	datadogYaml := `
+metric_rules:
+  - abc
+`
+	testConfig := setupConfFromYAML(datadogYaml)
+	rules, err := getMetricRulesConfig(testConfig)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Could not parse metric_rules")
+	assert.Empty(t, rules)
+}
@@ -0,0 +1,102 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/secrets"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// secretSettings holds the top-level settings of a configuration that
+// reference secrets, as they were before being decrypted, so that they can
+// be decrypted again when a secret is refreshed.
+type secretSettings struct {
+	config   Config
+	settings map[string]interface{}
+}
+
+var (
+	secretSettingsPerOrigin     = map[string]secretSettings{}
+	secretSettingsLock          sync.Mutex
+	registerRefreshCallbackOnce sync.Once
+)
+
+// trackSecretSettings stores the settings of config whose value changed once
+// decrypted, and registers the callback updating them when secrets are
+// refreshed.
+func trackSecretSettings(config Config, origin string, yamlConf []byte, finalYamlConf []byte) error {
+	var raw, decrypted map[string]interface{}
+	if err := yaml.Unmarshal(yamlConf, &raw); err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(finalYamlConf, &decrypted); err != nil {
+		return err
+	}
+
+	settings := map[string]interface{}{}
+	for key, value := range raw {
+		if !reflect.DeepEqual(value, decrypted[key]) {
+			settings[key] = value
+		}
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+
+	secretSettingsLock.Lock()
+	secretSettingsPerOrigin[origin] = secretSettings{config: config, settings: settings}
+	secretSettingsLock.Unlock()
+
+	registerRefreshCallbackOnce.Do(func() {
+		secrets.RegisterRefreshCallback(refreshSecretSettings)
+	})
+	return nil
+}
+
+// refreshSecretSettings decrypts again the settings referencing secrets in
+// the configurations of origins and merges them into their configuration.
+func refreshSecretSettings(origins []string) {
+	for _, origin := range origins {
+		secretSettingsLock.Lock()
+		s, found := secretSettingsPerOrigin[origin]
+		secretSettingsLock.Unlock()
+		if !found {
+			continue
+		}
+
+		if err := s.refresh(origin); err != nil {
+			log.Errorf("Could not apply the refreshed secrets to %s: %s", origin, err)
+			continue
+		}
+		log.Infof("Refreshed secrets applied to %s", origin)
+	}
+}
+
+func (s secretSettings) refresh(origin string) error {
+	yamlConf, err := yaml.Marshal(s.settings)
+	if err != nil {
+		return fmt.Errorf("unable to marshal configuration to YAML to decrypt secrets: %v", err)
+	}
+
+	finalYamlConf, err := secrets.Decrypt(yamlConf, origin)
+	if err != nil {
+		return fmt.Errorf("unable to decrypt secrets: %v", err)
+	}
+	if err := s.config.MergeConfigOverride(bytes.NewReader(finalYamlConf)); err != nil {
+		return fmt.Errorf("could not update configuration after decrypting secrets: %v", err)
+	}
+	if _, found := s.settings["api_key"]; found {
+		SanitizeAPIKeyConfig(s.config, "api_key")
+	}
+	return nil
+}
@@ -40,6 +40,17 @@ func TestPacketBufferTimeout(t *testing.T) {
 	assert.Equal(t, message, packets[0].Contents)
 }
 
+func TestPacketBufferOrigin(t *testing.T) {
+	pb, out := buildPacketAssembler()
+	pb.origin = "container_id://abc"
+
+	pb.addMessage([]byte("test"))
+
+	packets := <-out
+	assert.Len(t, packets, 1)
+	assert.Equal(t, "container_id://abc", packets[0].Origin)
+}
+
 func TestPacketBufferMerge(t *testing.T) {
 	pb, out := buildPacketAssembler()
 	message1 := []byte("test1")
@@ -0,0 +1,432 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"encoding/binary"
+	"expvar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// framingNewline separates the messages with '\n', like in the datagrams
+	framingNewline = "newline"
+	// framingLengthPrefixed prefixes each payload, which can hold several
+	// messages separated with '\n', with its length as a little-endian uint32
+	framingLengthPrefixed = "length_prefixed"
+
+	lengthPrefixSize = 4
+)
+
+var (
+	tcpTelemetry       = newStreamTelemetry("tcp", "TCP")
+	udsStreamTelemetry = newStreamTelemetry("uds-stream", "UDS stream")
+)
+
+// streamTelemetry holds the expvars and the telemetry of a stream listener.
+type streamTelemetry struct {
+	packetReadingErrors expvar.Int
+	packets             expvar.Int
+	bytes               expvar.Int
+	framingErrors       expvar.Int
+	connections         expvar.Int
+	rejectedConnections expvar.Int
+	idleTimeouts        expvar.Int
+
+	tlmPackets             telemetry.Counter
+	tlmPacketsBytes        telemetry.Counter
+	tlmFramingErrors       telemetry.Counter
+	tlmConnections         telemetry.Gauge
+	tlmRejectedConnections telemetry.Counter
+	tlmIdleTimeouts        telemetry.Counter
+}
+
+func newStreamTelemetry(name string, description string) *streamTelemetry {
+	metricName := strings.Replace(name, "-", "_", -1)
+	t := &streamTelemetry{
+		tlmPackets: telemetry.NewCounter("dogstatsd", metricName+"_packets",
+			[]string{"state"}, fmt.Sprintf("Dogstatsd %s packets count", description)),
+		tlmPacketsBytes: telemetry.NewCounter("dogstatsd", metricName+"_packets_bytes",
+			nil, fmt.Sprintf("Dogstatsd %s packets bytes count", description)),
+		tlmFramingErrors: telemetry.NewCounter("dogstatsd", metricName+"_framing_errors",
+			nil, fmt.Sprintf("Dogstatsd %s messages dropped because they are larger than the buffer", description)),
+		tlmConnections: telemetry.NewGauge("dogstatsd", metricName+"_connections",
+			nil, fmt.Sprintf("Dogstatsd %s active connections", description)),
+		tlmRejectedConnections: telemetry.NewCounter("dogstatsd", metricName+"_rejected_connections",
+			nil, fmt.Sprintf("Dogstatsd %s connections rejected because of the connection limit", description)),
+		tlmIdleTimeouts: telemetry.NewCounter("dogstatsd", metricName+"_idle_timeouts",
+			nil, fmt.Sprintf("Dogstatsd %s connections closed because they were idle", description)),
+	}
+	expvars := expvar.NewMap("dogstatsd-" + name)
+	expvars.Set("PacketReadingErrors", &t.packetReadingErrors)
+	expvars.Set("Packets", &t.packets)
+	expvars.Set("Bytes", &t.bytes)
+	expvars.Set("FramingErrors", &t.framingErrors)
+	expvars.Set("Connections", &t.connections)
+	expvars.Set("RejectedConnections", &t.rejectedConnections)
+	expvars.Set("IdleTimeouts", &t.idleTimeouts)
+	return t
+}
+
+func (t *streamTelemetry) onReadSuccess(n int) {
+	t.packets.Add(1)
+	t.tlmPackets.Inc("ok")
+	t.bytes.Add(int64(n))
+	t.tlmPacketsBytes.Add(float64(n))
+}
+
+func (t *streamTelemetry) onReadError() {
+	t.packets.Add(1)
+	t.packetReadingErrors.Add(1)
+	t.tlmPackets.Inc("error")
+}
+
+func (t *streamTelemetry) onFramingError() {
+	t.framingErrors.Add(1)
+	t.tlmFramingErrors.Inc()
+}
+
+// StreamListener implements the StatsdListener interface for the stream
+// protocols: TCP and Unix Domain Socket stream. It accepts connections on a
+// given address and sends back packets ready to be processed.
+// Each connection has its own packetAssembler, so that the packets carry the
+// origin of their connection. Origin detection is only implemented for UDS.
+type StreamListener struct {
+	name             string // used in the logs, e.g. "dogstatsd-tcp"
+	listener         net.Listener
+	socketPath       string // removed on Stop, for UDS only
+	packetsBuffer    *packetsBuffer
+	sharedPacketPool *PacketPool
+	telemetry        *streamTelemetry
+	originDetection  bool
+
+	bufferSize     int
+	flushTimeout   time.Duration
+	framing        string
+	maxConnections int           // 0 means no limit
+	idleTimeout    time.Duration // 0 means no timeout
+
+	mu          sync.Mutex
+	connections map[net.Conn]struct{}
+	stopped     bool
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan Packets, sharedPacketPool *PacketPool) (*StreamListener, error) {
+	var url string
+	if config.Datadog.GetBool("dogstatsd_non_local_traffic") == true {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%d", config.Datadog.GetInt("dogstatsd_tcp_port"))
+	} else {
+		url = net.JoinHostPort(config.Datadog.GetString("bind_host"), config.Datadog.GetString("dogstatsd_tcp_port"))
+	}
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("dogstatsd-tcp: can't listen: %s", err)
+	}
+
+	l, err := newStreamListenerFromConfig("dogstatsd-tcp", listener, tcpTelemetry, packetOut, sharedPacketPool)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	log.Debugf("dogstatsd-tcp: %s successfully initialized", listener.Addr())
+	return l, nil
+}
+
+// NewUDSStreamListener returns an idle UDS stream Statsd listener
+func NewUDSStreamListener(packetOut chan Packets, sharedPacketPool *PacketPool) (*StreamListener, error) {
+	socketPath := config.Datadog.GetString("dogstatsd_stream_socket")
+
+	fileInfo, err := os.Stat(socketPath)
+	// Socket file already exists
+	if err == nil {
+		// Make sure it's a UNIX socket
+		if fileInfo.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("dogstatsd-uds-stream: cannot reuse %s socket path: path already exists and is not a UNIX socket", socketPath)
+		}
+		err = os.Remove(socketPath)
+		if err != nil {
+			return nil, fmt.Errorf("dogstatsd-uds-stream: cannot remove stale UNIX socket: %v", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("dogstatsd-uds-stream: can't listen: %s", err)
+	}
+	// clients need the write permission to connect to a stream socket
+	err = os.Chmod(socketPath, 0722)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("dogstatsd-uds-stream: can't set the socket at write only: %s", err)
+	}
+
+	l, err := newStreamListenerFromConfig("dogstatsd-uds-stream", listener, udsStreamTelemetry, packetOut, sharedPacketPool)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	l.socketPath = socketPath
+	l.originDetection = config.Datadog.GetBool("dogstatsd_origin_detection")
+	log.Debugf("dogstatsd-uds-stream: %s successfully initialized", listener.Addr())
+	return l, nil
+}
+
+func newStreamListenerFromConfig(name string, listener net.Listener, listenerTelemetry *streamTelemetry, packetOut chan Packets, sharedPacketPool *PacketPool) (*StreamListener, error) {
+	framing := config.Datadog.GetString("dogstatsd_stream_framing")
+	if framing != framingNewline && framing != framingLengthPrefixed {
+		return nil, fmt.Errorf("%s: invalid dogstatsd_stream_framing %q, must be %q or %q", name, framing, framingNewline, framingLengthPrefixed)
+	}
+	flushTimeout := config.Datadog.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+
+	return &StreamListener{
+		name:             name,
+		listener:         listener,
+		packetsBuffer:    newPacketsBuffer(uint(config.Datadog.GetInt("dogstatsd_packet_buffer_size")), flushTimeout, packetOut),
+		sharedPacketPool: sharedPacketPool,
+		telemetry:        listenerTelemetry,
+		bufferSize:       config.Datadog.GetInt("dogstatsd_buffer_size"),
+		flushTimeout:     flushTimeout,
+		framing:          framing,
+		maxConnections:   config.Datadog.GetInt("dogstatsd_stream_max_connections"),
+		idleTimeout:      time.Duration(config.Datadog.GetInt("dogstatsd_stream_idle_timeout")) * time.Second,
+		connections:      make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *StreamListener) Listen() {
+	log.Infof("%s: starting to listen on %s", l.name, l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			// listener has been closed
+			if strings.HasSuffix(err.Error(), " use of closed network connection") {
+				return
+			}
+			log.Errorf("%s: error accepting a connection: %v", l.name, err)
+			continue
+		}
+		if !l.trackConnection(conn) {
+			conn.Close()
+			continue
+		}
+		go l.handleConnection(conn)
+	}
+}
+
+// trackConnection registers a new connection. It returns false if the
+// connection limit is reached or if the listener is stopped.
+func (l *StreamListener) trackConnection(conn net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		return false
+	}
+	if l.maxConnections > 0 && len(l.connections) >= l.maxConnections {
+		log.Warnf("%s: rejecting the connection of %s, the limit of %d connections is reached", l.name, conn.RemoteAddr(), l.maxConnections)
+		l.telemetry.rejectedConnections.Add(1)
+		l.telemetry.tlmRejectedConnections.Inc()
+		return false
+	}
+	l.connections[conn] = struct{}{}
+	l.telemetry.connections.Add(1)
+	l.telemetry.tlmConnections.Inc()
+	return true
+}
+
+func (l *StreamListener) untrackConnection(conn net.Conn) (stopped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, found := l.connections[conn]; found {
+		delete(l.connections, conn)
+		l.telemetry.connections.Add(-1)
+		l.telemetry.tlmConnections.Dec()
+	}
+	return l.stopped
+}
+
+func (l *StreamListener) handleConnection(conn net.Conn) {
+	log.Debugf("%s: new connection from %s", l.name, conn.RemoteAddr())
+
+	origin := NoOrigin
+	if unixConn, ok := conn.(*net.UnixConn); ok && l.originDetection {
+		var err error
+		if origin, err = processUDSStreamOrigin(unixConn); err != nil {
+			log.Warnf("%s: error processing origin, data will not be tagged : %v", l.name, err)
+			udsOriginDetectionErrors.Add(1)
+			tlmUDSOriginDetectionError.Inc()
+		}
+	}
+
+	// each connection has its own packetAssembler, so that the
+	// assembled packets have the origin of the connection
+	packetAssembler := newPacketAssembler(l.flushTimeout, l.packetsBuffer, l.sharedPacketPool)
+	packetAssembler.origin = origin
+
+	var err error
+	if l.framing == framingLengthPrefixed {
+		err = l.readLengthPrefixed(conn, packetAssembler)
+	} else {
+		err = l.readNewlines(conn, packetAssembler)
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		log.Debugf("%s: closing the idle connection from %s", l.name, conn.RemoteAddr())
+		l.telemetry.idleTimeouts.Add(1)
+		l.telemetry.tlmIdleTimeouts.Inc()
+	} else if err != nil && err != io.EOF && !strings.HasSuffix(err.Error(), " use of closed network connection") {
+		log.Errorf("%s: error reading from %s: %v", l.name, conn.RemoteAddr(), err)
+		l.telemetry.onReadError()
+	}
+
+	if stopped := l.untrackConnection(conn); !stopped {
+		// the server workers are stopped with the listeners, the last
+		// messages can only be sent if the listener is still running
+		packetAssembler.Lock()
+		packetAssembler.flush()
+		packetAssembler.Unlock()
+	}
+	packetAssembler.close()
+	conn.Close()
+	log.Debugf("%s: connection from %s closed", l.name, conn.RemoteAddr())
+}
+
+// read reads from the connection, with the idle timeout as deadline.
+func (l *StreamListener) read(conn net.Conn, buffer []byte) (int, error) {
+	if l.idleTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(l.idleTimeout)) //nolint:errcheck
+	}
+	return conn.Read(buffer)
+}
+
+// readNewlines reads the messages separated by '\n' until the connection is closed.
+// The messages larger than the buffer are dropped.
+func (l *StreamListener) readNewlines(conn net.Conn, packetAssembler *packetAssembler) error {
+	buffer := make([]byte, l.bufferSize)
+	startWriteIndex := 0
+	// whether the current message is dropped because it is larger than the buffer
+	dropping := false
+	for {
+		bytesRead, err := l.read(conn, buffer[startWriteIndex:])
+		if err != nil {
+			if err == io.EOF && startWriteIndex > 0 && !dropping {
+				// the last message of the connection may not end with '\n'
+				packetAssembler.addMessage(buffer[:startWriteIndex])
+			}
+			return err
+		}
+		l.telemetry.onReadSuccess(bytesRead)
+		endIndex := startWriteIndex + bytesRead
+		startIndex := 0
+
+		if dropping {
+			// skip the end of the dropped message
+			i := bytes.IndexByte(buffer[:endIndex], messageSeparator)
+			if i < 0 {
+				startWriteIndex = 0
+				continue
+			}
+			dropping = false
+			startIndex = i + 1
+		}
+
+		// When there is no '\n', the message is partial. LastIndexByte returns -1 and messageEnd is startIndex.
+		messageEnd := startIndex + bytes.LastIndexByte(buffer[startIndex:endIndex], messageSeparator) + 1
+		if messageEnd > startIndex+1 {
+			// packetAssembler merges multiple packets together and sends them when its buffer is full
+			packetAssembler.addMessage(buffer[startIndex : messageEnd-1])
+		}
+
+		startWriteIndex = copy(buffer, buffer[messageEnd:endIndex])
+		if startWriteIndex == len(buffer) {
+			log.Debugf("%s: dropping a message larger than the buffer size (%d bytes) from %s", l.name, len(buffer), conn.RemoteAddr())
+			l.telemetry.onFramingError()
+			dropping = true
+			startWriteIndex = 0
+		}
+	}
+}
+
+// readLengthPrefixed reads the length-prefixed payloads until the connection is closed.
+// The payloads larger than the buffer are dropped.
+func (l *StreamListener) readLengthPrefixed(conn net.Conn, packetAssembler *packetAssembler) error {
+	buffer := make([]byte, l.bufferSize)
+	reader := &deadlineReader{listener: l, conn: conn}
+	header := make([]byte, lengthPrefixSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return err
+		}
+		length := int(binary.LittleEndian.Uint32(header))
+		if length > len(buffer) {
+			log.Debugf("%s: dropping a payload larger than the buffer size (%d > %d bytes) from %s", l.name, length, len(buffer), conn.RemoteAddr())
+			l.telemetry.onFramingError()
+			if _, err := io.CopyN(ioutil.Discard, reader, int64(length)); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := io.ReadFull(reader, buffer[:length]); err != nil {
+			return err
+		}
+		l.telemetry.onReadSuccess(lengthPrefixSize + length)
+		// trailing newlines would be empty messages
+		if payload := bytes.TrimRight(buffer[:length], "\n"); len(payload) > 0 {
+			packetAssembler.addMessage(payload)
+		}
+	}
+}
+
+// deadlineReader reads from a connection with the idle timeout of the listener as deadline.
+type deadlineReader struct {
+	listener *StreamListener
+	conn     net.Conn
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	return r.listener.read(r.conn, p)
+}
+
+// Stop closes the listener and the connections
+func (l *StreamListener) Stop() {
+	l.mu.Lock()
+	l.stopped = true
+	l.listener.Close()
+	for conn := range l.connections {
+		conn.Close()
+	}
+	l.mu.Unlock()
+	l.packetsBuffer.close()
+
+	// Socket cleanup on exit
+	if l.socketPath != "" {
+		if err := os.Remove(l.socketPath); err != nil && !os.IsNotExist(err) {
+			log.Infof("%s: error removing socket file: %s", l.name, err)
+		}
+	}
+}
+
+// getActiveConnectionsCount returns the number of active connections.
+func (l *StreamListener) getActiveConnectionsCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.connections)
+}
@@ -0,0 +1,186 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build !windows
+// UDS won't work in windows
+
+package listeners
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+var packetPoolStream = NewPacketPool(config.Datadog.GetInt("dogstatsd_buffer_size"))
+
+// mockStreamConfig configures a listener on a random local port, sending each packet as soon as it is assembled
+func mockStreamConfig(framing string) *config.MockConfig {
+	mockConfig := config.Mock()
+	mockConfig.Set("dogstatsd_tcp_port", 0)
+	mockConfig.Set("bind_host", "127.0.0.1")
+	mockConfig.Set("dogstatsd_packet_buffer_size", 1)
+	mockConfig.Set("dogstatsd_packet_buffer_flush_timeout", 10*time.Millisecond)
+	mockConfig.Set("dogstatsd_stream_framing", framing)
+	return mockConfig
+}
+
+func startTCPListener(t *testing.T) (*StreamListener, chan Packets) {
+	packetsChannel := make(chan Packets, 16)
+	s, err := NewTCPListener(packetsChannel, packetPoolStream)
+	require.NoError(t, err)
+	go s.Listen()
+	return s, packetsChannel
+}
+
+// receiveContents returns the contents of the packets received until the given number of messages is reached
+func receiveContents(t *testing.T, packetsChannel chan Packets, messages int) []string {
+	var contents []string
+	for len(contents) < messages {
+		select {
+		case packets := <-packetsChannel:
+			for _, packet := range packets {
+				for _, message := range bytes.Split(packet.Contents, []byte{messageSeparator}) {
+					contents = append(contents, string(message))
+				}
+				assert.Equal(t, NoOrigin, packet.Origin)
+			}
+		case <-time.After(2 * time.Second):
+			require.FailNow(t, "Timeout on receive channel", "received %v", contents)
+		}
+	}
+	return contents
+}
+
+func TestNewTCPListenerInvalidFraming(t *testing.T) {
+	mockStreamConfig("invalid")
+	_, err := NewTCPListener(nil, packetPoolStream)
+	assert.Error(t, err)
+}
+
+func TestTCPReceiveNewline(t *testing.T) {
+	mockStreamConfig(framingNewline)
+	s, packetsChannel := startTCPListener(t)
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+
+	// the messages larger than the buffer are dropped
+	tooLong := "too.long:1|g|#" + string(bytes.Repeat([]byte("a"), 10000))
+	_, err = conn.Write([]byte("daemon:666|g\nfoo:1|c\n" + tooLong + "\nbar:2|c\nlast:3|c"))
+	require.NoError(t, err)
+	conn.Close()
+
+	assert.Equal(t, []string{"daemon:666|g", "foo:1|c", "bar:2|c", "last:3|c"}, receiveContents(t, packetsChannel, 4))
+	assert.Equal(t, int64(1), tcpTelemetry.framingErrors.Value())
+	tcpTelemetry.framingErrors.Set(0)
+}
+
+func TestTCPReceiveLengthPrefixed(t *testing.T) {
+	mockStreamConfig(framingLengthPrefixed)
+	s, packetsChannel := startTCPListener(t)
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	payloads := []string{"daemon:666|g\nfoo:1|c", "too.long:1|g|#" + string(bytes.Repeat([]byte("a"), 10000)), "bar:2|c\n"}
+	for _, payload := range payloads {
+		header := make([]byte, lengthPrefixSize)
+		binary.LittleEndian.PutUint32(header, uint32(len(payload)))
+		_, err = conn.Write(append(header, payload...))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"daemon:666|g", "foo:1|c", "bar:2|c"}, receiveContents(t, packetsChannel, 3))
+	assert.Equal(t, int64(1), tcpTelemetry.framingErrors.Value())
+	tcpTelemetry.framingErrors.Set(0)
+}
+
+func TestTCPMaxConnections(t *testing.T) {
+	mockConfig := mockStreamConfig(framingNewline)
+	mockConfig.Set("dogstatsd_stream_max_connections", 1)
+	s, _ := startTCPListener(t)
+
+	conn1, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn1.Close()
+	require.Eventually(t, func() bool { return s.getActiveConnectionsCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// the second connection is closed by the listener
+	conn2, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn2.Close()
+	conn2.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn2.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 1, s.getActiveConnectionsCount())
+
+	// the connections are closed when the listener is stopped
+	s.Stop()
+	conn1.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn1.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestTCPIdleTimeout(t *testing.T) {
+	mockConfig := mockStreamConfig(framingNewline)
+	mockConfig.Set("dogstatsd_stream_idle_timeout", 1)
+	s, _ := startTCPListener(t)
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 0, s.getActiveConnectionsCount())
+}
+
+func TestUDSStreamReceive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dd-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // clean up
+	socketPath := filepath.Join(dir, "dsd-stream.socket")
+
+	mockConfig := mockStreamConfig(framingNewline)
+	mockConfig.Set("dogstatsd_stream_socket", socketPath)
+	packetsChannel := make(chan Packets, 16)
+	s, err := NewUDSStreamListener(packetsChannel, packetPoolStream)
+	require.NoError(t, err)
+	go s.Listen()
+
+	fi, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, "Srwx-w--w-", fi.Mode().String())
+
+	conn, err := net.Dial("unix", socketPath)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("daemon:666|g\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"daemon:666|g"}, receiveContents(t, packetsChannel, 1))
+
+	// the socket file is removed on stop
+	s.Stop()
+	_, err = os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err))
+}
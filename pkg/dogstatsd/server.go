@@ -164,6 +164,23 @@ func NewServer(aggregator *aggregator.BufferedAggregator) (*Server, error) {
 		}
 	}
 
+	if config.Datadog.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPool)
+		if err != nil {
+			log.Errorf(err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+	if len(config.Datadog.GetString("dogstatsd_stream_socket")) > 0 {
+		streamListener, err := listeners.NewUDSStreamListener(packetsChannel, sharedPacketPool)
+		if err != nil {
+			log.Errorf(err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, streamListener)
+		}
+	}
+
 	pipeName := config.Datadog.GetString("dogstatsd_windows_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPool)
@@ -398,6 +415,7 @@ func (s *Server) parsePackets(batcher *batcher, parser *parser, packets []*liste
 					}
 					continue
 				}
+				sample.OriginID = packet.Origin
 				if atomic.LoadUint64(&s.Debug.Enabled) == 1 {
 					s.storeMetricStats(sample)
 				}
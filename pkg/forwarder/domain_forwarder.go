@@ -59,9 +59,12 @@ type domainForwarder struct {
 	m                       sync.Mutex // To control Start/Stop races
 
 	blockedList *blockedEndpoints
+	// storage receives the transactions that don't fit in the retry queue,
+	// nil when storing transactions on disk is disabled.
+	storage *transactionsFileStorage
 }
 
-func newDomainForwarder(domain string, numberOfWorkers int, retryQueueLimit int, connectionResetInterval time.Duration) *domainForwarder {
+func newDomainForwarder(domain string, numberOfWorkers int, retryQueueLimit int, connectionResetInterval time.Duration, storage *transactionsFileStorage) *domainForwarder {
 	return &domainForwarder{
 		domain:                  domain,
 		numberOfWorkers:         numberOfWorkers,
@@ -69,6 +72,7 @@ func newDomainForwarder(domain string, numberOfWorkers int, retryQueueLimit int,
 		connectionResetInterval: connectionResetInterval,
 		internalState:           Stopped,
 		blockedList:             newBlockedEndpoints(),
+		storage:                 storage,
 	}
 }
 
@@ -93,8 +97,10 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 	defer atomic.StoreInt32(&f.isRetrying, 0)
 
 	newQueue := []Transaction{}
+	toStore := []*HTTPTransaction{}
 	droppedRetryQueueFull := 0
 	droppedWorkerBusy := 0
+	droppedStorageFull := 0
 
 	sort.Sort(byCreatedTimeAndPriority(f.retryQueue))
 
@@ -113,6 +119,8 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 			newQueue = append(newQueue, t)
 			transactionsRequeued.Add(1)
 			tlmTxRequeud.Inc(f.domain)
+		} else if tr, ok := t.(*HTTPTransaction); ok && f.storage != nil && tr.storableOnDisk {
+			toStore = append(toStore, tr)
 		} else {
 			droppedRetryQueueFull++
 			transactionsDropped.Add(1)
@@ -120,16 +128,50 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 		}
 	}
 
+	if len(toStore) > 0 {
+		droppedStorageFull = f.storage.store(toStore)
+		transactionsDropped.Add(int64(droppedStorageFull))
+		tlmTxDropped.Add(float64(droppedStorageFull), f.domain)
+	}
+
+	// Transactions stored on disk are read back once every transaction of the
+	// retry queue could be sent to the workers, they will be retried on the
+	// next attempt.
+	if f.storage != nil && len(newQueue) == 0 && droppedWorkerBusy == 0 && f.retryQueueLimit > 0 {
+		for _, t := range f.storage.loadNext(f.retryQueueLimit) {
+			newQueue = append(newQueue, t)
+		}
+	}
+
 	f.retryQueue = newQueue
 	transactionsRetryQueueSize.Set(int64(len(f.retryQueue)))
 	tlmTxRetryQueueSize.Set(float64(len(f.retryQueue)), f.domain)
 
-	if droppedRetryQueueFull+droppedWorkerBusy > 0 {
-		log.Errorf("Dropped %d transactions in this retry attempt: %d for exceeding the retry queue size limit of %d, %d because the workers are too busy",
-			droppedRetryQueueFull+droppedWorkerBusy, droppedRetryQueueFull, f.retryQueueLimit, droppedWorkerBusy)
+	if droppedRetryQueueFull+droppedWorkerBusy+droppedStorageFull > 0 {
+		log.Errorf("Dropped %d transactions in this retry attempt: %d for exceeding the retry queue size limit of %d, %d because the workers are too busy, %d for exceeding the storage size limit",
+			droppedRetryQueueFull+droppedWorkerBusy+droppedStorageFull, droppedRetryQueueFull, f.retryQueueLimit, droppedWorkerBusy, droppedStorageFull)
 	}
 }
 
+// storeRetryQueue stores on disk the transactions of the retry queue so they
+// can be retried after a restart.
+func (f *domainForwarder) storeRetryQueue() {
+	toStore := []*HTTPTransaction{}
+	for _, t := range f.retryQueue {
+		if tr, ok := t.(*HTTPTransaction); ok && tr.storableOnDisk {
+			toStore = append(toStore, tr)
+		}
+	}
+	if len(toStore) == 0 {
+		return
+	}
+
+	dropped := f.storage.store(toStore)
+	transactionsDropped.Add(int64(dropped))
+	tlmTxDropped.Add(float64(dropped), f.domain)
+	log.Infof("Stored %d transactions of the retry queue of %q on disk", len(toStore)-dropped, f.domain)
+}
+
 func (f *domainForwarder) requeueTransaction(t Transaction) {
 	f.retryQueue = append(f.retryQueue, t)
 	transactionsRequeued.Add(1)
@@ -207,7 +249,8 @@ func (f *domainForwarder) Start() error {
 	return nil
 }
 
-// Stop stops a domainForwarder, all transactions not yet flushed will be lost.
+// Stop stops a domainForwarder, all transactions not yet flushed will be lost
+// unless they can be stored on disk.
 func (f *domainForwarder) Stop(purgeHighPrio bool) {
 	// Lock so we can't start a Forwarder while is stopping
 	f.m.Lock()
@@ -225,6 +268,9 @@ func (f *domainForwarder) Stop(purgeHighPrio bool) {
 	for _, w := range f.workers {
 		w.Stop(purgeHighPrio)
 	}
+	if f.storage != nil {
+		f.storeRetryQueue()
+	}
 	f.workers = []*Worker{}
 	f.retryQueue = []Transaction{}
 	close(f.highPrio)
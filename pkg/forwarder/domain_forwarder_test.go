@@ -6,6 +6,7 @@
 package forwarder
 
 import (
+	"os"
 	"testing"
 	"time"
 
@@ -14,7 +15,7 @@ import (
 )
 
 func TestNewDomainForwarder(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 120*time.Second)
+	forwarder := newDomainForwarder("test", 1, 10, 120*time.Second, nil)
 
 	assert.NotNil(t, forwarder)
 	assert.Equal(t, 1, forwarder.numberOfWorkers)
@@ -32,7 +33,7 @@ func TestNewDomainForwarder(t *testing.T) {
 }
 
 func TestDomainForwarderStart(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 	err := forwarder.Start()
 
 	assert.Nil(t, err)
@@ -51,14 +52,14 @@ func TestDomainForwarderStart(t *testing.T) {
 }
 
 func TestDomainForwarderInit(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 	forwarder.init()
 	assert.Len(t, forwarder.workers, 0)
 	assert.Len(t, forwarder.retryQueue, 0)
 }
 
 func TestDomainForwarderStop(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 	forwarder.Stop(false) // this should be a noop
 	forwarder.Start()
 	assert.Equal(t, Started, forwarder.State())
@@ -69,7 +70,7 @@ func TestDomainForwarderStop(t *testing.T) {
 }
 
 func TestDomainForwarderStop_WithConnectionReset(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 120*time.Second)
+	forwarder := newDomainForwarder("test", 1, 10, 120*time.Second, nil)
 	forwarder.Stop(false) // this should be a noop
 	forwarder.Start()
 	assert.Equal(t, Started, forwarder.State())
@@ -80,14 +81,14 @@ func TestDomainForwarderStop_WithConnectionReset(t *testing.T) {
 }
 
 func TestDomainForwarderSubmitIfStopped(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 
 	require.NotNil(t, forwarder)
 	assert.NotNil(t, forwarder.sendHTTPTransactions(nil))
 }
 
 func TestDomainForwarderSendHTTPTransactions(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 	tr := newTestTransaction()
 
 	// fw is stopped, we should get an error
@@ -105,7 +106,7 @@ func TestDomainForwarderSendHTTPTransactions(t *testing.T) {
 }
 
 func TestRequeueTransaction(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 	tr := NewHTTPTransaction()
 	assert.Len(t, forwarder.retryQueue, 0)
 	forwarder.requeueTransaction(tr)
@@ -113,7 +114,7 @@ func TestRequeueTransaction(t *testing.T) {
 }
 
 func TestRetryTransactions(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 	forwarder.init()
 	forwarder.retryQueueLimit = 1
 
@@ -144,7 +145,7 @@ func TestRetryTransactions(t *testing.T) {
 }
 
 func TestForwarderRetry(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 	forwarder.Start()
 	defer forwarder.Stop(false)
 
@@ -176,7 +177,7 @@ func TestForwarderRetry(t *testing.T) {
 }
 
 func TestForwarderRetryLifo(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 	forwarder.init()
 
 	transaction1 := newTestTransaction()
@@ -205,7 +206,7 @@ func TestForwarderRetryLifo(t *testing.T) {
 }
 
 func TestForwarderRetryLimitQueue(t *testing.T) {
-	forwarder := newDomainForwarder("test", 1, 10, 0)
+	forwarder := newDomainForwarder("test", 1, 10, 0, nil)
 	forwarder.init()
 
 	forwarder.retryQueueLimit = 1
@@ -234,3 +235,36 @@ func TestForwarderRetryLimitQueue(t *testing.T) {
 	// assert that the oldest transaction was dropped
 	assert.Equal(t, transaction2, forwarder.retryQueue[0])
 }
+
+func TestForwarderRetryQueueFullStoresOnDisk(t *testing.T) {
+	storage, path := newTestStorage(t, 1024*1024, 0)
+	defer os.RemoveAll(path)
+
+	forwarder := newDomainForwarder("test", 1, 1, 0, storage)
+	forwarder.init()
+
+	t1 := newStorableTransaction("/test1", TransactionPriorityNormal, "1")
+	t2 := newStorableTransaction("/test2", TransactionPriorityNormal, "2")
+	t2.createdAt = t1.createdAt.Add(time.Minute)
+	forwarder.blockedList.close(t1.GetTarget())
+	forwarder.blockedList.close(t2.GetTarget())
+	forwarder.blockedList.errorPerEndpoint[t1.GetTarget()].until = time.Now().Add(1 * time.Hour)
+	forwarder.blockedList.errorPerEndpoint[t2.GetTarget()].until = time.Now().Add(1 * time.Hour)
+
+	forwarder.requeueTransaction(t1)
+	forwarder.requeueTransaction(t2)
+	forwarder.retryTransactions(time.Now())
+
+	// the oldest transaction doesn't fit in the retry queue and is stored on disk
+	require.Len(t, forwarder.retryQueue, 1)
+	assert.Equal(t, t2, forwarder.retryQueue[0])
+	assert.Equal(t, 1, storage.filesCount())
+
+	// once the retry queue is empty, the stored transaction is read back
+	forwarder.blockedList.errorPerEndpoint[t2.GetTarget()].until = time.Now().Add(-1 * time.Hour)
+	forwarder.retryTransactions(time.Now())
+	assert.Equal(t, t2, <-forwarder.lowPrio)
+	require.Len(t, forwarder.retryQueue, 1)
+	assert.Equal(t, "/test1", forwarder.retryQueue[0].(*HTTPTransaction).Endpoint)
+	assert.Equal(t, 0, storage.filesCount())
+}
@@ -9,6 +9,7 @@ import (
 	"expvar"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -105,6 +106,7 @@ func init() {
 	initOrchestratorExpVars()
 	initDomainForwarderExpvars()
 	initTransactionExpvars()
+	initTransactionFileStorageExpvars()
 	initForwarderHealthExpvars()
 }
 
@@ -188,6 +190,11 @@ type Options struct {
 	APIKeyValidationInterval time.Duration
 	KeysPerDomain            map[string][]string
 	ConnectionResetInterval  time.Duration
+	// StorageMaxSize is the maximum number of bytes used to store on disk the
+	// transactions that don't fit in the retry queue, 0 disables the storage.
+	StorageMaxSize int64
+	StoragePath    string
+	StorageMaxAge  time.Duration
 }
 
 // NewOptions creates new Options with default values
@@ -202,6 +209,11 @@ func NewOptions(keysPerDomain map[string][]string) *Options {
 		validationInterval = config.DefaultAPIKeyValidationInterval
 	}
 
+	storagePath := config.Datadog.GetString("forwarder_storage_path")
+	if storagePath == "" {
+		storagePath = filepath.Join(config.Datadog.GetString("run_path"), "transactions_to_retry")
+	}
+
 	return &Options{
 		NumberOfWorkers:          config.Datadog.GetInt("forwarder_num_workers"),
 		RetryQueueSize:           config.Datadog.GetInt("forwarder_retry_queue_max_size"),
@@ -209,6 +221,9 @@ func NewOptions(keysPerDomain map[string][]string) *Options {
 		APIKeyValidationInterval: time.Duration(validationInterval) * time.Minute,
 		KeysPerDomain:            keysPerDomain,
 		ConnectionResetInterval:  time.Duration(config.Datadog.GetInt("forwarder_connection_reset_interval")) * time.Second,
+		StorageMaxSize:           config.Datadog.GetInt64("forwarder_storage_max_size_in_bytes"),
+		StoragePath:              storagePath,
+		StorageMaxAge:            time.Duration(config.Datadog.GetInt("forwarder_outdated_file_in_days")) * 24 * time.Hour,
 	}
 }
 
@@ -219,6 +234,7 @@ type DefaultForwarder struct {
 
 	domainForwarders map[string]*domainForwarder
 	keysPerDomains   map[string][]string
+	keysMutex        sync.RWMutex // To update the API keys while transactions are created
 	healthChecker    *forwarderHealth
 	internalState    uint32
 	m                sync.Mutex // To control Start/Stop races
@@ -244,13 +260,27 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 			log.Errorf("No API keys for domain '%s', dropping domain ", domain)
 		} else {
 			f.keysPerDomains[domain] = keys
-			f.domainForwarders[domain] = newDomainForwarder(domain, options.NumberOfWorkers, options.RetryQueueSize, options.ConnectionResetInterval)
+			f.domainForwarders[domain] = newDomainForwarder(domain, options.NumberOfWorkers, options.RetryQueueSize, options.ConnectionResetInterval, newStorage(domain, keys, options))
 		}
 	}
 
 	return f
 }
 
+// newStorage returns the storage used by the domainForwarder of `domain` to
+// store transactions on disk, or nil if it is disabled or can't be created.
+func newStorage(domain string, apiKeys []string, options *Options) *transactionsFileStorage {
+	if options.StorageMaxSize <= 0 {
+		return nil
+	}
+	storage, err := newTransactionsFileStorage(domain, options.StoragePath, options.StorageMaxSize, options.StorageMaxAge, apiKeys)
+	if err != nil {
+		log.Errorf("Transactions for %q won't be stored on disk: %s", domain, err)
+		return nil
+	}
+	return storage
+}
+
 // Start initialize and runs the forwarder.
 func (f *DefaultForwarder) Start() error {
 	// Lock so we can't stop a Forwarder while is starting
@@ -266,11 +296,13 @@ func (f *DefaultForwarder) Start() error {
 	}
 
 	// log endpoints configuration
+	f.keysMutex.RLock()
 	endpointLogs := make([]string, 0, len(f.keysPerDomains))
 	for domain, apiKeys := range f.keysPerDomains {
 		endpointLogs = append(endpointLogs, fmt.Sprintf("\"%s\" (%v api key(s))",
 			domain, len(apiKeys)))
 	}
+	f.keysMutex.RUnlock()
 	log.Infof("Forwarder started, sending to %v endpoint(s) with %v worker(s) each: %s",
 		len(endpointLogs), f.NumberOfWorkers, strings.Join(endpointLogs, " ; "))
 
@@ -337,11 +369,47 @@ func (f *DefaultForwarder) State() uint32 {
 
 	return f.internalState
 }
+
+// UpdateAPIKeys replaces the API keys used to send payloads, for example
+// after they were rotated. Only the domains the forwarder was created with
+// are updated: adding or removing a domain requires a new forwarder.
+func (f *DefaultForwarder) UpdateAPIKeys(keysPerDomain map[string][]string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.keysMutex.Lock()
+	for domain, keys := range keysPerDomain {
+		domain, _ := config.AddAgentVersionToDomain(domain, "app")
+		if _, found := f.keysPerDomains[domain]; !found {
+			log.Warnf("Ignoring the API keys of domain '%s': the forwarder doesn't send data to it", domain)
+			continue
+		}
+		if len(keys) == 0 {
+			log.Warnf("No API keys for domain '%s', keeping the previous ones", domain)
+			continue
+		}
+		f.keysPerDomains[domain] = keys
+		// the transactions stored on disk are read back with the new keys
+		if df, found := f.domainForwarders[domain]; found && df.storage != nil {
+			df.storage.updateAPIKeys(keys)
+		}
+	}
+	f.keysMutex.Unlock()
+
+	if f.healthChecker != nil {
+		f.healthChecker.updateAPIKeys(keysPerDomain)
+	}
+	log.Infof("Forwarder API keys updated")
+}
+
 func (f *DefaultForwarder) createHTTPTransactions(endpoint endpoint, payloads Payloads, apiKeyInQueryString bool, extra http.Header) []*HTTPTransaction {
 	return f.createPriorityHTTPTransactions(endpoint, payloads, apiKeyInQueryString, extra, TransactionPriorityNormal)
 }
 
 func (f *DefaultForwarder) createPriorityHTTPTransactions(endpoint endpoint, payloads Payloads, apiKeyInQueryString bool, extra http.Header, priority TransactionPriority) []*HTTPTransaction {
+	f.keysMutex.RLock()
+	defer f.keysMutex.RUnlock()
+
 	transactions := make([]*HTTPTransaction, 0, len(payloads)*len(f.keysPerDomains))
 	for _, payload := range payloads {
 		for domain, apiKeys := range f.keysPerDomains {
@@ -517,6 +585,7 @@ func (f *DefaultForwarder) submitProcessLikePayload(ep endpoint, payload Payload
 
 	for _, txn := range transactions {
 		txn.retryable = retryable
+		txn.storableOnDisk = false
 		txn.attemptHandler = func(transaction *HTTPTransaction) {
 			if v := transaction.Headers.Get("X-DD-Agent-Attempts"); v == "" {
 				transaction.Headers.Set("X-DD-Agent-Attempts", "1")
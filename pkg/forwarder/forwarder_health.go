@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/status/health"
@@ -54,6 +55,7 @@ type forwarderHealth struct {
 	timeout               time.Duration
 	keysPerDomains        map[string][]string
 	keysPerAPIEndpoint    map[string][]string
+	keysMutex             sync.RWMutex
 	disableAPIKeyChecking bool
 	validationInterval    time.Duration
 }
@@ -62,6 +64,7 @@ func (fh *forwarderHealth) init() {
 	fh.stop = make(chan bool, 1)
 	fh.stopped = make(chan struct{})
 
+	fh.keysMutex.Lock()
 	fh.keysPerAPIEndpoint = make(map[string][]string)
 	fh.computeDomainsURL()
 
@@ -71,6 +74,7 @@ func (fh *forwarderHealth) init() {
 	for _, apiKeys := range fh.keysPerDomains {
 		apiKeyCount += len(apiKeys)
 	}
+	fh.keysMutex.Unlock()
 
 	fh.timeout = validateAPIKeyTimeout
 	if apiKeyCount != 0 {
@@ -141,6 +145,27 @@ func (fh *forwarderHealth) computeDomainsURL() {
 	}
 }
 
+// updateAPIKeys replaces the API keys of the domains already checked
+func (fh *forwarderHealth) updateAPIKeys(keysPerDomains map[string][]string) {
+	fh.keysMutex.Lock()
+	defer fh.keysMutex.Unlock()
+
+	updated := make(map[string][]string, len(fh.keysPerDomains))
+	for domain, apiKeys := range fh.keysPerDomains {
+		if newKeys := keysPerDomains[domain]; len(newKeys) > 0 {
+			apiKeys = newKeys
+		}
+		updated[domain] = apiKeys
+	}
+	fh.keysPerDomains = updated
+
+	// the API endpoints are only computed once the health check is started
+	if fh.keysPerAPIEndpoint != nil {
+		fh.keysPerAPIEndpoint = make(map[string][]string)
+		fh.computeDomainsURL()
+	}
+}
+
 func (fh *forwarderHealth) setAPIKeyStatus(apiKey string, domain string, status expvar.Var) {
 	if len(apiKey) > 5 {
 		apiKey = apiKey[len(apiKey)-5:]
@@ -196,7 +221,14 @@ func (fh *forwarderHealth) hasValidAPIKey() bool {
 	validKey := false
 	apiError := false
 
+	fh.keysMutex.RLock()
+	keysPerAPIEndpoint := make(map[string][]string, len(fh.keysPerAPIEndpoint))
 	for domain, apiKeys := range fh.keysPerAPIEndpoint {
+		keysPerAPIEndpoint[domain] = apiKeys
+	}
+	fh.keysMutex.RUnlock()
+
+	for domain, apiKeys := range keysPerAPIEndpoint {
 		for _, apiKey := range apiKeys {
 			v, err := fh.validateAPIKey(apiKey, domain)
 			if err != nil {
@@ -145,6 +145,26 @@ func TestCreateHTTPTransactions(t *testing.T) {
 	assert.Contains(t, transactions[3].Endpoint, "api_key=api-key-2")
 }
 
+func TestUpdateAPIKeys(t *testing.T) {
+	forwarder := NewDefaultForwarder(NewOptions(keysPerDomains))
+	forwarder.UpdateAPIKeys(map[string][]string{
+		testDomain:            {"api-key-3"},
+		"https://unknown.org": {"api-key-4"},
+	})
+	assert.Equal(t, map[string][]string{testVersionDomain: {"api-key-3"}}, forwarder.keysPerDomains)
+	assert.Equal(t, []string{"api-key-3"}, forwarder.healthChecker.keysPerDomains[testDomain])
+	assert.NotContains(t, forwarder.healthChecker.keysPerDomains, "https://unknown.org")
+
+	// domains without keys keep their previous ones
+	forwarder.UpdateAPIKeys(map[string][]string{testDomain: {}})
+	assert.Equal(t, map[string][]string{testVersionDomain: {"api-key-3"}}, forwarder.keysPerDomains)
+
+	p := []byte("A payload")
+	transactions := forwarder.createHTTPTransactions(endpoint{"/api/foo", "foo"}, Payloads{&p}, false, make(http.Header))
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "api-key-3", transactions[0].Headers.Get("DD-Api-Key"))
+}
+
 func TestSendHTTPTransactions(t *testing.T) {
 	forwarder := NewDefaultForwarder(NewOptions(keysPerDomains))
 	endpoint := endpoint{"/api/foo", "foo"}
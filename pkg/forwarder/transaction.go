@@ -152,6 +152,9 @@ type HTTPTransaction struct {
 	createdAt time.Time
 	// retryable indicates whether this transaction can be retried
 	retryable bool
+	// storableOnDisk indicates whether this transaction can be stored on disk
+	// when the retry queue is full. Transactions with custom handlers can't.
+	storableOnDisk bool
 
 	// attemptHandler will be called with a transaction before the attempting to send the request
 	attemptHandler HTTPAttemptHandler
@@ -175,6 +178,7 @@ func NewHTTPTransaction() *HTTPTransaction {
 		createdAt:         time.Now(),
 		ErrorCount:        0,
 		retryable:         true,
+		storableOnDisk:    true,
 		Headers:           make(http.Header),
 		attemptHandler:    defaultAttemptHandler,
 		completionHandler: defaultCompletionHandler,
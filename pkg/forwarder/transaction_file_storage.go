@@ -0,0 +1,340 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package forwarder
+
+import (
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const retryFileExtension = ".retry"
+
+var (
+	transactionsStoredOnDisk       = expvar.Int{}
+	transactionsReadFromDisk       = expvar.Int{}
+	transactionsDroppedOnDiskByAge = expvar.Int{}
+	transactionsStorageBytes       = expvar.Int{}
+
+	tlmTxStoredOnDisk = telemetry.NewCounter("transactions", "stored_on_disk",
+		[]string{"domain"}, "Count of transactions stored on disk")
+	tlmTxReadFromDisk = telemetry.NewCounter("transactions", "read_from_disk",
+		[]string{"domain"}, "Count of transactions read back from disk")
+	tlmTxDroppedByAge = telemetry.NewCounter("transactions", "dropped_on_disk_by_age",
+		[]string{"domain"}, "Count of transactions stored on disk dropped because they were too old")
+	tlmTxStorageBytes = telemetry.NewGauge("transactions", "storage_bytes",
+		[]string{"domain"}, "Number of bytes used to store transactions on disk")
+
+	domainSanitizer = regexp.MustCompile(`[^a-zA-Z0-9.-]`)
+)
+
+func initTransactionFileStorageExpvars() {
+	transactionsExpvars.Set("StoredOnDisk", &transactionsStoredOnDisk)
+	transactionsExpvars.Set("ReadFromDisk", &transactionsReadFromDisk)
+	transactionsExpvars.Set("DroppedOnDiskByAge", &transactionsDroppedOnDiskByAge)
+	transactionsExpvars.Set("StorageBytes", &transactionsStorageBytes)
+}
+
+// retryFile describes a file holding transactions stored on disk. Its
+// metadata is encoded in the file name: <priority>_<created_at>_<count>.retry
+type retryFile struct {
+	path      string
+	priority  TransactionPriority
+	createdAt time.Time
+	count     int
+	size      int64
+}
+
+// transactionsFileStorage stores the transactions that don't fit in the retry
+// queue of a domainForwarder on disk, within a size and an age limit. It is
+// not thread safe: it is only used by the goroutine handling failed
+// transactions of a domainForwarder.
+type transactionsFileStorage struct {
+	domain      string
+	storagePath string
+	maxSize     int64
+	maxAge      time.Duration
+	serializer  *httpTransactionsSerializer
+
+	files        []retryFile
+	currentSize  int64
+	reportedSize int64
+}
+
+func newTransactionsFileStorage(domain string, rootPath string, maxSize int64, maxAge time.Duration, apiKeys []string) (*transactionsFileStorage, error) {
+	storagePath := filepath.Join(rootPath, domainSanitizer.ReplaceAllString(domain, "_"))
+	if err := os.MkdirAll(storagePath, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create the transactions storage folder %q: %s", storagePath, err)
+	}
+
+	s := &transactionsFileStorage{
+		domain:      domain,
+		storagePath: storagePath,
+		maxSize:     maxSize,
+		maxAge:      maxAge,
+		serializer:  newHTTPTransactionsSerializer(apiKeys),
+	}
+	if err := s.reloadExistingFiles(); err != nil {
+		return nil, err
+	}
+	s.removeOutdatedFiles(time.Now())
+	s.updateTelemetry()
+
+	if len(s.files) > 0 {
+		log.Infof("Found %d file(s) of transactions to retry for %q (%d bytes)", len(s.files), domain, s.currentSize)
+	}
+	return s, nil
+}
+
+// store writes the transactions on disk, one file per priority. Oldest files
+// with the lowest priority are removed when there is not enough space left.
+// It returns the number of transactions that were dropped.
+func (s *transactionsFileStorage) store(transactions []*HTTPTransaction) int {
+	byPriority := make(map[TransactionPriority][]*HTTPTransaction)
+	for _, t := range transactions {
+		byPriority[t.priority] = append(byPriority[t.priority], t)
+	}
+
+	dropped := 0
+	for priority, txs := range byPriority {
+		droppedTxs, stored := s.writeFile(priority, time.Now(), txs)
+		dropped += droppedTxs
+		if stored {
+			transactionsStoredOnDisk.Add(int64(len(txs)))
+			tlmTxStoredOnDisk.Add(float64(len(txs)), s.domain)
+		}
+	}
+
+	s.updateTelemetry()
+	return dropped
+}
+
+// writeFile writes transactions with the same priority in a new file. Files
+// with a lower or equal priority are removed when there is not enough space
+// left. It returns the number of transactions that were dropped, including
+// the given ones if they could not be stored, and whether they were stored.
+func (s *transactionsFileStorage) writeFile(priority TransactionPriority, createdAt time.Time, txs []*HTTPTransaction) (int, bool) {
+	content, err := s.serializer.serialize(txs)
+	if err != nil {
+		log.Errorf("Cannot serialize transactions for %q: %s", s.domain, err)
+		return len(txs), false
+	}
+
+	size := int64(len(content))
+	if size > s.maxSize {
+		log.Errorf("Cannot store %d transactions for %q on disk: %d bytes exceed 'forwarder_storage_max_size_in_bytes' (%d)", len(txs), s.domain, size, s.maxSize)
+		return len(txs), false
+	}
+	dropped, ok := s.makeRoomFor(size, priority)
+	if !ok {
+		log.Errorf("Cannot store %d transactions for %q on disk: the storage is full of transactions with a higher priority", len(txs), s.domain)
+		return len(txs), false
+	}
+
+	file := retryFile{
+		priority:  priority,
+		createdAt: createdAt,
+		count:     len(txs),
+		size:      size,
+	}
+	file.path = filepath.Join(s.storagePath, retryFileName(file))
+	if err := ioutil.WriteFile(file.path, content, 0600); err != nil {
+		log.Errorf("Cannot write transactions on disk for %q: %s", s.domain, err)
+		return dropped + len(txs), false
+	}
+
+	s.files = append(s.files, file)
+	s.currentSize += size
+	return dropped, true
+}
+
+// updateAPIKeys replaces the API keys set on the transactions read from disk.
+func (s *transactionsFileStorage) updateAPIKeys(apiKeys []string) {
+	s.serializer.updateAPIKeys(apiKeys)
+}
+
+// loadNext reads and removes the file with the highest priority, newest
+// first, and returns at most maxCount of its transactions. The other ones
+// are stored back on disk. It returns nil if there is no file.
+func (s *transactionsFileStorage) loadNext(maxCount int) []*HTTPTransaction {
+	s.removeOutdatedFiles(time.Now())
+
+	for len(s.files) > 0 {
+		s.sortFiles()
+		file := s.files[len(s.files)-1]
+		s.removeFile(len(s.files) - 1)
+		s.updateTelemetry()
+
+		content, err := ioutil.ReadFile(file.path)
+		// the file is removed even if it cannot be read to not retry it forever
+		_ = os.Remove(file.path)
+		if err != nil {
+			log.Errorf("Cannot read transactions from %q: %s", file.path, err)
+			continue
+		}
+
+		transactions, err := s.serializer.deserialize(content)
+		if err != nil {
+			log.Errorf("Cannot deserialize transactions from %q, dropping %d transactions: %s", file.path, file.count, err)
+			transactionsDropped.Add(int64(file.count))
+			tlmTxDropped.Add(float64(file.count), s.domain)
+			continue
+		}
+
+		if len(transactions) > maxCount {
+			// the creation time of the file is kept so they still expire with it
+			dropped, _ := s.writeFile(file.priority, file.createdAt, transactions[maxCount:])
+			transactionsDropped.Add(int64(dropped))
+			tlmTxDropped.Add(float64(dropped), s.domain)
+			s.updateTelemetry()
+			transactions = transactions[:maxCount]
+		}
+
+		transactionsReadFromDisk.Add(int64(len(transactions)))
+		tlmTxReadFromDisk.Add(float64(len(transactions)), s.domain)
+		return transactions
+	}
+	return nil
+}
+
+// filesCount returns the number of files currently stored on disk.
+func (s *transactionsFileStorage) filesCount() int {
+	return len(s.files)
+}
+
+// makeRoomFor removes the oldest files with the lowest priority until `size`
+// bytes can be stored, without removing files with a priority higher than
+// `priority`. It returns the number of transactions dropped, and false if
+// there is not enough space even so, in which case no file is removed.
+func (s *transactionsFileStorage) makeRoomFor(size int64, priority TransactionPriority) (int, bool) {
+	s.sortFiles()
+	available := s.maxSize - s.currentSize
+	for _, file := range s.files {
+		if available >= size || file.priority > priority {
+			break
+		}
+		available += file.size
+	}
+	if available < size {
+		return 0, false
+	}
+
+	dropped := 0
+	for len(s.files) > 0 && s.currentSize+size > s.maxSize {
+		file := s.files[0]
+		log.Errorf("Maximum size of the transactions storage reached for %q, dropping %d transactions", s.domain, file.count)
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Cannot remove %q: %s", file.path, err)
+		}
+		s.removeFile(0)
+		dropped += file.count
+	}
+	return dropped, true
+}
+
+func (s *transactionsFileStorage) removeOutdatedFiles(now time.Time) {
+	if s.maxAge <= 0 {
+		return
+	}
+	kept := s.files[:0]
+	for _, file := range s.files {
+		if now.Sub(file.createdAt) <= s.maxAge {
+			kept = append(kept, file)
+			continue
+		}
+		log.Warnf("Dropping %d transactions stored on disk for %q: they are older than %s", file.count, s.domain, s.maxAge)
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Cannot remove %q: %s", file.path, err)
+		}
+		s.currentSize -= file.size
+		transactionsDroppedOnDiskByAge.Add(int64(file.count))
+		tlmTxDroppedByAge.Add(float64(file.count), s.domain)
+	}
+	s.files = kept
+}
+
+func (s *transactionsFileStorage) reloadExistingFiles() error {
+	entries, err := ioutil.ReadDir(s.storagePath)
+	if err != nil {
+		return fmt.Errorf("cannot list the transactions storage folder %q: %s", s.storagePath, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != retryFileExtension {
+			continue
+		}
+		file, err := parseRetryFileName(entry.Name())
+		if err != nil {
+			log.Warnf("Ignoring unexpected file %q in the transactions storage folder: %s", entry.Name(), err)
+			continue
+		}
+		file.path = filepath.Join(s.storagePath, entry.Name())
+		file.size = entry.Size()
+		s.files = append(s.files, file)
+		s.currentSize += file.size
+	}
+	return nil
+}
+
+func (s *transactionsFileStorage) removeFile(index int) {
+	s.currentSize -= s.files[index].size
+	s.files = append(s.files[:index], s.files[index+1:]...)
+}
+
+// sortFiles sorts the files by ascending priority then creation time: the
+// first file is the first one to drop, the last one is the first to retry.
+func (s *transactionsFileStorage) sortFiles() {
+	sort.SliceStable(s.files, func(i, j int) bool {
+		if s.files[i].priority != s.files[j].priority {
+			return s.files[i].priority < s.files[j].priority
+		}
+		return s.files[i].createdAt.Before(s.files[j].createdAt)
+	})
+}
+
+func (s *transactionsFileStorage) updateTelemetry() {
+	// the expvar is shared by all the domains
+	transactionsStorageBytes.Add(s.currentSize - s.reportedSize)
+	s.reportedSize = s.currentSize
+	tlmTxStorageBytes.Set(float64(s.currentSize), s.domain)
+}
+
+func retryFileName(file retryFile) string {
+	return fmt.Sprintf("%d_%d_%d%s", file.priority, file.createdAt.UnixNano(), file.count, retryFileExtension)
+}
+
+func parseRetryFileName(name string) (retryFile, error) {
+	parts := strings.Split(strings.TrimSuffix(name, retryFileExtension), "_")
+	if len(parts) != 3 {
+		return retryFile{}, fmt.Errorf("invalid file name")
+	}
+	priority, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return retryFile{}, fmt.Errorf("invalid priority: %s", err)
+	}
+	createdAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return retryFile{}, fmt.Errorf("invalid creation time: %s", err)
+	}
+	count, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return retryFile{}, fmt.Errorf("invalid transactions count: %s", err)
+	}
+	return retryFile{
+		priority:  TransactionPriority(priority),
+		createdAt: time.Unix(0, createdAt),
+		count:     count,
+	}, nil
+}
@@ -0,0 +1,203 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package forwarder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStorableTransaction(endpoint string, priority TransactionPriority, payload string) *HTTPTransaction {
+	t := NewHTTPTransaction()
+	t.Domain = "https://app.datadoghq.com"
+	t.Endpoint = endpoint
+	t.priority = priority
+	p := []byte(payload)
+	t.Payload = &p
+	return t
+}
+
+func newTestStorage(t *testing.T, maxSize int64, maxAge time.Duration) (*transactionsFileStorage, string) {
+	path, err := ioutil.TempDir("", "forwarder-storage")
+	require.NoError(t, err)
+	storage, err := newTransactionsFileStorage("https://app.datadoghq.com", path, maxSize, maxAge, []string{"api_key1", "api_key2"})
+	require.NoError(t, err)
+	return storage, path
+}
+
+func TestHTTPTransactionsSerializerHidesAPIKeys(t *testing.T) {
+	serializer := newHTTPTransactionsSerializer([]string{"api_key1", "api_key2"})
+	tr := newStorableTransaction("/api/v1/series?api_key=api_key2", TransactionPriorityHigh, "payload")
+	tr.Headers.Set(apiHTTPHeaderKey, "api_key2")
+	tr.ErrorCount = 3
+
+	content, err := serializer.serialize([]*HTTPTransaction{tr})
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "api_key2")
+
+	transactions, err := serializer.deserialize(content)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, tr.Domain, transactions[0].Domain)
+	assert.Equal(t, "/api/v1/series?api_key=api_key2", transactions[0].Endpoint)
+	assert.Equal(t, "api_key2", transactions[0].Headers.Get(apiHTTPHeaderKey))
+	assert.Equal(t, "payload", string(*transactions[0].Payload))
+	assert.Equal(t, 3, transactions[0].ErrorCount)
+	assert.Equal(t, TransactionPriorityHigh, transactions[0].GetPriority())
+	assert.True(t, tr.GetCreatedAt().Equal(transactions[0].GetCreatedAt()))
+
+	// the API key configuration changed
+	serializer = newHTTPTransactionsSerializer([]string{"api_key1"})
+	_, err = serializer.deserialize(content)
+	assert.Error(t, err)
+}
+
+func TestHTTPTransactionsSerializerUpdateAPIKeys(t *testing.T) {
+	serializer := newHTTPTransactionsSerializer([]string{"api_key1", "api_key2"})
+	tr := newStorableTransaction("/api/v1/series?api_key=api_key2", TransactionPriorityNormal, "payload")
+	tr.Headers.Set(apiHTTPHeaderKey, "api_key2")
+
+	content, err := serializer.serialize([]*HTTPTransaction{tr})
+	require.NoError(t, err)
+
+	// the stored transactions are sent with the rotated API key
+	serializer.updateAPIKeys([]string{"api_key1", "api_key3"})
+	transactions, err := serializer.deserialize(content)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "/api/v1/series?api_key=api_key3", transactions[0].Endpoint)
+	assert.Equal(t, "api_key3", transactions[0].Headers.Get(apiHTTPHeaderKey))
+
+	// the transactions created before the rotation don't write the previous API key
+	content, err = serializer.serialize([]*HTTPTransaction{tr})
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "api_key2")
+	transactions, err = serializer.deserialize(content)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "api_key3", transactions[0].Headers.Get(apiHTTPHeaderKey))
+}
+
+func TestTransactionsFileStorageLoadsHighPriorityFirst(t *testing.T) {
+	storage, path := newTestStorage(t, 1024*1024, 0)
+	defer os.RemoveAll(path)
+
+	dropped := storage.store([]*HTTPTransaction{
+		newStorableTransaction("/normal1", TransactionPriorityNormal, "1"),
+		newStorableTransaction("/high", TransactionPriorityHigh, "2"),
+	})
+	assert.Equal(t, 0, dropped)
+	dropped = storage.store([]*HTTPTransaction{newStorableTransaction("/normal2", TransactionPriorityNormal, "3")})
+	assert.Equal(t, 0, dropped)
+	assert.Equal(t, 3, storage.filesCount())
+
+	transactions := storage.loadNext(100)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "/high", transactions[0].Endpoint)
+
+	transactions = storage.loadNext(100)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "/normal2", transactions[0].Endpoint)
+
+	transactions = storage.loadNext(100)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "/normal1", transactions[0].Endpoint)
+
+	assert.Nil(t, storage.loadNext(100))
+	assert.Equal(t, int64(0), storage.currentSize)
+}
+
+func TestTransactionsFileStorageMaxSize(t *testing.T) {
+	storage, path := newTestStorage(t, 1024*1024, 0)
+	defer os.RemoveAll(path)
+
+	storage.store([]*HTTPTransaction{newStorableTransaction("/first", TransactionPriorityNormal, "1")})
+	storage.maxSize = storage.currentSize + 1
+
+	dropped := storage.store([]*HTTPTransaction{newStorableTransaction("/second", TransactionPriorityNormal, "2")})
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, 1, storage.filesCount())
+
+	transactions := storage.loadNext(100)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "/second", transactions[0].Endpoint)
+}
+
+func TestTransactionsFileStorageMaxSizeKeepsHighPriority(t *testing.T) {
+	storage, path := newTestStorage(t, 1024*1024, 0)
+	defer os.RemoveAll(path)
+
+	storage.store([]*HTTPTransaction{newStorableTransaction("/high", TransactionPriorityHigh, "1")})
+	storage.maxSize = storage.currentSize + 1
+
+	// a transaction with a lower priority doesn't evict the high priority ones
+	dropped := storage.store([]*HTTPTransaction{newStorableTransaction("/normal", TransactionPriorityNormal, "2")})
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, 1, storage.filesCount())
+
+	transactions := storage.loadNext(100)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "/high", transactions[0].Endpoint)
+}
+
+func TestTransactionsFileStorageLoadNextMaxCount(t *testing.T) {
+	storage, path := newTestStorage(t, 1024*1024, 0)
+	defer os.RemoveAll(path)
+
+	storage.store([]*HTTPTransaction{
+		newStorableTransaction("/1", TransactionPriorityNormal, "1"),
+		newStorableTransaction("/2", TransactionPriorityNormal, "2"),
+		newStorableTransaction("/3", TransactionPriorityNormal, "3"),
+	})
+	require.Equal(t, 1, storage.filesCount())
+
+	// the transactions over the limit are stored back on disk
+	transactions := storage.loadNext(2)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "/1", transactions[0].Endpoint)
+	assert.Equal(t, "/2", transactions[1].Endpoint)
+	assert.Equal(t, 1, storage.filesCount())
+
+	transactions = storage.loadNext(2)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "/3", transactions[0].Endpoint)
+	assert.Nil(t, storage.loadNext(2))
+}
+
+func TestTransactionsFileStorageReloadAndMaxAge(t *testing.T) {
+	storage, path := newTestStorage(t, 1024*1024, time.Hour)
+	defer os.RemoveAll(path)
+
+	storage.store([]*HTTPTransaction{newStorableTransaction("/old", TransactionPriorityNormal, "1")})
+	storage.store([]*HTTPTransaction{newStorableTransaction("/recent", TransactionPriorityNormal, "2")})
+	require.Equal(t, 2, storage.filesCount())
+
+	// age the first file
+	storage.sortFiles()
+	old := storage.files[0]
+	old.createdAt = time.Now().Add(-2 * time.Hour)
+	oldPath := filepath.Join(storage.storagePath, retryFileName(old))
+	require.NoError(t, os.Rename(old.path, oldPath))
+
+	// a new storage reloads the existing files and drops the outdated ones
+	droppedByAge := transactionsDroppedOnDiskByAge.Value()
+	storage, err := newTransactionsFileStorage("https://app.datadoghq.com", path, 1024*1024, time.Hour, []string{"api_key1", "api_key2"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, storage.filesCount())
+	assert.Equal(t, droppedByAge+1, transactionsDroppedOnDiskByAge.Value())
+	_, err = os.Stat(oldPath)
+	assert.True(t, os.IsNotExist(err))
+
+	transactions := storage.loadNext(100)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "/recent", transactions[0].Endpoint)
+}
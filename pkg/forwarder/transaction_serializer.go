@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package forwarder
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const apiKeyPlaceholderFormat = "@@API_KEY_%d@@"
+
+// storedHTTPTransaction is the on-disk representation of an HTTPTransaction.
+// Handlers are not part of it: only transactions created with the default
+// handlers can be stored on disk (see HTTPTransaction.storableOnDisk).
+type storedHTTPTransaction struct {
+	Domain     string
+	Endpoint   string
+	Headers    http.Header
+	Payload    []byte
+	ErrorCount int
+	CreatedAt  time.Time
+	Priority   TransactionPriority
+}
+
+// httpTransactionsSerializer encodes and decodes a list of HTTPTransaction.
+// API keys are never written to disk: they are replaced by a placeholder
+// holding their index in `apiKeys` and resolved with the current API keys
+// when decoding, so that the transactions stored before the API keys were
+// rotated are sent with the new ones.
+type httpTransactionsSerializer struct {
+	m       sync.RWMutex
+	apiKeys []string
+	// previousAPIKeys holds the index of the API keys replaced by
+	// updateAPIKeys, still used by the transactions created before.
+	previousAPIKeys map[string]int
+}
+
+func newHTTPTransactionsSerializer(apiKeys []string) *httpTransactionsSerializer {
+	return &httpTransactionsSerializer{
+		apiKeys:         apiKeys,
+		previousAPIKeys: make(map[string]int),
+	}
+}
+
+// updateAPIKeys replaces the API keys, for example after they were rotated.
+func (s *httpTransactionsSerializer) updateAPIKeys(apiKeys []string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	for i, key := range s.apiKeys {
+		if i < len(apiKeys) && key != apiKeys[i] {
+			s.previousAPIKeys[key] = i
+		}
+	}
+	for _, key := range apiKeys {
+		delete(s.previousAPIKeys, key)
+	}
+	s.apiKeys = apiKeys
+}
+
+// serialize encodes the transactions. Transactions that cannot be stored on
+// disk must be filtered out by the caller.
+func (s *httpTransactionsSerializer) serialize(transactions []*HTTPTransaction) ([]byte, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	stored := make([]storedHTTPTransaction, 0, len(transactions))
+	for _, t := range transactions {
+		var payload []byte
+		if t.Payload != nil {
+			payload = *t.Payload
+		}
+		headers := make(http.Header, len(t.Headers))
+		for key, values := range t.Headers {
+			for _, v := range values {
+				headers.Add(key, s.hideAPIKeys(v))
+			}
+		}
+		stored = append(stored, storedHTTPTransaction{
+			Domain:     t.Domain,
+			Endpoint:   s.hideAPIKeys(t.Endpoint),
+			Headers:    headers,
+			Payload:    payload,
+			ErrorCount: t.ErrorCount,
+			CreatedAt:  t.createdAt,
+			Priority:   t.priority,
+		})
+	}
+
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(stored); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// deserialize decodes transactions previously encoded with serialize.
+func (s *httpTransactionsSerializer) deserialize(content []byte) ([]*HTTPTransaction, error) {
+	var stored []storedHTTPTransaction
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&stored); err != nil {
+		return nil, err
+	}
+
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	transactions := make([]*HTTPTransaction, 0, len(stored))
+	for _, st := range stored {
+		endpoint, err := s.restoreAPIKeys(st.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		t := NewHTTPTransaction()
+		t.Domain = st.Domain
+		t.Endpoint = endpoint
+		for key, values := range st.Headers {
+			for _, v := range values {
+				value, err := s.restoreAPIKeys(v)
+				if err != nil {
+					return nil, err
+				}
+				t.Headers.Add(key, value)
+			}
+		}
+		payload := st.Payload
+		t.Payload = &payload
+		t.ErrorCount = st.ErrorCount
+		t.createdAt = st.CreatedAt
+		t.priority = st.Priority
+		transactions = append(transactions, t)
+	}
+	return transactions, nil
+}
+
+func (s *httpTransactionsSerializer) hideAPIKeys(str string) string {
+	for i, key := range s.apiKeys {
+		if key == "" {
+			continue
+		}
+		str = strings.Replace(str, key, fmt.Sprintf(apiKeyPlaceholderFormat, i), -1)
+	}
+	for key, i := range s.previousAPIKeys {
+		str = strings.Replace(str, key, fmt.Sprintf(apiKeyPlaceholderFormat, i), -1)
+	}
+	return str
+}
+
+func (s *httpTransactionsSerializer) restoreAPIKeys(str string) (string, error) {
+	if !strings.Contains(str, "@@API_KEY_") {
+		return str, nil
+	}
+	for i, key := range s.apiKeys {
+		str = strings.Replace(str, fmt.Sprintf(apiKeyPlaceholderFormat, i), key, -1)
+	}
+	if strings.Contains(str, "@@API_KEY_") {
+		return "", fmt.Errorf("cannot restore the API key of a stored transaction: the API key configuration changed")
+	}
+	return str, nil
+}
@@ -20,8 +20,9 @@ import (
 
 // ContentType options,
 const (
-	TextContentType = "text/plain"
-	JSONContentType = "application/json"
+	TextContentType   = "text/plain"
+	JSONContentType   = "application/json"
+	NDJSONContentType = "application/x-ndjson"
 )
 
 // HTTP errors.
@@ -38,6 +39,7 @@ type Destination struct {
 	url                 string
 	contentType         string
 	contentEncoding     ContentEncoding
+	headers             map[string]string
 	client              *httputils.ResetClient
 	destinationsContext *client.DestinationsContext
 	once                sync.Once
@@ -50,6 +52,27 @@ func NewDestination(endpoint config.Endpoint, contentType string, destinationsCo
 	return newDestination(endpoint, contentType, destinationsContext, time.Second*10)
 }
 
+// NewAdditionalDestination returns a new Destination sending payloads to the url of an additional destination,
+// with the content type of its format and its custom headers.
+func NewAdditionalDestination(destination *config.AdditionalDestination, destinationsContext *client.DestinationsContext) *Destination {
+	contentType := JSONContentType
+	if destination.Format == config.NDJSONFormat {
+		contentType = NDJSONContentType
+	}
+	contentEncoding := IdentityContentType
+	if destination.UseCompression {
+		contentEncoding = NewGzipContentEncoding(destination.CompressionLevel)
+	}
+	return &Destination{
+		url:                 destination.URL,
+		contentType:         contentType,
+		contentEncoding:     contentEncoding,
+		headers:             destination.Headers,
+		client:              httputils.NewResetClient(0, httpClientFactory(time.Second*10)),
+		destinationsContext: destinationsContext,
+	}
+}
+
 func newDestination(endpoint config.Endpoint, contentType string, destinationsContext *client.DestinationsContext, timeout time.Duration) *Destination {
 	return &Destination{
 		url:                 buildURL(endpoint),
@@ -80,6 +103,9 @@ func (d *Destination) Send(payload []byte) error {
 	}
 	req.Header.Set("Content-Type", d.contentType)
 	req.Header.Set("Content-Encoding", d.contentEncoding.name())
+	for name, value := range d.headers {
+		req.Header.Set(name, value)
+	}
 	req = req.WithContext(ctx)
 
 	resp, err := d.client.Do(req)
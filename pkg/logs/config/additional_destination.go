@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Types of additional destinations.
+const (
+	// HTTPDestinationType sends logs to a generic HTTP endpoint.
+	HTTPDestinationType = "http"
+	// LokiDestinationType sends logs to the push API of Loki.
+	LokiDestinationType = "loki"
+)
+
+// Formats of the body of the requests sent to http additional destinations.
+const (
+	// JSONArrayFormat sends the logs of a batch as a JSON array.
+	JSONArrayFormat = "json_array"
+	// NDJSONFormat sends the logs of a batch as newline delimited JSON.
+	NDJSONFormat = "ndjson"
+)
+
+// Default settings of the additional destinations.
+const (
+	defaultDestinationBatchMaxSize        = 200
+	defaultDestinationBatchMaxContentSize = 1000000
+	defaultDestinationQueueSize           = 10
+	defaultDestinationMaxBackoff          = 60
+)
+
+// templateFuncs are the functions available in the templates of the additional destinations.
+var templateFuncs = template.FuncMap{
+	// json encodes a value in JSON, e.g. {"msg":{{json .Message}}}
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// destinationNameRegex matches the valid names of additional destinations,
+// which are used in telemetry tags and in the path of their disk buffer.
+var destinationNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// AdditionalDestination holds the configuration of a destination logs are
+// sent to besides the main one. Unlike additional endpoints, additional
+// destinations are independent from the main one: they have their own
+// batching, retries and disk buffer, and only receive the logs matching
+// their filter.
+type AdditionalDestination struct {
+	Name             string            `mapstructure:"name" json:"name"`
+	Type             string            `mapstructure:"type" json:"type"`
+	URL              string            `mapstructure:"url" json:"url"`
+	Headers          map[string]string `mapstructure:"headers" json:"headers"`
+	UseCompression   bool              `mapstructure:"use_compression" json:"use_compression"`
+	CompressionLevel int               `mapstructure:"compression_level" json:"compression_level"`
+
+	// Format is the format of the body of the requests of http destinations,
+	// Template the text template each log is rendered with, the logs are
+	// encoded in JSON when it is empty.
+	Format           string             `mapstructure:"format" json:"format"`
+	Template         string             `mapstructure:"template" json:"template"`
+	CompiledTemplate *template.Template `mapstructure:"-" json:"-"`
+
+	// LokiLabels are the labels of the streams of loki destinations, all the
+	// tags of the logs, their service, source and status are used when it
+	// is empty.
+	LokiLabels []string `mapstructure:"loki_labels" json:"loki_labels"`
+
+	// BatchWait is the maximum time in seconds a log waits before being sent,
+	// BatchMaxSize and BatchMaxContentSize the maximum number of logs and
+	// bytes of a batch.
+	BatchWait           int `mapstructure:"batch_wait" json:"batch_wait"`
+	BatchMaxSize        int `mapstructure:"batch_max_size" json:"batch_max_size"`
+	BatchMaxContentSize int `mapstructure:"batch_max_content_size" json:"batch_max_content_size"`
+
+	// QueueSize is the number of batches kept in memory while the
+	// destination is slow or unavailable, MaxBackoff the maximum time in
+	// seconds between two attempts to send a batch.
+	QueueSize  int `mapstructure:"queue_size" json:"queue_size"`
+	MaxBackoff int `mapstructure:"max_backoff" json:"max_backoff"`
+
+	// DiskBufferMaxSize is the maximum number of bytes of the batches stored
+	// on disk once the queue is full, 0 disables the disk buffer and the
+	// batches are dropped instead. DiskBufferPath is set from the run path.
+	DiskBufferMaxSize int64  `mapstructure:"disk_buffer_max_size" json:"disk_buffer_max_size"`
+	DiskBufferPath    string `mapstructure:"-" json:"-"`
+
+	// Sources, Services and Statuses restrict the logs sent to the
+	// destination, all logs are sent when they are empty.
+	Sources  []string `mapstructure:"sources" json:"sources"`
+	Services []string `mapstructure:"services" json:"services"`
+	Statuses []string `mapstructure:"statuses" json:"statuses"`
+}
+
+// Validate returns an error if the destination is misconfigured,
+// and sets the default values of the settings left empty.
+func (d *AdditionalDestination) Validate(batchWait int) error {
+	if !destinationNameRegex.MatchString(d.Name) {
+		return fmt.Errorf("invalid name %q for additional destination, it must only contain letters, digits, '_', '.' and '-'", d.Name)
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url for additional destination %s: %v", d.Name, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q for additional destination %s, it must be an http or https url", d.URL, d.Name)
+	}
+	switch d.Type {
+	case "":
+		d.Type = HTTPDestinationType
+	case HTTPDestinationType, LokiDestinationType:
+	default:
+		return fmt.Errorf("invalid type %q for additional destination %s, it must be %s or %s", d.Type, d.Name, HTTPDestinationType, LokiDestinationType)
+	}
+	if d.Type == LokiDestinationType {
+		if d.Format != "" || d.Template != "" {
+			return fmt.Errorf("format and template are not supported by the loki additional destination %s", d.Name)
+		}
+	} else {
+		if len(d.LokiLabels) > 0 {
+			return fmt.Errorf("loki_labels are only supported by loki additional destinations, not %s", d.Name)
+		}
+		switch d.Format {
+		case "":
+			d.Format = JSONArrayFormat
+		case JSONArrayFormat, NDJSONFormat:
+		default:
+			return fmt.Errorf("invalid format %q for additional destination %s, it must be %s or %s", d.Format, d.Name, JSONArrayFormat, NDJSONFormat)
+		}
+		if d.Template != "" {
+			d.CompiledTemplate, err = template.New(d.Name).Funcs(templateFuncs).Parse(d.Template)
+			if err != nil {
+				return fmt.Errorf("invalid template for additional destination %s: %v", d.Name, err)
+			}
+		}
+	}
+	if d.BatchWait <= 0 {
+		d.BatchWait = batchWait
+	}
+	if d.BatchMaxSize <= 0 {
+		d.BatchMaxSize = defaultDestinationBatchMaxSize
+	}
+	if d.BatchMaxContentSize <= 0 {
+		d.BatchMaxContentSize = defaultDestinationBatchMaxContentSize
+	}
+	if d.QueueSize <= 0 {
+		d.QueueSize = defaultDestinationQueueSize
+	}
+	if d.MaxBackoff <= 0 {
+		d.MaxBackoff = defaultDestinationMaxBackoff
+	}
+	return nil
+}
+
+// Matches returns whether a log with the given source, service and status
+// passes the filter of the destination.
+func (d *AdditionalDestination) Matches(source, service, status string) bool {
+	return matchesAny(d.Sources, source) && matchesAny(d.Services, service) && matchesAny(d.Statuses, status)
+}
+
+// matchesAny returns whether value is one of values, or values is empty.
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdditionalDestinationValidate(t *testing.T) {
+	destination := &AdditionalDestination{Name: "collector", URL: "https://collector.example.com/logs", BatchMaxSize: 10}
+	assert.Nil(t, destination.Validate(5))
+	assert.Equal(t, 5, destination.BatchWait)
+	assert.Equal(t, 10, destination.BatchMaxSize)
+	assert.Equal(t, defaultDestinationQueueSize, destination.QueueSize)
+	assert.Equal(t, HTTPDestinationType, destination.Type)
+	assert.Equal(t, JSONArrayFormat, destination.Format)
+	assert.Nil(t, destination.CompiledTemplate)
+
+	destination = &AdditionalDestination{Name: "collector", URL: "https://collector.example.com/logs", Format: NDJSONFormat, Template: `{"msg":{{json .Message}}}`}
+	assert.Nil(t, destination.Validate(5))
+	assert.NotNil(t, destination.CompiledTemplate)
+
+	destination = &AdditionalDestination{Name: "loki", Type: LokiDestinationType, URL: "http://loki:3100/loki/api/v1/push", LokiLabels: []string{"service"}}
+	assert.Nil(t, destination.Validate(5))
+	assert.Equal(t, "", destination.Format)
+
+	for _, destination := range []*AdditionalDestination{
+		{URL: "https://collector.example.com/logs"},
+		{Name: "../collector", URL: "https://collector.example.com/logs"},
+		{Name: "collector"},
+		{Name: "collector", URL: "collector.example.com:8080"},
+		{Name: "collector", URL: "tcp://collector.example.com:8080"},
+		{Name: "collector", URL: "https://collector.example.com/logs", Type: "syslog"},
+		{Name: "collector", URL: "https://collector.example.com/logs", Format: "xml"},
+		{Name: "collector", URL: "https://collector.example.com/logs", Template: "{{.Message"},
+		{Name: "collector", URL: "https://collector.example.com/logs", LokiLabels: []string{"service"}},
+		{Name: "loki", URL: "http://loki:3100/loki/api/v1/push", Type: LokiDestinationType, Format: NDJSONFormat},
+	} {
+		assert.NotNil(t, destination.Validate(5), destination)
+	}
+}
+
+func TestAdditionalDestinationMatches(t *testing.T) {
+	destination := &AdditionalDestination{}
+	assert.True(t, destination.Matches("nginx", "web", "info"))
+
+	destination = &AdditionalDestination{Sources: []string{"nginx", "apache"}, Statuses: []string{"Error", "warn"}}
+	assert.True(t, destination.Matches("nginx", "web", "error"))
+	assert.True(t, destination.Matches("apache", "", "warn"))
+	assert.False(t, destination.Matches("nginx", "web", "info"))
+	assert.False(t, destination.Matches("redis", "web", "error"))
+}
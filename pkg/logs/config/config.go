@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -106,13 +107,24 @@ func BuildEndpoints(httpConnectivity HTTPConnectivity) (*Endpoints, error) {
 	if coreConfig.Datadog.GetBool("logs_config.dev_mode_no_ssl") {
 		log.Warnf("Use of illegal configuration parameter, if you need to send your logs to a proxy, please use 'logs_config.logs_dd_url' and 'logs_config.logs_no_ssl' instead")
 	}
+	var endpoints *Endpoints
+	var err error
 	if isForceHTTPUse() || (bool(httpConnectivity) && !(isForceTCPUse() || isSocks5ProxySet() || hasAdditionalEndpoints())) {
-		return BuildHTTPEndpoints()
+		endpoints, err = BuildHTTPEndpoints()
+	} else {
+		log.Warn("You are currently sending Logs to Datadog through TCP (either because logs_config.use_tcp or logs_config.socks5_proxy_address is set or the HTTP connectivity test has failed) " +
+			"To benefit from increased reliability and better network performances, " +
+			"we strongly encourage switching over to compressed HTTPS which is now the default protocol.")
+		endpoints, err = buildTCPEndpoints()
+	}
+	if err != nil {
+		return nil, err
 	}
-	log.Warn("You are currently sending Logs to Datadog through TCP (either because logs_config.use_tcp or logs_config.socks5_proxy_address is set or the HTTP connectivity test has failed) " +
-		"To benefit from increased reliability and better network performances, " +
-		"we strongly encourage switching over to compressed HTTPS which is now the default protocol.")
-	return buildTCPEndpoints()
+	endpoints.AdditionalDestinations, err = buildAdditionalDestinations()
+	if err != nil {
+		return nil, err
+	}
+	return endpoints, nil
 }
 
 func isSocks5ProxySet() bool {
@@ -228,6 +240,40 @@ func getAdditionalEndpoints() []Endpoint {
 	return endpoints
 }
 
+// buildAdditionalDestinations returns the additional destinations to fan logs out to.
+func buildAdditionalDestinations() ([]*AdditionalDestination, error) {
+	var destinations []*AdditionalDestination
+	var err error
+	raw := coreConfig.Datadog.Get("logs_config.additional_destinations")
+	if raw == nil {
+		return destinations, nil
+	}
+	if s, ok := raw.(string); ok && s != "" {
+		err = json.Unmarshal([]byte(s), &destinations)
+	} else {
+		err = coreConfig.Datadog.UnmarshalKey("logs_config.additional_destinations", &destinations)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse additional_destinations: %v", err)
+	}
+	batchWait := int(batchWait(coreConfig.Datadog) / time.Second)
+	runPath := coreConfig.Datadog.GetString("logs_config.run_path")
+	names := make(map[string]bool, len(destinations))
+	for _, destination := range destinations {
+		if err := destination.Validate(batchWait); err != nil {
+			return nil, err
+		}
+		if names[destination.Name] {
+			return nil, fmt.Errorf("duplicate additional destination name %s", destination.Name)
+		}
+		names[destination.Name] = true
+		if destination.DiskBufferMaxSize > 0 {
+			destination.DiskBufferPath = filepath.Join(runPath, "destinations", destination.Name)
+		}
+	}
+	return destinations, nil
+}
+
 func isSetAndNotEmpty(config coreConfig.Config, key string) bool {
 	return config.IsSet(key) && len(config.GetString(key)) > 0
 }
@@ -271,6 +271,44 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 	suite.Equal(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestAdditionalDestinationsInConf() {
+	suite.config.Set("api_key", "123")
+	suite.config.Set("logs_config.use_http", true)
+	suite.config.Set("logs_config.run_path", "/opt/datadog-agent/run")
+	destinationsInConfig := []map[string]interface{}{
+		{
+			"name":                 "collector",
+			"url":                  "https://collector.example.com/logs",
+			"use_compression":      true,
+			"disk_buffer_max_size": 1000,
+			"statuses":             []string{"error"}},
+	}
+	suite.config.Set("logs_config.additional_destinations", destinationsInConfig)
+
+	endpoints, err := BuildEndpoints(HTTPConnectivitySuccess)
+	suite.Nil(err)
+	suite.Equal([]*AdditionalDestination{{
+		Name:                "collector",
+		Type:                HTTPDestinationType,
+		URL:                 "https://collector.example.com/logs",
+		UseCompression:      true,
+		Format:              JSONArrayFormat,
+		BatchWait:           coreConfig.DefaultBatchWait,
+		BatchMaxSize:        defaultDestinationBatchMaxSize,
+		BatchMaxContentSize: defaultDestinationBatchMaxContentSize,
+		QueueSize:           defaultDestinationQueueSize,
+		MaxBackoff:          defaultDestinationMaxBackoff,
+		DiskBufferMaxSize:   1000,
+		DiskBufferPath:      "/opt/datadog-agent/run/destinations/collector",
+		Statuses:            []string{"error"},
+	}}, endpoints.AdditionalDestinations)
+
+	destinationsInConfig = append(destinationsInConfig, map[string]interface{}{"name": "collector", "url": "http://other.example.com"})
+	suite.config.Set("logs_config.additional_destinations", destinationsInConfig)
+	_, err = BuildEndpoints(HTTPConnectivitySuccess)
+	suite.NotNil(err)
+}
+
 func (suite *ConfigTestSuite) TestMultipleTCPEndpointsInConf() {
 	suite.config.Set("api_key", "123")
 	suite.config.Set("logs_config.logs_dd_url", "agent-http-intake.logs.datadoghq.com:443")
@@ -21,13 +21,15 @@ type Endpoint struct {
 	ConnectionResetInterval time.Duration
 }
 
-// Endpoints holds the main endpoint and additional ones to dualship logs.
+// Endpoints holds the main endpoint and additional ones to dualship logs,
+// and the additional destinations to fan logs out to.
 type Endpoints struct {
-	Main        Endpoint
-	Additionals []Endpoint
-	UseProto    bool
-	UseHTTP     bool
-	BatchWait   time.Duration
+	Main                   Endpoint
+	Additionals            []Endpoint
+	AdditionalDestinations []*AdditionalDestination
+	UseProto               bool
+	UseHTTP                bool
+	BatchWait              time.Duration
 }
 
 // NewEndpoints returns a new endpoints composite.
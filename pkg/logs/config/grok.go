@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Attribute types of the grok captures
+const (
+	AttributeTypeString = "string"
+	AttributeTypeInt    = "int"
+	AttributeTypeFloat  = "float"
+)
+
+// maxGrokDepth is the maximum number of nested grok references
+const maxGrokDepth = 10
+
+// grokPatterns is the built-in library of grok patterns, a pattern can
+// reference other patterns of the library.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"NONNEGINT":         `\b\d+\b`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][+-]?\d+)?`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"EMAILADDRESS":      `[a-zA-Z0-9!#$%&'*+/=?^_{|}~.-]+@%{HOSTNAME}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}(?:%{IPV4}|[0-9A-Fa-f]{0,4})`,
+	"IP":                `%{IPV6}|%{IPV4}`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":          `%{IP}|%{HOSTNAME}`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+	"MONTH":             `\b(?:Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|Jun(?:e)?|Jul(?:y)?|Aug(?:ust)?|Sep(?:tember)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)\b`,
+	"MONTHDAY":          `(?:0[1-9]|[12]\d|3[01]|[1-9])`,
+	"YEAR":              `\d{4}`,
+	"TIME":              `(?:[01]?\d|2[0-3]):[0-5]\d(?::[0-5]\d(?:[.,]\d+)?)?`,
+	"ISO8601_TIMEZONE":  `Z|[+-](?:[01]?\d|2[0-3]):?[0-5]\d`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-\d{2}-\d{2}[T ]%{TIME}(?:%{ISO8601_TIMEZONE})?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} [+-]\d{4}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"COMMONAPACHELOG":   `%{IPORHOST:client_ip} %{NOTSPACE:ident} %{NOTSPACE:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:method} %{NOTSPACE:url}(?: HTTP/%{NUMBER:http_version})?|%{DATA:raw_request})" %{NUMBER:status_code:int} (?:%{NUMBER:bytes:int}|-)`,
+}
+
+var grokReference = regexp.MustCompile(`%{(\w+)(?::(\w+))?(?::(\w+))?}`)
+
+// expandGrokPatterns replaces the grok references of a pattern, formatted as
+// %{PATTERN}, %{PATTERN:name} or %{PATTERN:name:type}, by the regular
+// expressions of the built-in library. Named references become named
+// captures, their types are returned for the captures that are not strings.
+func expandGrokPatterns(pattern string) (string, map[string]string, error) {
+	types := make(map[string]string)
+	expanded, err := expandGrokReferences(pattern, types, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return expanded, types, nil
+}
+
+func expandGrokReferences(pattern string, types map[string]string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("too many nested grok patterns")
+	}
+
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(reference string) string {
+		if err != nil {
+			return ""
+		}
+		parts := grokReference.FindStringSubmatch(reference)
+		name, capture, captureType := parts[1], parts[2], parts[3]
+
+		definition, found := grokPatterns[name]
+		if !found {
+			err = fmt.Errorf("unknown grok pattern %s", name)
+			return ""
+		}
+		var inner string
+		inner, err = expandGrokReferences(definition, types, depth+1)
+		if err != nil {
+			return ""
+		}
+
+		if capture == "" {
+			return "(?:" + inner + ")"
+		}
+		switch captureType {
+		case "", AttributeTypeString:
+			break
+		case AttributeTypeInt, AttributeTypeFloat:
+			types[capture] = captureType
+		default:
+			err = fmt.Errorf("unsupported type %s for grok capture %s", captureType, capture)
+			return ""
+		}
+		return "(?P<" + capture + ">" + inner + ")"
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// hasNamedCapture returns whether the regular expression has at least one
+// named capture
+func hasNamedCapture(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
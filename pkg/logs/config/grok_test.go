@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandGrokPatterns(t *testing.T) {
+	expanded, types, err := expandGrokPatterns(`%{IPV4:ip} %{WORD} %{NUMBER:duration:float}ms`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"duration": AttributeTypeFloat}, types)
+
+	re := regexp.MustCompile(expanded)
+	assert.Equal(t, []string{"", "ip", "duration"}, re.SubexpNames())
+	assert.Equal(t, []string{"10.0.0.1 GET 12.5ms", "10.0.0.1", "12.5"}, re.FindStringSubmatch("10.0.0.1 GET 12.5ms"))
+	assert.False(t, re.MatchString("10.0.0.1 GET ms"))
+
+	// regular expressions without grok references are left untouched
+	expanded, types, err = expandGrokPatterns(`user=(?P<user>\w+)`)
+	require.NoError(t, err)
+	assert.Equal(t, `user=(?P<user>\w+)`, expanded)
+	assert.Empty(t, types)
+}
+
+func TestExpandGrokPatternsErrors(t *testing.T) {
+	_, _, err := expandGrokPatterns(`%{UNKNOWN:foo}`)
+	assert.NotNil(t, err)
+
+	_, _, err = expandGrokPatterns(`%{INT:foo:bool}`)
+	assert.NotNil(t, err)
+}
+
+func TestGrokPatternsCompile(t *testing.T) {
+	for name := range grokPatterns {
+		expanded, _, err := expandGrokPatterns("%{" + name + ":value}")
+		require.NoError(t, err, name)
+		_, err = regexp.Compile(expanded)
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestGrokCommonApacheLog(t *testing.T) {
+	expanded, types, err := expandGrokPatterns(`%{COMMONAPACHELOG}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"status_code": AttributeTypeInt, "bytes": AttributeTypeInt}, types)
+
+	re := regexp.MustCompile(expanded)
+	match := re.FindStringSubmatch(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`)
+	require.NotNil(t, match)
+	attributes := make(map[string]string)
+	for i, name := range re.SubexpNames() {
+		if name != "" && match[i] != "" {
+			attributes[name] = match[i]
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"client_ip":    "127.0.0.1",
+		"ident":        "-",
+		"auth":         "frank",
+		"timestamp":    "10/Oct/2000:13:55:36 -0700",
+		"method":       "GET",
+		"url":          "/apache_pb.gif",
+		"http_version": "1.0",
+		"status_code":  "200",
+		"bytes":        "2326",
+	}, attributes)
+}
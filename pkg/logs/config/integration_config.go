@@ -19,11 +19,16 @@ const (
 	JournaldType     = "journald"
 	WindowsEventType = "windows_event"
 	SnmpTrapsType    = "snmp_traps"
+	SyslogType       = "syslog"
 
 	// UTF16BE for UTF-16 Big endian encoding
 	UTF16BE string = "utf-16-be"
 	// UTF16LE for UTF-16 Little Endian encoding
 	UTF16LE string = "utf-16-le"
+
+	// TCPProtocol and UDPProtocol are the transport protocols of a syslog source
+	TCPProtocol = "tcp"
+	UDPProtocol = "udp"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -34,6 +39,11 @@ type LogsConfig struct {
 	Port int    // Network
 	Path string // File, Journald
 
+	Protocol    string `mapstructure:"protocol" json:"protocol"`           // Syslog
+	TLSCertFile string `mapstructure:"tls_cert_file" json:"tls_cert_file"` // Syslog
+	TLSKeyFile  string `mapstructure:"tls_key_file" json:"tls_key_file"`   // Syslog
+	IdleTimeout int    `mapstructure:"idle_timeout" json:"idle_timeout"`   // Syslog
+
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
@@ -57,6 +67,9 @@ type LogsConfig struct {
 	SourceCategory  string
 	Tags            []string
 	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+	RateLimit       *RateLimitConfig  `mapstructure:"rate_limit" json:"rate_limit"`
+	// AutoMultiLine overrides logs_config.auto_multi_line_detection when set
+	AutoMultiLine *bool `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection"`
 }
 
 // TailingMode type
@@ -120,6 +133,16 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType:
+		err := c.validateSyslog()
+		if err != nil {
+			return err
+		}
+	}
+	if c.RateLimit != nil {
+		if err := c.RateLimit.Validate(); err != nil {
+			return err
+		}
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -128,6 +151,28 @@ func (c *LogsConfig) Validate() error {
 	return CompileProcessingRules(c.ProcessingRules)
 }
 
+func (c *LogsConfig) validateSyslog() error {
+	if c.Port == 0 {
+		return fmt.Errorf("syslog source must have a port")
+	}
+	if c.IdleTimeout < 0 {
+		return fmt.Errorf("syslog source must have a positive idle_timeout")
+	}
+	switch c.Protocol {
+	case "", TCPProtocol:
+		if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+			return fmt.Errorf("syslog source must have both a tls_cert_file and a tls_key_file to use TLS")
+		}
+	case UDPProtocol:
+		if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+			return fmt.Errorf("TLS is not supported for syslog sources using the udp protocol")
+		}
+	default:
+		return fmt.Errorf("invalid protocol '%v' for syslog source, must be tcp or udp", c.Protocol)
+	}
+	return nil
+}
+
 func (c *LogsConfig) validateTailingMode() error {
 	mode, found := TailingModeFromString(c.TailingMode)
 	if !found && c.TailingMode != "" {
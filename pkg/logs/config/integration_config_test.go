@@ -21,6 +21,10 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 		{Type: DockerType},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: SnmpTrapsType},
+		{Type: SyslogType, Port: 514},
+		{Type: SyslogType, Port: 514, Protocol: UDPProtocol},
+		{Type: SyslogType, Port: 6514, Protocol: TCPProtocol, TLSCertFile: "/etc/cert.pem", TLSKeyFile: "/etc/key.pem"},
+		{Type: SyslogType, Port: 514, IdleTimeout: 300},
 	}
 
 	for _, config := range validConfigs {
@@ -35,6 +39,11 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: FileType},
 		{Type: TCPType},
 		{Type: UDPType},
+		{Type: SyslogType},
+		{Type: SyslogType, Port: 514, Protocol: "sctp"},
+		{Type: SyslogType, Port: 514, IdleTimeout: -1},
+		{Type: SyslogType, Port: 6514, TLSCertFile: "/etc/cert.pem"},
+		{Type: SyslogType, Port: 6514, Protocol: UDPProtocol, TLSCertFile: "/etc/cert.pem", TLSKeyFile: "/etc/key.pem"},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: "bar"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch}}},
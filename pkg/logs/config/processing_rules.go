@@ -16,18 +16,38 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	LogToMetric    = "log_to_metric"
+	// ExtractAttributes rules accept grok references in their pattern
+	ExtractAttributes = "extract_attributes"
 )
 
-// ProcessingRule defines an exclusion or a masking rule to
-// be applied on log lines
+// Metric types of the log_to_metric processing rules
+const (
+	MetricTypeCount        = "count"
+	MetricTypeDistribution = "distribution"
+)
+
+// ProcessingRule defines an exclusion, a masking or a metric generation rule
+// to be applied on log lines
 type ProcessingRule struct {
 	Type               string
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// log_to_metric rules only
+	MetricName   string   `mapstructure:"metric_name" json:"metric_name"`
+	MetricType   string   `mapstructure:"metric_type" json:"metric_type"`
+	ValueCapture string   `mapstructure:"value_capture" json:"value_capture"`
+	MetricTags   []string `mapstructure:"metric_tags" json:"metric_tags"`
+	DropLine     bool     `mapstructure:"drop_line" json:"drop_line"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	// ValueIndex is the index of the capture holding the value of the metric,
+	// -1 when the metric is a count of the matching lines
+	ValueIndex int
+	// AttributeTypes holds the types of the extracted attributes that are not strings
+	AttributeTypes map[string]string
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
@@ -42,7 +62,7 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, LogToMetric, ExtractAttributes:
 			break
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
@@ -53,10 +73,69 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
-		_, err := regexp.Compile(rule.Pattern)
+		if rule.Type == ExtractAttributes {
+			if err := validateExtractAttributesRule(rule); err != nil {
+				return err
+			}
+			continue
+		}
+
+		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
 		}
+
+		if rule.Type == LogToMetric {
+			if err := validateLogToMetricRule(rule, re); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateExtractAttributesRule checks that the pattern of an
+// extract_attributes rule only references known grok patterns and has at
+// least one named capture
+func validateExtractAttributesRule(rule *ProcessingRule) error {
+	expanded, _, err := expandGrokPatterns(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule %s: %v", rule.Pattern, rule.Name, err)
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+	}
+	if !hasNamedCapture(re) {
+		return fmt.Errorf("no named capture in the pattern of processing rule: %s", rule.Name)
+	}
+	return nil
+}
+
+// validateLogToMetricRule checks the metric settings of a log_to_metric rule:
+// - a metric name must be set
+// - the metric type must be either count (default) or distribution
+// - distributions need a value, taken from a named capture of the pattern
+func validateLogToMetricRule(rule *ProcessingRule, re *regexp.Regexp) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("no metric_name provided for processing rule: %s", rule.Name)
+	}
+
+	switch rule.MetricType {
+	case "", MetricTypeCount, MetricTypeDistribution:
+		break
+	default:
+		return fmt.Errorf("metric_type %s is not supported for processing rule `%s`", rule.MetricType, rule.Name)
+	}
+
+	if rule.ValueCapture == "" {
+		if rule.MetricType == MetricTypeDistribution {
+			return fmt.Errorf("value_capture must be set for the distribution of processing rule `%s`", rule.Name)
+		}
+		return nil
+	}
+	if subexpIndex(re, rule.ValueCapture) == -1 {
+		return fmt.Errorf("value_capture %s is not a named capture of the pattern of processing rule `%s`", rule.ValueCapture, rule.Name)
 	}
 	return nil
 }
@@ -64,6 +143,18 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.Type == ExtractAttributes {
+			expanded, types, err := expandGrokPatterns(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			if rule.Regex, err = regexp.Compile(expanded); err != nil {
+				return err
+			}
+			rule.AttributeTypes = types
+			continue
+		}
+
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
@@ -79,7 +170,23 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 			if err != nil {
 				return err
 			}
+		case LogToMetric:
+			rule.Regex = re
+			rule.ValueIndex = -1
+			if rule.ValueCapture != "" {
+				rule.ValueIndex = subexpIndex(re, rule.ValueCapture)
+			}
 		}
 	}
 	return nil
 }
+
+// subexpIndex returns the index of the capture with the given name, or -1
+func subexpIndex(re *regexp.Regexp, name string) int {
+	for i, subexpName := range re.SubexpNames() {
+		if i > 0 && subexpName == name {
+			return i
+		}
+	}
+	return -1
+}
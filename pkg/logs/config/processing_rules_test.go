@@ -33,3 +33,55 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateLogToMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Type: LogToMetric, Name: "count", Pattern: "error", MetricName: "app.errors"},
+		{Type: LogToMetric, Name: "count_value", Pattern: "sent (?P<bytes>\\d+) bytes", MetricName: "app.bytes", MetricType: MetricTypeCount, ValueCapture: "bytes"},
+		{Type: LogToMetric, Name: "distribution", Pattern: "took (?P<duration>\\d+)ms", MetricName: "app.duration", MetricType: MetricTypeDistribution, ValueCapture: "duration"},
+	}
+	for _, rule := range validRules {
+		assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+
+	invalidRules := []*ProcessingRule{
+		{Type: LogToMetric, Name: "no_metric_name", Pattern: "error"},
+		{Type: LogToMetric, Name: "unknown_type", Pattern: "error", MetricName: "app.errors", MetricType: "gauge"},
+		{Type: LogToMetric, Name: "distribution_without_value", Pattern: "took \\d+ms", MetricName: "app.duration", MetricType: MetricTypeDistribution},
+		{Type: LogToMetric, Name: "unknown_capture", Pattern: "took (?P<duration>\\d+)ms", MetricName: "app.duration", ValueCapture: "latency"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestCompileLogToMetricRules(t *testing.T) {
+	rules := []*ProcessingRule{
+		{Type: LogToMetric, Pattern: "(?P<status>\\d{3}) took (?P<duration>\\d+)ms", MetricName: "app.duration", ValueCapture: "duration"},
+		{Type: LogToMetric, Pattern: "(?P<status>\\d{3})", MetricName: "app.requests"},
+	}
+	err := CompileProcessingRules(rules)
+	assert.Nil(t, err)
+	assert.True(t, rules[0].Regex.MatchString("200 took 12ms"))
+	assert.Equal(t, 2, rules[0].ValueIndex)
+	assert.Equal(t, -1, rules[1].ValueIndex)
+}
+
+func TestValidateExtractAttributesRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Type: ExtractAttributes, Name: "regex", Pattern: "user=(?P<user>\\w+)"},
+		{Type: ExtractAttributes, Name: "grok", Pattern: "%{IP:client} %{INT:status:int}"},
+	}
+	for _, rule := range validRules {
+		assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+
+	invalidRules := []*ProcessingRule{
+		{Type: ExtractAttributes, Name: "no_capture", Pattern: "user=\\w+"},
+		{Type: ExtractAttributes, Name: "unknown_grok", Pattern: "%{FOO:foo}"},
+		{Type: ExtractAttributes, Name: "invalid_regex", Pattern: "%{INT:foo}(?=abf)"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
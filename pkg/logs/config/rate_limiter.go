@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Rate limit modes, defining what happens to the logs exceeding the budget of a source
+const (
+	// RateLimitDrop drops the logs
+	RateLimitDrop = "drop"
+	// RateLimitSample keeps one log out of sample_rate
+	RateLimitSample = "sample"
+	// RateLimitSummarize drops the logs and periodically sends a log giving the number dropped
+	RateLimitSummarize = "summarize"
+)
+
+const (
+	defaultRateLimitSampleRate      = 10
+	defaultRateLimitSummaryInterval = 10 * time.Second
+	rateLimitWindow                 = time.Second
+)
+
+// RateLimitConfig holds the budget of a source
+type RateLimitConfig struct {
+	LinesPerSecond int    `mapstructure:"lines_per_second" json:"lines_per_second"`
+	BytesPerSecond int    `mapstructure:"bytes_per_second" json:"bytes_per_second"`
+	Mode           string `mapstructure:"mode" json:"mode"`
+	// SampleRate is used in sample mode only
+	SampleRate int `mapstructure:"sample_rate" json:"sample_rate"`
+	// SummaryInterval, in seconds, is used in summarize mode only
+	SummaryInterval int `mapstructure:"summary_interval" json:"summary_interval"`
+}
+
+// Validate returns an error if the rate limit is misconfigured
+func (c *RateLimitConfig) Validate() error {
+	if c.LinesPerSecond < 0 || c.BytesPerSecond < 0 {
+		return fmt.Errorf("rate limit budgets can't be negative")
+	}
+	if c.LinesPerSecond == 0 && c.BytesPerSecond == 0 {
+		return fmt.Errorf("rate limit must have a lines_per_second or a bytes_per_second budget")
+	}
+	switch c.Mode {
+	case "", RateLimitDrop, RateLimitSample, RateLimitSummarize:
+	default:
+		return fmt.Errorf("invalid rate limit mode '%v', must be drop, sample or summarize", c.Mode)
+	}
+	if c.SampleRate < 0 || c.SummaryInterval < 0 {
+		return fmt.Errorf("rate limit sample_rate and summary_interval can't be negative")
+	}
+	return nil
+}
+
+// RateLimiter enforces the budget of a source, shared by all its inputs and
+// pipelines. Budgets are computed over windows of one second.
+type RateLimiter struct {
+	linesPerSecond  int
+	bytesPerSecond  int
+	mode            string
+	sampleRate      int
+	summaryInterval time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	windowLines int
+	windowBytes int
+	// usage of the last complete window, for the status
+	lastLines      int
+	lastBytes      int
+	overBudget     int
+	dropped        int64
+	pendingDropped int64
+	lastSummary    time.Time
+}
+
+// NewRateLimiter returns a rate limiter enforcing the given budget
+func NewRateLimiter(c *RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{
+		linesPerSecond:  c.LinesPerSecond,
+		bytesPerSecond:  c.BytesPerSecond,
+		mode:            c.Mode,
+		sampleRate:      c.SampleRate,
+		summaryInterval: time.Duration(c.SummaryInterval) * time.Second,
+		lastSummary:     time.Now(),
+	}
+	if l.mode == "" {
+		l.mode = RateLimitDrop
+	}
+	if l.sampleRate == 0 {
+		l.sampleRate = defaultRateLimitSampleRate
+	}
+	if l.summaryInterval == 0 {
+		l.summaryInterval = defaultRateLimitSummaryInterval
+	}
+	return l
+}
+
+// Allow returns whether a log of the given size fits in the budget, or is
+// kept by sampling.
+func (l *RateLimiter) Allow(size int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.windowStart) >= rateLimitWindow {
+		l.lastLines, l.lastBytes = l.windowLines, l.windowBytes
+		l.windowStart = now
+		l.windowLines, l.windowBytes = 0, 0
+		l.overBudget = 0
+	}
+
+	if (l.linesPerSecond == 0 || l.windowLines < l.linesPerSecond) &&
+		(l.bytesPerSecond == 0 || l.windowBytes+size <= l.bytesPerSecond) {
+		l.windowLines++
+		l.windowBytes += size
+		return true
+	}
+
+	l.overBudget++
+	if l.mode == RateLimitSample && l.overBudget%l.sampleRate == 0 {
+		return true
+	}
+	l.dropped++
+	if l.mode == RateLimitSummarize {
+		l.pendingDropped++
+	}
+	return false
+}
+
+// Summary returns the number of logs dropped since the last summary in
+// summarize mode, and whether a summary is due, once per summary interval.
+// The count is reset once a summary is due.
+func (l *RateLimiter) Summary(now time.Time) (int64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dropped := l.pendingDropped
+	if dropped == 0 || now.Sub(l.lastSummary) < l.summaryInterval {
+		return dropped, false
+	}
+	l.pendingDropped = 0
+	l.lastSummary = now
+	return dropped, true
+}
+
+// Mode returns what happens to the logs exceeding the budget
+func (l *RateLimiter) Mode() string {
+	return l.mode
+}
+
+// Usage returns a description of the budget usage, for the status
+func (l *RateLimiter) Usage() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// the last complete window is the current one once it is over, and
+	// nothing was received if it ended more than one window ago
+	lines, bytes := l.lastLines, l.lastBytes
+	if elapsed := time.Since(l.windowStart); elapsed >= 2*rateLimitWindow {
+		lines, bytes = 0, 0
+	} else if elapsed >= rateLimitWindow {
+		lines, bytes = l.windowLines, l.windowBytes
+	}
+
+	usage := ""
+	if l.linesPerSecond > 0 {
+		usage += fmt.Sprintf("%d/%d lines/s, ", lines, l.linesPerSecond)
+	}
+	if l.bytesPerSecond > 0 {
+		usage += fmt.Sprintf("%d/%d bytes/s, ", bytes, l.bytesPerSecond)
+	}
+	return usage + fmt.Sprintf("%d logs dropped (mode: %s)", l.dropped, l.mode)
+}
@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitConfigValidate(t *testing.T) {
+	validConfigs := []*RateLimitConfig{
+		{LinesPerSecond: 10},
+		{BytesPerSecond: 1000, Mode: RateLimitSample, SampleRate: 5},
+		{LinesPerSecond: 10, BytesPerSecond: 1000, Mode: RateLimitSummarize, SummaryInterval: 30},
+	}
+	for _, config := range validConfigs {
+		assert.Nil(t, config.Validate())
+	}
+
+	invalidConfigs := []*RateLimitConfig{
+		{},
+		{LinesPerSecond: -1},
+		{LinesPerSecond: 10, Mode: "block"},
+		{LinesPerSecond: 10, SampleRate: -1},
+	}
+	for _, config := range invalidConfigs {
+		assert.NotNil(t, config.Validate())
+	}
+}
+
+func TestRateLimiterDrop(t *testing.T) {
+	limiter := NewRateLimiter(&RateLimitConfig{LinesPerSecond: 2, BytesPerSecond: 10})
+	now := time.Now()
+
+	assert.True(t, limiter.Allow(4, now))
+	assert.False(t, limiter.Allow(7, now)) // over the bytes budget
+	assert.True(t, limiter.Allow(6, now))
+	assert.False(t, limiter.Allow(0, now)) // over the lines budget
+
+	// the budget is renewed every second
+	now = now.Add(time.Second)
+	assert.True(t, limiter.Allow(1, now))
+	assert.Equal(t, "2/2 lines/s, 10/10 bytes/s, 2 logs dropped (mode: drop)", limiter.Usage())
+
+	_, due := limiter.Summary(now.Add(time.Hour))
+	assert.False(t, due)
+}
+
+func TestRateLimiterSample(t *testing.T) {
+	limiter := NewRateLimiter(&RateLimitConfig{LinesPerSecond: 1, Mode: RateLimitSample, SampleRate: 3})
+	now := time.Now()
+
+	var allowed []bool
+	for i := 0; i < 7; i++ {
+		allowed = append(allowed, limiter.Allow(1, now))
+	}
+	assert.Equal(t, []bool{true, false, false, true, false, false, true}, allowed)
+}
+
+func TestRateLimiterSummarize(t *testing.T) {
+	limiter := NewRateLimiter(&RateLimitConfig{LinesPerSecond: 1, Mode: RateLimitSummarize, SummaryInterval: 5})
+	now := time.Now()
+
+	assert.True(t, limiter.Allow(1, now))
+	assert.False(t, limiter.Allow(1, now))
+	assert.False(t, limiter.Allow(1, now))
+
+	dropped, due := limiter.Summary(now)
+	assert.Equal(t, int64(2), dropped)
+	assert.False(t, due)
+
+	dropped, due = limiter.Summary(now.Add(5 * time.Second))
+	assert.Equal(t, int64(2), dropped)
+	assert.True(t, due)
+
+	dropped, due = limiter.Summary(now.Add(10 * time.Second))
+	assert.Equal(t, int64(0), dropped)
+	assert.False(t, due)
+}
@@ -30,6 +30,8 @@ type LogSource struct {
 	inputs   map[string]bool
 	lock     *sync.Mutex
 	Messages *Messages
+	// RateLimiter enforces the rate limit of the configuration, nil if there is none
+	RateLimiter *RateLimiter
 	// sourceType is the type of the source that we are tailing whereas Config.Type is the type of the tailer
 	// that reads log lines for this source. E.g, a sourceType == containerd and Config.Type == file means that
 	// the agent is tailing a file to read logs of a containerd container
@@ -38,7 +40,7 @@ type LogSource struct {
 
 // NewLogSource creates a new log source.
 func NewLogSource(name string, config *LogsConfig) *LogSource {
-	return &LogSource{
+	source := &LogSource{
 		Name:     name,
 		Config:   config,
 		Status:   NewLogStatus(),
@@ -46,6 +48,10 @@ func NewLogSource(name string, config *LogsConfig) *LogSource {
 		lock:     &sync.Mutex{},
 		Messages: NewMessages(),
 	}
+	if config != nil && config.RateLimit != nil {
+		source.RateLimiter = NewRateLimiter(config.RateLimit)
+	}
+	return source
 }
 
 // AddInput registers an input as being handled by this source.
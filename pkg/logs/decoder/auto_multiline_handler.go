@@ -0,0 +1,238 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package decoder
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// autoMultiLineMessageKey is the key of the message reporting the detection
+// in the status of the source
+const autoMultiLineMessageKey = "auto_multi_line"
+
+// autoMultiLinePatterns is the built-in library of patterns matching the
+// first line of a log, they are ordered from the most to the least specific
+// so that the first one wins in case of a tie.
+var autoMultiLinePatterns = []*regexp.Regexp{
+	// 2021-01-31T12:00:00, 2021-01-31 12:00:00,000
+	regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}`),
+	// [2021-01-31T12:00:00], [2021-01-31 12:00:00,000]
+	regexp.MustCompile(`^\[\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}`),
+	// 2021/01/31 12:00:00
+	regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}`),
+	// 31/Jan/2021:12:00:00 +0000
+	regexp.MustCompile(`^\[?\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2}`),
+	// Sun, 31 Jan 2021 12:00:00
+	regexp.MustCompile(`^[A-Z][a-z]{2}, \d{2} [A-Z][a-z]{2} \d{4} \d{2}:\d{2}:\d{2}`),
+	// Jan 31 12:00:00
+	regexp.MustCompile(`^[A-Z][a-z]{2} [ 0-3]\d \d{2}:\d{2}:\d{2}`),
+	// 01/31/2021 12:00:00, 1/31/21, 12:00 PM
+	regexp.MustCompile(`^\d{1,2}/\d{1,2}/\d{2,4},? \d{1,2}:\d{2}`),
+	// I0131 12:00:00.000000
+	regexp.MustCompile(`^[IWEF]\d{4} \d{2}:\d{2}:\d{2}`),
+	// 12:00:00.000
+	regexp.MustCompile(`^\d{2}:\d{2}:\d{2}[.,]\d+`),
+	// ERROR: ..., [WARN] ...
+	regexp.MustCompile(`^\[?(?:TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|CRITICAL|FATAL|SEVERE)\b`),
+}
+
+// AutoMultiLineDetection holds the result of the multi-line pattern detection
+// in the logs of a file. It is shared by the successive decoders of the file,
+// so that its lines are not sampled again, and sent as single lines meanwhile,
+// after each rotation.
+type AutoMultiLineDetection struct {
+	path     string
+	mu       sync.Mutex
+	detected bool
+	pattern  *regexp.Regexp
+}
+
+// NewAutoMultiLineDetection returns the detection state of the file at path,
+// which distinguishes the files of a wildcard source in its status.
+func NewAutoMultiLineDetection(path string) *AutoMultiLineDetection {
+	return &AutoMultiLineDetection{path: path}
+}
+
+// MessageKey returns the key of the message reporting the detection in the
+// status of the source.
+func (d *AutoMultiLineDetection) MessageKey() string {
+	if d.path == "" {
+		return autoMultiLineMessageKey
+	}
+	return autoMultiLineMessageKey + ":" + d.path
+}
+
+// addMessage reports the state of the detection in the status of the source
+func (d *AutoMultiLineDetection) addMessage(source *config.LogSource, message string) {
+	if d.path == "" {
+		source.Messages.AddMessage(d.MessageKey(), "Auto multi-line detection: "+message)
+		return
+	}
+	source.Messages.AddMessage(d.MessageKey(), fmt.Sprintf("Auto multi-line detection for %s: %s", d.path, message))
+}
+
+// result returns the pattern detected, nil if none was, and whether the
+// detection is complete.
+func (d *AutoMultiLineDetection) result() (*regexp.Regexp, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pattern, d.detected
+}
+
+// setResult completes the detection with the pattern detected, nil if none
+// was, unless it was already completed by another decoder.
+func (d *AutoMultiLineDetection) setResult(pattern *regexp.Regexp) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.detected {
+		d.detected = true
+		d.pattern = pattern
+	}
+}
+
+// AutoMultiLineHandler sends the first lines of a source as single lines
+// while scoring them against a library of patterns matching the start of a
+// log. Once enough lines have been sampled, it aggregates the following
+// lines with the pattern that matched the most lines, if any matched often
+// enough, and keeps sending single lines otherwise.
+type AutoMultiLineHandler struct {
+	inputChan         chan *Message
+	outputChan        chan *Message
+	singleLineHandler *SingleLineHandler
+	multiLineHandler  *MultiLineHandler
+	source            *config.LogSource
+	detection         *AutoMultiLineDetection
+	flushTimeout      time.Duration
+	lineLimit         int
+	sampleSize        int
+	matchThreshold    float64
+	linesSampled      int
+	scores            []int
+	detected          bool
+}
+
+// NewAutoMultiLineHandler returns a new AutoMultiLineHandler, continuing the
+// given detection if any.
+func NewAutoMultiLineHandler(outputChan chan *Message, source *config.LogSource, detection *AutoMultiLineDetection, sampleSize int, matchThreshold float64, flushTimeout time.Duration, lineLimit int) *AutoMultiLineHandler {
+	if detection == nil {
+		detection = NewAutoMultiLineDetection("")
+	}
+	return &AutoMultiLineHandler{
+		inputChan:         make(chan *Message),
+		outputChan:        outputChan,
+		singleLineHandler: NewSingleLineHandler(outputChan, lineLimit),
+		source:            source,
+		detection:         detection,
+		flushTimeout:      flushTimeout,
+		lineLimit:         lineLimit,
+		sampleSize:        sampleSize,
+		matchThreshold:    matchThreshold,
+		scores:            make([]int, len(autoMultiLinePatterns)),
+	}
+}
+
+// Handle puts all new lines into a channel for later processing.
+func (h *AutoMultiLineHandler) Handle(input *Message) {
+	h.inputChan <- input
+}
+
+// Stop stops the handler.
+func (h *AutoMultiLineHandler) Stop() {
+	close(h.inputChan)
+}
+
+// Start starts the handler.
+func (h *AutoMultiLineHandler) Start() {
+	if _, detected := h.detection.result(); !detected {
+		h.detection.addMessage(h.source, "sampling lines")
+	}
+	go h.run()
+}
+
+// run consumes new lines and processes them.
+func (h *AutoMultiLineHandler) run() {
+	for line := range h.inputChan {
+		h.process(line)
+	}
+	if h.multiLineHandler != nil {
+		// the multi-line handler closes the output channel once flushed
+		h.multiLineHandler.Stop()
+		return
+	}
+	close(h.outputChan)
+}
+
+// process scores and sends the sampled lines, then forwards the following
+// lines to the handler chosen by the detection.
+func (h *AutoMultiLineHandler) process(message *Message) {
+	if !h.detected {
+		if pattern, detected := h.detection.result(); detected {
+			// the detection was completed by another decoder of the file,
+			// e.g. before its rotation
+			h.useDetected(pattern)
+		}
+	}
+	if h.multiLineHandler != nil {
+		h.multiLineHandler.Handle(message)
+		return
+	}
+	if !h.detected {
+		h.score(message)
+	}
+	h.singleLineHandler.process(message)
+}
+
+// score matches a line against the patterns of the library and picks the
+// best one once the sample is complete.
+func (h *AutoMultiLineHandler) score(message *Message) {
+	for i, pattern := range autoMultiLinePatterns {
+		if pattern.Match(message.Content) {
+			h.scores[i]++
+		}
+	}
+	h.linesSampled++
+	if h.linesSampled < h.sampleSize {
+		return
+	}
+
+	best := 0
+	for i, score := range h.scores {
+		if score > h.scores[best] {
+			best = i
+		}
+	}
+	ratio := float64(h.scores[best]) / float64(h.linesSampled)
+	if h.scores[best] == 0 || ratio < h.matchThreshold {
+		log.Debugf("No multi-line pattern detected for source %s after %d lines", h.source.Name, h.linesSampled)
+		h.detection.addMessage(h.source, "no pattern detected, lines are sent as single lines")
+		h.detection.setResult(nil)
+		h.useDetected(nil)
+		return
+	}
+
+	pattern := autoMultiLinePatterns[best]
+	log.Infof("Detected multi-line pattern %s for source %s, matching %d out of %d lines", pattern, h.source.Name, h.scores[best], h.linesSampled)
+	h.detection.addMessage(h.source, fmt.Sprintf("detected pattern %s, matching %d out of %d lines", pattern, h.scores[best], h.linesSampled))
+	h.detection.setResult(pattern)
+	h.useDetected(pattern)
+}
+
+// useDetected aggregates the following lines with the pattern detected, if
+// any, or keeps sending single lines otherwise.
+func (h *AutoMultiLineHandler) useDetected(pattern *regexp.Regexp) {
+	h.detected = true
+	if pattern == nil {
+		return
+	}
+	h.multiLineHandler = NewMultiLineHandler(h.outputChan, pattern, h.flushTimeout, h.lineLimit)
+	h.multiLineHandler.Start()
+}
@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package decoder
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestAutoMultiLineHandlerDetectsPattern(t *testing.T) {
+	outputChan := make(chan *Message, 10)
+	source := config.NewLogSource("foo", &config.LogsConfig{})
+	h := NewAutoMultiLineHandler(outputChan, source, nil, 3, 0.5, time.Second, 100)
+	h.Start()
+
+	// the sampled lines are sent as single lines
+	for _, line := range []string{"2021-01-31 12:00:00 ERROR boom", "  at foo", "2021-01-31 12:00:01 INFO ok"} {
+		h.Handle(getDummyMessageWithLF(line))
+		assert.Equal(t, strings.TrimSpace(line), string((<-outputChan).Content))
+	}
+	assert.Equal(t, []string{`Auto multi-line detection: detected pattern ^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}, matching 2 out of 3 lines`}, source.Messages.GetMessages())
+
+	// the following lines are aggregated
+	h.Handle(getDummyMessageWithLF("2021-01-31 12:00:02 ERROR boom"))
+	h.Handle(getDummyMessageWithLF("  at foo"))
+	h.Handle(getDummyMessageWithLF("2021-01-31 12:00:03 INFO ok"))
+	output := <-outputChan
+	assert.Equal(t, `2021-01-31 12:00:02 ERROR boom\n  at foo`, string(output.Content))
+	assert.Equal(t, len("2021-01-31 12:00:02 ERROR boom")+len("  at foo")+2, output.RawDataLen)
+
+	h.Stop()
+	assert.Equal(t, "2021-01-31 12:00:03 INFO ok", string((<-outputChan).Content))
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+}
+
+func TestAutoMultiLineHandlerWithoutPattern(t *testing.T) {
+	outputChan := make(chan *Message, 10)
+	source := config.NewLogSource("foo", &config.LogsConfig{})
+	h := NewAutoMultiLineHandler(outputChan, source, nil, 3, 0.5, 10*time.Millisecond, 100)
+	h.Start()
+	assert.Equal(t, []string{"Auto multi-line detection: sampling lines"}, source.Messages.GetMessages())
+
+	// one line out of three is below the threshold
+	lines := []string{"2021-01-31 12:00:00 hello", "world", "foo", "bar", "2021-01-31 12:00:01 baz"}
+	for _, line := range lines {
+		h.Handle(getDummyMessageWithLF(line))
+		assert.Equal(t, line, string((<-outputChan).Content))
+	}
+	assert.Equal(t, []string{"Auto multi-line detection: no pattern detected, lines are sent as single lines"}, source.Messages.GetMessages())
+
+	h.Stop()
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+}
+
+func TestAutoMultiLineHandlerKeepsDetection(t *testing.T) {
+	outputChan := make(chan *Message, 10)
+	source := config.NewLogSource("foo", &config.LogsConfig{})
+	detection := NewAutoMultiLineDetection("/var/log/app.log")
+	h := NewAutoMultiLineHandler(outputChan, source, detection, 2, 0.5, time.Second, 100)
+	h.Start()
+	for _, line := range []string{"2021-01-31 12:00:00 ERROR boom", "  at foo"} {
+		h.Handle(getDummyMessageWithLF(line))
+		<-outputChan
+	}
+	h.Stop()
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+
+	// the files of a wildcard source are reported separately
+	other := NewAutoMultiLineHandler(make(chan *Message, 10), source, NewAutoMultiLineDetection("/var/log/other.log"), 2, 0.5, time.Second, 100)
+	other.Start()
+	defer other.Stop()
+	assert.ElementsMatch(t, []string{
+		`Auto multi-line detection for /var/log/app.log: detected pattern ^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}, matching 1 out of 2 lines`,
+		"Auto multi-line detection for /var/log/other.log: sampling lines",
+	}, source.Messages.GetMessages())
+
+	// the lines of the file are aggregated right away after its rotation
+	outputChan = make(chan *Message, 10)
+	h = NewAutoMultiLineHandler(outputChan, source, detection, 2, 0.5, time.Second, 100)
+	h.Start()
+	h.Handle(getDummyMessageWithLF("2021-01-31 12:00:02 ERROR boom"))
+	h.Handle(getDummyMessageWithLF("  at foo"))
+	h.Stop()
+	assert.Equal(t, `2021-01-31 12:00:02 ERROR boom\n  at foo`, string((<-outputChan).Content))
+	_, isOpen = <-outputChan
+	assert.False(t, isOpen)
+}
+
+func TestAutoMultiLinePatterns(t *testing.T) {
+	for _, line := range []string{
+		"2021-01-31T12:00:00.000Z INFO hello",
+		"2021-01-31 12:00:00,000 - root - INFO - hello",
+		"[2021-01-31 12:00:00] hello",
+		"2021/01/31 12:00:00 hello",
+		"31/Jan/2021:12:00:00 +0000 hello",
+		"Sun, 31 Jan 2021 12:00:00 GMT hello",
+		"Jan 31 12:00:00 host hello",
+		"Jan  1 12:00:00 host hello",
+		"1/31/2021, 12:00 PM hello",
+		"I0131 12:00:00.000000 1 main.go:10] hello",
+		"12:00:00.000 hello",
+		"ERROR: hello",
+		"[WARN] hello",
+	} {
+		matched := false
+		for _, pattern := range autoMultiLinePatterns {
+			matched = matched || pattern.MatchString(line)
+		}
+		assert.True(t, matched, line)
+	}
+
+	for _, line := range []string{
+		"  at com.example.Foo(Foo.java:10)",
+		"Traceback (most recent call last):",
+		"goroutine 1 [running]:",
+		`{"message": "hello"}`,
+		strings.Repeat("a", 20),
+	} {
+		for _, pattern := range autoMultiLinePatterns {
+			assert.False(t, pattern.MatchString(line), line)
+		}
+	}
+}
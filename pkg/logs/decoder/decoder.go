@@ -8,6 +8,7 @@ package decoder
 import (
 	"bytes"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/parser"
 )
@@ -75,11 +76,12 @@ type Decoder struct {
 
 // InitializeDecoder returns a properly initialized Decoder
 func InitializeDecoder(source *config.LogSource, parser parser.Parser) *Decoder {
-	return NewDecoderWithEndLineMatcher(source, parser, &NewLineMatcher{})
+	return NewDecoderWithEndLineMatcher(source, parser, &NewLineMatcher{}, nil)
 }
 
-// NewDecoderWithEndLineMatcher initialize a decoder with given endline strategy.
-func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parser.Parser, matcher EndLineMatcher) *Decoder {
+// NewDecoderWithEndLineMatcher initialize a decoder with given endline strategy,
+// autoMultiLine is the state of the multi-line pattern detection to continue, if any.
+func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parser.Parser, matcher EndLineMatcher, autoMultiLine *AutoMultiLineDetection) *Decoder {
 	inputChan := make(chan *Input)
 	outputChan := make(chan *Message)
 	lineLimit := defaultContentLenLimit
@@ -91,6 +93,11 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parser.Parser
 			lineHandler = NewMultiLineHandler(outputChan, rule.Regex, defaultFlushTimeout, lineLimit)
 		}
 	}
+	if lineHandler == nil && isAutoMultiLineEnabled(source) {
+		sampleSize := coreConfig.Datadog.GetInt("logs_config.auto_multi_line_sample_size")
+		matchThreshold := coreConfig.Datadog.GetFloat64("logs_config.auto_multi_line_match_threshold")
+		lineHandler = NewAutoMultiLineHandler(outputChan, source, autoMultiLine, sampleSize, matchThreshold, defaultFlushTimeout, lineLimit)
+	}
 	if lineHandler == nil {
 		lineHandler = NewSingleLineHandler(outputChan, lineLimit)
 	}
@@ -104,6 +111,15 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parser.Parser
 	return New(inputChan, outputChan, lineParser, lineLimit, matcher)
 }
 
+// isAutoMultiLineEnabled returns whether the multi-line pattern of a source
+// must be detected, the source configuration overrides the global one.
+func isAutoMultiLineEnabled(source *config.LogSource) bool {
+	if source.Config.AutoMultiLine != nil {
+		return *source.Config.AutoMultiLine
+	}
+	return coreConfig.Datadog.GetBool("logs_config.auto_multi_line_detection")
+}
+
 // New returns an initialized Decoder
 func New(InputChan chan *Input, OutputChan chan *Message, lineParser LineParser, contentLenLimit int, matcher EndLineMatcher) *Decoder {
 	var lineBuffer bytes.Buffer
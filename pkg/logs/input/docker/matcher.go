@@ -13,7 +13,7 @@ import (
 
 // InitializeDecoder returns a properly initialized Decoder
 func InitializeDecoder(source *config.LogSource, containerID string) *decoder.Decoder {
-	return decoder.NewDecoderWithEndLineMatcher(source, NewParser(containerID), &headerMatcher{})
+	return decoder.NewDecoderWithEndLineMatcher(source, NewParser(containerID), &headerMatcher{}, nil)
 }
 
 const (
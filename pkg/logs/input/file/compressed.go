@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// gzipExtension is the extension of the compressed files. As they can't be
+// appended to, compressed files are read once, from their beginning or from
+// their last committed offset, which is a position in the decompressed content.
+// Other formats, like zstd (.zst), are out of scope: they are not decompressed,
+// so they must not match the path of a source, and a rotated file compressed
+// with them is not finished from its copy.
+const gzipExtension = ".gz"
+
+// compressedFileSettleDelay is the time a compressed file must not have been
+// modified for before being read, to make sure it is complete.
+const compressedFileSettleDelay = scanPeriod
+
+// fingerprintSize is the number of bytes at the beginning of a file used to
+// recognize its compressed copy after a rotation.
+const fingerprintSize = 256
+
+// isCompressed returns whether the file at path is compressed
+func isCompressed(path string) bool {
+	return strings.HasSuffix(path, gzipExtension)
+}
+
+// isSettled returns whether the file at path was not modified recently
+func isSettled(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return time.Since(fi.ModTime()) >= compressedFileSettleDelay
+}
+
+// compressedCopyPattern returns the pattern matching the compressed copies of
+// a rotated file, e.g. /var/log/app*.gz for /var/log/app.log, which matches
+// app.log.1.gz, app.log-20200131.gz and app-20200131.log.gz
+func compressedCopyPattern(path string) string {
+	base := filepath.Base(path)
+	return filepath.Join(filepath.Dir(path), strings.TrimSuffix(base, filepath.Ext(base))+"*"+gzipExtension)
+}
+
+// compressedFileID returns an identifier of the compressed file at path built
+// from its beginning and its size, as it may be renamed by a later rotation,
+// e.g. from app.log.1.gz to app.log.2.gz
+func compressedFileID(path string) (string, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	content := make([]byte, fingerprintSize)
+	n, err := io.ReadFull(f, content)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return fmt.Sprintf("%x-%d", sha256.Sum256(content[:n]), fi.Size()), nil
+}
+
+// hasPrefix returns whether the decompressed content of the file at path
+// starts with prefix
+func hasPrefix(path string, prefix []byte) bool {
+	f, err := openFile(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return false
+	}
+	content := make([]byte, len(prefix))
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return false
+	}
+	return bytes.Equal(content, prefix)
+}
+
+// setupCompressed sets up the tailer of a compressed file, skipping the
+// decompressed content up to offset
+func (t *Tailer) setupCompressed(offset int64) error {
+	fullpath, err := filepath.Abs(t.path)
+	if err != nil {
+		return err
+	}
+	t.fullpath = fullpath
+
+	// adds metadata to enable users to filter logs by filename
+	t.tags = t.buildTailerTags()
+
+	log.Info("Opening compressed file", t.path, "for tailer key", buildTailerKey(t))
+	f, err := openFile(fullpath)
+	if err != nil {
+		return err
+	}
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	skipped, err := io.CopyN(ioutil.Discard, reader, offset)
+	if err != nil && err != io.EOF {
+		f.Close()
+		return err
+	}
+
+	t.file = f
+	t.reader = reader
+	t.readOffset = skipped
+	t.decodedOffset = skipped
+
+	return nil
+}
+
+// readCompressed reads the decompressed content of a file, it returns io.EOF
+// once the whole file has been read.
+func (t *Tailer) readCompressed() (int, error) {
+	inBuf := make([]byte, 4096)
+	n, err := t.reader.Read(inBuf)
+	if n > 0 {
+		t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+		t.incrementReadOffset(n)
+	}
+	if err == io.EOF {
+		log.Info("Finished reading compressed file ", t.path)
+		return n, err
+	}
+	if err != nil {
+		// an unexpected error occurred, stop the tailer
+		t.source.Status.Error(err)
+		return n, log.Error("Unexpected error occurred while reading compressed file: ", err)
+	}
+	return n, nil
+}
@@ -10,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/status"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -21,6 +23,21 @@ import (
 // files are tailed
 const openFilesLimitWarningType = "open_files_limit_warning"
 
+// maxUntailedFilesReported is the maximum number of files not tailed because
+// of the open files limit listed in the status of a source
+const maxUntailedFilesReported = 10
+
+// Orders of the files matching a wildcard path, the first ones are tailed
+// when the open files limit is reached
+const (
+	// WildcardOrderByName tails the files in reverse lexicographical order
+	WildcardOrderByName = "by_name"
+	// WildcardOrderByModificationTime tails the most recently modified files,
+	// the files which are not written to anymore are swapped at each scan
+	// with the ones which are
+	WildcardOrderByModificationTime = "by_modification_time"
+)
+
 // File represents a file to tail
 type File struct {
 	Path string
@@ -55,21 +72,30 @@ func (t *File) getSourceIdentifier() string {
 // Provider implements the logic to retrieve at most filesLimit Files defined in sources
 type Provider struct {
 	filesLimit      int
+	wildcardOrder   string
 	shouldLogErrors bool
 }
 
-// NewProvider returns a new Provider
-func NewProvider(filesLimit int) *Provider {
+// NewProvider returns a new Provider, the files matching a wildcard path are
+// returned in wildcardOrder
+func NewProvider(filesLimit int, wildcardOrder string) *Provider {
+	switch wildcardOrder {
+	case WildcardOrderByName, WildcardOrderByModificationTime:
+	default:
+		log.Warnf("Invalid wildcard order %q, defaulting to %s", wildcardOrder, WildcardOrderByName)
+		wildcardOrder = WildcardOrderByName
+	}
 	return &Provider{
 		filesLimit:      filesLimit,
+		wildcardOrder:   wildcardOrder,
 		shouldLogErrors: true,
 	}
 }
 
 // FilesToTail returns all the Files matching paths in sources,
 // it cannot return more than filesLimit Files.
-// For now, there is no way to prioritize specific Files over others,
-// they are just returned in reverse lexicographical order, see `searchFiles`
+// Sources are prioritized in their order, and the files matching a wildcard
+// path in the order of the provider, see `searchFiles`
 func (p *Provider) FilesToTail(sources []*config.LogSource) []*File {
 	var filesToTail []*File
 	shouldLogErrors := p.shouldLogErrors
@@ -82,6 +108,7 @@ func (p *Provider) FilesToTail(sources []*config.LogSource) []*File {
 		isWildcardPath := config.ContainsWildcard(source.Config.Path)
 		if err != nil {
 			source.Status.Error(err)
+			source.Messages.RemoveMessage(untailedFilesMessageKey(source))
 			if isWildcardPath {
 				source.Messages.AddMessage(source.Config.Path, fmt.Sprintf("%d files tailed out of %d files matching", tailedFileCounter, len(files)))
 			}
@@ -90,10 +117,14 @@ func (p *Provider) FilesToTail(sources []*config.LogSource) []*File {
 			}
 			continue
 		}
-		for j := 0; j < len(files) && len(filesToTail) < p.filesLimit; j++ {
-			file := files[j]
-			filesToTail = append(filesToTail, file)
-			tailedFileCounter++
+		var untailedFiles []string
+		for _, file := range files {
+			if len(filesToTail) < p.filesLimit {
+				filesToTail = append(filesToTail, file)
+				tailedFileCounter++
+			} else {
+				untailedFiles = append(untailedFiles, file.Path)
+			}
 		}
 
 		if len(filesToTail) >= p.filesLimit {
@@ -111,6 +142,11 @@ func (p *Provider) FilesToTail(sources []*config.LogSource) []*File {
 		if isWildcardPath {
 			source.Messages.AddMessage(source.Config.Path, fmt.Sprintf("%d files tailed out of %d files matching", tailedFileCounter, len(files)))
 		}
+		if len(untailedFiles) > 0 {
+			source.Messages.AddMessage(untailedFilesMessageKey(source), untailedFilesMessage(untailedFiles))
+		} else {
+			source.Messages.RemoveMessage(untailedFilesMessageKey(source))
+		}
 	}
 
 	if len(filesToTail) == p.filesLimit {
@@ -164,6 +200,23 @@ func (p *Provider) searchFiles(pattern string, source *config.LogSource) ([]*Fil
 	sort.SliceStable(paths, func(i, j int) bool {
 		return filepath.Base(paths[i]) > filepath.Base(paths[j])
 	})
+	if p.wildcardOrder == WildcardOrderByModificationTime {
+		// then by descending modification times, the files which can't be
+		// stat'ed come last
+		modTimes := make(map[string]time.Time, len(paths))
+		for _, path := range paths {
+			if fi, err := os.Stat(path); err == nil {
+				modTimes[path] = fi.ModTime()
+			}
+		}
+		sort.SliceStable(paths, func(i, j int) bool {
+			return modTimes[paths[i]].After(modTimes[paths[j]])
+		})
+	}
+	// the compressed files, which are read once, come last
+	sort.SliceStable(paths, func(i, j int) bool {
+		return !isCompressed(paths[i]) && isCompressed(paths[j])
+	})
 
 	// Resolve excluded path(s)
 	excludedPaths := make(map[string]int)
@@ -189,6 +242,22 @@ func (p *Provider) searchFiles(pattern string, source *config.LogSource) ([]*Fil
 	return files, nil
 }
 
+// untailedFilesMessageKey returns the key of the message listing the files
+// of a source which are not tailed
+func untailedFilesMessageKey(source *config.LogSource) string {
+	return source.Config.Path + ":untailed"
+}
+
+// untailedFilesMessage returns the message listing the files which are not
+// tailed because of the open files limit
+func untailedFilesMessage(paths []string) string {
+	message := "Files not tailed because of the open files limit: "
+	if len(paths) <= maxUntailedFilesReported {
+		return message + strings.Join(paths, ", ")
+	}
+	return message + fmt.Sprintf("%s and %d more", strings.Join(paths[:maxUntailedFilesReported], ", "), len(paths)-maxUntailedFilesReported)
+}
+
 // exists returns true if the file at path filePath exists
 // Note: we can't rely on os.IsNotExist for windows, so we check error nullity.
 // As we're tailing with *, the error is related to the path being malformed.
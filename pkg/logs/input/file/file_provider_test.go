@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 
@@ -75,7 +76,7 @@ func (suite *ProviderTestSuite) TearDownTest() {
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFile() {
 	path := fmt.Sprintf("%s/1/1.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardOrderByName)
 	logSources := suite.newLogSources(path)
 	config.CreateSources(logSources)
 	files := fileProvider.FilesToTail(logSources)
@@ -88,7 +89,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFile() {
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromDirectory() {
 	path := fmt.Sprintf("%s/1/*.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardOrderByName)
 	logSources := suite.newLogSources(path)
 	status.InitStatus(config.CreateSources(logSources))
 	files := fileProvider.FilesToTail(logSources)
@@ -113,7 +114,7 @@ func (suite *ProviderTestSuite) TestCollectFilesWildcardFlag() {
 	// with wildcard
 
 	path := fmt.Sprintf("%s/1/*.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardOrderByName)
 	logSources := suite.newLogSources(path)
 	files, err := fileProvider.CollectFiles(logSources[0])
 	suite.NoError(err, "searching for files in this directory shouldn't fail")
@@ -124,7 +125,7 @@ func (suite *ProviderTestSuite) TestCollectFilesWildcardFlag() {
 	// without wildcard
 
 	path = fmt.Sprintf("%s/1/1.log", suite.testDir)
-	fileProvider = NewProvider(suite.filesLimit)
+	fileProvider = NewProvider(suite.filesLimit, WildcardOrderByName)
 	logSources = suite.newLogSources(path)
 	files, err = fileProvider.CollectFiles(logSources[0])
 	suite.NoError(err, "searching for files in this directory shouldn't fail")
@@ -135,7 +136,7 @@ func (suite *ProviderTestSuite) TestCollectFilesWildcardFlag() {
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromAnyDirectoryWithRightPermissions() {
 	path := fmt.Sprintf("%s/*/*1.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardOrderByName)
 	logSources := suite.newLogSources(path)
 	config.CreateSources(logSources)
 	files := fileProvider.FilesToTail(logSources)
@@ -150,7 +151,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromAnyDirectoryWi
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFileWithWildcard() {
 	path := fmt.Sprintf("%s/1/?.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardOrderByName)
 	logSources := suite.newLogSources(path)
 	status.InitStatus(config.CreateSources(logSources))
 	files := fileProvider.FilesToTail(logSources)
@@ -174,7 +175,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFileWithWildcard()
 func (suite *ProviderTestSuite) TestWildcardPathsAreSorted() {
 	filesLimit := 6
 	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
-	fileProvider := NewProvider(filesLimit)
+	fileProvider := NewProvider(filesLimit, WildcardOrderByName)
 	logSources := suite.newLogSources(path)
 	files := fileProvider.FilesToTail(logSources)
 	suite.Equal(5, len(files))
@@ -188,14 +189,60 @@ func (suite *ProviderTestSuite) TestWildcardPathsAreSorted() {
 	suite.Equal(fmt.Sprintf("%s/1/1.log", suite.testDir), files[4].Path)
 }
 
+func (suite *ProviderTestSuite) TestCompressedPathsAreSortedLast() {
+	_, err := os.Create(fmt.Sprintf("%s/1/4.log.gz", suite.testDir))
+	suite.Nil(err)
+
+	path := fmt.Sprintf("%s/1/*", suite.testDir)
+	fileProvider := NewProvider(suite.filesLimit, WildcardOrderByName)
+	files := fileProvider.FilesToTail(suite.newLogSources(path))
+	suite.Equal(3, len(files))
+	suite.Equal(fmt.Sprintf("%s/1/3.log", suite.testDir), files[0].Path)
+	suite.Equal(fmt.Sprintf("%s/1/2.log", suite.testDir), files[1].Path)
+	suite.Equal(fmt.Sprintf("%s/1/1.log", suite.testDir), files[2].Path)
+}
+
+func (suite *ProviderTestSuite) TestWildcardPathsAreSortedByModificationTime() {
+	now := time.Now()
+	for i, name := range []string{"1/1.log", "2/2.log", "1/3.log", "1/2.log", "2/1.log"} {
+		modTime := now.Add(-time.Duration(i) * time.Minute)
+		suite.Nil(os.Chtimes(fmt.Sprintf("%s/%s", suite.testDir, name), modTime, modTime))
+	}
+
+	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
+	fileProvider := NewProvider(suite.filesLimit, WildcardOrderByModificationTime)
+	logSources := suite.newLogSources(path)
+	files := fileProvider.FilesToTail(logSources)
+	suite.Equal(3, len(files))
+	suite.Equal(fmt.Sprintf("%s/1/1.log", suite.testDir), files[0].Path)
+	suite.Equal(fmt.Sprintf("%s/2/2.log", suite.testDir), files[1].Path)
+	suite.Equal(fmt.Sprintf("%s/1/3.log", suite.testDir), files[2].Path)
+	suite.ElementsMatch([]string{
+		"3 files tailed out of 5 files matching",
+		fmt.Sprintf("Files not tailed because of the open files limit: %s/1/2.log, %s/2/1.log", suite.testDir, suite.testDir),
+	}, logSources[0].Messages.GetMessages())
+}
+
+func (suite *ProviderTestSuite) TestUntailedFilesMessage() {
+	var paths []string
+	for i := 0; i < 12; i++ {
+		paths = append(paths, fmt.Sprintf("%d.log", i))
+	}
+	suite.Equal("Files not tailed because of the open files limit: 0.log, 1.log", untailedFilesMessage(paths[:2]))
+	suite.Equal("Files not tailed because of the open files limit: 0.log, 1.log, 2.log, 3.log, 4.log, 5.log, 6.log, 7.log, 8.log, 9.log and 2 more", untailedFilesMessage(paths))
+}
+
 func (suite *ProviderTestSuite) TestNumberOfFilesToTailDoesNotExceedLimit() {
 	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardOrderByName)
 	logSources := suite.newLogSources(path)
 	status.InitStatus(config.CreateSources(logSources))
 	files := fileProvider.FilesToTail(logSources)
 	suite.Equal(suite.filesLimit, len(files))
-	suite.Equal([]string{"3 files tailed out of 5 files matching"}, logSources[0].Messages.GetMessages())
+	suite.ElementsMatch([]string{
+		"3 files tailed out of 5 files matching",
+		fmt.Sprintf("Files not tailed because of the open files limit: %s/2/1.log, %s/1/1.log", suite.testDir, suite.testDir),
+	}, logSources[0].Messages.GetMessages())
 	suite.Equal(
 		[]string{
 			"The limit on the maximum number of files in use (3) has been reached. Increase this limit (thanks to the attribute logs_config.open_files_limit in datadog.yaml) or decrease the number of tailed file.",
@@ -206,7 +253,7 @@ func (suite *ProviderTestSuite) TestNumberOfFilesToTailDoesNotExceedLimit() {
 
 func (suite *ProviderTestSuite) TestAllWildcardPathsAreUpdated() {
 	filesLimit := 2
-	fileProvider := NewProvider(filesLimit)
+	fileProvider := NewProvider(filesLimit, WildcardOrderByName)
 	logSources := []*config.LogSource{
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/1/*.log", suite.testDir)}),
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/2/*.log", suite.testDir)}),
@@ -214,14 +261,20 @@ func (suite *ProviderTestSuite) TestAllWildcardPathsAreUpdated() {
 	status.InitStatus(config.CreateSources(logSources))
 	files := fileProvider.FilesToTail(logSources)
 	suite.Equal(2, len(files))
-	suite.Equal([]string{"2 files tailed out of 3 files matching"}, logSources[0].Messages.GetMessages())
+	suite.ElementsMatch([]string{
+		"2 files tailed out of 3 files matching",
+		fmt.Sprintf("Files not tailed because of the open files limit: %s/1/1.log", suite.testDir),
+	}, logSources[0].Messages.GetMessages())
 	suite.Equal(
 		[]string{
 			"The limit on the maximum number of files in use (2) has been reached. Increase this limit (thanks to the attribute logs_config.open_files_limit in datadog.yaml) or decrease the number of tailed file.",
 		},
 		status.Get().Warnings,
 	)
-	suite.Equal([]string{"0 files tailed out of 2 files matching"}, logSources[1].Messages.GetMessages())
+	suite.ElementsMatch([]string{
+		"0 files tailed out of 2 files matching",
+		fmt.Sprintf("Files not tailed because of the open files limit: %s/2/2.log, %s/2/1.log", suite.testDir, suite.testDir),
+	}, logSources[1].Messages.GetMessages())
 	suite.Equal(
 		[]string{
 			"The limit on the maximum number of files in use (2) has been reached. Increase this limit (thanks to the attribute logs_config.open_files_limit in datadog.yaml) or decrease the number of tailed file.",
@@ -257,7 +310,7 @@ func (suite *ProviderTestSuite) TestExcludePath() {
 	filesLimit := 6
 	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
 	excludePaths := []string{fmt.Sprintf("%s/2/*.log", suite.testDir)}
-	fileProvider := NewProvider(filesLimit)
+	fileProvider := NewProvider(filesLimit, WildcardOrderByName)
 	logSources := []*config.LogSource{
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, ExcludePaths: excludePaths}),
 	}
@@ -6,6 +6,8 @@
 package file
 
 import (
+	"io"
+	"path/filepath"
 	"sync/atomic"
 	"time"
 
@@ -21,6 +23,17 @@ import (
 // scanPeriod represents the period of time between two scans.
 const scanPeriod = 10 * time.Second
 
+// rotatedFileLookupPeriod is the time during which the compressed copy of a
+// rotated file is looked up, once its tailer is stopped.
+const rotatedFileLookupPeriod = time.Minute
+
+// rotatedFile is a file which has been rotated, it may be compressed before
+// its tailer could read it until its end
+type rotatedFile struct {
+	tailer    *Tailer
+	rotatedAt time.Time
+}
+
 // Scanner checks all files provided by fileProvider and create new tailers
 // or update the old ones if needed
 type Scanner struct {
@@ -31,20 +44,24 @@ type Scanner struct {
 	tailingLimit        int
 	fileProvider        *Provider
 	tailers             map[string]*Tailer
+	backfilledFiles     map[string]bool // compressed files read until their end, by file ID
+	rotatedFiles        []*rotatedFile
 	registry            auditor.Registry
 	tailerSleepDuration time.Duration
 	stop                chan struct{}
 }
 
 // NewScanner returns a new scanner.
-func NewScanner(sources *config.LogSources, tailingLimit int, pipelineProvider pipeline.Provider, registry auditor.Registry, tailerSleepDuration time.Duration) *Scanner {
+// The files matching a wildcard path are tailed in wildcardOrder when there are more than tailingLimit.
+func NewScanner(sources *config.LogSources, tailingLimit int, wildcardOrder string, pipelineProvider pipeline.Provider, registry auditor.Registry, tailerSleepDuration time.Duration) *Scanner {
 	return &Scanner{
 		pipelineProvider:    pipelineProvider,
 		tailingLimit:        tailingLimit,
 		addedSources:        sources.GetAddedForType(config.FileType),
 		removedSources:      sources.GetRemovedForType(config.FileType),
-		fileProvider:        NewProvider(tailingLimit),
+		fileProvider:        NewProvider(tailingLimit, wildcardOrder),
 		tailers:             make(map[string]*Tailer),
+		backfilledFiles:     make(map[string]bool),
 		registry:            registry,
 		tailerSleepDuration: tailerSleepDuration,
 		stop:                make(chan struct{}),
@@ -101,7 +118,23 @@ func (s *Scanner) cleanup() {
 // The Scanner needs to stop that previous tailer,
 // and start a new one for the new file.
 func (s *Scanner) scan() {
+	s.backfillRotatedFiles()
+
 	files := s.fileProvider.FilesToTail(s.activeSources)
+	filesListed := make(map[string]bool)
+	for _, file := range files {
+		filesListed[buildTailerKey(file)] = true
+	}
+	fileIDsListed := make(map[string]bool)
+
+	// stop first the tailers of the files which are not selected anymore,
+	// to make room for the files which are
+	for _, tailer := range s.tailers {
+		if !filesListed[buildTailerKey(tailer)] && !s.isReadingRotatedCopy(tailer) {
+			s.stopTailer(tailer)
+		}
+	}
+
 	filesTailed := make(map[string]bool)
 	tailersLen := len(s.tailers)
 
@@ -117,10 +150,27 @@ func (s *Scanner) scan() {
 		// tailer is tailing the file for the new container).
 		tailerKey := buildTailerKey(file)
 		tailer, isTailed := s.tailers[tailerKey]
+		if isTailed && tailer.compressed {
+			fileIDsListed[tailer.fileID] = true
+		}
 		if isTailed && atomic.LoadInt32(&tailer.shouldStop) != 0 {
+			if tailer.compressed {
+				// the compressed file has been read until its end
+				s.backfilledFiles[tailer.fileID] = true
+			}
 			// skip this tailer as it must be stopped
 			continue
 		}
+		if !isTailed && isCompressed(file.Path) {
+			// the compressed file may have been read under another path
+			// before a rotation renamed it
+			if fileID, err := compressedFileID(file.Path); err == nil {
+				fileIDsListed[fileID] = true
+				if s.backfilledFiles[fileID] {
+					continue
+				}
+			}
+		}
 		if !isTailed && tailersLen >= s.tailingLimit {
 			// can't create new tailer because tailingLimit is reached
 			continue
@@ -138,6 +188,12 @@ func (s *Scanner) scan() {
 			continue
 		}
 
+		if tailer.compressed {
+			// compressed files are not rotated
+			filesTailed[tailerKey] = true
+			continue
+		}
+
 		didRotate, err := DidRotate(tailer.file, tailer.GetReadOffset())
 		if err != nil {
 			continue
@@ -155,12 +211,91 @@ func (s *Scanner) scan() {
 	}
 
 	for _, tailer := range s.tailers {
-		// stop all tailers which have not been selected
+		// stop all tailers which have not been selected, the compressed
+		// copies of the rotated files are read until their end
 		_, shouldTail := filesTailed[buildTailerKey(tailer)]
-		if !shouldTail {
+		if !shouldTail && !s.isReadingRotatedCopy(tailer) {
 			s.stopTailer(tailer)
 		}
 	}
+
+	for fileID := range s.backfilledFiles {
+		if !fileIDsListed[fileID] {
+			delete(s.backfilledFiles, fileID)
+		}
+	}
+}
+
+// isReadingRotatedCopy returns whether the tailer is reading the compressed
+// copy of a rotated file, which is read until its end
+func (s *Scanner) isReadingRotatedCopy(tailer *Tailer) bool {
+	return tailer.isRotatedCopy && atomic.LoadInt32(&tailer.shouldStop) == 0
+}
+
+// backfillRotatedFiles finishes to read the rotated files from their
+// compressed copy, starting from the offset their tailer stopped at.
+func (s *Scanner) backfillRotatedFiles() {
+	var pending []*rotatedFile
+	for _, rotated := range s.rotatedFiles {
+		if atomic.LoadInt32(&rotated.tailer.shouldStop) == 0 {
+			// the tailer is still reading the rotated file
+			pending = append(pending, rotated)
+			continue
+		}
+		if path, found := s.findCompressedCopy(rotated); found {
+			file := NewFile(path, rotated.tailer.source, rotated.tailer.isWildcardPath)
+			if len(s.tailers) < s.tailingLimit && s.startRotatedCopyTailer(file, rotated.tailer.GetReadOffset()) {
+				continue
+			}
+		}
+		if time.Since(rotated.rotatedAt) < rotated.tailer.closeTimeout+rotatedFileLookupPeriod {
+			pending = append(pending, rotated)
+		}
+	}
+	s.rotatedFiles = pending
+}
+
+// findCompressedCopy returns the path of the compressed copy of a rotated
+// file, recognized by the beginning of its content.
+func (s *Scanner) findCompressedCopy(rotated *rotatedFile) (string, bool) {
+	fingerprint := rotated.tailer.getFingerprint()
+	if len(fingerprint) == 0 {
+		return "", false
+	}
+	paths, err := filepath.Glob(compressedCopyPattern(rotated.tailer.fullpath))
+	if err != nil {
+		return "", false
+	}
+	for _, path := range paths {
+		file := NewFile(path, rotated.tailer.source, rotated.tailer.isWildcardPath)
+		if _, isTailed := s.tailers[buildTailerKey(file)]; isTailed {
+			continue
+		}
+		if fileID, err := compressedFileID(path); err != nil || s.backfilledFiles[fileID] {
+			continue
+		}
+		if isSettled(path) && hasPrefix(path, fingerprint) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// hasPendingRotatedFile returns whether the compressed file at path may be
+// the copy of a rotated file whose tailer is not stopped yet or whose copy
+// has not been found yet, in which case the file is not read from its
+// beginning to avoid sending the logs of the rotated file twice.
+func (s *Scanner) hasPendingRotatedFile(path string) bool {
+	fullpath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, rotated := range s.rotatedFiles {
+		if matched, _ := filepath.Match(compressedCopyPattern(rotated.tailer.fullpath), fullpath); matched {
+			return true
+		}
+	}
+	return false
 }
 
 // addSource keeps track of the new source and launch new tailers for this source.
@@ -211,7 +346,26 @@ func (s *Scanner) launchTailers(source *config.LogSource) {
 // startNewTailer creates a new tailer, making it tail from the last committed offset, the beginning or the end of the file,
 // returns true if the operation succeeded, false otherwise
 func (s *Scanner) startNewTailer(file *File, m config.TailingMode) bool {
+	if isCompressed(file.Path) {
+		if !isSettled(file.Path) || s.hasPendingRotatedFile(file.Path) {
+			// let's try to read this file in the next scan
+			return false
+		}
+		// compressed files are read from their beginning if no offset has been recorded
+		m = config.Beginning
+	}
+
 	tailer := s.createTailer(file, s.pipelineProvider.NextPipelineChan())
+	if tailer.compressed {
+		// compressed files are identified by their content in the registry,
+		// as they may be renamed by a later rotation
+		fileID, err := compressedFileID(file.Path)
+		if err != nil {
+			log.Warn(err)
+			return false
+		}
+		tailer.fileID = fileID
+	}
 
 	var offset int64
 	var whence int
@@ -234,6 +388,30 @@ func (s *Scanner) startNewTailer(file *File, m config.TailingMode) bool {
 	return true
 }
 
+// startRotatedCopyTailer creates a new tailer reading the compressed copy of
+// a rotated file from offset, returns true if the operation succeeded.
+func (s *Scanner) startRotatedCopyTailer(file *File, offset int64) bool {
+	tailer := s.createTailer(file, s.pipelineProvider.NextPipelineChan())
+	tailer.isRotatedCopy = true
+	fileID, err := compressedFileID(file.Path)
+	if err != nil {
+		log.Warn(err)
+		return false
+	}
+	tailer.fileID = fileID
+
+	log.Infof("Starting a new tailer for the compressed copy of a rotated file: %s (offset: %d) for tailer key %s", file.Path, offset, buildTailerKey(file))
+
+	err = tailer.Start(offset, io.SeekStart)
+	if err != nil {
+		log.Warn(err)
+		return false
+	}
+
+	s.tailers[buildTailerKey(file)] = tailer
+	return true
+}
+
 // handleTailingModeChange determines the tailing behaviour when the tailing mode for a given file has its
 // configuration change. Two case may happen we can switch from "end" to "beginning" (1) and from "beginning" to
 // "end" (2). If the tailing mode is set to forceEnd or forceBeginning it will remain unchanged.
@@ -267,7 +445,9 @@ func (s *Scanner) stopTailer(tailer *Tailer) {
 func (s *Scanner) restartTailerAfterFileRotation(tailer *Tailer, file *File) bool {
 	log.Info("Log rotation happened to ", tailer.path)
 	tailer.StopAfterFileRotation()
-	tailer = s.createTailer(file, tailer.outputChan)
+	s.rotatedFiles = append(s.rotatedFiles, &rotatedFile{tailer: tailer, rotatedAt: time.Now()})
+	// the new file is expected to hold the same kind of logs
+	tailer = newTailer(tailer.outputChan, file.Source, file.Path, s.tailerSleepDuration, file.IsWildcardPath, tailer.autoMultiLine)
 	// force reading file from beginning since it has been log-rotated
 	err := tailer.StartFromBeginning()
 	if err != nil {
@@ -61,7 +61,7 @@ func (suite *ScannerTestSuite) SetupTest() {
 	suite.openFilesLimit = 100
 	suite.source = config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: suite.testPath})
 	sleepDuration := 20 * time.Millisecond
-	suite.s = NewScanner(config.NewLogSources(), suite.openFilesLimit, suite.pipelineProvider, auditor.NewRegistry(), sleepDuration)
+	suite.s = NewScanner(config.NewLogSources(), suite.openFilesLimit, WildcardOrderByName, suite.pipelineProvider, auditor.NewRegistry(), sleepDuration)
 	suite.s.activeSources = append(suite.s.activeSources, suite.source)
 	status.InitStatus(config.CreateSources([]*config.LogSource{suite.source}))
 	suite.s.scan()
@@ -129,6 +129,8 @@ func (suite *ScannerTestSuite) TestScannerScanWithLogRotation() {
 	s.scan()
 	newTailer = s.tailers[suite.testPath]
 	suite.True(tailer != newTailer)
+	// the multi-line pattern detected before the rotation is kept
+	suite.True(tailer.autoMultiLine == newTailer.autoMultiLine)
 
 	_, err = f.WriteString("hello again\n")
 	suite.Nil(err)
@@ -216,7 +218,7 @@ func TestScannerScanStartNewTailer(t *testing.T) {
 	path = fmt.Sprintf("%s/*.log", testDir)
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
-	scanner := NewScanner(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
+	scanner := NewScanner(config.NewLogSources(), openFilesLimit, WildcardOrderByName, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
 	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
 	scanner.activeSources = append(scanner.activeSources, source)
 	status.Clear()
@@ -260,7 +262,7 @@ func TestScannerTailFromTheBeginning(t *testing.T) {
 	// create scanner
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
-	scanner := NewScanner(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
+	scanner := NewScanner(config.NewLogSources(), openFilesLimit, WildcardOrderByName, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
 	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, TailingMode: "beginning"})
 	// scanner.activeSources = append(scanner.activeSources, source)
 	status.Clear()
@@ -324,7 +326,7 @@ func TestScannerScanWithTooManyFiles(t *testing.T) {
 	path = fmt.Sprintf("%s/*.log", testDir)
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
-	scanner := NewScanner(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
+	scanner := NewScanner(config.NewLogSources(), openFilesLimit, WildcardOrderByName, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
 	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
 	scanner.activeSources = append(scanner.activeSources, source)
 	status.Clear()
@@ -339,9 +341,124 @@ func TestScannerScanWithTooManyFiles(t *testing.T) {
 	err = os.Remove(path)
 	assert.Nil(t, err)
 
+	// the tailer of the removed file is replaced in the same scan
 	scanner.scan()
-	assert.Equal(t, 1, len(scanner.tailers))
+	assert.Equal(t, 2, len(scanner.tailers))
+	assert.NotNil(t, scanner.tailers[fmt.Sprintf("%s/1.log", testDir)])
+}
 
+func TestScannerSwapsIdleTailers(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-scanner-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	now := time.Now()
+	for i, name := range []string{"1.log", "2.log", "3.log"} {
+		path := fmt.Sprintf("%s/%s", testDir, name)
+		_, err = os.Create(path)
+		assert.Nil(t, err)
+		modTime := now.Add(-time.Duration(i) * time.Hour)
+		assert.Nil(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	scanner := NewScanner(config.NewLogSources(), 2, WildcardOrderByModificationTime, mock.NewMockProvider(), auditor.NewRegistry(), 20*time.Millisecond)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.log", testDir)})
+	scanner.activeSources = append(scanner.activeSources, source)
+	status.Clear()
+	status.InitStatus(config.CreateSources([]*config.LogSource{source}))
+	defer status.Clear()
+	defer scanner.cleanup()
+
+	// the most recently modified files are tailed
 	scanner.scan()
 	assert.Equal(t, 2, len(scanner.tailers))
+	assert.NotNil(t, scanner.tailers[fmt.Sprintf("%s/1.log", testDir)])
+	assert.NotNil(t, scanner.tailers[fmt.Sprintf("%s/2.log", testDir)])
+
+	// 3.log is written to while 2.log is idle
+	path := fmt.Sprintf("%s/3.log", testDir)
+	assert.Nil(t, os.Chtimes(path, now.Add(time.Minute), now.Add(time.Minute)))
+	scanner.scan()
+	assert.Equal(t, 2, len(scanner.tailers))
+	assert.NotNil(t, scanner.tailers[fmt.Sprintf("%s/1.log", testDir)])
+	assert.NotNil(t, scanner.tailers[path])
+}
+
+func TestScannerBackfillsCompressedFiles(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-scanner-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	_, err = os.Create(path)
+	assert.Nil(t, err)
+	compressedPath := fmt.Sprintf("%s/test.log.1.gz", testDir)
+	assert.Nil(t, writeCompressedFile(compressedPath, "hello\nworld\n"))
+
+	scanner := NewScanner(config.NewLogSources(), 2, WildcardOrderByName, mock.NewMockProvider(), auditor.NewRegistry(), 20*time.Millisecond)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/test.log*", testDir)})
+	scanner.activeSources = append(scanner.activeSources, source)
+	status.Clear()
+	status.InitStatus(config.CreateSources([]*config.LogSource{source}))
+	defer status.Clear()
+	defer scanner.cleanup()
+
+	scanner.scan()
+	assert.Equal(t, 2, len(scanner.tailers))
+	tailer := scanner.tailers[compressedPath]
+	msg := <-tailer.outputChan
+	assert.Equal(t, "hello", string(msg.Content))
+	msg = <-tailer.outputChan
+	assert.Equal(t, "world", string(msg.Content))
+	<-tailer.done
+
+	// the compressed file is read only once
+	scanner.scan()
+	assert.Equal(t, 1, len(scanner.tailers))
+	assert.True(t, scanner.backfilledFiles[tailer.fileID])
+	scanner.scan()
+	assert.Equal(t, 1, len(scanner.tailers))
+
+	// even when a rotation renames it
+	renamedPath := fmt.Sprintf("%s/test.log.2.gz", testDir)
+	assert.Nil(t, os.Rename(compressedPath, renamedPath))
+	scanner.scan()
+	assert.Equal(t, 1, len(scanner.tailers))
+	assert.True(t, scanner.backfilledFiles[tailer.fileID])
+
+	// files which are not matching anymore are forgotten
+	assert.Nil(t, os.Remove(renamedPath))
+	scanner.scan()
+	assert.Empty(t, scanner.backfilledFiles)
+}
+
+func (suite *ScannerTestSuite) TestScannerScanWithLogRotationCompressed() {
+	s := suite.s
+
+	tailer := s.tailers[suite.testPath]
+	tailer.closeTimeout = 10 * time.Millisecond
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	msg := <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+	// the read offset is updated once the line is sent to the decoder
+	suite.Eventually(func() bool { return tailer.GetReadOffset() == 12 }, time.Second, 10*time.Millisecond)
+
+	// the file is copied, with a line the tailer did not read, compressed
+	// and truncated
+	suite.Nil(writeCompressedFile(suite.testPath+".2.gz", "hello world\nhello again\n"))
+	suite.testFile.Truncate(0)
+	suite.testFile.Seek(0, 0)
+
+	s.scan()
+	suite.True(tailer != s.tailers[suite.testPath])
+	suite.Len(s.rotatedFiles, 1)
+	<-tailer.done
+
+	// the rotated file is finished from its compressed copy
+	s.scan()
+	suite.Empty(s.rotatedFiles)
+	suite.True(s.tailers[suite.testPath+".2.gz"].isRotatedCopy)
+	msg = <-suite.outputChan
+	suite.Equal("hello again", string(msg.Content))
 }
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -41,11 +42,26 @@ type Tailer struct {
 	isWildcardPath bool
 	tags           []string
 
+	// compressed files are read from reader until their end
+	compressed bool
+	reader     io.Reader
+	// fileID identifies a compressed file by its content rather than by its path
+	fileID string
+	// isRotatedCopy is set for the compressed copy of a rotated file
+	isRotatedCopy bool
+	// fingerprint is the beginning of the file, to recognize its compressed copy after a rotation,
+	// it is read by the scanner while the tailer may still update it
+	fingerprint      []byte
+	fingerprintMutex sync.Mutex
+
 	outputChan  chan *message.Message
 	decoder     *decoder.Decoder
 	source      *config.LogSource
 	tagProvider tag.Provider
 
+	// autoMultiLine is the multi-line pattern detection of the file, kept after its rotations
+	autoMultiLine *decoder.AutoMultiLineDetection
+
 	sleepDuration time.Duration
 
 	closeTimeout  time.Duration
@@ -60,6 +76,11 @@ type Tailer struct {
 
 // NewTailer returns an initialized Tailer
 func NewTailer(outputChan chan *message.Message, source *config.LogSource, path string, sleepDuration time.Duration, isWildcardPath bool) *Tailer {
+	return newTailer(outputChan, source, path, sleepDuration, isWildcardPath, decoder.NewAutoMultiLineDetection(path))
+}
+
+// newTailer returns an initialized Tailer continuing the given multi-line pattern detection
+func newTailer(outputChan chan *message.Message, source *config.LogSource, path string, sleepDuration time.Duration, isWildcardPath bool, autoMultiLine *decoder.AutoMultiLineDetection) *Tailer {
 	// TODO: remove those checks and add to source a reference to a tagProvider and a lineParser.
 	var parser lineParser.Parser
 	var matcher decoder.EndLineMatcher
@@ -97,7 +118,8 @@ func NewTailer(outputChan chan *message.Message, source *config.LogSource, path
 	return &Tailer{
 		path:           path,
 		outputChan:     outputChan,
-		decoder:        decoder.NewDecoderWithEndLineMatcher(source, parser, matcher),
+		decoder:        decoder.NewDecoderWithEndLineMatcher(source, parser, matcher, autoMultiLine),
+		autoMultiLine:  autoMultiLine,
 		source:         source,
 		tagProvider:    tagProvider,
 		readOffset:     0,
@@ -106,6 +128,7 @@ func NewTailer(outputChan chan *message.Message, source *config.LogSource, path
 		stop:           make(chan struct{}, 1),
 		done:           make(chan struct{}, 1),
 		isWildcardPath: isWildcardPath,
+		compressed:     isCompressed(path),
 		forwardContext: forwardContext,
 		stopForward:    stopForward,
 	}
@@ -118,6 +141,9 @@ func NewTailer(outputChan chan *message.Message, source *config.LogSource, path
 // where the dead container still has a tailer running on the log file, and the tailer
 // of the freshly spawned container starts tailing this file as well.
 func (t *Tailer) Identifier() string {
+	if t.fileID != "" {
+		return fmt.Sprintf("compressed_file:%s", t.fileID)
+	}
 	return fmt.Sprintf("file:%s", t.path)
 }
 
@@ -136,7 +162,12 @@ func (t *Tailer) getSourceIdentifier() string {
 
 // Start let's the tailer open a file and tail from whence
 func (t *Tailer) Start(offset int64, whence int) error {
-	err := t.setup(offset, whence)
+	var err error
+	if t.compressed {
+		err = t.setupCompressed(offset)
+	} else {
+		err = t.setup(offset, whence)
+	}
 	if err != nil {
 		t.source.Status.Error(err)
 		return err
@@ -156,7 +187,13 @@ func (t *Tailer) Start(offset int64, whence int) error {
 func (t *Tailer) readForever() {
 	defer t.onStop()
 	for {
-		n, err := t.read()
+		var n int
+		var err error
+		if t.compressed {
+			n, err = t.readCompressed()
+		} else {
+			n, err = t.read()
+		}
 		if err != nil {
 			return
 		}
@@ -197,6 +234,7 @@ func (t *Tailer) Stop() {
 	atomic.StoreInt32(&t.didFileRotate, 0)
 	t.stop <- struct{}{}
 	t.source.RemoveInput(t.path)
+	t.source.Messages.RemoveMessage(t.autoMultiLine.MessageKey())
 	// wait for the decoder to be flushed
 	<-t.done
 }
@@ -258,6 +296,45 @@ func (t *Tailer) forwardMessages() {
 	}
 }
 
+// captureFingerprint keeps the beginning of the file, to recognize its
+// compressed copy after a rotation
+func (t *Tailer) captureFingerprint(f *os.File) {
+	fingerprint := make([]byte, fingerprintSize)
+	n, _ := f.ReadAt(fingerprint, 0)
+	t.fingerprintMutex.Lock()
+	defer t.fingerprintMutex.Unlock()
+	t.fingerprint = fingerprint[:n]
+}
+
+// resetFingerprint forgets the beginning of the file, when it is read again
+// from its beginning
+func (t *Tailer) resetFingerprint() {
+	t.fingerprintMutex.Lock()
+	defer t.fingerprintMutex.Unlock()
+	t.fingerprint = nil
+}
+
+// getFingerprint returns a copy of the beginning of the file read so far
+func (t *Tailer) getFingerprint() []byte {
+	t.fingerprintMutex.Lock()
+	defer t.fingerprintMutex.Unlock()
+	return append([]byte(nil), t.fingerprint...)
+}
+
+// updateFingerprint keeps the beginning of the file read at offset
+func (t *Tailer) updateFingerprint(data []byte, offset int64) {
+	t.fingerprintMutex.Lock()
+	defer t.fingerprintMutex.Unlock()
+	if len(t.fingerprint) >= fingerprintSize || int64(len(t.fingerprint)) != offset {
+		return
+	}
+	end := fingerprintSize - len(t.fingerprint)
+	if end > len(data) {
+		end = len(data)
+	}
+	t.fingerprint = append(t.fingerprint, data[:end]...)
+}
+
 func (t *Tailer) incrementReadOffset(n int) {
 	atomic.AddInt64(&t.readOffset, int64(n))
 }
@@ -33,6 +33,7 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	}
 
 	t.file = f
+	t.captureFingerprint(f)
 	ret, _ := f.Seek(offset, whence)
 	t.readOffset = ret
 	t.decodedOffset = ret
@@ -54,6 +55,7 @@ func (t *Tailer) read() (int, error) {
 	if n == 0 {
 		return 0, nil
 	}
+	t.updateFingerprint(inBuf[:n], t.GetReadOffset())
 	t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
 	t.incrementReadOffset(n)
 	return n, nil
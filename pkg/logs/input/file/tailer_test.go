@@ -8,11 +8,13 @@
 package file
 
 import (
+	"compress/gzip"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -293,6 +295,67 @@ func (suite *TailerTestSuite) TestBuildTagsFileDir() {
 	suite.Equal("dirname:"+filepath.Dir(suite.testFile.Name()), tags[1])
 }
 
+func (suite *TailerTestSuite) TestTailCompressedFile() {
+	lines := []string{"hello world\n", "hello again\n", "good bye\n"}
+	path := fmt.Sprintf("%s/tailer.log.1.gz", suite.testDir)
+	suite.Nil(writeCompressedFile(path, strings.Join(lines, "")))
+
+	suite.tailer = NewTailer(suite.outputChan, suite.source, path, 10*time.Millisecond, false)
+	suite.True(suite.tailer.compressed)
+
+	// the offset is a position in the decompressed content
+	suite.Nil(suite.tailer.Start(int64(len(lines[0])), io.SeekStart))
+
+	msg := <-suite.outputChan
+	suite.Equal("hello again", string(msg.Content))
+	suite.Equal(len(lines[0])+len(lines[1]), toInt(msg.Origin.Offset))
+	suite.Equal(suite.tailer.Identifier(), msg.Origin.Identifier)
+
+	msg = <-suite.outputChan
+	suite.Equal("good bye", string(msg.Content))
+	suite.Equal(len(lines[0])+len(lines[1])+len(lines[2]), toInt(msg.Origin.Offset))
+
+	// the tailer stops by itself at the end of the file
+	select {
+	case <-suite.tailer.done:
+	case <-time.After(10 * time.Second):
+		suite.Fail("timeout")
+	}
+}
+
+func (suite *TailerTestSuite) TestFingerprint() {
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	suite.tailer.StartFromBeginning()
+	<-suite.outputChan
+
+	_, err = suite.testFile.WriteString("hello again\n")
+	suite.Nil(err)
+	<-suite.outputChan
+
+	suite.Equal("hello world\nhello again\n", string(suite.tailer.getFingerprint()))
+}
+
+// writeCompressedFile writes a compressed file which was not modified recently
+func writeCompressedFile(path string, content string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(f)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	modTime := time.Now().Add(-2 * compressedFileSettleDelay)
+	return os.Chtimes(path, modTime, modTime)
+}
+
 func toInt(str string) int {
 	if value, err := strconv.ParseInt(str, 10, 64); err == nil {
 		return int(value)
@@ -32,6 +32,7 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	if err != nil {
 		return err
 	}
+	t.captureFingerprint(f)
 	filePos, _ := f.Seek(offset, whence)
 	f.Close()
 
@@ -61,10 +62,12 @@ func (t *Tailer) readAvailable() (err error) {
 		log.Debug("File size now zero, resetting offset")
 		t.SetReadOffset(0)
 		t.SetDecodedOffset(0)
+		t.resetFingerprint()
 	} else if sz < offset {
 		log.Debug("Offset off end of file, resetting")
 		t.SetReadOffset(0)
 		t.SetDecodedOffset(0)
+		t.resetFingerprint()
 	}
 	f.Seek(t.GetReadOffset(), io.SeekStart)
 
@@ -76,6 +79,7 @@ func (t *Tailer) readAvailable() (err error) {
 			return err
 		}
 		log.Debugf("Sending %d bytes to input channel", n)
+		t.updateFingerprint(inBuf[:n], t.GetReadOffset())
 		t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
 		t.incrementReadOffset(n)
 	}
@@ -0,0 +1,313 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+)
+
+// syslogMaxMessageSize is the maximum size of a syslog message, the trailing
+// part of longer messages is dropped.
+const syslogMaxMessageSize = 256 * 1000
+
+// syslogMaxLengthDigits is the maximum number of digits of the length of an
+// octet-counted frame
+const syslogMaxLengthDigits = 10
+
+// A SyslogListener receives syslog messages over TCP, optionally with TLS, or
+// over UDP. Each message is parsed to set the status, the tags and the service
+// of the log sent.
+type SyslogListener struct {
+	pipelineProvider pipeline.Provider
+	source           *config.LogSource
+	frameSize        int
+	listener         net.Listener
+	packetConn       net.PacketConn
+	conns            map[net.Conn]struct{}
+	mu               sync.Mutex
+	wg               sync.WaitGroup
+	stop             chan struct{}
+}
+
+// NewSyslogListener returns an initialized SyslogListener
+func NewSyslogListener(pipelineProvider pipeline.Provider, source *config.LogSource, frameSize int) *SyslogListener {
+	return &SyslogListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		frameSize:        frameSize,
+		conns:            make(map[net.Conn]struct{}),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts listening to syslog messages.
+func (l *SyslogListener) Start() {
+	log.Infof("Starting syslog server on port %d, protocol: %s", l.source.Config.Port, l.protocol())
+	var err error
+	if l.protocol() == config.UDPProtocol {
+		err = l.startUDP()
+	} else {
+		err = l.startTCP()
+	}
+	if err != nil {
+		log.Errorf("Can't start syslog server on port %d: %v", l.source.Config.Port, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.source.Status.Success()
+}
+
+// Stop stops accepting new messages and closes all the connections.
+func (l *SyslogListener) Stop() {
+	log.Infof("Stopping syslog server on port %d", l.source.Config.Port)
+	close(l.stop)
+	l.mu.Lock()
+	if l.listener != nil {
+		l.listener.Close()
+	}
+	if l.packetConn != nil {
+		l.packetConn.Close()
+	}
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+	l.wg.Wait()
+}
+
+func (l *SyslogListener) protocol() string {
+	if l.source.Config.Protocol == "" {
+		return config.TCPProtocol
+	}
+	return l.source.Config.Protocol
+}
+
+// startTCP starts accepting TCP connections, using TLS when a certificate
+// is configured.
+func (l *SyslogListener) startTCP() error {
+	address := fmt.Sprintf(":%d", l.source.Config.Port)
+	var listener net.Listener
+	var err error
+	if l.source.Config.TLSCertFile != "" {
+		var cert tls.Certificate
+		cert, err = tls.LoadX509KeyPair(l.source.Config.TLSCertFile, l.source.Config.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("unable to load the TLS certificate: %v", err)
+		}
+		listener, err = tls.Listen("tcp", address, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	} else {
+		listener, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	l.listener = listener
+
+	l.wg.Add(1)
+	go l.acceptConnections()
+	return nil
+}
+
+// acceptConnections accepts new TCP connections and reads each one in its
+// own goroutine.
+func (l *SyslogListener) acceptConnections() {
+	defer l.wg.Done()
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if isClosedConnError(err) {
+				return
+			}
+			log.Warnf("Can't accept syslog connection on port %d: %v", l.source.Config.Port, err)
+			continue
+		}
+
+		l.mu.Lock()
+		select {
+		case <-l.stop:
+			// the connection was accepted while stopping
+			l.mu.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		l.conns[conn] = struct{}{}
+		l.wg.Add(1)
+		l.mu.Unlock()
+		go l.readConnection(conn)
+	}
+}
+
+// readConnection forwards the messages of a TCP connection until it is closed,
+// or until no data is received for idle_timeout seconds if it is set.
+func (l *SyslogListener) readConnection(conn net.Conn) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		conn.Close()
+		l.wg.Done()
+	}()
+
+	reader := bufio.NewReaderSize(conn, l.frameSize)
+	outputChan := l.pipelineProvider.NextPipelineChan()
+	idleTimeout := time.Duration(l.source.Config.IdleTimeout) * time.Second
+	for {
+		if idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(idleTimeout)) //nolint:errcheck
+		}
+		frame, err := readSyslogFrame(reader)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				log.Debugf("Closing idle syslog connection from %s", conn.RemoteAddr())
+			} else if err != io.EOF && !isClosedConnError(err) {
+				log.Warnf("Couldn't read syslog message from connection: %v", err)
+			}
+			return
+		}
+		l.forward(frame, outputChan)
+	}
+}
+
+// startUDP starts reading UDP packets, each one holding a single message.
+func (l *SyslogListener) startUDP() error {
+	packetConn, err := net.ListenPacket("udp", fmt.Sprintf(":%d", l.source.Config.Port))
+	if err != nil {
+		return err
+	}
+	l.packetConn = packetConn
+
+	l.wg.Add(1)
+	go l.readPackets()
+	return nil
+}
+
+// readPackets forwards the messages of the UDP packets until the listener
+// is stopped.
+func (l *SyslogListener) readPackets() {
+	defer l.wg.Done()
+	outputChan := l.pipelineProvider.NextPipelineChan()
+	buffer := make([]byte, l.frameSize)
+	for {
+		n, _, err := l.packetConn.ReadFrom(buffer)
+		if err != nil {
+			if isClosedConnError(err) {
+				return
+			}
+			log.Warnf("Couldn't read syslog message from UDP packet: %v", err)
+			continue
+		}
+		frame := make([]byte, n)
+		copy(frame, buffer[:n])
+		l.forward(bytes.TrimRight(frame, "\r\n"), outputChan)
+	}
+}
+
+// forward parses a syslog message and sends it to the pipeline. Messages
+// which can not be parsed are sent as is.
+func (l *SyslogListener) forward(frame []byte, outputChan chan *message.Message) {
+	if len(frame) == 0 {
+		return
+	}
+
+	origin := message.NewOrigin(l.source)
+	status := message.StatusInfo
+	content := frame
+	if m, err := parseSyslogMessage(frame); err != nil {
+		log.Debugf("Could not parse syslog message, sending it as is: %v", err)
+	} else {
+		content = m.content()
+		status = m.status()
+		origin.SetTags(m.tags())
+		origin.SetService(m.appName)
+	}
+	outputChan <- message.NewMessage(content, origin, status)
+}
+
+// readSyslogFrame reads the next message of a stream. Messages are either
+// octet-counted, prefixed by their length and a space as described in
+// RFC6587, or terminated by a new line.
+func readSyslogFrame(reader *bufio.Reader) ([]byte, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		return readOctetCountedFrame(reader)
+	}
+	return readLineFrame(reader)
+}
+
+// readOctetCountedFrame reads a message prefixed by its length
+func readOctetCountedFrame(reader *bufio.Reader) ([]byte, error) {
+	var digits []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == ' ' {
+			break
+		}
+		if b < '0' || b > '9' || len(digits) == syslogMaxLengthDigits {
+			return nil, fmt.Errorf("invalid length of octet-counted syslog message")
+		}
+		digits = append(digits, b)
+	}
+	length, err := strconv.Atoi(string(digits))
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, min(length, syslogMaxMessageSize))
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		return nil, err
+	}
+	if length > len(frame) {
+		if _, err := reader.Discard(length - len(frame)); err != nil {
+			return nil, err
+		}
+	}
+	return bytes.TrimRight(frame, "\r\n"), nil
+}
+
+// readLineFrame reads a message terminated by a new line
+func readLineFrame(reader *bufio.Reader) ([]byte, error) {
+	var frame []byte
+	for {
+		line, err := reader.ReadSlice('\n')
+		if len(frame) < syslogMaxMessageSize {
+			frame = append(frame, line[:min(len(line), syslogMaxMessageSize-len(frame))]...)
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && len(frame) > 0:
+			// the last message of the stream may not be terminated
+			break
+		case err != nil:
+			return nil, err
+		}
+		return bytes.TrimRight(frame, "\r\n"), nil
+	}
+}
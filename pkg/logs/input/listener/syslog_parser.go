@@ -0,0 +1,231 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package listener
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// syslogNilValue is the value of the empty fields of RFC5424 messages
+const syslogNilValue = "-"
+
+// utf8BOM may prefix the MSG part of RFC5424 messages
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// syslogFacilities are the keywords of the syslog facilities, by code
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// syslogStatuses are the statuses of the syslog severities, by code
+var syslogStatuses = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// syslogMessage holds the fields of a syslog message. Fields which are not
+// present in the message are empty.
+type syslogMessage struct {
+	facility       int
+	severity       int
+	timestamp      string
+	hostname       string
+	appName        string
+	procID         string
+	msgID          string
+	structuredData string
+	msg            []byte
+}
+
+// status returns the status of the severity of the message
+func (m *syslogMessage) status() string {
+	return syslogStatuses[m.severity]
+}
+
+// tags returns the tags describing where the message comes from
+func (m *syslogMessage) tags() []string {
+	tags := []string{"syslog_facility:" + syslogFacilities[m.facility]}
+	if m.hostname != "" {
+		tags = append(tags, "syslog_hostname:"+m.hostname)
+	}
+	if m.procID != "" {
+		tags = append(tags, "syslog_procid:"+m.procID)
+	}
+	if m.msgID != "" {
+		tags = append(tags, "syslog_msgid:"+m.msgID)
+	}
+	return tags
+}
+
+// content returns the content to send, the structured data of RFC5424
+// messages is kept in front of their MSG part
+func (m *syslogMessage) content() []byte {
+	if m.structuredData == "" {
+		return m.msg
+	}
+	if len(m.msg) == 0 {
+		return []byte(m.structuredData)
+	}
+	content := make([]byte, 0, len(m.structuredData)+1+len(m.msg))
+	content = append(content, m.structuredData...)
+	content = append(content, ' ')
+	return append(content, m.msg...)
+}
+
+// parseSyslogMessage parses a RFC5424 or a RFC3164 message, depending on the
+// version following its priority.
+func parseSyslogMessage(frame []byte) (*syslogMessage, error) {
+	m := &syslogMessage{}
+	rest, err := m.parsePriority(frame)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 1 && rest[0] == '1' && rest[1] == ' ' {
+		err = m.parseRFC5424(rest[2:])
+	} else {
+		m.parseRFC3164(rest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parsePriority parses the <PRI> header shared by both formats
+func (m *syslogMessage) parsePriority(frame []byte) ([]byte, error) {
+	if len(frame) < 3 || frame[0] != '<' {
+		return nil, fmt.Errorf("missing syslog priority")
+	}
+	end := bytes.IndexByte(frame[:min(len(frame), 5)], '>')
+	if end < 2 {
+		return nil, fmt.Errorf("invalid syslog priority")
+	}
+	priority, err := strconv.Atoi(string(frame[1:end]))
+	if err != nil || priority < 0 || priority >= len(syslogFacilities)*len(syslogStatuses) {
+		return nil, fmt.Errorf("invalid syslog priority %q", frame[1:end])
+	}
+	m.facility = priority / len(syslogStatuses)
+	m.severity = priority % len(syslogStatuses)
+	return frame[end+1:], nil
+}
+
+// parseRFC5424 parses the header following the version of a RFC5424 message:
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func (m *syslogMessage) parseRFC5424(data []byte) error {
+	fields := make([]string, 5)
+	for i := range fields {
+		end := bytes.IndexByte(data, ' ')
+		if end == -1 {
+			return fmt.Errorf("truncated RFC5424 header")
+		}
+		if field := string(data[:end]); field != syslogNilValue {
+			fields[i] = field
+		}
+		data = data[end+1:]
+	}
+	m.timestamp, m.hostname, m.appName, m.procID, m.msgID = fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	end, err := structuredDataEnd(data)
+	if err != nil {
+		return err
+	}
+	if sd := string(data[:end]); sd != syslogNilValue {
+		m.structuredData = sd
+	}
+	data = data[end:]
+	if len(data) > 0 && data[0] == ' ' {
+		data = data[1:]
+	}
+	m.msg = bytes.TrimPrefix(data, utf8BOM)
+	return nil
+}
+
+// structuredDataEnd returns the index following the STRUCTURED-DATA part of
+// a RFC5424 message, made of "-" or of SD-ELEMENTs between brackets.
+func structuredDataEnd(data []byte) (int, error) {
+	if len(data) > 0 && data[0] == '-' {
+		return 1, nil
+	}
+	i := 0
+	for i < len(data) && data[i] == '[' {
+		inQuotes := false
+		for i++; i < len(data); i++ {
+			if data[i] == '\\' {
+				i++
+				continue
+			}
+			if data[i] == '"' {
+				inQuotes = !inQuotes
+			} else if data[i] == ']' && !inQuotes {
+				break
+			}
+		}
+		if i >= len(data) {
+			return 0, fmt.Errorf("unterminated RFC5424 structured data")
+		}
+		i++
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid RFC5424 structured data")
+	}
+	return i, nil
+}
+
+// parseRFC3164 parses a BSD syslog message, TIMESTAMP HOSTNAME TAG: MSG.
+// As senders seldom follow it strictly, the hostname and the tag are
+// optional, and the message is kept whole when its header is not recognized.
+func (m *syslogMessage) parseRFC3164(data []byte) {
+	// the timestamp is formatted as "Mmm dd hh:mm:ss"
+	if len(data) >= 16 && data[3] == ' ' && data[6] == ' ' && data[9] == ':' && data[12] == ':' && data[15] == ' ' {
+		m.timestamp = string(data[:15])
+		data = data[16:]
+	} else {
+		m.msg = data
+		return
+	}
+
+	// the hostname is followed by the tag, which ends with a colon
+	if end := bytes.IndexByte(data, ' '); end > 0 && !isRFC3164Tag(data[:end]) {
+		m.hostname = string(data[:end])
+		data = data[end+1:]
+	}
+
+	if end := bytes.IndexByte(data, ' '); end > 0 && isRFC3164Tag(data[:end]) {
+		tag := data[:end-1]
+		if start := bytes.IndexByte(tag, '['); start > 0 && tag[len(tag)-1] == ']' {
+			m.procID = string(tag[start+1 : len(tag)-1])
+			tag = tag[:start]
+		}
+		m.appName = string(tag)
+		data = data[end+1:]
+	}
+	m.msg = data
+}
+
+// isRFC3164Tag returns whether a word is a RFC3164 tag, optionally followed
+// by a process ID between brackets, and a colon.
+func isRFC3164Tag(word []byte) bool {
+	return len(word) > 1 && word[len(word)-1] == ':'
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
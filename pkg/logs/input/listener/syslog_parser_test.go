@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package listener
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	m, err := parseSyslogMessage([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application"] An application event`))
+	require.NoError(t, err)
+	assert.Equal(t, message.StatusNotice, m.status())
+	assert.Equal(t, "2003-10-11T22:14:15.003Z", m.timestamp)
+	assert.Equal(t, "evntslog", m.appName)
+	assert.Equal(t, []string{"syslog_facility:local4", "syslog_hostname:mymachine.example.com", "syslog_msgid:ID47"}, m.tags())
+	assert.Equal(t, `[exampleSDID@32473 iut="3" eventSource="Application"] An application event`, string(m.content()))
+
+	m, err = parseSyslogMessage([]byte("<34>1 2003-10-11T22:14:15.003Z mymachine su 1234 - - \xEF\xBB\xBF'su root' failed"))
+	require.NoError(t, err)
+	assert.Equal(t, message.StatusCritical, m.status())
+	assert.Equal(t, "su", m.appName)
+	assert.Equal(t, []string{"syslog_facility:auth", "syslog_hostname:mymachine", "syslog_procid:1234"}, m.tags())
+	assert.Equal(t, "'su root' failed", string(m.content()))
+
+	// brackets and quotes can be escaped in structured data
+	m, err = parseSyslogMessage([]byte(`<14>1 - - - - - [a b="c\]d" e="f]g"][h] msg`))
+	require.NoError(t, err)
+	assert.Equal(t, `[a b="c\]d" e="f]g"][h]`, m.structuredData)
+	assert.Equal(t, "msg", string(m.msg))
+
+	m, err = parseSyslogMessage([]byte(`<14>1 - - - - - -`))
+	require.NoError(t, err)
+	assert.Empty(t, m.content())
+}
+
+func TestParseRFC3164(t *testing.T) {
+	m, err := parseSyslogMessage([]byte("<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8"))
+	require.NoError(t, err)
+	assert.Equal(t, message.StatusCritical, m.status())
+	assert.Equal(t, "Oct 11 22:14:15", m.timestamp)
+	assert.Equal(t, "su", m.appName)
+	assert.Equal(t, []string{"syslog_facility:auth", "syslog_hostname:mymachine", "syslog_procid:123"}, m.tags())
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(m.content()))
+
+	// without hostname
+	m, err = parseSyslogMessage([]byte("<13>Feb  5 17:32:18 sshd: Accepted publickey"))
+	require.NoError(t, err)
+	assert.Equal(t, message.StatusNotice, m.status())
+	assert.Equal(t, "sshd", m.appName)
+	assert.Equal(t, []string{"syslog_facility:user"}, m.tags())
+	assert.Equal(t, "Accepted publickey", string(m.content()))
+
+	// without a recognized header
+	m, err = parseSyslogMessage([]byte("<0>hello world"))
+	require.NoError(t, err)
+	assert.Equal(t, message.StatusEmergency, m.status())
+	assert.Equal(t, []string{"syslog_facility:kern"}, m.tags())
+	assert.Equal(t, "hello world", string(m.content()))
+}
+
+func TestParseInvalidSyslogMessages(t *testing.T) {
+	for _, frame := range []string{
+		"hello world",
+		"<>hello",
+		"<abc>hello",
+		"<192>hello",
+		"<1234>hello",
+		"<14>1 2003-10-11T22:14:15.003Z mymachine",
+		"<14>1 - - - - - [unterminated",
+		"<14>1 - - - - - invalid",
+	} {
+		_, err := parseSyslogMessage([]byte(frame))
+		assert.NotNil(t, err, frame)
+	}
+}
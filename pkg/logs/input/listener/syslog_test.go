@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package listener
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+func TestSyslogTCPShouldReceiveMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.SyslogType})
+	listener := NewSyslogListener(pp, source, 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// octet-counted framing
+	msg := "<11>1 2003-10-11T22:14:15.003Z host app 42 - - first\nmessage"
+	fmt.Fprintf(conn, "%d %s", len(msg), msg)
+	received := <-msgChan
+	assert.Equal(t, "first\nmessage", string(received.Content))
+	assert.Equal(t, message.StatusError, received.GetStatus())
+	assert.Equal(t, "app", received.Origin.Service())
+	assert.Equal(t, []string{"syslog_facility:user", "syslog_hostname:host", "syslog_procid:42"}, received.Origin.Tags())
+
+	// new line framing
+	fmt.Fprintf(conn, "<14>Oct 11 22:14:15 host app: second message\r\n")
+	received = <-msgChan
+	assert.Equal(t, "second message", string(received.Content))
+	assert.Equal(t, message.StatusInfo, received.GetStatus())
+
+	// unparsable messages are sent as is
+	fmt.Fprintf(conn, "not a syslog message\n")
+	received = <-msgChan
+	assert.Equal(t, "not a syslog message", string(received.Content))
+	assert.Equal(t, message.StatusInfo, received.GetStatus())
+}
+
+func TestSyslogTCPShouldCloseIdleConnections(t *testing.T) {
+	pp := mock.NewMockProvider()
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, IdleTimeout: 1})
+	listener := NewSyslogListener(pp, source, 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSyslogUDPShouldReceiveMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Protocol: config.UDPProtocol})
+	listener := NewSyslogListener(pp, source, 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := net.Dial("udp", listener.packetConn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "<12>Oct 11 22:14:15 host app[1]: hello world\n")
+	received := <-msgChan
+	assert.Equal(t, "hello world", string(received.Content))
+	assert.Equal(t, message.StatusWarning, received.GetStatus())
+	assert.Equal(t, "app", received.Origin.Service())
+}
+
+func TestReadSyslogFrame(t *testing.T) {
+	reader := bufio.NewReaderSize(strings.NewReader("5 hello3 foo\nline one\r\n\nline two"), 16)
+
+	var frames []string
+	for {
+		frame, err := readSyslogFrame(reader)
+		if err != nil {
+			break
+		}
+		frames = append(frames, string(frame))
+	}
+	assert.Equal(t, []string{"hello", "foo", "", "line one", "", "line two"}, frames)
+
+	reader = bufio.NewReader(strings.NewReader("12345678901 foo"))
+	_, err := readSyslogFrame(reader)
+	assert.NotNil(t, err)
+}
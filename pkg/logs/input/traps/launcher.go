@@ -33,9 +33,9 @@ func (l *Launcher) Start() {
 	go l.run()
 }
 
-func (l *Launcher) startNewTailer(source *config.LogSource, inputChan chan *traps.SnmpPacket) {
+func (l *Launcher) startNewTailer(source *config.LogSource, oidResolver traps.OIDResolver, inputChan chan *traps.SnmpPacket) {
 	outputChan := l.pipelineProvider.NextPipelineChan()
-	l.tailer = NewTailer(source, inputChan, outputChan)
+	l.tailer = NewTailer(source, oidResolver, inputChan, outputChan)
 	l.tailer.Start()
 }
 
@@ -44,7 +44,7 @@ func (l *Launcher) run() {
 		select {
 		case source := <-l.sources:
 			if l.tailer == nil {
-				l.startNewTailer(source, traps.GetPacketsChannel())
+				l.startNewTailer(source, traps.GetOIDResolver(), traps.GetPacketsChannel())
 				source.Status.Success()
 			}
 		case <-l.stop:
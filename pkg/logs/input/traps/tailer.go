@@ -16,19 +16,21 @@ import (
 
 // Tailer consumes and processes a stream of trap packets, and sends them to a stream of log messages.
 type Tailer struct {
-	source     *config.LogSource
-	inputChan  traps.PacketsChannel
-	outputChan chan *message.Message
-	done       chan interface{}
+	source      *config.LogSource
+	oidResolver traps.OIDResolver
+	inputChan   traps.PacketsChannel
+	outputChan  chan *message.Message
+	done        chan interface{}
 }
 
 // NewTailer returns a new Tailer
-func NewTailer(source *config.LogSource, inputChan traps.PacketsChannel, outputChan chan *message.Message) *Tailer {
+func NewTailer(source *config.LogSource, oidResolver traps.OIDResolver, inputChan traps.PacketsChannel, outputChan chan *message.Message) *Tailer {
 	return &Tailer{
-		source:     source,
-		inputChan:  inputChan,
-		outputChan: outputChan,
-		done:       make(chan interface{}, 1),
+		source:      source,
+		oidResolver: oidResolver,
+		inputChan:   inputChan,
+		outputChan:  outputChan,
+		done:        make(chan interface{}, 1),
 	}
 }
 
@@ -49,7 +51,7 @@ func (t *Tailer) run() {
 
 	// Loop terminates when the channel is closed.
 	for packet := range t.inputChan {
-		data, err := traps.FormatPacketToJSON(packet)
+		data, err := traps.FormatPacketToJSON(packet, t.oidResolver)
 		if err != nil {
 			log.Errorf("failed to format packet: %s", err)
 			continue
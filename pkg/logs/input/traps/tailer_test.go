@@ -23,7 +23,7 @@ import (
 func TestTrapsShouldReceiveMessages(t *testing.T) {
 	inputChan := make(traps.PacketsChannel, 1)
 	outputChan := make(chan *message.Message)
-	tailer := NewTailer(config.NewLogSource("test", &config.LogsConfig{}), inputChan, outputChan)
+	tailer := NewTailer(config.NewLogSource("test", &config.LogsConfig{}), traps.NoopOIDResolver{}, inputChan, outputChan)
 	tailer.Start()
 
 	p := &traps.SnmpPacket{
@@ -55,7 +55,7 @@ func TestTrapsShouldReceiveMessages(t *testing.T) {
 }
 
 func format(t *testing.T, p *traps.SnmpPacket) []byte {
-	data, err := traps.FormatPacketToJSON(p)
+	data, err := traps.FormatPacketToJSON(p, traps.NoopOIDResolver{})
 	assert.NoError(t, err)
 	content, err := json.Marshal(data)
 	assert.NoError(t, err)
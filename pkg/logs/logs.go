@@ -14,6 +14,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	coreMetrics "github.com/DataDog/datadog-agent/pkg/metrics"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -41,7 +42,9 @@ var (
 // getAC is a func returning the prepared AutoConfig. It is nil until
 // the AutoConfig is ready, please consider using BlockUntilAutoConfigRanOnce
 // instead of directly using it.
-func Start(getAC func() *autodiscovery.AutoConfig) error {
+// metricsOut receives the metric samples generated by the log_to_metric
+// processing rules.
+func Start(getAC func() *autodiscovery.AutoConfig, metricsOut chan<- *coreMetrics.MetricSample) error {
 	if IsAgentRunning() {
 		return nil
 	}
@@ -81,7 +84,7 @@ func Start(getAC func() *autodiscovery.AutoConfig) error {
 	}
 
 	// setup and start the agent
-	agent = NewAgent(sources, services, processingRules, endpoints)
+	agent = NewAgent(sources, services, processingRules, endpoints, metricsOut)
 	log.Info("Starting logs-agent...")
 	agent.Start()
 	atomic.StoreInt32(&isRunning, 1)
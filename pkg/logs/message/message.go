@@ -12,6 +12,8 @@ type Message struct {
 	Content []byte
 	Origin  *Origin
 	status  string
+	// Attributes are the structured attributes extracted from the content
+	Attributes map[string]interface{}
 }
 
 // NewMessageWithSource constructs message with content, status and log source.
@@ -51,6 +51,47 @@ var (
 	// TlmEncodedBytesSent is the total number of sent bytes after encoding if any
 	TlmEncodedBytesSent = telemetry.NewCounter("logs", "encoded_bytes_sent",
 		nil, "Total number of sent bytes after encoding if any")
+
+	// MetricsGenerated is the total number of metric samples generated from logs
+	MetricsGenerated = expvar.Int{}
+	// TlmMetricsGenerated is the total number of metric samples generated from logs per rule
+	TlmMetricsGenerated = telemetry.NewCounter("logs", "metrics_generated",
+		[]string{"rule"}, "Total number of metric samples generated from logs per rule")
+	// MetricsDropped is the total number of metric samples generated from logs dropped because the aggregator was busy
+	MetricsDropped = expvar.Int{}
+	// TlmMetricsDropped is the total number of metric samples generated from logs dropped per rule
+	TlmMetricsDropped = telemetry.NewCounter("logs", "metrics_dropped",
+		[]string{"rule"}, "Total number of metric samples generated from logs dropped per rule")
+	// MetricsGenerationErrors is the total number of matching logs whose metric value could not be parsed
+	MetricsGenerationErrors = expvar.Int{}
+	// TlmMetricsGenerationErrors is the total number of matching logs whose metric value could not be parsed per rule
+	TlmMetricsGenerationErrors = telemetry.NewCounter("logs", "metrics_generation_errors",
+		[]string{"rule"}, "Total number of matching logs whose metric value could not be parsed per rule")
+	// AttributesParsingErrors is the total number of logs not matching the pattern of an extract_attributes rule
+	AttributesParsingErrors = expvar.Int{}
+	// TlmAttributesParsingErrors is the total number of logs not matching the pattern of an extract_attributes rule per rule
+	TlmAttributesParsingErrors = telemetry.NewCounter("logs", "attributes_parsing_errors",
+		[]string{"rule"}, "Total number of logs not matching the pattern of an extract_attributes rule per rule")
+	// LogsRateLimited is the total number of logs dropped by the rate limit of their source
+	LogsRateLimited = expvar.Int{}
+	// TlmLogsRateLimited is the total number of logs dropped by the rate limit of their source
+	TlmLogsRateLimited = telemetry.NewCounter("logs", "rate_limited",
+		nil, "Total number of logs dropped by the rate limit of their source")
+	// AdditionalDestinationLogsSent is the total number of logs sent per additional destination
+	AdditionalDestinationLogsSent = expvar.Map{}
+	// TlmAdditionalDestinationLogsSent is the total number of logs sent per additional destination
+	TlmAdditionalDestinationLogsSent = telemetry.NewCounter("logs", "additional_destination_sent",
+		[]string{"destination"}, "Total number of logs sent per additional destination")
+	// AdditionalDestinationErrors is the total number of errors per additional destination
+	AdditionalDestinationErrors = expvar.Map{}
+	// TlmAdditionalDestinationErrors is the total number of errors per additional destination
+	TlmAdditionalDestinationErrors = telemetry.NewCounter("logs", "additional_destination_errors",
+		[]string{"destination"}, "Total number of errors per additional destination")
+	// AdditionalDestinationLogsStoredOnDisk is the total number of logs stored in the disk buffer per additional destination
+	AdditionalDestinationLogsStoredOnDisk = expvar.Map{}
+	// TlmAdditionalDestinationLogsStoredOnDisk is the total number of logs stored in the disk buffer per additional destination
+	TlmAdditionalDestinationLogsStoredOnDisk = telemetry.NewCounter("logs", "additional_destination_stored_on_disk",
+		[]string{"destination"}, "Total number of logs stored in the disk buffer per additional destination")
 	// TODO: Add LogsCollected for the total number of collected logs.
 
 )
@@ -64,4 +105,12 @@ func init() {
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
 	LogsExpvars.Set("BytesSent", &BytesSent)
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
+	LogsExpvars.Set("MetricsGenerated", &MetricsGenerated)
+	LogsExpvars.Set("MetricsDropped", &MetricsDropped)
+	LogsExpvars.Set("MetricsGenerationErrors", &MetricsGenerationErrors)
+	LogsExpvars.Set("AttributesParsingErrors", &AttributesParsingErrors)
+	LogsExpvars.Set("LogsRateLimited", &LogsRateLimited)
+	LogsExpvars.Set("AdditionalDestinationLogsSent", &AdditionalDestinationLogsSent)
+	LogsExpvars.Set("AdditionalDestinationErrors", &AdditionalDestinationErrors)
+	LogsExpvars.Set("AdditionalDestinationLogsStoredOnDisk", &AdditionalDestinationLogsStoredOnDisk)
 }
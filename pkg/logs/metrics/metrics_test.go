@@ -12,5 +12,5 @@ import (
 )
 
 func TestMetrics(t *testing.T) {
-	assert.Equal(t, LogsExpvars.String(), `{"BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0}`)
+	assert.Equal(t, LogsExpvars.String(), `{"AdditionalDestinationErrors": {}, "AdditionalDestinationLogsSent": {}, "AdditionalDestinationLogsStoredOnDisk": {}, "AttributesParsingErrors": 0, "BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "LogsDecoded": 0, "LogsProcessed": 0, "LogsRateLimited": 0, "LogsSent": 0, "MetricsDropped": 0, "MetricsGenerated": 0, "MetricsGenerationErrors": 0}`)
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 // Pipeline processes and sends messages to the backend
@@ -22,8 +23,9 @@ type Pipeline struct {
 	sender    *sender.Sender
 }
 
-// NewPipeline returns a new Pipeline
-func NewPipeline(outputChan chan *message.Message, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) *Pipeline {
+// NewPipeline returns a new Pipeline, the processed messages are also written to the
+// senders of the additional destinations.
+func NewPipeline(outputChan chan *message.Message, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, destinationSenders []*sender.DestinationSender, metricsOut chan<- *metrics.MetricSample) *Pipeline {
 	var destinations *client.Destinations
 	if endpoints.UseHTTP {
 		main := http.NewDestination(endpoints.Main, http.JSONContentType, destinationsContext)
@@ -60,8 +62,13 @@ func NewPipeline(outputChan chan *message.Message, processingRules []*config.Pro
 		encoder = processor.RawEncoder
 	}
 
+	outputs := make([]processor.Output, 0, len(destinationSenders))
+	for _, destinationSender := range destinationSenders {
+		outputs = append(outputs, destinationSender)
+	}
+
 	inputChan := make(chan *message.Message, config.ChanSize)
-	processor := processor.New(inputChan, senderChan, processingRules, encoder)
+	processor := processor.New(inputChan, senderChan, outputs, processingRules, encoder, metricsOut)
 
 	return &Pipeline{
 		InputChan: inputChan,
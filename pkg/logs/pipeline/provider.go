@@ -10,9 +10,13 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Provider provides message channels
@@ -29,14 +33,16 @@ type provider struct {
 	outputChan        chan *message.Message
 	processingRules   []*config.ProcessingRule
 	endpoints         *config.Endpoints
+	metricsOut        chan<- *metrics.MetricSample
 
 	pipelines            []*Pipeline
+	destinationSenders   []*sender.DestinationSender
 	currentPipelineIndex int32
 	destinationsContext  *client.DestinationsContext
 }
 
 // NewProvider returns a new Provider
-func NewProvider(numberOfPipelines int, auditor *auditor.Auditor, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) Provider {
+func NewProvider(numberOfPipelines int, auditor *auditor.Auditor, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, metricsOut chan<- *metrics.MetricSample) Provider {
 	return &provider{
 		numberOfPipelines:   numberOfPipelines,
 		auditor:             auditor,
@@ -44,6 +50,7 @@ func NewProvider(numberOfPipelines int, auditor *auditor.Auditor, processingRule
 		endpoints:           endpoints,
 		pipelines:           []*Pipeline{},
 		destinationsContext: destinationsContext,
+		metricsOut:          metricsOut,
 	}
 }
 
@@ -52,8 +59,19 @@ func (p *provider) Start() {
 	// This requires the auditor to be started before.
 	p.outputChan = p.auditor.Channel()
 
+	// the additional destinations are shared by all pipelines
+	for _, destination := range p.endpoints.AdditionalDestinations {
+		destinationSender, err := sender.NewDestinationSender(destination, http.NewAdditionalDestination(destination, p.destinationsContext), p.destinationsContext)
+		if err != nil {
+			log.Errorf("Could not start additional destination %s: %v", destination.Name, err)
+			continue
+		}
+		destinationSender.Start()
+		p.destinationSenders = append(p.destinationSenders, destinationSender)
+	}
+
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.destinationSenders, p.metricsOut)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
@@ -68,6 +86,13 @@ func (p *provider) Stop() {
 	}
 	stopper.Stop()
 	p.pipelines = p.pipelines[:0]
+	// the additional destinations are stopped once no pipeline writes to them anymore
+	stopper = restart.NewParallelStopper()
+	for _, destinationSender := range p.destinationSenders {
+		stopper.Add(destinationSender)
+	}
+	stopper.Stop()
+	p.destinationSenders = p.destinationSenders[:0]
 	p.outputChan = nil
 }
 
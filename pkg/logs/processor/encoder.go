@@ -6,6 +6,7 @@
 package processor
 
 import (
+	"encoding/json"
 	"unicode"
 	"unicode/utf8"
 
@@ -45,3 +46,18 @@ func getHostname() string {
 	}
 	return hostname
 }
+
+// withAttributes returns the content of a message as a JSON object holding the
+// attributes extracted from it, the content itself being kept in the message
+// attribute. The content is returned as is when there are no attributes.
+func withAttributes(msg *message.Message, redactedMsg []byte) ([]byte, error) {
+	if len(msg.Attributes) == 0 {
+		return redactedMsg, nil
+	}
+	attributes := make(map[string]interface{}, len(msg.Attributes)+1)
+	for name, value := range msg.Attributes {
+		attributes[name] = value
+	}
+	attributes["message"] = toValidUtf8(redactedMsg)
+	return json.Marshal(attributes)
+}
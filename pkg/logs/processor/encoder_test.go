@@ -7,6 +7,7 @@ package processor
 
 import (
 	"encoding/json"
+	"strconv"
 	"testing"
 
 	"strings"
@@ -17,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/pb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRawEncoder(t *testing.T) {
@@ -221,9 +223,54 @@ func TestJsonEncoder(t *testing.T) {
 	assert.NotEmpty(t, log.Timestamp)
 }
 
+func TestTemplateEncoder(t *testing.T) {
+	destination := &config.AdditionalDestination{
+		Name:     "test",
+		URL:      "http://localhost",
+		Template: `{"msg":{{json .Message}},"level":"{{.Status}}","service":"{{.Service}}","tags":{{json .Tags}}}`,
+	}
+	assert.Nil(t, destination.Validate(5))
+
+	source := config.NewLogSource("", &config.LogsConfig{Service: "foo", Tags: []string{"env:prod"}})
+	msg := newMessage([]byte("message"), source, message.StatusError)
+
+	content, err := NewTemplateEncoder(destination.CompiledTemplate).Encode(msg, []byte(`a "quoted" message`))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"msg":"a \"quoted\" message","level":"error","service":"foo","tags":["env:prod"]}`, string(content))
+}
+
+func TestLokiEncoder(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("message"), source, message.StatusError)
+
+	content, err := LokiEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	var entry []string
+	assert.Nil(t, json.Unmarshal(content, &entry))
+	require.Len(t, entry, 2)
+	timestamp, err := strconv.ParseInt(entry[0], 10, 64)
+	assert.Nil(t, err)
+	assert.InDelta(t, time.Now().UnixNano(), timestamp, float64(time.Minute))
+	assert.Equal(t, "redacted", entry[1])
+}
+
 func TestEncoderToValidUTF8(t *testing.T) {
 	assert.Equal(t, "a�z", toValidUtf8([]byte("a\xfez")))
 	assert.Equal(t, "a��z", toValidUtf8([]byte("a\xc0\xafz")))
 	assert.Equal(t, "a���z", toValidUtf8([]byte("a\xed\xa0\x80z")))
 	assert.Equal(t, "a����z", toValidUtf8([]byte("a\xf0\x8f\xbf\xbfz")))
 }
+
+func TestWithAttributes(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	msg := newMessage([]byte("message"), source, "")
+	content, err := withAttributes(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("redacted"), content)
+
+	msg.Attributes = map[string]interface{}{"status": int64(404), "path": "/foo"}
+	content, err = withAttributes(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"message":"redacted","path":"/foo","status":404}`, string(content))
+}
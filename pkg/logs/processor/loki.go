@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// LokiEncoder is a shared Loki encoder.
+var LokiEncoder Encoder = &lokiEncoder{}
+
+// lokiEncoder transforms a message into a Loki entry, which is a JSON array
+// holding the timestamp of the message in nanoseconds and its content.
+// The labels of the entry are added when the entries are grouped in streams.
+type lokiEncoder struct{}
+
+// Encode encodes a message into a Loki entry.
+func (l *lokiEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
+	return json.Marshal([]string{
+		strconv.FormatInt(time.Now().UTC().UnixNano(), 10),
+		toValidUtf8(redactedMsg),
+	})
+}
@@ -6,6 +6,11 @@
 package processor
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
+	coreMetrics "github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
@@ -13,24 +18,41 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 )
 
+// Output receives a copy of the processed messages, holding their content
+// before encoding, besides the outputChan of the Processor. Write must not block.
+type Output interface {
+	Write(msg *message.Message)
+}
+
 // A Processor updates messages from an inputChan and pushes
 // in an outputChan.
 type Processor struct {
 	inputChan       chan *message.Message
 	outputChan      chan *message.Message
+	outputs         []Output
 	processingRules []*config.ProcessingRule
 	encoder         Encoder
-	done            chan struct{}
+	metricsOut      chan<- *coreMetrics.MetricSample
+	// summarizedSources are the sources with logs dropped by their rate
+	// limit in summarize mode, waiting for a summary
+	summarizedSources map[*config.LogSource]struct{}
+	done              chan struct{}
 }
 
 // New returns an initialized Processor.
-func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder) *Processor {
+// The processed messages are also written to the additional outputs, if any.
+// The samples generated by the log_to_metric rules are sent to metricsOut,
+// they are discarded when it is nil.
+func New(inputChan, outputChan chan *message.Message, outputs []Output, processingRules []*config.ProcessingRule, encoder Encoder, metricsOut chan<- *coreMetrics.MetricSample) *Processor {
 	return &Processor{
-		inputChan:       inputChan,
-		outputChan:      outputChan,
-		processingRules: processingRules,
-		encoder:         encoder,
-		done:            make(chan struct{}),
+		inputChan:         inputChan,
+		outputChan:        outputChan,
+		outputs:           outputs,
+		processingRules:   processingRules,
+		encoder:           encoder,
+		metricsOut:        metricsOut,
+		summarizedSources: make(map[*config.LogSource]struct{}),
+		done:              make(chan struct{}),
 	}
 }
 
@@ -51,30 +73,108 @@ func (p *Processor) run() {
 	defer func() {
 		p.done <- struct{}{}
 	}()
-	for msg := range p.inputChan {
-		metrics.LogsDecoded.Add(1)
-		metrics.TlmLogsDecoded.Inc()
-		if shouldProcess, redactedMsg := p.applyRedactingRules(msg); shouldProcess {
-			metrics.LogsProcessed.Add(1)
-			metrics.TlmLogsProcessed.Inc()
-
-			// Encode the message to its final format
-			content, err := p.encoder.Encode(msg, redactedMsg)
-			if err != nil {
-				log.Error("unable to encode msg ", err)
-				continue
+	summaryTicker := time.NewTicker(time.Second)
+	defer summaryTicker.Stop()
+	for {
+		select {
+		case msg, ok := <-p.inputChan:
+			if !ok {
+				return
 			}
-			msg.Content = content
-			p.outputChan <- msg
+			p.processMessage(msg)
+		case now := <-summaryTicker.C:
+			p.sendRateLimitSummaries(now)
 		}
 	}
 }
 
+// processMessage applies the processing rules and the rate limit to a message,
+// and sends it to the outputChan if it is kept. Only the logs kept by the
+// processing rules count against the budget of their source.
+func (p *Processor) processMessage(msg *message.Message) {
+	metrics.LogsDecoded.Add(1)
+	metrics.TlmLogsDecoded.Inc()
+	shouldProcess, redactedMsg := p.applyRedactingRules(msg)
+	if !shouldProcess || !p.applyRateLimit(msg) {
+		return
+	}
+	metrics.LogsProcessed.Add(1)
+	metrics.TlmLogsProcessed.Inc()
+	p.encodeAndSend(msg, redactedMsg)
+}
+
+// encodeAndSend encodes the message to its final format and sends it to the outputChan.
+func (p *Processor) encodeAndSend(msg *message.Message, redactedMsg []byte) {
+	content, err := withAttributes(msg, redactedMsg)
+	if err != nil {
+		log.Error("unable to encode the attributes of msg ", err)
+		return
+	}
+	p.writeToOutputs(msg, content)
+	content, err = p.encoder.Encode(msg, content)
+	if err != nil {
+		log.Error("unable to encode msg ", err)
+		return
+	}
+	msg.Content = content
+	p.outputChan <- msg
+}
+
+// writeToOutputs writes a copy of the message holding its processed content
+// to the additional outputs.
+func (p *Processor) writeToOutputs(msg *message.Message, content []byte) {
+	if len(p.outputs) == 0 {
+		return
+	}
+	processed := message.NewMessage(content, msg.Origin, msg.GetStatus())
+	for _, output := range p.outputs {
+		output.Write(processed)
+	}
+}
+
+// applyRateLimit returns whether the message fits in the budget of its source.
+func (p *Processor) applyRateLimit(msg *message.Message) bool {
+	source := msg.Origin.LogSource
+	if source.RateLimiter == nil || source.RateLimiter.Allow(len(msg.Content), time.Now()) {
+		return true
+	}
+	metrics.LogsRateLimited.Add(1)
+	metrics.TlmLogsRateLimited.Inc()
+	if source.RateLimiter.Mode() == config.RateLimitSummarize && p.summarizedSources != nil {
+		p.summarizedSources[source] = struct{}{}
+	}
+	return false
+}
+
+// sendRateLimitSummaries sends a log giving the number of logs dropped for
+// each source whose summary is due. As sources are shared by all processors,
+// only the first one to find a summary due sends it.
+func (p *Processor) sendRateLimitSummaries(now time.Time) {
+	for source := range p.summarizedSources {
+		dropped, due := source.RateLimiter.Summary(now)
+		if !due {
+			if dropped == 0 {
+				// sent by another processor
+				delete(p.summarizedSources, source)
+			}
+			continue
+		}
+		delete(p.summarizedSources, source)
+		content := []byte(fmt.Sprintf("%d logs were dropped by the rate limit of this source", dropped))
+		p.encodeAndSend(message.NewMessageWithSource(content, message.StatusWarning, source), content)
+	}
+}
+
 // applyRedactingRules returns given a message if we should process it or not,
-// and a copy of the message with some fields redacted, depending on config
+// and a copy of the message with some fields redacted, depending on config.
+// It also generates the metrics of the log_to_metric rules matching the message,
+// and extracts the attributes of the extract_attributes rules into the message.
+// The attributes are extracted once all the rules are applied, so that they are
+// masked by the mask_sequences rules, wherever they are in the list.
 func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 	content := msg.Content
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+	var extractRules []*config.ProcessingRule
 	for _, rule := range rules {
 		switch rule.Type {
 		case config.ExcludeAtMatch:
@@ -87,7 +187,114 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		case config.LogToMetric:
+			if p.generateMetric(rule, content) && rule.DropLine {
+				return false, nil
+			}
+		case config.ExtractAttributes:
+			extractRules = append(extractRules, rule)
 		}
 	}
+	for _, rule := range extractRules {
+		extractAttributes(rule, msg, content)
+	}
 	return true, content
 }
+
+// generateMetric sends the metric sample of a log_to_metric rule if the
+// content matches its pattern, and returns whether it matched.
+// The named captures of the pattern, except the one holding the value,
+// are added as tags to the sample.
+func (p *Processor) generateMetric(rule *config.ProcessingRule, content []byte) bool {
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		return false
+	}
+
+	value := 1.0
+	if rule.ValueIndex > 0 {
+		var err error
+		value, err = strconv.ParseFloat(string(match[rule.ValueIndex]), 64)
+		if err != nil {
+			log.Debugf("Unable to parse the value of the metric %s of processing rule %s: %v", rule.MetricName, rule.Name, err)
+			metrics.MetricsGenerationErrors.Add(1)
+			metrics.TlmMetricsGenerationErrors.Inc(rule.Name)
+			return true
+		}
+	}
+
+	tags := make([]string, 0, len(rule.MetricTags)+len(match))
+	tags = append(tags, rule.MetricTags...)
+	for i, name := range rule.Regex.SubexpNames() {
+		if i == 0 || i == rule.ValueIndex || name == "" || len(match[i]) == 0 {
+			continue
+		}
+		tags = append(tags, name+":"+string(match[i]))
+	}
+
+	mtype := coreMetrics.CountType
+	if rule.MetricType == config.MetricTypeDistribution {
+		mtype = coreMetrics.DistributionType
+	}
+
+	if p.metricsOut == nil {
+		return true
+	}
+	metrics.MetricsGenerated.Add(1)
+	metrics.TlmMetricsGenerated.Inc(rule.Name)
+	sample := &coreMetrics.MetricSample{
+		Name:       rule.MetricName,
+		Value:      value,
+		Mtype:      mtype,
+		Tags:       tags,
+		Host:       getHostname(),
+		SampleRate: 1,
+		Timestamp:  float64(time.Now().UnixNano()) / float64(time.Second),
+	}
+	// the pipeline must not be blocked by the aggregator, the sample is dropped instead
+	select {
+	case p.metricsOut <- sample:
+	default:
+		metrics.MetricsDropped.Add(1)
+		metrics.TlmMetricsDropped.Inc(rule.Name)
+	}
+	return true
+}
+
+// extractAttributes adds the named captures of an extract_attributes rule to
+// the attributes of the message. The message is left untouched if the content
+// does not match the pattern of the rule.
+func extractAttributes(rule *config.ProcessingRule, msg *message.Message, content []byte) {
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		metrics.AttributesParsingErrors.Add(1)
+		metrics.TlmAttributesParsingErrors.Inc(rule.Name)
+		return
+	}
+
+	for i, name := range rule.Regex.SubexpNames() {
+		if i == 0 || name == "" || match[i] == nil {
+			continue
+		}
+		if msg.Attributes == nil {
+			msg.Attributes = make(map[string]interface{})
+		}
+		msg.Attributes[name] = attributeValue(string(match[i]), rule.AttributeTypes[name])
+	}
+}
+
+// attributeValue converts the value of an attribute to its type, values that
+// can not be converted are kept as strings
+func attributeValue(value string, attributeType string) interface{} {
+	switch attributeType {
+	case config.AttributeTypeInt:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case config.AttributeTypeFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
@@ -8,10 +8,14 @@ package processor
 import (
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	coreMetrics "github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExclusion(t *testing.T) {
@@ -138,6 +142,208 @@ func TestTruncate(t *testing.T) {
 	assert.Equal(t, []byte("hello"), redactedMessage)
 }
 
+func TestLogToMetric(t *testing.T) {
+	metricsOut := make(chan *coreMetrics.MetricSample, 10)
+	p := &Processor{metricsOut: metricsOut}
+
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		newLogToMetricRule("(?P<status>\\d{3}) in (?P<duration>[0-9.]+)ms", "app.duration", config.MetricTypeDistribution, "duration", false),
+		newLogToMetricRule("level=(?P<level>debug)", "app.debug", "", "", true),
+	}}}
+
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte("GET /foo 404 in 12.5ms"), &source, ""))
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("GET /foo 404 in 12.5ms"), redactedMessage)
+	require.Len(t, metricsOut, 1)
+	sample := <-metricsOut
+	assert.Equal(t, "app.duration", sample.Name)
+	assert.Equal(t, 12.5, sample.Value)
+	assert.Equal(t, coreMetrics.DistributionType, sample.Mtype)
+	assert.Equal(t, []string{"rule:test", "status:404"}, sample.Tags)
+
+	// matching lines are dropped when requested
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("level=debug msg=hello"), &source, ""))
+	assert.Equal(t, false, shouldProcess)
+	require.Len(t, metricsOut, 1)
+	sample = <-metricsOut
+	assert.Equal(t, "app.debug", sample.Name)
+	assert.Equal(t, 1.0, sample.Value)
+	assert.Equal(t, coreMetrics.CountType, sample.Mtype)
+	assert.Equal(t, []string{"rule:test", "level:debug"}, sample.Tags)
+
+	// lines without a metric are kept
+	shouldProcess, redactedMessage = p.applyRedactingRules(newMessage([]byte("level=info msg=hello"), &source, ""))
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("level=info msg=hello"), redactedMessage)
+	assert.Len(t, metricsOut, 0)
+
+	// metrics are computed after masking
+	source.Config.ProcessingRules = []*config.ProcessingRule{
+		newProcessingRule("mask_sequences", "[masked]", "\\d{3}"),
+		newLogToMetricRule("(?P<status>\\d{3}|\\[masked\\])", "app.requests", "", "", false),
+	}
+	p.applyRedactingRules(newMessage([]byte("GET /foo 404"), &source, ""))
+	require.Len(t, metricsOut, 1)
+	sample = <-metricsOut
+	assert.Equal(t, []string{"rule:test", "status:[masked]"}, sample.Tags)
+}
+
+func TestLogToMetricRateLimited(t *testing.T) {
+	metricsOut := make(chan *coreMetrics.MetricSample, 10)
+	outputChan := make(chan *message.Message, 10)
+	p := New(nil, outputChan, nil, nil, RawEncoder, metricsOut)
+	source := config.NewLogSource("", &config.LogsConfig{
+		RateLimit:       &config.RateLimitConfig{LinesPerSecond: 1},
+		ProcessingRules: []*config.ProcessingRule{newLogToMetricRule("hello", "app.hello", "", "", false)},
+	})
+
+	// the metrics count the logs dropped by the rate limit
+	for i := 0; i < 3; i++ {
+		p.processMessage(newMessage([]byte("hello"), source, ""))
+	}
+	assert.Len(t, outputChan, 1)
+	assert.Len(t, metricsOut, 3)
+}
+
+func TestLogToMetricDropped(t *testing.T) {
+	metricsOut := make(chan *coreMetrics.MetricSample, 1)
+	p := &Processor{metricsOut: metricsOut}
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		newLogToMetricRule("hello", "app.hello", "", "", false),
+	}}}
+	dropped := metrics.MetricsDropped.Value()
+
+	// the samples are dropped instead of blocking the pipeline when the output is full
+	for i := 0; i < 3; i++ {
+		shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("hello"), &source, ""))
+		assert.Equal(t, true, shouldProcess)
+	}
+	assert.Len(t, metricsOut, 1)
+	assert.Equal(t, dropped+2, metrics.MetricsDropped.Value())
+}
+
+func TestExtractAttributes(t *testing.T) {
+	p := &Processor{}
+
+	rule := &config.ProcessingRule{Type: config.ExtractAttributes, Name: "test", Pattern: "%{IPV4:client} %{WORD:method} (?P<path>\\S+) %{INT:status:int}"}
+	require.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}}}
+
+	msg := newMessage([]byte("10.0.0.1 GET /foo 404"), &source, "")
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("10.0.0.1 GET /foo 404"), redactedMessage)
+	assert.Equal(t, map[string]interface{}{
+		"client": "10.0.0.1",
+		"method": "GET",
+		"path":   "/foo",
+		"status": int64(404),
+	}, msg.Attributes)
+
+	// lines not matching the pattern are kept untouched
+	msg = newMessage([]byte("hello world"), &source, "")
+	shouldProcess, redactedMessage = p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("hello world"), redactedMessage)
+	assert.Nil(t, msg.Attributes)
+
+	// the attributes are masked by the rules following the extraction
+	rule = &config.ProcessingRule{Type: config.ExtractAttributes, Name: "test", Pattern: "%{WORD:method} (?P<path>\\S+)"}
+	require.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source.Config.ProcessingRules = []*config.ProcessingRule{rule, newProcessingRule("mask_sequences", "[masked]", "\\d+")}
+	msg = newMessage([]byte("GET /users/42"), &source, "")
+	shouldProcess, redactedMessage = p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("GET /users/[masked]"), redactedMessage)
+	assert.Equal(t, map[string]interface{}{"method": "GET", "path": "/users/[masked]"}, msg.Attributes)
+}
+
+func TestRateLimit(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	p := New(nil, outputChan, nil, nil, RawEncoder, nil)
+
+	source := config.NewLogSource("", &config.LogsConfig{RateLimit: &config.RateLimitConfig{LinesPerSecond: 1, Mode: config.RateLimitSummarize, SummaryInterval: 1}})
+	assert.True(t, p.applyRateLimit(newMessage([]byte("first"), source, "")))
+	assert.False(t, p.applyRateLimit(newMessage([]byte("second"), source, "")))
+	assert.False(t, p.applyRateLimit(newMessage([]byte("third"), source, "")))
+
+	// the summary is sent once its interval is over
+	p.sendRateLimitSummaries(time.Now())
+	assert.Len(t, outputChan, 0)
+	p.sendRateLimitSummaries(time.Now().Add(time.Second))
+	require.Len(t, outputChan, 1)
+	summary := <-outputChan
+	assert.Equal(t, message.StatusWarning, summary.GetStatus())
+	assert.Contains(t, string(summary.Content), "2 logs were dropped by the rate limit of this source")
+	assert.Empty(t, p.summarizedSources)
+
+	// sources without rate limit are not limited
+	source = config.NewLogSource("", &config.LogsConfig{})
+	for i := 0; i < 10; i++ {
+		assert.True(t, p.applyRateLimit(newMessage([]byte("hello"), source, "")))
+	}
+
+	// the logs excluded by the processing rules don't count against the budget
+	source = config.NewLogSource("", &config.LogsConfig{
+		RateLimit:       &config.RateLimitConfig{LinesPerSecond: 1},
+		ProcessingRules: []*config.ProcessingRule{newProcessingRule("exclude_at_match", "", "debug")},
+	})
+	p.processMessage(newMessage([]byte("debug"), source, ""))
+	p.processMessage(newMessage([]byte("info"), source, ""))
+	require.Len(t, outputChan, 1)
+	assert.Contains(t, string((<-outputChan).Content), "info")
+}
+
+type mockOutput struct {
+	messages []*message.Message
+}
+
+func (o *mockOutput) Write(msg *message.Message) {
+	o.messages = append(o.messages, msg)
+}
+
+func TestOutputs(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	output := &mockOutput{}
+	rule := newProcessingRule("mask_sequences", "[masked]", "secret")
+	p := New(nil, outputChan, []Output{output}, []*config.ProcessingRule{rule}, JSONEncoder, nil)
+
+	source := config.NewLogSource("", &config.LogsConfig{Service: "foo"})
+	p.processMessage(newMessage([]byte("my secret"), source, message.StatusError))
+
+	// the outputs receive the processed content before encoding
+	require.Len(t, output.messages, 1)
+	assert.Equal(t, "my [masked]", string(output.messages[0].Content))
+	assert.Equal(t, message.StatusError, output.messages[0].GetStatus())
+	assert.Equal(t, "foo", output.messages[0].Origin.Service())
+
+	require.Len(t, outputChan, 1)
+	assert.Contains(t, string((<-outputChan).Content), `"message":"my [masked]"`)
+
+	// excluded logs are not written to the outputs
+	p.processingRules = []*config.ProcessingRule{newProcessingRule("exclude_at_match", "", "secret")}
+	p.processMessage(newMessage([]byte("my secret"), source, message.StatusError))
+	assert.Len(t, output.messages, 1)
+	assert.Len(t, outputChan, 0)
+}
+
+func newLogToMetricRule(pattern, metricName, metricType, valueCapture string, dropLine bool) *config.ProcessingRule {
+	rule := &config.ProcessingRule{
+		Type:         config.LogToMetric,
+		Name:         "test",
+		Pattern:      pattern,
+		MetricName:   metricName,
+		MetricType:   metricType,
+		ValueCapture: valueCapture,
+		MetricTags:   []string{"rule:test"},
+		DropLine:     dropLine,
+	}
+	if err := config.CompileProcessingRules([]*config.ProcessingRule{rule}); err != nil {
+		panic(err)
+	}
+	return rule
+}
+
 func newProcessingRule(ruleType, replacePlaceholder, pattern string) *config.ProcessingRule {
 	return &config.ProcessingRule{
 		Type:               ruleType,
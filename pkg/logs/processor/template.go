@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// templateEncoder transforms a message into the output of a text template.
+type templateEncoder struct {
+	template *template.Template
+}
+
+// templateFields are the fields of a message available to the templates.
+type templateFields struct {
+	Message   string
+	Status    string
+	Timestamp int64
+	Hostname  string
+	Service   string
+	Source    string
+	Tags      []string
+}
+
+// NewTemplateEncoder returns an encoder rendering messages with the given template,
+// the fields available are the ones of the JSON encoding, the timestamp being in milliseconds.
+func NewTemplateEncoder(template *template.Template) Encoder {
+	return &templateEncoder{
+		template: template,
+	}
+}
+
+// Encode encodes a message with the template.
+func (t *templateEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := t.template.Execute(&buffer, templateFields{
+		Message:   toValidUtf8(redactedMsg),
+		Status:    msg.GetStatus(),
+		Timestamp: time.Now().UTC().UnixNano() / nanoToMillis,
+		Hostname:  getHostname(),
+		Service:   msg.Origin.Service(),
+		Source:    msg.Origin.Source(),
+		Tags:      msg.Origin.Tags(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
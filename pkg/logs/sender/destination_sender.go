@@ -0,0 +1,296 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
+)
+
+// errStopped is returned when a payload could not be sent before the sender was stopped.
+var errStopped = errors.New("sender stopped")
+
+// defaultDiskBufferCheckPeriod is the period at which the disk buffer is checked
+// for payloads to send while no payload is queued.
+const defaultDiskBufferCheckPeriod = 10 * time.Second
+
+// DestinationSender sends the logs matching the filter of an additional
+// destination, independently from the main destination: it has its own
+// queue, batching and retries, so that a slow or unavailable destination
+// never blocks the pipelines. The batches that don't fit in its queue are
+// stored on disk when a disk buffer is configured, and dropped otherwise.
+type DestinationSender struct {
+	config              *config.AdditionalDestination
+	destination         client.Destination
+	destinationsContext *client.DestinationsContext
+	encoder             processor.Encoder
+	serializer          Serializer
+	diskBuffer          *diskBuffer
+	diskBufferCheck     time.Duration
+	minBackoff          time.Duration
+	inputChan           chan *message.Message
+	payloadChan         chan *payload
+	stop                chan struct{}
+	done                chan struct{}
+}
+
+// NewDestinationSender returns a new sender for an additional destination,
+// it returns an error if its disk buffer could not be set up.
+func NewDestinationSender(destinationConfig *config.AdditionalDestination, destination client.Destination, destinationsContext *client.DestinationsContext) (*DestinationSender, error) {
+	var buffer *diskBuffer
+	if destinationConfig.DiskBufferMaxSize > 0 {
+		var err error
+		buffer, err = newDiskBuffer(destinationConfig.DiskBufferPath, destinationConfig.DiskBufferMaxSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var encoder processor.Encoder
+	var serializer Serializer
+	switch {
+	case destinationConfig.Type == config.LokiDestinationType:
+		encoder = processor.LokiEncoder
+		serializer = NewLokiSerializer(destinationConfig.LokiLabels)
+	case destinationConfig.CompiledTemplate != nil:
+		encoder = processor.NewTemplateEncoder(destinationConfig.CompiledTemplate)
+	default:
+		encoder = processor.JSONEncoder
+	}
+	if serializer == nil {
+		serializer = ArraySerializer
+		if destinationConfig.Format == config.NDJSONFormat {
+			serializer = NDJSONSerializer
+		}
+	}
+	return &DestinationSender{
+		config:              destinationConfig,
+		destination:         destination,
+		destinationsContext: destinationsContext,
+		encoder:             encoder,
+		serializer:          serializer,
+		diskBuffer:          buffer,
+		diskBufferCheck:     defaultDiskBufferCheckPeriod,
+		minBackoff:          time.Second,
+	}, nil
+}
+
+// Start starts the sender.
+func (s *DestinationSender) Start() {
+	s.inputChan = make(chan *message.Message, s.config.BatchMaxSize)
+	s.payloadChan = make(chan *payload, s.config.QueueSize)
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.batch()
+	go s.send()
+}
+
+// Stop stops the sender, the logs which are not sent yet are sent once,
+// and stored on disk if they can't.
+// This call blocks until all logs are processed.
+func (s *DestinationSender) Stop() {
+	close(s.stop)
+	close(s.inputChan)
+	<-s.done
+}
+
+// Write queues a log for the destination if it matches its filter,
+// the log is dropped if the queue is full.
+func (s *DestinationSender) Write(msg *message.Message) {
+	if !s.config.Matches(msg.Origin.Source(), msg.Origin.Service(), msg.GetStatus()) {
+		return
+	}
+	select {
+	case s.inputChan <- msg:
+	default:
+		s.drop(1)
+	}
+}
+
+// batch encodes and accumulates logs, and queues the batches when they are
+// full or outdated.
+func (s *DestinationSender) batch() {
+	buffer := NewMessageBuffer(s.config.BatchMaxSize, s.config.BatchMaxContentSize)
+	batchWait := time.Duration(s.config.BatchWait) * time.Second
+	flushTicker := time.NewTicker(batchWait)
+	defer flushTicker.Stop()
+	defer close(s.payloadChan)
+
+	for {
+		select {
+		case msg, isOpen := <-s.inputChan:
+			if !isOpen {
+				s.flush(buffer)
+				return
+			}
+			content, err := s.encoder.Encode(msg, msg.Content)
+			if err != nil {
+				log.Debugf("Unable to encode log for destination %s: %v", s.config.Name, err)
+				s.drop(1)
+				continue
+			}
+			encoded := message.NewMessage(content, msg.Origin, msg.GetStatus())
+			if !buffer.AddMessage(encoded) {
+				s.flush(buffer)
+				if !buffer.AddMessage(encoded) {
+					// the log is bigger than a batch
+					s.drop(1)
+				}
+			}
+			if buffer.IsFull() {
+				s.flush(buffer)
+			}
+		case <-flushTicker.C:
+			s.flush(buffer)
+		}
+	}
+}
+
+// flush queues the logs of the buffer as a payload, the payload is stored
+// on disk if the queue is full.
+func (s *DestinationSender) flush(buffer *MessageBuffer) {
+	if buffer.IsEmpty() {
+		return
+	}
+	defer buffer.Clear()
+	p := &payload{
+		content: s.serializer.Serialize(buffer.GetMessages()),
+		count:   len(buffer.GetMessages()),
+	}
+	select {
+	case s.payloadChan <- p:
+	default:
+		s.storeOrDrop(p)
+	}
+}
+
+// send sends the queued payloads, and the ones stored on disk once the
+// queue is empty. The payloads read from disk are removed once they are sent.
+func (s *DestinationSender) send() {
+	defer close(s.done)
+	var diskBufferCheck <-chan time.Time
+	if s.diskBuffer != nil {
+		ticker := time.NewTicker(s.diskBufferCheck)
+		defer ticker.Stop()
+		diskBufferCheck = ticker.C
+	}
+	for {
+		p, isOpen := s.nextPayload(diskBufferCheck)
+		if !isOpen {
+			return
+		}
+		if err := s.sendWithRetries(p); err == errStopped || err == context.Canceled {
+			// keep the payloads left for the next run
+			if p.file == nil {
+				s.storeOrDrop(p)
+			}
+			for p := range s.payloadChan {
+				s.storeOrDrop(p)
+			}
+			return
+		}
+		if p.file != nil {
+			s.diskBuffer.remove(p)
+		}
+	}
+}
+
+// nextPayload returns the next payload to send, the queued ones first. It
+// blocks until one is queued, checking the disk buffer on each tick of
+// diskBufferCheck.
+func (s *DestinationSender) nextPayload(diskBufferCheck <-chan time.Time) (*payload, bool) {
+	for {
+		select {
+		case p, isOpen := <-s.payloadChan:
+			return p, isOpen
+		default:
+		}
+		if s.diskBuffer != nil {
+			if p := s.diskBuffer.peek(); p != nil {
+				return p, true
+			}
+		}
+		select {
+		case p, isOpen := <-s.payloadChan:
+			return p, isOpen
+		case <-diskBufferCheck:
+		}
+	}
+}
+
+// sendWithRetries sends a payload, retrying with an exponential backoff
+// while the error is retryable. Once the sender is stopped, the payload is
+// only sent once.
+func (s *DestinationSender) sendWithRetries(p *payload) error {
+	for retries := uint(0); ; retries++ {
+		err := s.destination.Send(p.content)
+		if err == nil {
+			metrics.AdditionalDestinationLogsSent.Add(s.config.Name, int64(p.count))
+			metrics.TlmAdditionalDestinationLogsSent.Add(float64(p.count), s.config.Name)
+			return nil
+		}
+		if err == context.Canceled {
+			return err
+		}
+		metrics.AdditionalDestinationErrors.Add(s.config.Name, 1)
+		metrics.TlmAdditionalDestinationErrors.Inc(s.config.Name)
+		if _, ok := err.(*client.RetryableError); !ok {
+			log.Warnf("Could not send payload to destination %s, dropping it: %v", s.config.Name, err)
+			s.drop(p.count)
+			return err
+		}
+		if err := s.backoff(retries); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff waits for a randomized exponential delay, it returns an error if
+// the sender is stopped in the meantime.
+func (s *DestinationSender) backoff(retries uint) error {
+	maxBackoff := time.Duration(s.config.MaxBackoff) * time.Second
+	delay := maxBackoff
+	if retries < 32 && s.minBackoff<<retries < maxBackoff {
+		delay = s.minBackoff << retries
+	}
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-s.stop:
+		return errStopped
+	case <-s.destinationsContext.Context().Done():
+		return context.Canceled
+	}
+}
+
+// storeOrDrop stores a payload on disk, or drops it if there is no disk
+// buffer or it is full.
+func (s *DestinationSender) storeOrDrop(p *payload) {
+	if s.diskBuffer != nil && s.diskBuffer.store(p) {
+		metrics.AdditionalDestinationLogsStoredOnDisk.Add(s.config.Name, int64(p.count))
+		metrics.TlmAdditionalDestinationLogsStoredOnDisk.Add(float64(p.count), s.config.Name)
+		return
+	}
+	s.drop(p.count)
+}
+
+// drop records logs dropped for the destination.
+func (s *DestinationSender) drop(count int) {
+	metrics.DestinationLogsDropped.Add(s.config.Name, int64(count))
+	metrics.TlmLogsDropped.Add(float64(count), s.config.Name)
+}
@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// mockDestination sends the payloads to a channel while it is available.
+type mockDestination struct {
+	payloads    chan []byte
+	unavailable int32
+}
+
+func newMockDestination(available bool) *mockDestination {
+	d := &mockDestination{payloads: make(chan []byte, 10)}
+	d.setAvailable(available)
+	return d
+}
+
+func (d *mockDestination) setAvailable(available bool) {
+	var unavailable int32
+	if !available {
+		unavailable = 1
+	}
+	atomic.StoreInt32(&d.unavailable, unavailable)
+}
+
+func (d *mockDestination) Send(payload []byte) error {
+	if atomic.LoadInt32(&d.unavailable) == 1 {
+		return client.NewRetryableError(errors.New("unavailable"))
+	}
+	d.payloads <- payload
+	return nil
+}
+
+func (d *mockDestination) SendAsync(payload []byte) {}
+
+func newDestinationSender(t *testing.T, destinationConfig *config.AdditionalDestination, destination client.Destination) *DestinationSender {
+	require.Nil(t, destinationConfig.Validate(1))
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	s, err := NewDestinationSender(destinationConfig, destination, destinationsCtx)
+	require.Nil(t, err)
+	s.minBackoff = time.Millisecond
+	return s
+}
+
+func payloadMessages(t *testing.T, payload []byte) []string {
+	var logs []map[string]interface{}
+	require.Nil(t, json.Unmarshal(payload, &logs))
+	var messages []string
+	for _, log := range logs {
+		messages = append(messages, log["message"].(string))
+	}
+	return messages
+}
+
+func TestDestinationSenderFiltersAndBatchesLogs(t *testing.T) {
+	destination := newMockDestination(true)
+	s := newDestinationSender(t, &config.AdditionalDestination{Name: "test", URL: "http://localhost", BatchMaxSize: 2, Statuses: []string{message.StatusError}}, destination)
+	s.Start()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+	s.Write(newMessage([]byte("a"), source, message.StatusError))
+	s.Write(newMessage([]byte("b"), source, message.StatusInfo))
+	s.Write(newMessage([]byte("c"), source, message.StatusError))
+	assert.Equal(t, []string{"a", "c"}, payloadMessages(t, <-destination.payloads))
+
+	// the last batch is sent on stop
+	s.Write(newMessage([]byte("d"), source, message.StatusError))
+	s.Stop()
+	assert.Equal(t, []string{"d"}, payloadMessages(t, <-destination.payloads))
+}
+
+func TestDestinationSenderBuffersOnDiskWhileUnavailable(t *testing.T) {
+	path, err := ioutil.TempDir("", "destination")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	destination := newMockDestination(false)
+	s := newDestinationSender(t, &config.AdditionalDestination{Name: "test", URL: "http://localhost", BatchMaxSize: 1, QueueSize: 1, MaxBackoff: 1, DiskBufferMaxSize: 1000, DiskBufferPath: path}, destination)
+	s.Start()
+
+	// the first batch is retried, the second one is queued, the following ones are stored on disk
+	source := config.NewLogSource("", &config.LogsConfig{})
+	for _, content := range []string{"a", "b", "c", "d", "e"} {
+		s.Write(newMessage([]byte(content), source, message.StatusInfo))
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Eventually(t, func() bool {
+		files, _ := ioutil.ReadDir(path)
+		return len(files) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	// the batches are sent in order once the destination is available
+	destination.setAvailable(true)
+	for _, content := range []string{"a", "b", "c", "d", "e"} {
+		assert.Equal(t, []string{content}, payloadMessages(t, <-destination.payloads))
+	}
+	s.Stop()
+	files, err := ioutil.ReadDir(path)
+	assert.Nil(t, err)
+	assert.Len(t, files, 0)
+}
+
+func TestDestinationSenderStoresLogsOnStop(t *testing.T) {
+	path, err := ioutil.TempDir("", "destination")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+	destinationConfig := &config.AdditionalDestination{Name: "test", URL: "http://localhost", DiskBufferMaxSize: 1000, DiskBufferPath: path}
+
+	destination := newMockDestination(false)
+	s := newDestinationSender(t, destinationConfig, destination)
+	s.Start()
+	source := config.NewLogSource("", &config.LogsConfig{})
+	s.Write(newMessage([]byte("a"), source, message.StatusInfo))
+	s.Stop()
+	assert.Len(t, destination.payloads, 0)
+
+	// the logs are sent by the next run
+	destination = newMockDestination(true)
+	s = newDestinationSender(t, destinationConfig, destination)
+	s.Start()
+	assert.Equal(t, []string{"a"}, payloadMessages(t, <-destination.payloads))
+	s.Stop()
+}
+
+func TestDestinationSenderRemovesStoredPayloadsOnceSent(t *testing.T) {
+	path, err := ioutil.TempDir("", "destination")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+	buffer, err := newDiskBuffer(path, 1000)
+	require.Nil(t, err)
+	require.True(t, buffer.store(&payload{content: []byte(`[{"message":"a"}]`), count: 1}))
+
+	destination := newMockDestination(false)
+	s := newDestinationSender(t, &config.AdditionalDestination{Name: "test", URL: "http://localhost", MaxBackoff: 1, DiskBufferMaxSize: 1000, DiskBufferPath: path}, destination)
+	s.Start()
+
+	// the payload stays on disk while it is retried
+	time.Sleep(50 * time.Millisecond)
+	files, err := ioutil.ReadDir(path)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	destination.setAvailable(true)
+	assert.Equal(t, []string{"a"}, payloadMessages(t, <-destination.payloads))
+	assert.Eventually(t, func() bool {
+		files, _ := ioutil.ReadDir(path)
+		return len(files) == 0
+	}, time.Second, 10*time.Millisecond)
+	s.Stop()
+}
+
+func TestDestinationSenderChecksDiskBufferPeriodically(t *testing.T) {
+	path, err := ioutil.TempDir("", "destination")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	destination := newMockDestination(true)
+	s := newDestinationSender(t, &config.AdditionalDestination{Name: "test", URL: "http://localhost", DiskBufferMaxSize: 1000, DiskBufferPath: path}, destination)
+	s.diskBufferCheck = 10 * time.Millisecond
+	s.Start()
+	defer s.Stop()
+
+	// the payloads stored while the queue is empty are sent on the next check
+	time.Sleep(20 * time.Millisecond)
+	require.True(t, s.diskBuffer.store(&payload{content: []byte(`[{"message":"a"}]`), count: 1}))
+	select {
+	case p := <-destination.payloads:
+		assert.Equal(t, []string{"a"}, payloadMessages(t, p))
+	case <-time.After(time.Second):
+		assert.Fail(t, "the payload stored on disk was not sent")
+	}
+}
+
+func TestDestinationSenderFormats(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Service: "web"})
+
+	destination := newMockDestination(true)
+	s := newDestinationSender(t, &config.AdditionalDestination{Name: "test", URL: "http://localhost", Format: config.NDJSONFormat, Template: `{"msg":{{json .Message}}}`}, destination)
+	s.Start()
+	s.Write(newMessage([]byte("a"), source, message.StatusInfo))
+	s.Write(newMessage([]byte("b"), source, message.StatusInfo))
+	s.Stop()
+	assert.Equal(t, "{\"msg\":\"a\"}\n{\"msg\":\"b\"}\n", string(<-destination.payloads))
+
+	destination = newMockDestination(true)
+	s = newDestinationSender(t, &config.AdditionalDestination{Name: "test", Type: config.LokiDestinationType, URL: "http://localhost/loki/api/v1/push"}, destination)
+	s.Start()
+	s.Write(newMessage([]byte("a"), source, message.StatusInfo))
+	s.Stop()
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][]string        `json:"values"`
+		} `json:"streams"`
+	}
+	require.Nil(t, json.Unmarshal(<-destination.payloads, &push))
+	require.Len(t, push.Streams, 1)
+	assert.Equal(t, map[string]string{"service": "web", "status": "info"}, push.Streams[0].Stream)
+	require.Len(t, push.Streams[0].Values, 1)
+	assert.Equal(t, "a", push.Streams[0].Values[0][1])
+}
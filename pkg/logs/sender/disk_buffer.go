@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const payloadFileExtension = ".payload"
+
+// payload is a batch of logs serialized for a destination.
+type payload struct {
+	content []byte
+	count   int
+	// file is the file holding the payload when it was read from a disk
+	// buffer, it is removed from the buffer once the payload is sent.
+	file *payloadFile
+}
+
+// payloadFile describes a file holding a payload stored on disk. Its
+// metadata is encoded in the file name: <sequence>_<count>.payload
+type payloadFile struct {
+	name     string
+	sequence uint64
+	count    int
+	size     int64
+}
+
+// diskBuffer stores the payloads of a destination on disk, within a size
+// limit, until they can be sent. Each payload is stored in its own file,
+// and they are read back in the order they were stored, including the ones
+// left by a previous run.
+type diskBuffer struct {
+	mu           sync.Mutex
+	path         string
+	maxSize      int64
+	files        []payloadFile
+	currentSize  int64
+	nextSequence uint64
+}
+
+// newDiskBuffer returns a disk buffer storing the payloads in path.
+func newDiskBuffer(path string, maxSize int64) (*diskBuffer, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create the disk buffer folder %q: %v", path, err)
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the disk buffer folder %q: %v", path, err)
+	}
+	b := &diskBuffer{
+		path:    path,
+		maxSize: maxSize,
+	}
+	for _, entry := range entries {
+		file, ok := parsePayloadFileName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		file.size = entry.Size()
+		b.files = append(b.files, file)
+		b.currentSize += file.size
+		if file.sequence >= b.nextSequence {
+			b.nextSequence = file.sequence + 1
+		}
+	}
+	sort.Slice(b.files, func(i, j int) bool {
+		return b.files[i].sequence < b.files[j].sequence
+	})
+	if len(b.files) > 0 {
+		log.Infof("Found %d payloads to send in %s", len(b.files), path)
+	}
+	return b, nil
+}
+
+// parsePayloadFileName returns the metadata of a payload file from its name.
+func parsePayloadFileName(name string) (payloadFile, bool) {
+	var file payloadFile
+	if !strings.HasSuffix(name, payloadFileExtension) {
+		return file, false
+	}
+	if _, err := fmt.Sscanf(strings.TrimSuffix(name, payloadFileExtension), "%d_%d", &file.sequence, &file.count); err != nil {
+		return file, false
+	}
+	file.name = name
+	return file, true
+}
+
+// store stores a payload on disk, it returns false if the payload does not
+// fit in the buffer or could not be written.
+func (b *diskBuffer) store(p *payload) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	size := int64(len(p.content))
+	if b.currentSize+size > b.maxSize {
+		return false
+	}
+	file := payloadFile{
+		name:     fmt.Sprintf("%020d_%d%s", b.nextSequence, p.count, payloadFileExtension),
+		sequence: b.nextSequence,
+		count:    p.count,
+		size:     size,
+	}
+	if err := ioutil.WriteFile(filepath.Join(b.path, file.name), p.content, 0600); err != nil {
+		log.Warnf("Could not store payload on disk: %v", err)
+		return false
+	}
+	b.nextSequence++
+	b.files = append(b.files, file)
+	b.currentSize += size
+	return true
+}
+
+// peek returns the oldest payload of the buffer, which stays on disk until
+// it is removed, it returns nil if the buffer is empty.
+func (b *diskBuffer) peek() *payload {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.files) > 0 {
+		file := b.files[0]
+		content, err := ioutil.ReadFile(filepath.Join(b.path, file.name))
+		if err != nil {
+			log.Warnf("Could not read payload file %s, dropping it: %v", file.name, err)
+			b.removeFile(0)
+			continue
+		}
+		return &payload{
+			content: content,
+			count:   file.count,
+			file:    &file,
+		}
+	}
+	return nil
+}
+
+// remove removes a payload read from the buffer, once it is sent.
+func (b *diskBuffer) remove(p *payload) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, file := range b.files {
+		if file.name == p.file.name {
+			b.removeFile(i)
+			return
+		}
+	}
+}
+
+func (b *diskBuffer) removeFile(index int) {
+	file := b.files[index]
+	path := filepath.Join(b.path, file.name)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove payload file %s: %v", path, err)
+	}
+	b.files = append(b.files[:index], b.files[index+1:]...)
+	b.currentSize -= file.size
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskBuffer(t *testing.T) {
+	path, err := ioutil.TempDir("", "disk_buffer")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	buffer, err := newDiskBuffer(path, 10)
+	require.Nil(t, err)
+	assert.True(t, buffer.store(&payload{content: []byte("abc"), count: 1}))
+	assert.True(t, buffer.store(&payload{content: []byte("defg"), count: 2}))
+	// the payload does not fit in the buffer
+	assert.False(t, buffer.store(&payload{content: []byte("hijk"), count: 1}))
+
+	// the payloads left are loaded by the next buffer
+	buffer, err = newDiskBuffer(path, 10)
+	require.Nil(t, err)
+	assert.Equal(t, int64(7), buffer.currentSize)
+	assertPop(t, buffer, "abc", 1)
+	assert.True(t, buffer.store(&payload{content: []byte("hijk"), count: 1}))
+	assertPop(t, buffer, "defg", 2)
+	assertPop(t, buffer, "hijk", 1)
+	assert.Nil(t, buffer.peek())
+	assert.Equal(t, int64(0), buffer.currentSize)
+}
+
+// assertPop checks the oldest payload of the buffer, which is kept on disk
+// until it is removed, then removes it.
+func assertPop(t *testing.T, buffer *diskBuffer, content string, count int) {
+	p := buffer.peek()
+	require.NotNil(t, p)
+	assert.Equal(t, content, string(p.content))
+	assert.Equal(t, count, p.count)
+	assert.Equal(t, p, buffer.peek())
+	buffer.remove(p)
+	_, err := os.Stat(filepath.Join(buffer.path, p.file.name))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// defaultLokiLabel is the label of the streams of the messages without any
+// other label, as Loki requires at least one label per stream.
+const defaultLokiLabel = "job"
+
+// lokiStream holds the entries of the messages sharing the same labels.
+type lokiStream struct {
+	labels  map[string]string
+	entries [][]byte
+}
+
+// lokiSerializer transforms an array of Loki entries into a Loki push
+// request, grouping the entries in streams by labels. The labels of a
+// message are its service, source, status and the key:value pairs of its
+// tags, restricted to the allowed labels if any.
+type lokiSerializer struct {
+	allowedLabels map[string]bool
+}
+
+// NewLokiSerializer returns a new Loki serializer, all labels are used when
+// allowedLabels is empty.
+func NewLokiSerializer(allowedLabels []string) Serializer {
+	s := &lokiSerializer{
+		allowedLabels: make(map[string]bool, len(allowedLabels)),
+	}
+	for _, label := range allowedLabels {
+		s.allowedLabels[sanitizeLokiLabel(label)] = true
+	}
+	return s
+}
+
+// Serialize groups the entries in streams,
+// for example:
+// `["1","a"]` with labels service=foo, `["2","b"]` with labels service=bar
+// returns `{"streams":[{"stream":{"service":"foo"},"values":[["1","a"]]},{"stream":{"service":"bar"},"values":[["2","b"]]}]}`
+func (s *lokiSerializer) Serialize(messages []*message.Message) []byte {
+	var streams []*lokiStream
+	streamsByKey := make(map[string]*lokiStream)
+	for _, message := range messages {
+		labels := s.labels(message)
+		key := lokiStreamKey(labels)
+		stream, exists := streamsByKey[key]
+		if !exists {
+			stream = &lokiStream{labels: labels}
+			streamsByKey[key] = stream
+			streams = append(streams, stream)
+		}
+		stream.entries = append(stream.entries, message.Content)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(`{"streams":[`)
+	for i, stream := range streams {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		// labels can always be encoded as they are strings
+		labels, _ := json.Marshal(stream.labels)
+		buffer.WriteString(`{"stream":`)
+		buffer.Write(labels)
+		buffer.WriteString(`,"values":[`)
+		buffer.Write(bytes.Join(stream.entries, []byte{','}))
+		buffer.WriteString(`]}`)
+	}
+	buffer.WriteString(`]}`)
+	return buffer.Bytes()
+}
+
+// labels returns the labels of a message, the first value of a label wins.
+func (s *lokiSerializer) labels(msg *message.Message) map[string]string {
+	labels := make(map[string]string)
+	add := func(name, value string) {
+		name = sanitizeLokiLabel(name)
+		if value == "" || (len(s.allowedLabels) > 0 && !s.allowedLabels[name]) {
+			return
+		}
+		if _, exists := labels[name]; !exists {
+			labels[name] = value
+		}
+	}
+	add("service", msg.Origin.Service())
+	add("source", msg.Origin.Source())
+	add("status", msg.GetStatus())
+	for _, tag := range msg.Origin.Tags() {
+		if parts := strings.SplitN(tag, ":", 2); len(parts) == 2 {
+			add(parts[0], parts[1])
+		}
+	}
+	if len(labels) == 0 {
+		labels[defaultLokiLabel] = "datadog-agent"
+	}
+	return labels
+}
+
+// lokiStreamKey returns a key identifying a set of labels.
+func lokiStreamKey(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+// sanitizeLokiLabel returns a valid label name, replacing the invalid
+// characters by underscores.
+func sanitizeLokiLabel(name string) string {
+	sanitized := []byte(name)
+	for i, c := range sanitized {
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')) {
+			sanitized[i] = '_'
+		}
+	}
+	return string(sanitized)
+}
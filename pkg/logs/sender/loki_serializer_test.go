@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestLokiSerializer(t *testing.T) {
+	foo := config.NewLogSource("", &config.LogsConfig{Service: "foo", Tags: []string{"env:prod", "kube-namespace:default", "nokey"}})
+	bar := config.NewLogSource("", &config.LogsConfig{Service: "bar"})
+	messages := []*message.Message{
+		newMessage([]byte(`["1","a"]`), foo, message.StatusInfo),
+		newMessage([]byte(`["2","b"]`), bar, message.StatusInfo),
+		newMessage([]byte(`["3","c"]`), foo, message.StatusInfo),
+		newMessage([]byte(`["4","d"]`), foo, message.StatusError),
+	}
+
+	payload := NewLokiSerializer(nil).Serialize(messages)
+	assert.Equal(t, `{"streams":[`+
+		`{"stream":{"env":"prod","kube_namespace":"default","service":"foo","status":"info"},"values":[["1","a"],["3","c"]]},`+
+		`{"stream":{"service":"bar","status":"info"},"values":[["2","b"]]},`+
+		`{"stream":{"env":"prod","kube_namespace":"default","service":"foo","status":"error"},"values":[["4","d"]]}]}`, string(payload))
+
+	payload = NewLokiSerializer([]string{"kube-namespace"}).Serialize(messages)
+	assert.Equal(t, `{"streams":[`+
+		`{"stream":{"kube_namespace":"default"},"values":[["1","a"],["3","c"],["4","d"]]},`+
+		`{"stream":{"job":"datadog-agent"},"values":[["2","b"]]}]}`, string(payload))
+}
+
+func TestSanitizeLokiLabel(t *testing.T) {
+	assert.Equal(t, "kube_namespace", sanitizeLokiLabel("kube-namespace"))
+	assert.Equal(t, "_xx", sanitizeLokiLabel("9xx"))
+	assert.Equal(t, "a1_b", sanitizeLokiLabel("a1.b"))
+}
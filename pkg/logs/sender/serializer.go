@@ -16,6 +16,8 @@ var (
 	LineSerializer Serializer = &lineSerializer{}
 	// ArraySerializer is a shared line serializer.
 	ArraySerializer Serializer = &arraySerializer{}
+	// NDJSONSerializer is a shared newline delimited JSON serializer.
+	NDJSONSerializer Serializer = &ndjsonSerializer{}
 )
 
 // Serializer transforms a batch of messages into a payload.
@@ -62,3 +64,18 @@ func (s *arraySerializer) Serialize(messages []*message.Message) []byte {
 	buffer.WriteByte(']')
 	return buffer.Bytes()
 }
+
+// ndjsonSerializer transforms a message array into a payload
+// terminating each content by a new line character.
+type ndjsonSerializer struct{}
+
+// Serialize concatenates all messages, each one being followed
+// by a new line character.
+func (s *ndjsonSerializer) Serialize(messages []*message.Message) []byte {
+	var buffer bytes.Buffer
+	for _, message := range messages {
+		buffer.Write(message.Content)
+		buffer.WriteByte('\n')
+	}
+	return buffer.Bytes()
+}
@@ -48,3 +48,17 @@ func TestArraySerializer(t *testing.T) {
 	payload = serializer.Serialize(messages)
 	assert.Equal(t, []byte("[a,b]"), payload)
 }
+
+func TestNDJSONSerializer(t *testing.T) {
+	var messages []*message.Message
+	var payload []byte
+
+	serializer := NDJSONSerializer
+
+	payload = serializer.Serialize(messages)
+	assert.Len(t, payload, 0)
+
+	messages = []*message.Message{message.NewMessage([]byte("a"), nil, ""), message.NewMessage([]byte("b"), nil, "")}
+	payload = serializer.Serialize(messages)
+	assert.Equal(t, []byte("a\nb\n"), payload)
+}
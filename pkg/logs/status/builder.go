@@ -8,6 +8,7 @@ package status
 import (
 	"expvar"
 	"fmt"
+	"net/url"
 	"strings"
 	"sync/atomic"
 
@@ -66,9 +67,30 @@ func (b *Builder) getEndpoints() []string {
 	for _, additional := range b.endpoints.Additionals {
 		result = append(result, b.formatEndpoint(additional, "Additional: "))
 	}
+	for _, destination := range b.endpoints.AdditionalDestinations {
+		result = append(result, b.formatAdditionalDestination(destination))
+	}
 	return result
 }
 
+func (b *Builder) formatAdditionalDestination(destination *config.AdditionalDestination) string {
+	compression := "uncompressed"
+	if destination.UseCompression {
+		compression = "compressed"
+	}
+	// the credentials of the url are not displayed
+	address := destination.URL
+	if u, err := url.Parse(destination.URL); err == nil {
+		u.User = nil
+		address = u.String()
+	}
+	protocol := fmt.Sprintf("HTTP (%s)", destination.Format)
+	if destination.Type == config.LokiDestinationType {
+		protocol = "Loki push API"
+	}
+	return fmt.Sprintf("Additional destination %s: Sending %s logs in %s to %s", destination.Name, compression, protocol, address)
+}
+
 func (b *Builder) formatEndpoint(endpoint config.Endpoint, prefix string) string {
 	compression := "uncompressed"
 	if endpoint.UseCompression {
@@ -122,12 +144,17 @@ func (b *Builder) getIntegrations() []Integration {
 	for name, logSources := range b.groupSourcesByName() {
 		var sources []Source
 		for _, source := range logSources {
+			var rateLimit string
+			if source.RateLimiter != nil {
+				rateLimit = source.RateLimiter.Usage()
+			}
 			sources = append(sources, Source{
 				Type:          source.Config.Type,
 				Configuration: b.toDictionary(source.Config),
 				Status:        b.toString(source.Status),
 				Inputs:        source.GetInputs(),
 				Messages:      source.Messages.GetMessages(),
+				RateLimit:     rateLimit,
 			})
 		}
 		integrations = append(integrations, Integration{
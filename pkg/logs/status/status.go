@@ -39,6 +39,7 @@ type Source struct {
 	Status        string                 `json:"status"`
 	Inputs        []string               `json:"inputs"`
 	Messages      []string               `json:"messages"`
+	RateLimit     string                 `json:"rate_limit,omitempty"`
 }
 
 // Integration provides some information about a logs integration.
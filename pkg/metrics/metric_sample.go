@@ -70,6 +70,8 @@ type MetricSample struct {
 	Host       string
 	SampleRate float64
 	Timestamp  float64
+	// OriginID identifies the dogstatsd client which sent the sample, if known
+	OriginID string
 }
 
 // Implement the MetricSampleContext interface
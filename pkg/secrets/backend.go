@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// BackendTypeCommand fetches secrets by running "secret_backend_command"
+	BackendTypeCommand = "command"
+	// BackendTypeFile reads secrets from files, one file per handle
+	BackendTypeFile = "file"
+	// BackendTypeEnv reads secrets from environment variables
+	BackendTypeEnv = "env"
+	// BackendTypeVault reads secrets from a HashiCorp Vault KV secrets engine
+	BackendTypeVault = "vault"
+)
+
+// SecretBackend fetches the value of secret handles. Errors specific to one
+// handle are reported through the ErrorMsg field of its Secret, the returned
+// error is reserved for failures affecting every handle.
+type SecretBackend interface {
+	// Name returns the name of the backend used in logs and error messages.
+	Name() string
+	// FetchSecrets returns the secrets for the given handles.
+	FetchSecrets(handles []string) (map[string]Secret, error)
+}
+
+// newSecretBackend builds the built-in backend of the given type, it returns
+// nil for the command backend that is configured with the legacy options.
+func newSecretBackend(backendType string, backendConfig map[string]interface{}) (SecretBackend, error) {
+	switch backendType {
+	case "", BackendTypeCommand:
+		return nil, nil
+	case BackendTypeFile:
+		return newFileBackend(backendConfig)
+	case BackendTypeEnv:
+		return newEnvBackend(backendConfig)
+	case BackendTypeVault:
+		return newVaultBackend(backendConfig)
+	default:
+		return nil, fmt.Errorf("unknown secret_backend_type '%s'", backendType)
+	}
+}
+
+// getBackend returns the backend used to fetch secrets. The command backend
+// is used when no built-in backend is configured.
+func getBackend() SecretBackend {
+	if secretBackend != nil {
+		return secretBackend
+	}
+	return commandBackend{}
+}
+
+func configString(config map[string]interface{}, key string) (string, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return "", nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("'%s' must be a string, got %T", key, value)
+	}
+	return str, nil
+}
+
+func configInt(config map[string]interface{}, key string, defaultValue int) (int, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("'%s' must be an integer: %s", key, err)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("'%s' must be an integer, got %T", key, value)
+	}
+}
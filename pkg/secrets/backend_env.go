@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"os"
+)
+
+// envBackend reads secrets from environment variables, the name of the
+// variable being the handle with an optional prefix.
+type envBackend struct {
+	prefix string
+}
+
+func newEnvBackend(config map[string]interface{}) (*envBackend, error) {
+	prefix, err := configString(config, "prefix")
+	if err != nil {
+		return nil, err
+	}
+	return &envBackend{prefix: prefix}, nil
+}
+
+// Name implements SecretBackend#Name
+func (b *envBackend) Name() string {
+	return "environment variables secret backend"
+}
+
+// FetchSecrets implements SecretBackend#FetchSecrets
+func (b *envBackend) FetchSecrets(handles []string) (map[string]Secret, error) {
+	secrets := make(map[string]Secret, len(handles))
+	for _, handle := range handles {
+		name := b.prefix + handle
+		value, found := os.LookupEnv(name)
+		if !found {
+			secrets[handle] = Secret{ErrorMsg: fmt.Sprintf("environment variable '%s' is not set", name)}
+			continue
+		}
+		secrets[handle] = Secret{Value: value}
+	}
+	return secrets, nil
+}
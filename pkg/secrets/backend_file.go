@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileBackend reads secrets from the files of a directory, the handle being
+// the path of the file relative to the directory. This is the layout used by
+// Kubernetes to mount secrets in a volume.
+type fileBackend struct {
+	secretsPath string
+}
+
+func newFileBackend(config map[string]interface{}) (*fileBackend, error) {
+	secretsPath, err := configString(config, "secrets_path")
+	if err != nil {
+		return nil, err
+	}
+	if secretsPath == "" {
+		return nil, fmt.Errorf("'secrets_path' is required by the %s secret backend", BackendTypeFile)
+	}
+
+	// Kubernetes secrets are symlinks to files in a hidden folder of the
+	// volume: paths are compared once resolved.
+	secretsPath, err = filepath.EvalSymlinks(secretsPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'secrets_path': %s", err)
+	}
+	return &fileBackend{secretsPath: secretsPath}, nil
+}
+
+// Name implements SecretBackend#Name
+func (b *fileBackend) Name() string {
+	return "file secret backend"
+}
+
+// FetchSecrets implements SecretBackend#FetchSecrets
+func (b *fileBackend) FetchSecrets(handles []string) (map[string]Secret, error) {
+	secrets := make(map[string]Secret, len(handles))
+	for _, handle := range handles {
+		value, err := b.readSecret(handle)
+		if err != nil {
+			secrets[handle] = Secret{ErrorMsg: err.Error()}
+			continue
+		}
+		secrets[handle] = Secret{Value: value}
+	}
+	return secrets, nil
+}
+
+func (b *fileBackend) readSecret(handle string) (string, error) {
+	path, err := filepath.EvalSymlinks(filepath.Join(b.secretsPath, handle))
+	if err != nil {
+		return "", err
+	}
+	relPath, err := filepath.Rel(b.secretsPath, path)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("secret file is outside of '%s'", b.secretsPath)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("secret is not a regular file")
+	}
+	if info.Size() > int64(SecretBackendOutputMaxSize) {
+		return "", fmt.Errorf("secret file exceeds the maximum size of %d bytes", SecretBackendOutputMaxSize)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
@@ -0,0 +1,234 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/common"
+)
+
+func TestNewSecretBackend(t *testing.T) {
+	backend, err := newSecretBackend("", nil)
+	require.NoError(t, err)
+	assert.Nil(t, backend)
+
+	backend, err = newSecretBackend(BackendTypeCommand, nil)
+	require.NoError(t, err)
+	assert.Nil(t, backend)
+
+	backend, err = newSecretBackend(BackendTypeEnv, map[string]interface{}{"prefix": "DD_"})
+	require.NoError(t, err)
+	assert.IsType(t, &envBackend{}, backend)
+
+	_, err = newSecretBackend(BackendTypeFile, nil)
+	assert.EqualError(t, err, "'secrets_path' is required by the file secret backend")
+
+	_, err = newSecretBackend(BackendTypeEnv, map[string]interface{}{"prefix": 12})
+	assert.EqualError(t, err, "'prefix' must be a string, got int")
+
+	_, err = newSecretBackend("unknown", nil)
+	assert.EqualError(t, err, "unknown secret_backend_type 'unknown'")
+}
+
+// newKubernetesSecretDir mimics the layout of a Kubernetes secret mounted in
+// a volume: each key is a symlink to a file in a hidden timestamped folder.
+func newKubernetesSecretDir(t *testing.T, secrets map[string]string) string {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+
+	dataDir := filepath.Join(dir, "..2020_07_01_12_00_00.000000000")
+	require.NoError(t, os.Mkdir(dataDir, 0700))
+	require.NoError(t, os.Symlink(dataDir, filepath.Join(dir, "..data")))
+	for name, value := range secrets {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dataDir, name), []byte(value), 0600))
+		require.NoError(t, os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)))
+	}
+	return dir
+}
+
+func TestFileBackend(t *testing.T) {
+	dir := newKubernetesSecretDir(t, map[string]string{
+		"password": "secret1\n",
+		"api_key":  "secret2",
+	})
+	defer os.RemoveAll(dir)
+
+	outside, err := ioutil.TempFile("", "outside")
+	require.NoError(t, err)
+	defer os.Remove(outside.Name())
+	outside.Close()
+
+	backend, err := newFileBackend(map[string]interface{}{"secrets_path": dir})
+	require.NoError(t, err)
+
+	secrets, err := backend.FetchSecrets([]string{"password", "api_key", "missing", "../" + filepath.Base(outside.Name()), "..data"})
+	require.NoError(t, err)
+
+	assert.Equal(t, Secret{Value: "secret1"}, secrets["password"])
+	assert.Equal(t, Secret{Value: "secret2"}, secrets["api_key"])
+	assert.NotEmpty(t, secrets["missing"].ErrorMsg)
+	assert.Contains(t, secrets["../"+filepath.Base(outside.Name())].ErrorMsg, "outside of")
+	assert.Equal(t, "secret is not a regular file", secrets["..data"].ErrorMsg)
+}
+
+func TestEnvBackend(t *testing.T) {
+	os.Setenv("DD_TEST_SECRET_password", "secret1")
+	defer os.Unsetenv("DD_TEST_SECRET_password")
+
+	backend, err := newEnvBackend(map[string]interface{}{"prefix": "DD_TEST_SECRET_"})
+	require.NoError(t, err)
+
+	secrets, err := backend.FetchSecrets([]string{"password", "missing"})
+	require.NoError(t, err)
+
+	assert.Equal(t, Secret{Value: "secret1"}, secrets["password"])
+	assert.Equal(t, "environment variable 'DD_TEST_SECRET_missing' is not set", secrets["missing"].ErrorMsg)
+}
+
+func newVaultStub(t *testing.T, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.Header.Get(vaultTokenHeader) != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/datadog/agent":
+			fmt.Fprint(w, `{"data":{"data":{"value":"secret1","api_key":"secret2","port":8080},"metadata":{"version":3}}}`)
+		case "/v1/kv/datadog/agent":
+			fmt.Fprint(w, `{"data":{"value":"secret3"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":[]}`)
+		}
+	}))
+}
+
+func TestVaultBackendKVv2(t *testing.T) {
+	requests := 0
+	server := newVaultStub(t, &requests)
+	defer server.Close()
+
+	backend, err := newVaultBackend(map[string]interface{}{
+		"address": server.URL,
+		"token":   "test-token",
+	})
+	require.NoError(t, err)
+
+	secrets, err := backend.FetchSecrets([]string{
+		"datadog/agent",
+		"datadog/agent#api_key",
+		"datadog/agent#port",
+		"datadog/agent#missing",
+		"datadog/unknown#value",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, Secret{Value: "secret1"}, secrets["datadog/agent"])
+	assert.Equal(t, Secret{Value: "secret2"}, secrets["datadog/agent#api_key"])
+	assert.Equal(t, "key 'port' at path 'datadog/agent' is not a string", secrets["datadog/agent#port"].ErrorMsg)
+	assert.Equal(t, "key 'missing' not found at path 'datadog/agent'", secrets["datadog/agent#missing"].ErrorMsg)
+	assert.Contains(t, secrets["datadog/unknown#value"].ErrorMsg, "Vault returned status 404")
+	// each path is only read once
+	assert.Equal(t, 2, requests)
+}
+
+func TestVaultBackendKVv1(t *testing.T) {
+	requests := 0
+	server := newVaultStub(t, &requests)
+	defer server.Close()
+
+	backend, err := newVaultBackend(map[string]interface{}{
+		"address":    server.URL,
+		"token":      "test-token",
+		"mount":      "kv",
+		"kv_version": 1,
+	})
+	require.NoError(t, err)
+
+	secrets, err := backend.FetchSecrets([]string{"datadog/agent"})
+	require.NoError(t, err)
+	assert.Equal(t, Secret{Value: "secret3"}, secrets["datadog/agent"])
+}
+
+func TestVaultBackendConfig(t *testing.T) {
+	_, err := newVaultBackend(map[string]interface{}{"token": "test-token"})
+	assert.EqualError(t, err, "'address' is required by the vault secret backend")
+
+	_, err = newVaultBackend(map[string]interface{}{"address": "http://127.0.0.1:8200", "token": "test-token", "kv_version": 3})
+	assert.EqualError(t, err, "'kv_version' must be 1 or 2, got 3")
+
+	tokenFile, err := ioutil.TempFile("", "vault-token")
+	require.NoError(t, err)
+	defer os.Remove(tokenFile.Name())
+	tokenFile.WriteString("file-token\n")
+	tokenFile.Close()
+
+	backend, err := newVaultBackend(map[string]interface{}{"address": "http://127.0.0.1:8200/", "token_file": tokenFile.Name()})
+	require.NoError(t, err)
+	assert.Equal(t, "file-token", backend.token)
+	assert.Equal(t, "http://127.0.0.1:8200", backend.address)
+	assert.Equal(t, "secret", backend.mount)
+	assert.Equal(t, 2, backend.kvVersion)
+}
+
+func TestVaultBackendForbidden(t *testing.T) {
+	requests := 0
+	server := newVaultStub(t, &requests)
+	defer server.Close()
+
+	backend, err := newVaultBackend(map[string]interface{}{
+		"address": server.URL,
+		"token":   "wrong-token",
+	})
+	require.NoError(t, err)
+
+	secrets, err := backend.FetchSecrets([]string{"datadog/agent"})
+	require.NoError(t, err)
+	assert.Equal(t, "Vault returned status 403 for 'datadog/agent': permission denied", secrets["datadog/agent"].ErrorMsg)
+}
+
+func TestDecryptWithBackend(t *testing.T) {
+	os.Setenv("DD_TEST_SECRET_pass1", "password1")
+	defer os.Unsetenv("DD_TEST_SECRET_pass1")
+
+	err := Init("", nil, 5, SecretBackendOutputMaxSize, false, BackendTypeEnv, map[string]interface{}{"prefix": "DD_TEST_SECRET_"})
+	require.NoError(t, err)
+	defer func() {
+		secretBackend = nil
+		secretBackendType = ""
+		secretCache = map[string]string{}
+		secretOrigin = map[string]common.StringSet{}
+	}()
+
+	newConf, err := Decrypt([]byte("password: ENC[pass1]\n"), "test")
+	require.NoError(t, err)
+	assert.Equal(t, "password: password1\n", string(newConf))
+
+	info, err := GetDebugInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "environment variables secret backend", info.BackendName)
+	assert.Equal(t, "", info.ExecutablePath)
+}
+
+func TestInitUnknownBackend(t *testing.T) {
+	err := Init("", nil, 5, SecretBackendOutputMaxSize, false, "unknown", nil)
+	assert.EqualError(t, err, "could not initialize the 'unknown' secret backend: unknown secret_backend_type 'unknown'")
+	assert.Nil(t, secretBackend)
+}
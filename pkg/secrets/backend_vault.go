@@ -0,0 +1,227 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	vaultTokenHeader     = "X-Vault-Token"
+	vaultNamespaceHeader = "X-Vault-Namespace"
+	vaultDefaultMount    = "secret"
+	vaultDefaultKey      = "value"
+)
+
+// vaultBackend reads secrets from a HashiCorp Vault KV secrets engine (version
+// 1 or 2). Handles have the form "<path>#<key>", the key defaults to "value".
+type vaultBackend struct {
+	address   string
+	token     string
+	namespace string
+	mount     string
+	kvVersion int
+	client    *http.Client
+}
+
+func newVaultBackend(config map[string]interface{}) (*vaultBackend, error) {
+	b := &vaultBackend{
+		client: &http.Client{
+			Timeout: time.Duration(secretBackendTimeout) * time.Second,
+		},
+	}
+
+	var err error
+	if b.address, err = configString(config, "address"); err != nil {
+		return nil, err
+	}
+	if b.address == "" {
+		b.address = os.Getenv("VAULT_ADDR")
+	}
+	if b.address == "" {
+		return nil, fmt.Errorf("'address' is required by the %s secret backend", BackendTypeVault)
+	}
+	b.address = strings.TrimRight(b.address, "/")
+
+	if b.token, err = vaultToken(config); err != nil {
+		return nil, err
+	}
+
+	if b.namespace, err = configString(config, "namespace"); err != nil {
+		return nil, err
+	}
+	if b.namespace == "" {
+		b.namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+
+	if b.mount, err = configString(config, "mount"); err != nil {
+		return nil, err
+	}
+	if b.mount == "" {
+		b.mount = vaultDefaultMount
+	}
+	b.mount = strings.Trim(b.mount, "/")
+
+	if b.kvVersion, err = configInt(config, "kv_version", 2); err != nil {
+		return nil, err
+	}
+	if b.kvVersion != 1 && b.kvVersion != 2 {
+		return nil, fmt.Errorf("'kv_version' must be 1 or 2, got %d", b.kvVersion)
+	}
+
+	return b, nil
+}
+
+func vaultToken(config map[string]interface{}) (string, error) {
+	token, err := configString(config, "token")
+	if err != nil || token != "" {
+		return token, err
+	}
+
+	tokenFile, err := configString(config, "token_file")
+	if err != nil {
+		return "", err
+	}
+	if tokenFile != "" {
+		content, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("could not read the Vault token file: %s", err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	return "", fmt.Errorf("'token' or 'token_file' is required by the %s secret backend", BackendTypeVault)
+}
+
+// Name implements SecretBackend#Name
+func (b *vaultBackend) Name() string {
+	return "vault secret backend"
+}
+
+// FetchSecrets implements SecretBackend#FetchSecrets
+func (b *vaultBackend) FetchSecrets(handles []string) (map[string]Secret, error) {
+	secrets := make(map[string]Secret, len(handles))
+	// several handles usually reference different keys of the same path
+	readPaths := make(map[string]map[string]interface{})
+	readErrors := make(map[string]error)
+
+	for _, handle := range handles {
+		path, key := parseVaultHandle(handle)
+
+		data, read := readPaths[path]
+		err := readErrors[path]
+		if !read && err == nil {
+			data, err = b.read(path)
+			if err != nil {
+				readErrors[path] = err
+			} else {
+				readPaths[path] = data
+			}
+		}
+		if err != nil {
+			secrets[handle] = Secret{ErrorMsg: err.Error()}
+			continue
+		}
+
+		value, found := data[key]
+		if !found {
+			secrets[handle] = Secret{ErrorMsg: fmt.Sprintf("key '%s' not found at path '%s'", key, path)}
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			secrets[handle] = Secret{ErrorMsg: fmt.Sprintf("key '%s' at path '%s' is not a string", key, path)}
+			continue
+		}
+		secrets[handle] = Secret{Value: str}
+	}
+	return secrets, nil
+}
+
+func parseVaultHandle(handle string) (string, string) {
+	path := handle
+	key := vaultDefaultKey
+	if idx := strings.LastIndex(handle, "#"); idx >= 0 {
+		path = handle[:idx]
+		key = handle[idx+1:]
+	}
+	return strings.Trim(path, "/"), key
+}
+
+// read returns the key/value pairs stored at a path of the KV secrets engine.
+func (b *vaultBackend) read(path string) (map[string]interface{}, error) {
+	var secretURL string
+	if b.kvVersion == 2 {
+		secretURL = fmt.Sprintf("%s/v1/%s/data/%s", b.address, b.mount, escapeVaultPath(path))
+	} else {
+		secretURL = fmt.Sprintf("%s/v1/%s/%s", b.address, b.mount, escapeVaultPath(path))
+	}
+
+	req, err := http.NewRequest("GET", secretURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(vaultTokenHeader, b.token)
+	if b.namespace != "" {
+		req.Header.Set(vaultNamespaceHeader, b.namespace)
+	}
+
+	log.Debugf("reading secret path '%s' from Vault", path)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading '%s' from Vault: %s", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(SecretBackendOutputMaxSize)))
+	if err != nil {
+		return nil, fmt.Errorf("error while reading '%s' from Vault: %s", path, err)
+	}
+
+	var response struct {
+		Errors []string               `json:"errors"`
+		Data   map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the Vault response for '%s' (status %d): %s", path, resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Vault returned status %d for '%s': %s", resp.StatusCode, path, strings.Join(response.Errors, ", "))
+	}
+
+	if b.kvVersion == 1 {
+		return response.Data, nil
+	}
+	// KV version 2 nests the secret in a 'data' field next to its metadata
+	data, ok := response.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected Vault response for '%s': missing 'data' field", path)
+	}
+	return data, nil
+}
+
+func escapeVaultPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -100,13 +100,20 @@ type Secret struct {
 // for testing purpose
 var runCommand = execCommand
 
-// fetchSecret receives a list of secrets name to fetch, exec a custom
-// executable to fetch the actual secrets and returns them. Origin should be
-// the name of the configuration where the secret was referenced.
-func fetchSecret(secretsHandle []string, origin string) (map[string]string, error) {
+// commandBackend fetches secrets by running "secret_backend_command" with the
+// list of handles as a JSON payload on its standard input.
+type commandBackend struct{}
+
+// Name implements SecretBackend#Name
+func (commandBackend) Name() string {
+	return "secret_backend_command"
+}
+
+// FetchSecrets implements SecretBackend#FetchSecrets
+func (commandBackend) FetchSecrets(handles []string) (map[string]Secret, error) {
 	payload := map[string]interface{}{
 		"version": PayloadVersion,
-		"secrets": secretsHandle,
+		"secrets": handles,
 	}
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
@@ -123,26 +130,48 @@ func fetchSecret(secretsHandle []string, origin string) (map[string]string, erro
 	if err != nil {
 		return nil, fmt.Errorf("could not unmarshal 'secret_backend_command' output: %s", err)
 	}
+	return secrets, nil
+}
+
+// fetchSecret receives a list of secrets name to fetch, fetches the actual
+// secrets from the configured backend and returns them. Origin should be the
+// name of the configuration where the secret was referenced.
+func fetchSecret(secretsHandle []string, origin string) (map[string]string, error) {
+	backend := getBackend()
+	secrets, err := backend.FetchSecrets(secretsHandle)
+	if err != nil {
+		return nil, err
+	}
 
 	res := map[string]string{}
 	for _, sec := range secretsHandle {
-		v, ok := secrets[sec]
-		if ok == false {
-			return nil, fmt.Errorf("secret handle '%s' was not decrypted by the secret_backend_command", sec)
-		}
-
-		if v.ErrorMsg != "" {
-			return nil, fmt.Errorf("an error occurred while decrypting '%s': %s", sec, v.ErrorMsg)
-		}
-		if v.Value == "" {
-			return nil, fmt.Errorf("decrypted secret for '%s' is empty", sec)
+		value, err := secretValue(sec, secrets, backend)
+		if err != nil {
+			return nil, err
 		}
 
 		// add it to the cache
-		secretCache[sec] = v.Value
+		secretCache[sec] = value
 		// keep track of place where a handle was found
 		secretOrigin[sec] = common.NewStringSet(origin)
-		res[sec] = v.Value
+		res[sec] = value
 	}
 	return res, nil
 }
+
+// secretValue returns the value of a handle from the secrets returned by a
+// backend, or an error if the backend couldn't decrypt it.
+func secretValue(handle string, secrets map[string]Secret, backend SecretBackend) (string, error) {
+	v, ok := secrets[handle]
+	if ok == false {
+		return "", fmt.Errorf("secret handle '%s' was not decrypted by the %s", handle, backend.Name())
+	}
+
+	if v.ErrorMsg != "" {
+		return "", fmt.Errorf("an error occurred while decrypting '%s': %s", handle, v.ErrorMsg)
+	}
+	if v.Value == "" {
+		return "", fmt.Errorf("decrypted secret for '%s' is empty", handle)
+	}
+	return v.Value, nil
+}
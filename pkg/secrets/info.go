@@ -10,31 +10,49 @@ import (
 	"io"
 	"runtime"
 	"strings"
+	"time"
 )
 
+// SecretRotation records a change of the value of a handle detected when
+// refreshing the secrets
+type SecretRotation struct {
+	Handle  string
+	Origins []string
+	Time    time.Time
+}
+
 // SecretInfo export troubleshooting information about the decrypted secrets
 type SecretInfo struct {
+	BackendName    string
 	ExecutablePath string
 	Rights         string
 	RightDetails   string
 	UnixOwner      string
 	UnixGroup      string
 	SecretsHandles map[string][]string
+
+	RefreshInterval time.Duration
+	Rotations       []SecretRotation
 }
 
 // Print output a SecretInfo to a io.Writer
 func (si *SecretInfo) Print(w io.Writer) {
-	fmt.Fprintf(w, "=== Checking executable rights ===\n")
-	fmt.Fprintf(w, "Executable path: %s\n", si.ExecutablePath)
+	if si.ExecutablePath == "" {
+		fmt.Fprintf(w, "=== Secret backend ===\n")
+		fmt.Fprintf(w, "Backend: %s\n", si.BackendName)
+	} else {
+		fmt.Fprintf(w, "=== Checking executable rights ===\n")
+		fmt.Fprintf(w, "Executable path: %s\n", si.ExecutablePath)
 
-	fmt.Fprintf(w, "Check Rights: %s\n", si.Rights)
+		fmt.Fprintf(w, "Check Rights: %s\n", si.Rights)
 
-	fmt.Fprintf(w, "\nRights Detail:\n")
-	fmt.Fprintf(w, "%s\n", si.RightDetails)
+		fmt.Fprintf(w, "\nRights Detail:\n")
+		fmt.Fprintf(w, "%s\n", si.RightDetails)
 
-	if runtime.GOOS != "windows" {
-		fmt.Fprintf(w, "Owner username: %s\n", si.UnixOwner)
-		fmt.Fprintf(w, "Group name: %s\n", si.UnixGroup)
+		if runtime.GOOS != "windows" {
+			fmt.Fprintf(w, "Owner username: %s\n", si.UnixOwner)
+			fmt.Fprintf(w, "Group name: %s\n", si.UnixGroup)
+		}
 	}
 
 	fmt.Fprintf(w, "\n=== Secrets stats ===\n")
@@ -43,4 +61,13 @@ func (si *SecretInfo) Print(w io.Writer) {
 	for handle, origins := range si.SecretsHandles {
 		fmt.Fprintf(w, "- %s: from %s\n", handle, strings.Join(origins, ", "))
 	}
+
+	if si.RefreshInterval > 0 {
+		fmt.Fprintf(w, "\n=== Secrets refresh ===\n")
+		fmt.Fprintf(w, "Refresh interval: %s\n", si.RefreshInterval)
+		fmt.Fprintf(w, "Secrets handle rotated:\n")
+		for _, rotation := range si.Rotations {
+			fmt.Fprintf(w, "- %s: %s, used in %s\n", rotation.Time.Format(time.RFC3339), rotation.Handle, strings.Join(rotation.Origins, ", "))
+		}
+	}
 }
@@ -11,13 +11,25 @@ package secrets
 
 import (
 	"fmt"
+	"time"
 )
 
 // SecretBackendOutputMaxSize defines max size of the JSON output from a secrets reader backend
 var SecretBackendOutputMaxSize = 1024 * 1024
 
 // Init placeholder when compiled without the 'secrets' build tag
-func Init(command string, arguments []string, timeout int, maxSize int, groupExecPerm bool) {}
+func Init(command string, arguments []string, timeout int, maxSize int, groupExecPerm bool, backendType string, backendConfig map[string]interface{}) error {
+	return nil
+}
+
+// RefreshCallback is called after a refresh changed the value of some handles
+type RefreshCallback func(origins []string)
+
+// RegisterRefreshCallback placeholder when compiled without the 'secrets' build tag
+func RegisterRefreshCallback(callback RefreshCallback) {}
+
+// StartRefreshRoutine placeholder when compiled without the 'secrets' build tag
+func StartRefreshRoutine(interval time.Duration) {}
 
 // Decrypt encrypted secrets are not available on windows
 func Decrypt(data []byte, origin string) ([]byte, error) {
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/common"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// maxSecretRotations is the number of rotations kept for the 'secret' command
+// and the flare
+const maxSecretRotations = 50
+
+// RefreshCallback is called after a refresh changed the value of some
+// handles, with the origins referencing at least one of them.
+type RefreshCallback func(origins []string)
+
+var (
+	secretRefreshInterval time.Duration
+	secretRefreshOnce     sync.Once
+	// the following are protected by secretLock
+	refreshCallbacks []RefreshCallback
+	secretRotations  []SecretRotation
+)
+
+// RegisterRefreshCallback registers a callback called when the value of a
+// secret changes. Callbacks are called in the order they were registered.
+func RegisterRefreshCallback(callback RefreshCallback) {
+	secretLock.Lock()
+	defer secretLock.Unlock()
+	refreshCallbacks = append(refreshCallbacks, callback)
+}
+
+// StartRefreshRoutine periodically fetches again every known secret from the
+// backend. It does nothing if the interval is not positive or if the routine
+// was already started.
+func StartRefreshRoutine(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	secretRefreshOnce.Do(func() {
+		secretRefreshInterval = interval
+		log.Infof("Secrets will be refreshed every %s", interval)
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				if _, err := refresh(); err != nil {
+					log.Errorf("Could not refresh secrets: %s", err)
+				}
+			}
+		}()
+	})
+}
+
+// refresh fetches the value of every handle in the cache, updates the ones
+// that changed and notifies the registered callbacks. It returns the list of
+// handles that changed.
+func refresh() ([]string, error) {
+	if !isEnabled() {
+		return nil, nil
+	}
+
+	secretLock.Lock()
+	handles := make([]string, 0, len(secretCache))
+	for handle := range secretCache {
+		handles = append(handles, handle)
+	}
+	secretLock.Unlock()
+
+	if len(handles) == 0 {
+		return nil, nil
+	}
+	sort.Strings(handles)
+
+	// the backend is queried without holding the lock to not block the
+	// decryption of new configurations
+	backend := getBackend()
+	secrets, err := backend.FetchSecrets(handles)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	changedOrigins := common.NewStringSet()
+
+	secretLock.Lock()
+	now := time.Now()
+	for _, handle := range handles {
+		value, err := secretValue(handle, secrets, backend)
+		if err != nil {
+			log.Warnf("Could not refresh secret '%s', keeping its previous value: %s", handle, err)
+			continue
+		}
+		if secretCache[handle] == value {
+			continue
+		}
+
+		secretCache[handle] = value
+		origins := secretOrigin[handle].GetAll()
+		sort.Strings(origins)
+		log.Infof("Secret '%s' has a new value, reloading where it is used: %s", handle, strings.Join(origins, ", "))
+
+		secretRotations = append(secretRotations, SecretRotation{Handle: handle, Origins: origins, Time: now})
+		if len(secretRotations) > maxSecretRotations {
+			secretRotations = secretRotations[len(secretRotations)-maxSecretRotations:]
+		}
+		for _, origin := range origins {
+			changedOrigins.Add(origin)
+		}
+		changed = append(changed, handle)
+	}
+	callbacks := append([]RefreshCallback{}, refreshCallbacks...)
+	secretLock.Unlock()
+
+	if len(changed) == 0 {
+		return changed, nil
+	}
+
+	// callbacks usually decrypt configurations again so they're called
+	// without holding the lock
+	origins := changedOrigins.GetAll()
+	sort.Strings(origins)
+	for _, callback := range callbacks {
+		callback(origins)
+	}
+	return changed, nil
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/common"
+)
+
+func setupRefreshTest(t *testing.T) func() {
+	err := Init("", nil, 5, SecretBackendOutputMaxSize, false, BackendTypeEnv, map[string]interface{}{"prefix": "DD_TEST_REFRESH_"})
+	require.NoError(t, err)
+	return func() {
+		secretBackend = nil
+		secretBackendType = ""
+		secretCache = map[string]string{}
+		secretOrigin = map[string]common.StringSet{}
+		refreshCallbacks = nil
+		secretRotations = nil
+		os.Unsetenv("DD_TEST_REFRESH_pass1")
+		os.Unsetenv("DD_TEST_REFRESH_pass2")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	defer setupRefreshTest(t)()
+	os.Setenv("DD_TEST_REFRESH_pass1", "password1")
+	os.Setenv("DD_TEST_REFRESH_pass2", "password2")
+
+	_, err := Decrypt([]byte("password: ENC[pass1]\n"), "conf1")
+	require.NoError(t, err)
+	_, err = Decrypt([]byte("password: ENC[pass2]\n"), "conf2")
+	require.NoError(t, err)
+
+	var notified [][]string
+	RegisterRefreshCallback(func(origins []string) {
+		// configurations can be decrypted again from callbacks
+		newConf, err := Decrypt([]byte("password: ENC[pass1]\n"), "conf1")
+		require.NoError(t, err)
+		assert.Equal(t, "password: rotated1\n", string(newConf))
+		notified = append(notified, origins)
+	})
+
+	// nothing changed
+	changed, err := refresh()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.Empty(t, notified)
+
+	os.Setenv("DD_TEST_REFRESH_pass1", "rotated1")
+	changed, err = refresh()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pass1"}, changed)
+	assert.Equal(t, [][]string{{"conf1"}}, notified)
+	assert.Equal(t, "rotated1", secretCache["pass1"])
+	assert.Equal(t, "password2", secretCache["pass2"])
+
+	info, err := GetDebugInfo()
+	require.NoError(t, err)
+	require.Len(t, info.Rotations, 1)
+	assert.Equal(t, "pass1", info.Rotations[0].Handle)
+	assert.Equal(t, []string{"conf1"}, info.Rotations[0].Origins)
+}
+
+func TestRefreshKeepsPreviousValueOnError(t *testing.T) {
+	defer setupRefreshTest(t)()
+	os.Setenv("DD_TEST_REFRESH_pass1", "password1")
+
+	_, err := Decrypt([]byte("password: ENC[pass1]\n"), "conf1")
+	require.NoError(t, err)
+
+	os.Unsetenv("DD_TEST_REFRESH_pass1")
+	changed, err := refresh()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.Equal(t, "password1", secretCache["pass1"])
+}
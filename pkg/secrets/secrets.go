@@ -10,6 +10,7 @@ package secrets
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -21,12 +22,19 @@ var (
 	secretCache map[string]string
 	// list of handles and where they were found
 	secretOrigin map[string]common.StringSet
+	// secretLock protects secretCache and secretOrigin from concurrent
+	// refreshes
+	secretLock sync.Mutex
 
 	secretBackendCommand               string
 	secretBackendArguments             []string
 	secretBackendTimeout               = 5
 	secretBackendCommandAllowGroupExec bool
 
+	// built-in backend used instead of the command when secret_backend_type is set
+	secretBackendType string
+	secretBackend     SecretBackend
+
 	// SecretBackendOutputMaxSize defines max size of the JSON output from a secrets reader backend
 	SecretBackendOutputMaxSize = 1024 * 1024
 )
@@ -39,7 +47,7 @@ func init() {
 // Init initializes the command and other options of the secrets package. Since
 // this package is used by the 'config' package to decrypt itself we can't
 // directly use it.
-func Init(command string, arguments []string, timeout int, maxSize int, groupExecPerm bool) {
+func Init(command string, arguments []string, timeout int, maxSize int, groupExecPerm bool, backendType string, backendConfig map[string]interface{}) error {
 	secretBackendCommand = command
 	secretBackendArguments = arguments
 	secretBackendTimeout = timeout
@@ -48,6 +56,24 @@ func Init(command string, arguments []string, timeout int, maxSize int, groupExe
 	if secretBackendCommandAllowGroupExec {
 		log.Warnf("Agent configuration relax permissions constraint on the secret backend cmd, Group can read and exec")
 	}
+
+	backend, err := newSecretBackend(backendType, backendConfig)
+	if err != nil {
+		secretBackendType = ""
+		secretBackend = nil
+		return fmt.Errorf("could not initialize the '%s' secret backend: %s", backendType, err)
+	}
+	secretBackendType = backendType
+	secretBackend = backend
+	if secretBackend != nil && secretBackendCommand != "" {
+		log.Warnf("secret_backend_command is ignored since secret_backend_type is set to '%s'", backendType)
+	}
+	return nil
+}
+
+// isEnabled returns true if either the command or a built-in backend is configured
+func isEnabled() bool {
+	return secretBackendCommand != "" || secretBackend != nil
 }
 
 type walkerCallback func(string) (string, error)
@@ -149,13 +175,16 @@ func isEnc(str string) (bool, string) {
 // testing purpose
 var secretFetcher = fetchSecret
 
-// Decrypt replaces all encrypted secrets in data by executing
-// "secret_backend_command" once if all secrets aren't present in the cache.
+// Decrypt replaces all encrypted secrets in data by querying the secret
+// backend once if all secrets aren't present in the cache.
 func Decrypt(data []byte, origin string) ([]byte, error) {
-	if data == nil || secretBackendCommand == "" {
+	if data == nil || !isEnabled() {
 		return data, nil
 	}
 
+	secretLock.Lock()
+	defer secretLock.Unlock()
+
 	var config interface{}
 	err := yaml.Unmarshal(data, &config)
 	if err != nil {
@@ -199,7 +228,7 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 		err = walk(&config, func(str string) (string, error) {
 			if ok, handle := isEnc(str); ok {
 				if secret, ok := secrets[handle]; ok {
-					log.Debugf("Secret '%s' was retrieved from executable", handle)
+					log.Debugf("Secret '%s' was retrieved from the backend", handle)
 					return secret, nil
 				}
 				// This should never happen since fetchSecret will return an error
@@ -222,15 +251,23 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 
 // GetDebugInfo exposes debug informations about secrets to be included in a flare
 func GetDebugInfo() (*SecretInfo, error) {
-	if secretBackendCommand == "" {
+	if !isEnabled() {
 		return nil, fmt.Errorf("No secret_backend_command set: secrets feature is not enabled")
 	}
-	info := &SecretInfo{ExecutablePath: secretBackendCommand}
-	info.populateRights()
+	info := &SecretInfo{BackendName: getBackend().Name()}
+	if secretBackend == nil {
+		info.ExecutablePath = secretBackendCommand
+		info.populateRights()
+	}
+
+	secretLock.Lock()
+	defer secretLock.Unlock()
 
 	info.SecretsHandles = map[string][]string{}
 	for handle, originNames := range secretOrigin {
 		info.SecretsHandles[handle] = originNames.GetAll()
 	}
+	info.RefreshInterval = secretRefreshInterval
+	info.Rotations = append([]SecretRotation{}, secretRotations...)
 	return info, nil
 }
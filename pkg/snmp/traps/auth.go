@@ -13,10 +13,17 @@ import (
 )
 
 func validateCredentials(p *gosnmp.SnmpPacket, c *Config) error {
-	if p.Version != gosnmp.Version2c {
+	switch p.Version {
+	case gosnmp.Version1, gosnmp.Version2c:
+		return validateCommunity(p, c)
+	case gosnmp.Version3:
+		return validateUser(p, c)
+	default:
 		return fmt.Errorf("Unsupported version: %s", p.Version)
 	}
+}
 
+func validateCommunity(p *gosnmp.SnmpPacket, c *Config) error {
 	// At least one of the known community strings must match.
 	for _, community := range c.CommunityStrings {
 		if community == p.Community {
@@ -26,3 +33,31 @@ func validateCredentials(p *gosnmp.SnmpPacket, c *Config) error {
 
 	return errors.New("Unknown community string")
 }
+
+func validateUser(p *gosnmp.SnmpPacket, c *Config) error {
+	// Authentication and decryption are done by GoSNMP when decoding the packet, we only have
+	// to check that it was sent by a known user with the expected security level and engine ID.
+	params, ok := p.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if p.SecurityModel != gosnmp.UserSecurityModel || !ok {
+		return errors.New("Unsupported security model")
+	}
+
+	for _, user := range c.Users {
+		if user.Username != params.UserName {
+			continue
+		}
+		if p.MsgFlags&gosnmp.AuthPriv != user.msgFlags() {
+			return fmt.Errorf("Unexpected security level for user %s", params.UserName)
+		}
+		engineID, err := user.engineID()
+		if err != nil {
+			return err
+		}
+		if engineID != "" && params.AuthoritativeEngineID != engineID {
+			return fmt.Errorf("Unexpected authoritative engine ID for user %s", params.UserName)
+		}
+		return nil
+	}
+
+	return errors.New("Unknown user")
+}
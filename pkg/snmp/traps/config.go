@@ -6,8 +6,11 @@
 package traps
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
+
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/soniah/gosnmp"
 )
@@ -17,11 +20,24 @@ func IsEnabled() bool {
 	return config.Datadog.GetBool("snmp_traps_enabled")
 }
 
+// UserV3 contains the definition of an SNMPv3 user of the user-based security model (USM).
+// YAML field tags provided for test marshalling purposes.
+type UserV3 struct {
+	Username     string `mapstructure:"user" yaml:"user"`
+	AuthKey      string `mapstructure:"auth_key" yaml:"auth_key"`
+	AuthProtocol string `mapstructure:"auth_protocol" yaml:"auth_protocol"`
+	PrivKey      string `mapstructure:"priv_key" yaml:"priv_key"`
+	PrivProtocol string `mapstructure:"priv_protocol" yaml:"priv_protocol"`
+	// EngineID is the hex-encoded authoritative engine ID of the devices sending traps as this user.
+	EngineID string `mapstructure:"engine_id" yaml:"engine_id"`
+}
+
 // Config contains configuration for SNMP trap listeners.
 // YAML field tags provided for test marshalling purposes.
 type Config struct {
 	Port             uint16   `mapstructure:"port" yaml:"port"`
 	CommunityStrings []string `mapstructure:"community_strings" yaml:"community_strings"`
+	Users            []UserV3 `mapstructure:"users" yaml:"users"`
 	BindHost         string   `mapstructure:"bind_host" yaml:"bind_host"`
 	StopTimeout      int      `mapstructure:"stop_timeout" yaml:"stop_timeout"`
 }
@@ -35,8 +51,19 @@ func ReadConfig() (*Config, error) {
 	}
 
 	// Validate required fields.
-	if c.CommunityStrings == nil || len(c.CommunityStrings) == 0 {
-		return nil, errors.New("`community_strings` is required and must be non-empty")
+	if len(c.CommunityStrings) == 0 && len(c.Users) == 0 {
+		return nil, errors.New("`community_strings` or `users` is required and must be non-empty")
+	}
+	// The SNMPv3 packets are decoded with the security parameters of their user, selected by name.
+	usernames := make(map[string]bool, len(c.Users))
+	for _, user := range c.Users {
+		if _, err := user.buildSecurityParameters(); err != nil {
+			return nil, fmt.Errorf("invalid SNMPv3 user %q: %s", user.Username, err)
+		}
+		if usernames[user.Username] {
+			return nil, fmt.Errorf("duplicate SNMPv3 user %q in `users`", user.Username)
+		}
+		usernames[user.Username] = true
 	}
 
 	// Set defaults.
@@ -68,3 +95,139 @@ func (c *Config) BuildV2Params() *gosnmp.GoSNMP {
 		Logger:    &trapLogger{},
 	}
 }
+
+// BuildListenerParams returns the GoSNMP params structures used by the trap listener:
+// SNMPv1 and SNMPv2c packets are decoded with the returned SNMPv2 params, and SNMPv3
+// packets with the params of the configured user they were sent by, by user name.
+func (c *Config) BuildListenerParams() (*gosnmp.GoSNMP, map[string]*gosnmp.GoSNMP, error) {
+	v3Params := make(map[string]*gosnmp.GoSNMP, len(c.Users))
+	for _, user := range c.Users {
+		params, err := c.BuildV3Params(user)
+		if err != nil {
+			return nil, nil, err
+		}
+		v3Params[user.Username] = params
+	}
+	return c.BuildV2Params(), v3Params, nil
+}
+
+// BuildV3Params returns a valid GoSNMP SNMPv3 params structure for a user.
+func (c *Config) BuildV3Params(user UserV3) (*gosnmp.GoSNMP, error) {
+	securityParameters, err := user.buildSecurityParameters()
+	if err != nil {
+		return nil, err
+	}
+	return &gosnmp.GoSNMP{
+		Port:               c.Port,
+		Transport:          "udp",
+		Version:            gosnmp.Version3,
+		Logger:             &trapLogger{},
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           user.msgFlags(),
+		SecurityParameters: securityParameters,
+	}, nil
+}
+
+func (u UserV3) buildSecurityParameters() (*gosnmp.UsmSecurityParameters, error) {
+	if u.Username == "" {
+		return nil, errors.New("`user` is required")
+	}
+	authProtocol, err := parseAuthProtocol(u.AuthProtocol)
+	if err != nil {
+		return nil, err
+	}
+	privProtocol, err := parsePrivProtocol(u.PrivProtocol)
+	if err != nil {
+		return nil, err
+	}
+	if u.AuthKey == "" && u.PrivKey != "" {
+		return nil, errors.New("`priv_key` requires `auth_key` to be set")
+	}
+	engineID, err := u.engineID()
+	if err != nil {
+		return nil, err
+	}
+
+	if u.AuthKey == "" {
+		authProtocol = gosnmp.NoAuth
+	} else if authProtocol == gosnmp.NoAuth {
+		authProtocol = gosnmp.MD5
+	}
+	if u.PrivKey == "" {
+		privProtocol = gosnmp.NoPriv
+	} else if privProtocol == gosnmp.NoPriv {
+		privProtocol = gosnmp.DES
+	}
+
+	return &gosnmp.UsmSecurityParameters{
+		UserName:                 u.Username,
+		AuthoritativeEngineID:    engineID,
+		AuthenticationProtocol:   authProtocol,
+		AuthenticationPassphrase: u.AuthKey,
+		PrivacyProtocol:          privProtocol,
+		PrivacyPassphrase:        u.PrivKey,
+		Logger:                   &trapLogger{},
+	}, nil
+}
+
+// engineID returns the decoded authoritative engine ID of the user, empty if not set.
+func (u UserV3) engineID() (string, error) {
+	engineID, err := hex.DecodeString(strings.TrimPrefix(u.EngineID, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("`engine_id` must be hex-encoded: %s", err)
+	}
+	return string(engineID), nil
+}
+
+// msgFlags returns the security level of the user.
+func (u UserV3) msgFlags() gosnmp.SnmpV3MsgFlags {
+	if u.AuthKey == "" {
+		return gosnmp.NoAuthNoPriv
+	}
+	if u.PrivKey == "" {
+		return gosnmp.AuthNoPriv
+	}
+	return gosnmp.AuthPriv
+}
+
+func parseAuthProtocol(protocol string) (gosnmp.SnmpV3AuthProtocol, error) {
+	switch strings.ToLower(protocol) {
+	case "":
+		return gosnmp.NoAuth, nil
+	case "md5":
+		return gosnmp.MD5, nil
+	case "sha":
+		return gosnmp.SHA, nil
+	case "sha224":
+		return gosnmp.SHA224, nil
+	case "sha256":
+		return gosnmp.SHA256, nil
+	case "sha384":
+		return gosnmp.SHA384, nil
+	case "sha512":
+		return gosnmp.SHA512, nil
+	default:
+		return gosnmp.NoAuth, fmt.Errorf("unsupported `auth_protocol` %q, expected one of md5, sha, sha224, sha256, sha384 or sha512", protocol)
+	}
+}
+
+func parsePrivProtocol(protocol string) (gosnmp.SnmpV3PrivProtocol, error) {
+	switch strings.ToLower(protocol) {
+	case "":
+		return gosnmp.NoPriv, nil
+	case "des":
+		return gosnmp.DES, nil
+	case "aes":
+		return gosnmp.AES, nil
+	case "aes192":
+		return gosnmp.AES192, nil
+	case "aes256":
+		return gosnmp.AES256, nil
+	case "aes192c":
+		return gosnmp.AES192C, nil
+	case "aes256c":
+		return gosnmp.AES256C, nil
+	default:
+		return gosnmp.NoPriv, fmt.Errorf("unsupported `priv_protocol` %q, expected one of des, aes, aes192, aes256, aes192c or aes256c", protocol)
+	}
+}
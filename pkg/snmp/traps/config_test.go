@@ -6,9 +6,11 @@
 package traps
 
 import (
+	"testing"
+
 	"github.com/soniah/gosnmp"
 	"github.com/stretchr/testify/assert"
-	"testing"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig(t *testing.T) {
@@ -71,3 +73,59 @@ func TestStopTimeout(t *testing.T) {
 
 	assert.Equal(t, 11, config.StopTimeout)
 }
+
+func TestUsersWithoutCommunityStrings(t *testing.T) {
+	Configure(t, Config{
+		Port: 1234,
+		Users: []UserV3{{
+			Username:     "datadog",
+			AuthKey:      "authPassphrase",
+			AuthProtocol: "SHA256",
+			PrivKey:      "privPassphrase",
+			PrivProtocol: "AES",
+			EngineID:     "0x8000000001020304",
+		}},
+	})
+	config, err := ReadConfig()
+	require.NoError(t, err)
+
+	_, v3Params, err := config.BuildListenerParams()
+	require.NoError(t, err)
+	require.Len(t, v3Params, 1)
+	params := v3Params["datadog"]
+	require.NotNil(t, params)
+	assert.Equal(t, uint16(1234), params.Port)
+	assert.Equal(t, gosnmp.Version3, params.Version)
+	assert.Equal(t, gosnmp.UserSecurityModel, params.SecurityModel)
+	assert.Equal(t, gosnmp.AuthPriv, params.MsgFlags)
+
+	securityParams, ok := params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	require.True(t, ok)
+	assert.Equal(t, "datadog", securityParams.UserName)
+	assert.Equal(t, gosnmp.SHA256, securityParams.AuthenticationProtocol)
+	assert.Equal(t, gosnmp.AES, securityParams.PrivacyProtocol)
+	assert.Equal(t, "\x80\x00\x00\x00\x01\x02\x03\x04", securityParams.AuthoritativeEngineID)
+}
+
+func TestUserSecurityLevel(t *testing.T) {
+	assert.Equal(t, gosnmp.NoAuthNoPriv, UserV3{Username: "datadog"}.msgFlags())
+	assert.Equal(t, gosnmp.AuthNoPriv, UserV3{Username: "datadog", AuthKey: "auth"}.msgFlags())
+	assert.Equal(t, gosnmp.AuthPriv, UserV3{Username: "datadog", AuthKey: "auth", PrivKey: "priv"}.msgFlags())
+}
+
+func TestInvalidUsers(t *testing.T) {
+	for name, users := range map[string][]UserV3{
+		"missing user name":     {{AuthKey: "auth"}},
+		"unknown auth protocol": {{Username: "datadog", AuthKey: "auth", AuthProtocol: "sha1024"}},
+		"unknown priv protocol": {{Username: "datadog", AuthKey: "auth", PrivKey: "priv", PrivProtocol: "rot13"}},
+		"privacy without auth":  {{Username: "datadog", PrivKey: "priv"}},
+		"invalid engine ID":     {{Username: "datadog", EngineID: "not-hex"}},
+		"duplicate user":        {{Username: "datadog"}, {Username: "datadog", AuthKey: "auth"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			Configure(t, Config{CommunityStrings: []string{"public"}, Users: users})
+			_, err := ReadConfig()
+			assert.Error(t, err)
+		})
+	}
+}
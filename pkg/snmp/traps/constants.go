@@ -9,4 +9,6 @@ const (
 	defaultPort        = uint16(162) // Standard UDP port for traps.
 	defaultStopTimeout = 5
 	packetsChanSize    = 100
+	// trapsDBDirectory is the directory, relative to the conf.d directory, holding the OID database files.
+	trapsDBDirectory = "snmp.d/traps_db"
 )
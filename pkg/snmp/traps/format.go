@@ -15,11 +15,32 @@ import (
 const (
 	sysUpTimeInstanceOID = "1.3.6.1.2.1.1.3.0"
 	snmpTrapOID          = "1.3.6.1.6.3.1.1.4.1.0"
+	// genericTrapOID is the prefix of the SNMPv2 OIDs of the generic SNMPv1 traps (coldStart, warmStart...).
+	// See: https://tools.ietf.org/html/rfc3584#section-3.1
+	genericTrapOID = "1.3.6.1.6.3.1.1.5"
+	// enterpriseSpecificGenericTrap is the generic trap ID of SNMPv1 vendor-specific traps.
+	enterpriseSpecificGenericTrap = 6
 )
 
 // FormatPacketToJSON converts an SNMP trap packet to a JSON-serializable object.
-func FormatPacketToJSON(packet *SnmpPacket) (map[string]interface{}, error) {
-	return formatTrapPDUs(packet.Content.Variables)
+// OIDs known by the resolver are completed with their symbolic names, unknown OIDs are left as is.
+func FormatPacketToJSON(packet *SnmpPacket, resolver OIDResolver) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	if packet.Content.Version == gosnmp.Version1 {
+		data = formatV1Trap(packet.Content, resolver)
+	} else {
+		var err error
+		data, err = formatTrapPDUs(packet.Content.Variables, resolver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if trap, ok := resolver.GetTrapMetadata(data["oid"].(string)); ok {
+		data["name"] = trap.Name
+		data["mib"] = trap.MIB
+	}
+	return data, nil
 }
 
 // GetTags returns a list of tags associated to an SNMP trap packet.
@@ -32,14 +53,18 @@ func GetTags(packet *SnmpPacket) []string {
 
 func formatVersion(packet *SnmpPacket) string {
 	switch packet.Content.Version {
+	case gosnmp.Version1:
+		return "1"
 	case gosnmp.Version2c:
 		return "2"
+	case gosnmp.Version3:
+		return "3"
 	default:
 		return "unknown"
 	}
 }
 
-func formatTrapPDUs(variables []gosnmp.SnmpPDU) (map[string]interface{}, error) {
+func formatTrapPDUs(variables []gosnmp.SnmpPDU, resolver OIDResolver) (map[string]interface{}, error) {
 	/*
 		An SNMPv2 trap packet consists in the following variables (PDUs):
 		{sysUpTime.0, snmpTrapOID.0, additionalDataVariables...}
@@ -63,11 +88,36 @@ func formatTrapPDUs(variables []gosnmp.SnmpPDU) (map[string]interface{}, error)
 	}
 	data["oid"] = trapOID
 
-	data["variables"] = parseVariables(variables[2:])
+	data["variables"] = parseVariables(variables[2:], resolver)
 
 	return data, nil
 }
 
+func formatV1Trap(packet *gosnmp.SnmpPacket, resolver OIDResolver) map[string]interface{} {
+	/*
+		An SNMPv1 trap PDU holds the trap identification in dedicated fields
+		instead of variables. The trap OID is translated to its SNMPv2 equivalent
+		so that SNMPv1 traps have the same shape as SNMPv2 traps.
+		See: https://tools.ietf.org/html/rfc3584#section-3.1
+	*/
+	data := make(map[string]interface{})
+	data["uptime"] = uint32(packet.Timestamp)
+
+	enterpriseOID := normalizeOID(packet.Enterprise)
+	if packet.GenericTrap == enterpriseSpecificGenericTrap {
+		data["oid"] = fmt.Sprintf("%s.0.%d", enterpriseOID, packet.SpecificTrap)
+	} else {
+		data["oid"] = fmt.Sprintf("%s.%d", genericTrapOID, packet.GenericTrap+1)
+	}
+	data["enterprise_oid"] = enterpriseOID
+	data["generic_trap"] = packet.GenericTrap
+	data["specific_trap"] = packet.SpecificTrap
+
+	data["variables"] = parseVariables(packet.Variables, resolver)
+
+	return data
+}
+
 func normalizeOID(value string) string {
 	// OIDs can be formatted as ".1.2.3..." ("absolute form") or "1.2.3..." ("relative form").
 	// Convert everything to relative form, like we do in the Python check.
@@ -107,7 +157,7 @@ func parseSnmpTrapOID(variable gosnmp.SnmpPDU) (string, error) {
 	return normalizeOID(value), nil
 }
 
-func parseVariables(variables []gosnmp.SnmpPDU) []map[string]interface{} {
+func parseVariables(variables []gosnmp.SnmpPDU, resolver OIDResolver) []map[string]interface{} {
 	var parsedVariables []map[string]interface{}
 
 	for _, variable := range variables {
@@ -115,12 +165,36 @@ func parseVariables(variables []gosnmp.SnmpPDU) []map[string]interface{} {
 		parsedVariable["oid"] = normalizeOID(variable.Name)
 		parsedVariable["type"] = formatType(variable)
 		parsedVariable["value"] = formatValue(variable)
+
+		if metadata, suffix, ok := resolver.GetVariableMetadata(variable.Name); ok {
+			parsedVariable["name"] = metadata.Name + suffix
+			if label, ok := enumLabel(variable, metadata); ok {
+				parsedVariable["value"] = label
+			}
+		}
 		parsedVariables = append(parsedVariables, parsedVariable)
 	}
 
 	return parsedVariables
 }
 
+// enumLabel returns the label of the value of an enumeration variable,
+// formatted as "label(value)" like NetSNMP does.
+func enumLabel(variable gosnmp.SnmpPDU, metadata VariableMetadata) (string, bool) {
+	if len(metadata.Enum) == 0 || variable.Type != gosnmp.Integer {
+		return "", false
+	}
+	value, ok := variable.Value.(int)
+	if !ok {
+		return "", false
+	}
+	label, ok := metadata.Enum[value]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%s(%d)", label, value), true
+}
+
 func formatType(variable gosnmp.SnmpPDU) string {
 	switch variable.Type {
 	case gosnmp.Integer, gosnmp.Uinteger32:
@@ -15,11 +15,25 @@ import (
 )
 
 func createTestPacket() *SnmpPacket {
+	variables := make([]gosnmp.SnmpPDU, len(NetSNMPExampleHeartbeatNotificationVariables))
+	copy(variables, NetSNMPExampleHeartbeatNotificationVariables)
 	return &SnmpPacket{
 		Content: &gosnmp.SnmpPacket{
 			Version:   gosnmp.Version2c,
 			Community: "public",
-			Variables: NetSNMPExampleHeartbeatNotificationVariables,
+			Variables: variables,
+		},
+		Addr: &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 13156},
+	}
+}
+
+func createTestV1Packet(trap gosnmp.SnmpTrap) *SnmpPacket {
+	return &SnmpPacket{
+		Content: &gosnmp.SnmpPacket{
+			Version:   gosnmp.Version1,
+			Community: "public",
+			Variables: trap.Variables,
+			SnmpTrap:  trap,
 		},
 		Addr: &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 13156},
 	}
@@ -28,7 +42,7 @@ func createTestPacket() *SnmpPacket {
 func TestFormatPacketToJSON(t *testing.T) {
 	packet := createTestPacket()
 
-	data, err := FormatPacketToJSON(packet)
+	data, err := FormatPacketToJSON(packet, NoopOIDResolver{})
 	require.NoError(t, err)
 
 	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", data["oid"])
@@ -49,13 +63,48 @@ func TestFormatPacketToJSON(t *testing.T) {
 	assert.Equal(t, heartBeatName["value"], "test")
 }
 
+func TestFormatV1PacketToJSON(t *testing.T) {
+	packet := createTestV1Packet(NetSNMPExampleHeartbeatNotificationV1Trap)
+
+	data, err := FormatPacketToJSON(packet, NoopOIDResolver{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", data["oid"])
+	assert.Equal(t, "1.3.6.1.4.1.8072.2.3", data["enterprise_oid"])
+	assert.Equal(t, 6, data["generic_trap"])
+	assert.Equal(t, 1, data["specific_trap"])
+	assert.Equal(t, uint32(1000), data["uptime"])
+
+	variables, ok := data["variables"].([]map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, len(variables), 2)
+
+	heartBeatRate := variables[0]
+	assert.Equal(t, heartBeatRate["oid"], "1.3.6.1.4.1.8072.2.3.2.1")
+	assert.Equal(t, heartBeatRate["type"], "integer")
+	assert.Equal(t, heartBeatRate["value"], 1024)
+}
+
+func TestFormatV1GenericTrapToJSON(t *testing.T) {
+	trap := NetSNMPExampleHeartbeatNotificationV1Trap
+	trap.GenericTrap = 3 // linkUp
+	trap.SpecificTrap = 0
+	packet := createTestV1Packet(trap)
+
+	data, err := FormatPacketToJSON(packet, NoopOIDResolver{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.3.6.1.6.3.1.1.5.4", data["oid"])
+	assert.Equal(t, 3, data["generic_trap"])
+}
+
 func TestFormatPacketToJSONShouldFailIfNotEnoughVariables(t *testing.T) {
 	packet := createTestPacket()
 
 	packet.Content.Variables = []gosnmp.SnmpPDU{
 		// No variables at all.
 	}
-	_, err := FormatPacketToJSON(packet)
+	_, err := FormatPacketToJSON(packet, NoopOIDResolver{})
 	require.Error(t, err)
 
 	packet.Content.Variables = []gosnmp.SnmpPDU{
@@ -64,7 +113,7 @@ func TestFormatPacketToJSONShouldFailIfNotEnoughVariables(t *testing.T) {
 		{Name: "1.3.6.1.4.1.8072.2.3.2.1", Type: gosnmp.Integer, Value: 1024},
 		{Name: "1.3.6.1.4.1.8072.2.3.2.2", Type: gosnmp.OctetString, Value: "test"},
 	}
-	_, err = FormatPacketToJSON(packet)
+	_, err = FormatPacketToJSON(packet, NoopOIDResolver{})
 	require.Error(t, err)
 
 	packet.Content.Variables = []gosnmp.SnmpPDU{
@@ -73,7 +122,7 @@ func TestFormatPacketToJSONShouldFailIfNotEnoughVariables(t *testing.T) {
 		{Name: "1.3.6.1.4.1.8072.2.3.2.1", Type: gosnmp.Integer, Value: 1024},
 		{Name: "1.3.6.1.4.1.8072.2.3.2.2", Type: gosnmp.OctetString, Value: "test"},
 	}
-	_, err = FormatPacketToJSON(packet)
+	_, err = FormatPacketToJSON(packet, NoopOIDResolver{})
 	require.Error(t, err)
 }
 
@@ -86,13 +135,73 @@ func TestGetTags(t *testing.T) {
 	})
 }
 
-func TestGetTagsForUnsupportedVersionShouldStillSucceed(t *testing.T) {
+func TestGetTagsV3(t *testing.T) {
 	packet := createTestPacket()
 	packet.Content.Version = gosnmp.Version3
 	packet.Content.Community = ""
 	tags := GetTags(packet)
+	assert.Equal(t, tags, []string{
+		"snmp_version:3",
+		"snmp_device:127.0.0.1",
+	})
+}
+
+func TestGetTagsForUnsupportedVersionShouldStillSucceed(t *testing.T) {
+	packet := createTestPacket()
+	packet.Content.Version = gosnmp.SnmpVersion(0xaa)
+	packet.Content.Community = ""
+	tags := GetTags(packet)
 	assert.Equal(t, tags, []string{
 		"snmp_version:unknown",
 		"snmp_device:127.0.0.1",
 	})
 }
+
+func TestFormatPacketToJSONWithResolver(t *testing.T) {
+	resolver := newTestOIDResolver(t)
+	packet := createTestPacket()
+	packet.Content.Variables = append(packet.Content.Variables,
+		// ifAdminStatus.3, an unknown value of an enumeration and an unknown OID
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.7.3", Type: gosnmp.Integer, Value: 2},
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.7.4", Type: gosnmp.Integer, Value: 42},
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.1", Type: gosnmp.Integer, Value: 1},
+	)
+
+	data, err := FormatPacketToJSON(packet, resolver)
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", data["oid"])
+	assert.Equal(t, "netSnmpExampleHeartbeatNotification", data["name"])
+	assert.Equal(t, "NET-SNMP-EXAMPLES-MIB", data["mib"])
+
+	variables, ok := data["variables"].([]map[string]interface{})
+	require.True(t, ok)
+	require.Len(t, variables, 5)
+
+	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.2.1", variables[0]["oid"])
+	assert.Equal(t, "netSnmpExampleHeartbeatRate", variables[0]["name"])
+	assert.Equal(t, 1024, variables[0]["value"])
+
+	assert.Equal(t, "ifAdminStatus.3", variables[2]["name"])
+	assert.Equal(t, "down(2)", variables[2]["value"])
+
+	assert.Equal(t, "ifAdminStatus.4", variables[3]["name"])
+	assert.Equal(t, 42, variables[3]["value"])
+
+	assert.Equal(t, "1.3.6.1.4.1.99999.1", variables[4]["oid"])
+	assert.NotContains(t, variables[4], "name")
+	assert.Equal(t, 1, variables[4]["value"])
+}
+
+func TestFormatUnknownTrapOIDWithResolver(t *testing.T) {
+	resolver := newTestOIDResolver(t)
+	packet := createTestPacket()
+	packet.Content.Variables[1].Value = "1.3.6.1.4.1.99999.0.1"
+
+	data, err := FormatPacketToJSON(packet, resolver)
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.3.6.1.4.1.99999.0.1", data["oid"])
+	assert.NotContains(t, data, "name")
+	assert.NotContains(t, data, "mib")
+}
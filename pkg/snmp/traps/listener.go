@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020 Datadog, Inc.
+
+package traps
+
+import (
+	"net"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/soniah/gosnmp"
+)
+
+// BER types read from the header of SNMPv3 packets.
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berSequence    = 0x30
+)
+
+// trapListener receives trap packets on a UDP socket. Unlike the GoSNMP trap listener, which
+// decodes all the packets with a single set of security parameters, it decodes the SNMPv3
+// packets with the security parameters of the user they were sent by.
+type trapListener struct {
+	config   *Config
+	conn     *net.UDPConn
+	v2Params *gosnmp.GoSNMP
+	v3Params map[string]*gosnmp.GoSNMP
+	packets  PacketsChannel
+	closing  int32
+	stopped  chan struct{}
+}
+
+func startSNMPTrapListener(c *Config, packets PacketsChannel) (*trapListener, error) {
+	v2Params, v3Params, err := c.BuildListenerParams()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", c.Addr())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	listener := &trapListener{
+		config:   c,
+		conn:     conn,
+		v2Params: v2Params,
+		v3Params: v3Params,
+		packets:  packets,
+		stopped:  make(chan struct{}),
+	}
+	log.Infof("Start listening for traps on %s", c.Addr())
+	go listener.listen()
+
+	return listener, nil
+}
+
+func (l *trapListener) listen() {
+	defer close(l.stopped)
+	for {
+		// The decoded packets may reference the buffer, which can't be reused.
+		buf := make([]byte, 4096)
+		n, addr, err := l.conn.ReadFromUDP(buf)
+		if err != nil {
+			if atomic.LoadInt32(&l.closing) == 1 {
+				return
+			}
+			log.Warnf("Error reading a packet on listener %s: %s", l.config.Addr(), err)
+			continue
+		}
+		l.handle(buf[:n], addr)
+	}
+}
+
+func (l *trapListener) handle(msg []byte, u *net.UDPAddr) {
+	params := l.v2Params
+	if username, isV3 := parseV3Username(msg); isV3 {
+		var ok bool
+		if params, ok = l.v3Params[username]; !ok {
+			log.Warnf("Unknown user %q from %s on listener %s, dropping packet", username, u.String(), l.config.Addr())
+			trapsPacketsAuthErrors.Add(1)
+			return
+		}
+	}
+
+	// GoSNMP authenticates and decrypts the SNMPv3 packets when decoding them.
+	p := params.UnmarshalTrap(msg)
+	if p == nil {
+		log.Warnf("Unable to decode packet from %s on listener %s, dropping packet", u.String(), l.config.Addr())
+		trapsPacketsAuthErrors.Add(1)
+		return
+	}
+	if err := validateCredentials(p, l.config); err != nil {
+		log.Warnf("Invalid credentials from %s on listener %s, dropping packet", u.String(), l.config.Addr())
+		trapsPacketsAuthErrors.Add(1)
+		return
+	}
+	log.Debugf("Packet received from %s on listener %s", u.String(), l.config.Addr())
+	trapsPackets.Add(1)
+	l.packets <- &SnmpPacket{Content: p, Addr: u}
+}
+
+// Stop stops listening and waits for the packet being handled, if any.
+func (l *trapListener) Stop() {
+	atomic.StoreInt32(&l.closing, 1)
+	l.conn.Close()
+	<-l.stopped
+}
+
+// parseV3Username reads the user name from the header of an SNMPv3 packet, whose
+// security parameters are not encrypted. isV3 is false for the other versions.
+func parseV3Username(msg []byte) (username string, isV3 bool) {
+	tag, msg, _, ok := readBER(msg)
+	if !ok || tag != berSequence {
+		return "", false
+	}
+	tag, version, msg, ok := readBER(msg)
+	if !ok || tag != berInteger || len(version) != 1 || version[0] != byte(gosnmp.Version3) {
+		return "", false
+	}
+
+	// skip the global data to get the USM security parameters
+	_, _, msg, ok = readBER(msg)
+	if !ok {
+		return "", true
+	}
+	tag, securityParameters, _, ok := readBER(msg)
+	if !ok || tag != berOctetString {
+		return "", true
+	}
+	tag, usm, _, ok := readBER(securityParameters)
+	if !ok || tag != berSequence {
+		return "", true
+	}
+	// the user name follows the engine ID, boots and time
+	var value []byte
+	for i := 0; i < 4; i++ {
+		if tag, value, usm, ok = readBER(usm); !ok {
+			return "", true
+		}
+	}
+	if tag != berOctetString {
+		return "", true
+	}
+	return string(value), true
+}
+
+// readBER reads a BER-encoded element, returning its type, its value and the data following it.
+func readBER(data []byte) (tag byte, value []byte, rest []byte, ok bool) {
+	if len(data) < 2 {
+		return 0, nil, nil, false
+	}
+	tag, length, data := data[0], int(data[1]), data[2:]
+	if length&0x80 != 0 {
+		// long form, the indefinite form is not used by SNMP
+		n := length & 0x7f
+		if n == 0 || n > 3 || len(data) < n {
+			return 0, nil, nil, false
+		}
+		length = 0
+		for _, b := range data[:n] {
+			length = length<<8 | int(b)
+		}
+		data = data[n:]
+	}
+	if length > len(data) {
+		return 0, nil, nil, false
+	}
+	return tag, data[:length], data[length:], true
+}
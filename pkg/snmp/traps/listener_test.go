@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020 Datadog, Inc.
+
+package traps
+
+import (
+	"testing"
+
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseV3Username(t *testing.T) {
+	user := UserV3{Username: "datadog", EngineID: "8000000001020304"}
+	params, err := (&Config{}).BuildV3Params(user)
+	require.NoError(t, err)
+	packet := &gosnmp.SnmpPacket{
+		Version:            gosnmp.Version3,
+		PDUType:            gosnmp.SNMPv2Trap,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           gosnmp.NoAuthNoPriv,
+		SecurityParameters: params.SecurityParameters,
+		Variables:          NetSNMPExampleHeartbeatNotificationVariables,
+	}
+	msg, err := packet.MarshalMsg()
+	require.NoError(t, err)
+
+	username, isV3 := parseV3Username(msg)
+	assert.True(t, isV3)
+	assert.Equal(t, "datadog", username)
+
+	// truncated packets are left to GoSNMP, failing to decode them
+	_, isV3 = parseV3Username(msg[:20])
+	assert.False(t, isV3)
+
+	packet = &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		PDUType:   gosnmp.SNMPv2Trap,
+		Community: "public",
+		Variables: NetSNMPExampleHeartbeatNotificationVariables,
+	}
+	msg, err = packet.MarshalMsg()
+	require.NoError(t, err)
+	_, isV3 = parseV3Username(msg)
+	assert.False(t, isV3)
+}
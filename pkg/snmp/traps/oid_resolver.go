@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020 Datadog, Inc.
+
+package traps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"gopkg.in/yaml.v2"
+)
+
+// TrapMetadata is the symbolic definition of a trap OID.
+type TrapMetadata struct {
+	Name string `json:"name" yaml:"name"`
+	MIB  string `json:"mib" yaml:"mib"`
+}
+
+// VariableMetadata is the symbolic definition of a variable OID, with the
+// labels of its values when the variable is an enumeration.
+type VariableMetadata struct {
+	Name string         `json:"name" yaml:"name"`
+	Enum map[int]string `json:"enum" yaml:"enum"`
+}
+
+// trapDBFile is the content of an OID database file, compiled from MIBs.
+type trapDBFile struct {
+	Traps map[string]TrapMetadata     `json:"traps" yaml:"traps"`
+	Vars  map[string]VariableMetadata `json:"vars" yaml:"vars"`
+}
+
+// OIDResolver resolves numeric OIDs to their symbolic names.
+type OIDResolver interface {
+	GetTrapMetadata(trapOID string) (TrapMetadata, bool)
+	// GetVariableMetadata returns the metadata of the variable, and the
+	// instance suffix of the OID when it is an instance of a known object.
+	GetVariableMetadata(variableOID string) (VariableMetadata, string, bool)
+}
+
+// NoopOIDResolver is an OIDResolver that doesn't know any OID.
+type NoopOIDResolver struct{}
+
+// GetTrapMetadata implements OIDResolver#GetTrapMetadata.
+func (NoopOIDResolver) GetTrapMetadata(trapOID string) (TrapMetadata, bool) {
+	return TrapMetadata{}, false
+}
+
+// GetVariableMetadata implements OIDResolver#GetVariableMetadata.
+func (NoopOIDResolver) GetVariableMetadata(variableOID string) (VariableMetadata, string, bool) {
+	return VariableMetadata{}, "", false
+}
+
+// MultiFilesOIDResolver is an OIDResolver loading its definitions from all the
+// JSON and YAML files of a directory.
+type MultiFilesOIDResolver struct {
+	traps map[string]TrapMetadata
+	vars  map[string]VariableMetadata
+}
+
+// NewMultiFilesOIDResolver loads the OID database files of a directory.
+// A missing directory results in an empty resolver.
+func NewMultiFilesOIDResolver(directory string) (*MultiFilesOIDResolver, error) {
+	resolver := &MultiFilesOIDResolver{
+		traps: make(map[string]TrapMetadata),
+		vars:  make(map[string]VariableMetadata),
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	if os.IsNotExist(err) {
+		log.Debugf("No OID database found in %s, OIDs won't be resolved", directory)
+		return resolver, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the OID database files in %s: %s", directory, err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(directory, file.Name())
+		if err := resolver.loadFile(path); err != nil {
+			log.Warnf("Unable to load the OID database file %s, skipping it: %s", path, err)
+		}
+	}
+	log.Infof("Loaded %d trap and %d variable OID definitions from %s", len(resolver.traps), len(resolver.vars), directory)
+
+	return resolver, nil
+}
+
+func (r *MultiFilesOIDResolver) loadFile(path string) error {
+	var unmarshal func([]byte, interface{}) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		unmarshal = json.Unmarshal
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	default:
+		return fmt.Errorf("unsupported file extension, expected .json, .yaml or .yml")
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var db trapDBFile
+	if err := unmarshal(content, &db); err != nil {
+		return err
+	}
+
+	for oid, trap := range db.Traps {
+		r.traps[normalizeOID(oid)] = trap
+	}
+	for oid, variable := range db.Vars {
+		r.vars[normalizeOID(oid)] = variable
+	}
+	return nil
+}
+
+// GetTrapMetadata implements OIDResolver#GetTrapMetadata.
+func (r *MultiFilesOIDResolver) GetTrapMetadata(trapOID string) (TrapMetadata, bool) {
+	trap, ok := r.traps[normalizeOID(trapOID)]
+	return trap, ok
+}
+
+// GetVariableMetadata implements OIDResolver#GetVariableMetadata.
+// Variables are usually instances of objects (eg: ifIndex.3), so the longest
+// known prefix of the OID is used.
+func (r *MultiFilesOIDResolver) GetVariableMetadata(variableOID string) (VariableMetadata, string, bool) {
+	oid := normalizeOID(variableOID)
+	suffix := ""
+	for {
+		if variable, ok := r.vars[oid]; ok {
+			return variable, suffix, true
+		}
+		index := strings.LastIndex(oid, ".")
+		if index < 0 {
+			return VariableMetadata{}, "", false
+		}
+		suffix = oid[index:] + suffix
+		oid = oid[:index]
+	}
+}
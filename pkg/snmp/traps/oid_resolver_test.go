@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020 Datadog, Inc.
+
+package traps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const netSnmpExamplesDB = `{
+  "traps": {
+    "1.3.6.1.4.1.8072.2.3.0.1": {"name": "netSnmpExampleHeartbeatNotification", "mib": "NET-SNMP-EXAMPLES-MIB"}
+  },
+  "vars": {
+    "1.3.6.1.4.1.8072.2.3.2.1": {"name": "netSnmpExampleHeartbeatRate"},
+    "1.3.6.1.4.1.8072.2.3.2.2": {"name": "netSnmpExampleHeartbeatName"}
+  }
+}`
+
+const ifMibDB = `
+traps:
+  .1.3.6.1.6.3.1.1.5.3:
+    name: linkDown
+    mib: IF-MIB
+vars:
+  .1.3.6.1.2.1.2.2.1.7:
+    name: ifAdminStatus
+    enum:
+      1: up
+      2: down
+      3: testing
+`
+
+func newTestOIDResolver(t *testing.T) *MultiFilesOIDResolver {
+	directory, err := ioutil.TempDir("", "traps_db")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(directory, "net-snmp.json"), []byte(netSnmpExamplesDB), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(directory, "if-mib.yaml"), []byte(ifMibDB), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(directory, "README.md"), []byte("not a database"), 0644))
+
+	resolver, err := NewMultiFilesOIDResolver(directory)
+	require.NoError(t, err)
+	return resolver
+}
+
+func TestOIDResolverTraps(t *testing.T) {
+	resolver := newTestOIDResolver(t)
+
+	trap, ok := resolver.GetTrapMetadata("1.3.6.1.4.1.8072.2.3.0.1")
+	require.True(t, ok)
+	assert.Equal(t, TrapMetadata{Name: "netSnmpExampleHeartbeatNotification", MIB: "NET-SNMP-EXAMPLES-MIB"}, trap)
+
+	trap, ok = resolver.GetTrapMetadata(".1.3.6.1.6.3.1.1.5.3")
+	require.True(t, ok)
+	assert.Equal(t, "linkDown", trap.Name)
+
+	_, ok = resolver.GetTrapMetadata("1.3.6.1.4.1.8072.2.3.0")
+	assert.False(t, ok)
+}
+
+func TestOIDResolverVariables(t *testing.T) {
+	resolver := newTestOIDResolver(t)
+
+	variable, suffix, ok := resolver.GetVariableMetadata("1.3.6.1.4.1.8072.2.3.2.2")
+	require.True(t, ok)
+	assert.Equal(t, "netSnmpExampleHeartbeatName", variable.Name)
+	assert.Equal(t, "", suffix)
+
+	variable, suffix, ok = resolver.GetVariableMetadata(".1.3.6.1.2.1.2.2.1.7.12.1")
+	require.True(t, ok)
+	assert.Equal(t, "ifAdminStatus", variable.Name)
+	assert.Equal(t, ".12.1", suffix)
+	assert.Equal(t, "testing", variable.Enum[3])
+
+	_, _, ok = resolver.GetVariableMetadata("1.3.6.1.2.1.2.2.1.8.1")
+	assert.False(t, ok)
+}
+
+func TestOIDResolverMissingDirectory(t *testing.T) {
+	resolver, err := NewMultiFilesOIDResolver("/does/not/exist")
+	require.NoError(t, err)
+
+	_, ok := resolver.GetTrapMetadata("1.3.6.1.4.1.8072.2.3.0.1")
+	assert.False(t, ok)
+}
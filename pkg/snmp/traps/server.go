@@ -7,8 +7,10 @@ package traps
 
 import (
 	"net"
+	"path/filepath"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/soniah/gosnmp"
 )
@@ -22,12 +24,13 @@ type SnmpPacket struct {
 // PacketsChannel is the type of channels of trap packets.
 type PacketsChannel = chan *SnmpPacket
 
-// TrapServer manages an SNMPv2 trap listener.
+// TrapServer manages an SNMP trap listener.
 type TrapServer struct {
-	Addr     string
-	config   *Config
-	listener *gosnmp.TrapListener
-	packets  PacketsChannel
+	Addr        string
+	config      *Config
+	listener    *trapListener
+	packets     PacketsChannel
+	oidResolver OIDResolver
 }
 
 var (
@@ -62,77 +65,47 @@ func GetPacketsChannel() PacketsChannel {
 	return serverInstance.packets
 }
 
+// GetOIDResolver returns the resolver of the OIDs of received trap packets.
+func GetOIDResolver() OIDResolver {
+	return serverInstance.oidResolver
+}
+
 // NewTrapServer configures and returns a running SNMP traps server.
 func NewTrapServer() (*TrapServer, error) {
-	config, err := ReadConfig()
+	trapConfig, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	oidResolver, err := NewMultiFilesOIDResolver(filepath.Join(config.Datadog.GetString("confd_path"), trapsDBDirectory))
 	if err != nil {
 		return nil, err
 	}
 
 	packets := make(PacketsChannel, packetsChanSize)
 
-	listener, err := startSNMPv2Listener(config, packets)
+	listener, err := startSNMPTrapListener(trapConfig, packets)
 	if err != nil {
 		return nil, err
 	}
 
 	server := &TrapServer{
-		listener: listener,
-		config:   config,
-		packets:  packets,
+		listener:    listener,
+		config:      trapConfig,
+		packets:     packets,
+		oidResolver: oidResolver,
 	}
 
 	return server, nil
 }
 
-func startSNMPv2Listener(c *Config, packets PacketsChannel) (*gosnmp.TrapListener, error) {
-	listener := gosnmp.NewTrapListener()
-	listener.Params = c.BuildV2Params()
-
-	listener.OnNewTrap = func(p *gosnmp.SnmpPacket, u *net.UDPAddr) {
-		if err := validateCredentials(p, c); err != nil {
-			log.Warnf("Invalid credentials from %s on listener %s, dropping packet", u.String(), c.Addr())
-			trapsPacketsAuthErrors.Add(1)
-			return
-		}
-		log.Debugf("Packet received from %s on listener %s", u.String(), c.Addr())
-		trapsPackets.Add(1)
-		packets <- &SnmpPacket{Content: p, Addr: u}
-	}
-
-	errors := make(chan error, 1)
-
-	// Start actually listening in the background.
-	go func() {
-		log.Infof("Start listening for traps on %s", c.Addr())
-		err := listener.Listen(c.Addr())
-		if err != nil {
-			errors <- err
-		}
-	}()
-
-	select {
-	// Wait for listener to be started and listening to traps.
-	// See: https://godoc.org/github.com/soniah/gosnmp#TrapListener.Listening
-	case <-listener.Listening():
-		break
-	// If the listener failed to start (eg because it couldn't bind to a socket),
-	// we'll get an error here.
-	case err := <-errors:
-		close(errors)
-		return nil, err
-	}
-
-	return listener, nil
-}
-
 // Stop stops the TrapServer.
 func (s *TrapServer) Stop() {
 	stopped := make(chan interface{})
 
 	go func() {
 		log.Infof("Stop listening on %s", s.config.Addr())
-		s.listener.Close()
+		s.listener.Stop()
 		close(stopped)
 	}()
 
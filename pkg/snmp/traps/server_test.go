@@ -8,6 +8,7 @@ package traps
 import (
 	"testing"
 
+	"github.com/soniah/gosnmp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,6 +39,101 @@ func TestServerV2BadCredentials(t *testing.T) {
 	assertNoPacketReceived(t)
 }
 
+func TestServerV1(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	sendTestV1Trap(t, config, "public")
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	require.Equal(t, gosnmp.Version1, packet.Content.Version)
+	require.Equal(t, "public", packet.Content.Community)
+	require.Equal(t, 6, packet.Content.GenericTrap)
+	require.Equal(t, 1, packet.Content.SpecificTrap)
+}
+
+func TestServerV3(t *testing.T) {
+	user := UserV3{
+		Username:     "datadog",
+		AuthKey:      "authPassphrase",
+		AuthProtocol: "sha",
+		PrivKey:      "privPassphrase",
+		PrivProtocol: "aes",
+		EngineID:     "8000000001020304",
+	}
+	config := Config{Port: GetPort(t), Users: []UserV3{user}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	sendTestV3Trap(t, config, user)
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	require.Equal(t, gosnmp.Version3, packet.Content.Version)
+	assertV2Variables(t, packet)
+}
+
+func TestServerV3BadCredentials(t *testing.T) {
+	user := UserV3{
+		Username:     "datadog",
+		AuthKey:      "authPassphrase",
+		AuthProtocol: "sha",
+		EngineID:     "8000000001020304",
+	}
+	config := Config{Port: GetPort(t), Users: []UserV3{user}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	user.Username = "unknown"
+	sendTestV3Trap(t, config, user)
+	assertNoPacketReceived(t)
+}
+
+func TestServerV3MultipleUsers(t *testing.T) {
+	users := []UserV3{
+		{Username: "datadog", AuthKey: "authPassphrase", AuthProtocol: "sha", EngineID: "8000000001020304"},
+		{Username: "other", AuthKey: "otherPassphrase", AuthProtocol: "md5", PrivKey: "privPassphrase", PrivProtocol: "des", EngineID: "8000000001020305"},
+	}
+	config := Config{Port: GetPort(t), Users: users}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	// each packet is decoded with the security parameters of its user
+	for _, user := range users {
+		sendTestV3Trap(t, config, user)
+		packet := receivePacket(t)
+		require.NotNil(t, packet)
+		require.Equal(t, gosnmp.Version3, packet.Content.Version)
+		assertV2Variables(t, packet)
+	}
+}
+
+func TestServerV3BadEngineID(t *testing.T) {
+	user := UserV3{Username: "datadog", EngineID: "8000000001020304"}
+	config := Config{Port: GetPort(t), Users: []UserV3{user}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	user.EngineID = "8000000001020305"
+	sendTestV3Trap(t, config, user)
+	assertNoPacketReceived(t)
+}
+
 func TestStartFailure(t *testing.T) {
 	/*
 		Start two servers with the same config to trigger an "address already in use" error.
@@ -32,6 +32,22 @@ var (
 		// heartBeatName
 		{Name: "1.3.6.1.4.1.8072.2.3.2.2", Type: gosnmp.OctetString, Value: "test"},
 	}
+
+	// NetSNMPExampleHeartbeatNotificationV1Trap is the SNMPv1 equivalent of the
+	// NetSNMP::ExampleHeartBeatNotification trap: no sysUpTime and snmpTrapOID variables.
+	NetSNMPExampleHeartbeatNotificationV1Trap = gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{
+			// heartBeatRate
+			{Name: "1.3.6.1.4.1.8072.2.3.2.1", Type: gosnmp.Integer, Value: 1024},
+			// heartBeatName
+			{Name: "1.3.6.1.4.1.8072.2.3.2.2", Type: gosnmp.OctetString, Value: "test"},
+		},
+		Enterprise:   ".1.3.6.1.4.1.8072.2.3",
+		AgentAddress: "127.0.0.1",
+		GenericTrap:  6,
+		SpecificTrap: 1,
+		Timestamp:    1000,
+	}
 )
 
 func parsePort(t *testing.T, addr string) uint16 {
@@ -84,6 +100,40 @@ func sendTestV2Trap(t *testing.T, trapConfig Config, community string) *gosnmp.G
 	return params
 }
 
+func sendTestV1Trap(t *testing.T, trapConfig Config, community string) *gosnmp.GoSNMP {
+	params := trapConfig.BuildV2Params()
+	params.Version = gosnmp.Version1
+	params.Community = community
+	params.Timeout = 1 * time.Second // Must be non-zero when sending traps.
+	params.Retries = 1               // Must be non-zero when sending traps.
+
+	err := params.Connect()
+	require.NoError(t, err)
+	defer params.Conn.Close()
+
+	_, err = params.SendTrap(NetSNMPExampleHeartbeatNotificationV1Trap)
+	require.NoError(t, err)
+
+	return params
+}
+
+func sendTestV3Trap(t *testing.T, trapConfig Config, user UserV3) *gosnmp.GoSNMP {
+	params, err := trapConfig.BuildV3Params(user)
+	require.NoError(t, err)
+	params.Timeout = 1 * time.Second // Must be non-zero when sending traps.
+	params.Retries = 1               // Must be non-zero when sending traps.
+
+	err = params.Connect()
+	require.NoError(t, err)
+	defer params.Conn.Close()
+
+	trap := gosnmp.SnmpTrap{Variables: NetSNMPExampleHeartbeatNotificationVariables}
+	_, err = params.SendTrap(trap)
+	require.NoError(t, err)
+
+	return params
+}
+
 // receivePacket waits for a received trap packet and returns it.
 func receivePacket(t *testing.T) *SnmpPacket {
 	select {
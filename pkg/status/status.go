@@ -329,6 +329,16 @@ func expvarStats(stats map[string]interface{}) (map[string]interface{}, error) {
 	for name, value := range dogstatsdUDPStats {
 		dogstatsdStats["Udp"+name] = value
 	}
+	dogstatsdTCPStats := make(map[string]interface{})
+	json.Unmarshal([]byte(expvar.Get("dogstatsd-tcp").String()), &dogstatsdTCPStats) //nolint:errcheck
+	for name, value := range dogstatsdTCPStats {
+		dogstatsdStats["Tcp"+name] = value
+	}
+	dogstatsdUdsStreamStats := make(map[string]interface{})
+	json.Unmarshal([]byte(expvar.Get("dogstatsd-uds-stream").String()), &dogstatsdUdsStreamStats) //nolint:errcheck
+	for name, value := range dogstatsdUdsStreamStats {
+		dogstatsdStats["UdsStream"+name] = value
+	}
 	stats["dogstatsdStats"] = dogstatsdStats
 
 	pyLoaderData := expvar.Get("pyLoader")
@@ -0,0 +1,222 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package collectors
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/DataDog/datadog-agent/cmd/agent/api/pb"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/errors"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	remoteCollectorName = "remote"
+	// remoteRetryDelay is the delay between two connections to the core agent
+	remoteRetryDelay = 5 * time.Second
+)
+
+// RemoteCatalog only holds the collector mirroring the tagger of the core
+// agent. It is used instead of DefaultCatalog by the agents running next to it.
+var RemoteCatalog = Catalog{remoteCollectorName: remoteFactory}
+
+// RemoteCollector streams the entities of the tagger of the core agent through
+// its gRPC API. It resubscribes when the connection is lost, and deletes the
+// entities that disappeared in the meantime.
+type RemoteCollector struct {
+	conn          *grpc.ClientConn
+	client        pb.AgentSecureClient
+	infoOut       chan<- []*TagInfo
+	stop          chan bool
+	ctx           context.Context
+	cancel        context.CancelFunc
+	knownEntities map[string]struct{}
+}
+
+// Detect prepares the connection to the core agent, the stream itself is
+// started and retried by Stream
+func (c *RemoteCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error) {
+	address, err := config.GetIPCAddress()
+	if err != nil {
+		return NoCollection, err
+	}
+
+	// the IPC API uses a self-signed certificate, clients are authenticated
+	// with the auth token
+	creds := credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	conn, err := grpc.Dial(fmt.Sprintf("%v:%v", address, config.Datadog.GetInt("cmd_port")), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return NoCollection, err
+	}
+
+	c.conn = conn
+	c.client = pb.NewAgentSecureClient(conn)
+	c.infoOut = out
+	c.stop = make(chan bool)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.knownEntities = make(map[string]struct{})
+
+	return StreamCollection, nil
+}
+
+// Stream streams the entities of the core agent until Stop is called.
+// Must be called in a goroutine.
+func (c *RemoteCollector) Stream() error {
+	healthHandle := health.RegisterLiveness("tagger-remote")
+
+	go c.run()
+
+	for {
+		select {
+		case <-c.stop:
+			c.cancel()
+			healthHandle.Deregister() //nolint:errcheck
+			return c.conn.Close()
+		case <-healthHandle.C:
+		}
+	}
+}
+
+// Stop queues a shutdown of RemoteCollector
+func (c *RemoteCollector) Stop() error {
+	c.stop <- true
+	return nil
+}
+
+// Fetch never finds anything, every entity known by the core agent is
+// already streamed
+func (c *RemoteCollector) Fetch(entity string) ([]string, []string, []string, error) {
+	return nil, nil, nil, errors.NewNotFound(entity)
+}
+
+// run (re)subscribes to the entities of the core agent until the collector
+// is stopped
+func (c *RemoteCollector) run() {
+	for {
+		err := c.streamEntities()
+		if c.ctx.Err() != nil {
+			return
+		}
+		log.Warnf("Error streaming the tags of the core agent, retrying in %s: %s", remoteRetryDelay, err)
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(remoteRetryDelay):
+		}
+	}
+}
+
+func (c *RemoteCollector) streamEntities() error {
+	if err := util.SetAuthToken(); err != nil {
+		return fmt.Errorf("unable to read the auth token: %s", err)
+	}
+	ctx := metadata.NewOutgoingContext(c.ctx, metadata.MD{
+		"authorization": []string{fmt.Sprintf("Bearer %s", util.GetAuthToken())},
+	})
+
+	stream, err := c.client.TaggerStreamEntities(ctx, &pb.StreamTagsRequest{
+		Cardinality: pb.TagCardinality_HIGH,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the first response of a stream holds every entity of the core agent
+	resync := true
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		infos := c.processResponse(response, resync)
+		if resync {
+			log.Infof("Streaming the tags of %d entities from the core agent", len(c.knownEntities))
+			resync = false
+		}
+		if len(infos) == 0 {
+			continue
+		}
+
+		select {
+		case c.infoOut <- infos:
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+	}
+}
+
+// processResponse converts the events of a response to TagInfo. When resync
+// is set, the response holds every entity of the core agent and the entities
+// missing from it are deleted.
+func (c *RemoteCollector) processResponse(response *pb.StreamTagsResponse, resync bool) []*TagInfo {
+	var infos []*TagInfo
+	streamed := make(map[string]struct{}, len(response.GetEvents()))
+
+	for _, event := range response.GetEvents() {
+		entity := event.GetEntity()
+		if entity.GetId() == "" {
+			continue
+		}
+
+		if event.GetType() == pb.EventType_DELETED {
+			delete(c.knownEntities, entity.GetId())
+			infos = append(infos, &TagInfo{
+				Source:       remoteCollectorName,
+				Entity:       entity.GetId(),
+				DeleteEntity: true,
+			})
+			continue
+		}
+
+		c.knownEntities[entity.GetId()] = struct{}{}
+		streamed[entity.GetId()] = struct{}{}
+		infos = append(infos, &TagInfo{
+			Source:               remoteCollectorName,
+			Entity:               entity.GetId(),
+			LowCardTags:          entity.GetLowCardinalityTags(),
+			OrchestratorCardTags: entity.GetOrchestratorCardinalityTags(),
+			HighCardTags:         entity.GetHighCardinalityTags(),
+			StandardTags:         entity.GetStandardTags(),
+		})
+	}
+
+	if resync {
+		for entityID := range c.knownEntities {
+			if _, found := streamed[entityID]; found {
+				continue
+			}
+			delete(c.knownEntities, entityID)
+			infos = append(infos, &TagInfo{
+				Source:       remoteCollectorName,
+				Entity:       entityID,
+				DeleteEntity: true,
+			})
+		}
+	}
+
+	return infos
+}
+
+func remoteFactory() Collector {
+	return &RemoteCollector{}
+}
+
+func init() {
+	// not registered in the default catalog, only its priority is needed
+	CollectorPriorities[remoteCollectorName] = NodeRuntime
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package collectors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/DataDog/datadog-agent/cmd/agent/api/pb"
+)
+
+func TestRemoteProcessResponse(t *testing.T) {
+	c := &RemoteCollector{knownEntities: make(map[string]struct{})}
+
+	// initial state of the core agent
+	infos := c.processResponse(&pb.StreamTagsResponse{
+		Events: []*pb.StreamTagsEvent{
+			{
+				Type: pb.EventType_ADDED,
+				Entity: &pb.Entity{
+					Id:                          "container_id://foo",
+					LowCardinalityTags:          []string{"image_name:foo"},
+					OrchestratorCardinalityTags: []string{"pod_name:foo-1"},
+					HighCardinalityTags:         []string{"container_id:foo"},
+					StandardTags:                []string{"service:foo"},
+				},
+			},
+			{
+				Type:   pb.EventType_ADDED,
+				Entity: &pb.Entity{Id: "container_id://bar"},
+			},
+		},
+	}, true)
+	assert.Equal(t, []*TagInfo{
+		{
+			Source:               remoteCollectorName,
+			Entity:               "container_id://foo",
+			LowCardTags:          []string{"image_name:foo"},
+			OrchestratorCardTags: []string{"pod_name:foo-1"},
+			HighCardTags:         []string{"container_id:foo"},
+			StandardTags:         []string{"service:foo"},
+		},
+		{
+			Source: remoteCollectorName,
+			Entity: "container_id://bar",
+		},
+	}, infos)
+
+	infos = c.processResponse(&pb.StreamTagsResponse{
+		Events: []*pb.StreamTagsEvent{
+			{
+				Type:   pb.EventType_DELETED,
+				Entity: &pb.Entity{Id: "container_id://bar"},
+			},
+		},
+	}, false)
+	assert.Equal(t, []*TagInfo{
+		{Source: remoteCollectorName, Entity: "container_id://bar", DeleteEntity: true},
+	}, infos)
+	assert.Len(t, c.knownEntities, 1)
+
+	// after a reconnection, entities deleted in the meantime are removed
+	infos = c.processResponse(&pb.StreamTagsResponse{
+		Events: []*pb.StreamTagsEvent{
+			{
+				Type:   pb.EventType_ADDED,
+				Entity: &pb.Entity{Id: "container_id://baz"},
+			},
+		},
+	}, true)
+	assert.Equal(t, []*TagInfo{
+		{Source: remoteCollectorName, Entity: "container_id://baz"},
+		{Source: remoteCollectorName, Entity: "container_id://foo", DeleteEntity: true},
+	}, infos)
+	assert.Equal(t, map[string]struct{}{"container_id://baz": {}}, c.knownEntities)
+}
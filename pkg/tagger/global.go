@@ -28,6 +28,17 @@ var DogstatsdCardinality collectors.TagCardinality
 
 // Init must be called once config is available, call it in your cmd
 func Init() {
+	initWithCatalog(collectors.DefaultCatalog)
+}
+
+// InitRemote is an alternative to Init for the agents running next to the
+// core agent: instead of running the collectors, the tagger mirrors the one
+// of the core agent by streaming its entities through the IPC API.
+func InitRemote() {
+	initWithCatalog(collectors.RemoteCatalog)
+}
+
+func initWithCatalog(catalog collectors.Catalog) {
 	initOnce.Do(func() {
 		var err error
 		checkCard := config.Datadog.GetString("checks_tag_cardinality")
@@ -44,7 +55,7 @@ func Init() {
 			DogstatsdCardinality = collectors.LowCardinality
 		}
 
-		defaultTagger.Init(collectors.DefaultCatalog)
+		defaultTagger.Init(catalog)
 	})
 }
 
@@ -81,6 +92,17 @@ func GetEntityHash(entity string) string {
 	return defaultTagger.GetEntityHash(entity)
 }
 
+// Subscribe returns a channel receiving the events of the entities of the
+// defaultTagger, starting with every known entity
+func Subscribe(cardinality collectors.TagCardinality) chan []EntityEvent {
+	return defaultTagger.Subscribe(cardinality)
+}
+
+// Unsubscribe ends a subscription to the defaultTagger
+func Unsubscribe(ch chan []EntityEvent) {
+	defaultTagger.Unsubscribe(ch)
+}
+
 func init() {
 	defaultTagger = newTagger()
 }
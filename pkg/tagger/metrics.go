@@ -19,4 +19,16 @@ var (
 	queries = telemetry.NewCounterWithOpts("tagger", "queries",
 		[]string{"cardinality"}, "Queries made against the tagger.",
 		telemetry.Options{NoDoubleUnderscoreSep: true})
+
+	subscribers = telemetry.NewGaugeWithOpts("tagger", "subscribers",
+		[]string{}, "Number of channels subscribing to tagger events.",
+		telemetry.Options{NoDoubleUnderscoreSep: true})
+
+	sentEvents = telemetry.NewCounterWithOpts("tagger", "sent_events",
+		[]string{"cardinality"}, "Number of events sent to tagger subscribers.",
+		telemetry.Options{NoDoubleUnderscoreSep: true})
+
+	droppedSubscribers = telemetry.NewCounterWithOpts("tagger", "dropped_subscribers",
+		[]string{}, "Number of subscribers unsubscribed for lagging behind.",
+		telemetry.Options{NoDoubleUnderscoreSep: true})
 )
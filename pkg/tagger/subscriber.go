@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package tagger
+
+import (
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// subscriberBufferSize is the number of batches of events a subscriber can
+// lag behind before being unsubscribed
+const subscriberBufferSize = 100
+
+// EventType is the kind of change made to an entity of the tagger
+type EventType int
+
+// List of possible event types
+const (
+	EventTypeAdded EventType = iota
+	EventTypeModified
+	EventTypeDeleted
+)
+
+// Entity is an entity of the tagger with its tags split by cardinality
+type Entity struct {
+	ID                          string
+	Hash                        string
+	LowCardinalityTags          []string
+	OrchestratorCardinalityTags []string
+	HighCardinalityTags         []string
+	StandardTags                []string
+}
+
+// EntityEvent is sent to subscribers when an entity is added, modified or
+// deleted. The entity of a deletion only holds its ID.
+type EntityEvent struct {
+	EventType EventType
+	Entity    Entity
+}
+
+// forCardinality returns a copy of the entity without the tags above the
+// given cardinality
+func (e Entity) forCardinality(cardinality collectors.TagCardinality) Entity {
+	switch cardinality {
+	case collectors.LowCardinality:
+		e.OrchestratorCardinalityTags = nil
+		e.HighCardinalityTags = nil
+	case collectors.OrchestratorCardinality:
+		e.HighCardinalityTags = nil
+	}
+	return e
+}
+
+// subscriber dispatches the events of the store to the channels of its
+// subscribers, each one with their own cardinality.
+type subscriber struct {
+	sync.RWMutex
+	subscribers map[chan []EntityEvent]collectors.TagCardinality
+}
+
+func newSubscriber() *subscriber {
+	return &subscriber{
+		subscribers: make(map[chan []EntityEvent]collectors.TagCardinality),
+	}
+}
+
+// subscribe returns a new channel receiving the events of the store. The
+// given events are sent to this subscriber only, as its first batch.
+func (s *subscriber) subscribe(cardinality collectors.TagCardinality, events []EntityEvent) chan []EntityEvent {
+	ch := make(chan []EntityEvent, subscriberBufferSize)
+	ch <- filterEvents(events, cardinality)
+
+	s.Lock()
+	s.subscribers[ch] = cardinality
+	subscribers.Set(float64(len(s.subscribers)))
+	s.Unlock()
+
+	sentEvents.Add(float64(len(events)), tagCardinalityToString(cardinality))
+	return ch
+}
+
+// unsubscribe closes the channel of a subscriber, if it is still subscribed
+func (s *subscriber) unsubscribe(ch chan []EntityEvent) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, found := s.subscribers[ch]; !found {
+		return
+	}
+	delete(s.subscribers, ch)
+	close(ch)
+	subscribers.Set(float64(len(s.subscribers)))
+}
+
+// hasSubscribers returns whether events need to be computed at all
+func (s *subscriber) hasSubscribers() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.subscribers) > 0
+}
+
+// notify sends the events to every subscriber without blocking. Subscribers
+// lagging too far behind are unsubscribed, they have to subscribe again to
+// get a fresh state of the store.
+func (s *subscriber) notify(events []EntityEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for ch, cardinality := range s.subscribers {
+		select {
+		case ch <- filterEvents(events, cardinality):
+			sentEvents.Add(float64(len(events)), tagCardinalityToString(cardinality))
+		default:
+			log.Warnf("Tagger subscriber is lagging behind, unsubscribing it")
+			delete(s.subscribers, ch)
+			close(ch)
+			droppedSubscribers.Inc()
+		}
+	}
+	subscribers.Set(float64(len(s.subscribers)))
+}
+
+func filterEvents(events []EntityEvent, cardinality collectors.TagCardinality) []EntityEvent {
+	filtered := make([]EntityEvent, 0, len(events))
+	for _, event := range events {
+		filtered = append(filtered, EntityEvent{
+			EventType: event.EventType,
+			Entity:    event.Entity.forCardinality(cardinality),
+		})
+	}
+	return filtered
+}
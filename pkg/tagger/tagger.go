@@ -320,6 +320,19 @@ func (t *Tagger) List(cardinality collectors.TagCardinality) response.TaggerList
 	return r
 }
 
+// Subscribe returns a channel receiving batches of events whenever entities
+// are added, modified or deleted, with their tags up to the given cardinality.
+// The first batch holds an EventTypeAdded event for every known entity. The
+// channel is closed if the subscriber lags too far behind.
+func (t *Tagger) Subscribe(cardinality collectors.TagCardinality) chan []EntityEvent {
+	return t.tagStore.subscribe(cardinality)
+}
+
+// Unsubscribe ends a subscription and closes its channel
+func (t *Tagger) Unsubscribe(ch chan []EntityEvent) {
+	t.tagStore.unsubscribe(ch)
+}
+
 // copyArray makes sure the tagger does not return internal slices
 // that could be modified by others, by explicitly copying the slice
 // contents to a new slice. As strings are references, the size of
@@ -3,6 +3,7 @@ package tagger
 import (
 	"fmt"
 	"hash/fnv"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -35,12 +36,14 @@ type tagStore struct {
 	store         map[string]*entityTags
 	toDeleteMutex sync.RWMutex
 	toDelete      map[string]struct{} // set emulation
+	subscriber    *subscriber
 }
 
 func newTagStore() *tagStore {
 	return &tagStore{
-		store:    make(map[string]*entityTags),
-		toDelete: make(map[string]struct{}),
+		store:      make(map[string]*entityTags),
+		toDelete:   make(map[string]struct{}),
+		subscriber: newSubscriber(),
 	}
 }
 
@@ -61,10 +64,17 @@ func (s *tagStore) processTagInfo(info *collectors.TagInfo) error {
 		return nil
 	}
 
-	// TODO: check if real change
 	s.storeMutex.Lock()
 	defer s.storeMutex.Unlock()
+
+	// events are only computed when someone listens to them
+	notify := s.subscriber.hasSubscribers()
+	var previous Entity
+
 	storedTags, exist := s.store[info.Entity]
+	if exist && notify {
+		previous = storedTags.toEntity(info.Entity)
+	}
 	if !exist {
 		storedTags = &entityTags{
 			lowCardTags:          make(map[string][]string),
@@ -80,9 +90,9 @@ func (s *tagStore) processTagInfo(info *collectors.TagInfo) error {
 	updatedEntities.Inc()
 
 	storedTags.Lock()
-	defer storedTags.Unlock()
 	_, found := storedTags.lowCardTags[info.Source]
 	if found && info.CacheMiss {
+		storedTags.Unlock()
 		// check if the source tags is already present for this entry
 		// Only check once since we always write all cardinality tag levels.
 		err := fmt.Errorf("try to overwrite an existing entry with and empty cache-miss entry, info.Source: %s, info.Entity: %s", info.Source, info.Entity)
@@ -94,10 +104,46 @@ func (s *tagStore) processTagInfo(info *collectors.TagInfo) error {
 	storedTags.highCardTags[info.Source] = info.HighCardTags
 	storedTags.standardTags[info.Source] = info.StandardTags
 	storedTags.cacheValid = false
+	storedTags.Unlock()
+
+	if !notify {
+		return nil
+	}
+	current := storedTags.toEntity(info.Entity)
+	switch {
+	case !exist:
+		s.subscriber.notify([]EntityEvent{{EventType: EventTypeAdded, Entity: current}})
+	case !reflect.DeepEqual(previous, current):
+		s.subscriber.notify([]EntityEvent{{EventType: EventTypeModified, Entity: current}})
+	}
 
 	return nil
 }
 
+// subscribe returns a channel receiving the events of the store, starting
+// with an EventTypeAdded event for every entity already stored.
+func (s *tagStore) subscribe(cardinality collectors.TagCardinality) chan []EntityEvent {
+	// holding the store lock guarantees that no event is missed or sent
+	// twice between the initial state and the subscription
+	s.storeMutex.RLock()
+	defer s.storeMutex.RUnlock()
+
+	events := make([]EntityEvent, 0, len(s.store))
+	for entityID, storedTags := range s.store {
+		events = append(events, EntityEvent{
+			EventType: EventTypeAdded,
+			Entity:    storedTags.toEntity(entityID),
+		})
+	}
+
+	return s.subscriber.subscribe(cardinality, events)
+}
+
+// unsubscribe stops sending events to a channel returned by subscribe
+func (s *tagStore) unsubscribe(ch chan []EntityEvent) {
+	s.subscriber.unsubscribe(ch)
+}
+
 func computeTagsHash(tags []string) string {
 	hash := ""
 	if len(tags) > 0 {
@@ -125,9 +171,18 @@ func (s *tagStore) prune() error {
 
 	s.storeMutex.Lock()
 	defer s.storeMutex.Unlock()
+	events := make([]EntityEvent, 0, len(s.toDelete))
 	for entity := range s.toDelete {
+		if _, found := s.store[entity]; !found {
+			continue
+		}
 		delete(s.store, entity)
+		events = append(events, EntityEvent{
+			EventType: EventTypeDeleted,
+			Entity:    Entity{ID: entity},
+		})
 	}
+	s.subscriber.notify(events)
 
 	remainingEntities := len(s.store)
 	log.Debugf("pruned %d removed entities, %d remaining", len(s.toDelete), remainingEntities)
@@ -186,17 +241,48 @@ func (e *entityTags) get(cardinality collectors.TagCardinality) ([]string, []str
 	e.Lock()
 	defer e.Unlock()
 
-	// Cache hit
-	if e.cacheValid {
-		if cardinality == collectors.HighCardinality {
-			return e.cachedAll, e.cachedSource, e.tagsHash
-		} else if cardinality == collectors.OrchestratorCardinality {
-			return e.cachedOrchestrator, e.cachedSource, e.tagsHash
-		}
-		return e.cachedLow, e.cachedSource, e.tagsHash
+	if !e.cacheValid {
+		e.computeCache()
 	}
 
-	// Cache miss
+	if cardinality == collectors.HighCardinality {
+		return e.cachedAll, e.cachedSource, e.tagsHash
+	} else if cardinality == collectors.OrchestratorCardinality {
+		return e.cachedOrchestrator, e.cachedSource, e.tagsHash
+	}
+	return e.cachedLow, e.cachedSource, e.tagsHash
+}
+
+// toEntity returns a sorted copy of the tags of the entity, split by
+// cardinality, to be sent to subscribers.
+func (e *entityTags) toEntity(id string) Entity {
+	e.Lock()
+	defer e.Unlock()
+
+	if !e.cacheValid {
+		e.computeCache()
+	}
+
+	lowEnd := len(e.cachedLow)
+	orchestratorEnd := len(e.cachedOrchestrator)
+	var standard []string
+	for _, tags := range e.standardTags {
+		standard = append(standard, tags...)
+	}
+
+	return Entity{
+		ID:                          id,
+		Hash:                        e.tagsHash,
+		LowCardinalityTags:          sortedCopy(e.cachedAll[:lowEnd]),
+		OrchestratorCardinalityTags: sortedCopy(e.cachedAll[lowEnd:orchestratorEnd]),
+		HighCardinalityTags:         sortedCopy(e.cachedAll[orchestratorEnd:]),
+		StandardTags:                sortedCopy(standard),
+	}
+}
+
+// computeCache merges the tags of every source, resolving duplicates with
+// the collector priorities. It must be called with the lock held.
+func (e *entityTags) computeCache() {
 	var sources []string
 	tagPrioMapper := make(map[string][]tagPriority)
 
@@ -250,13 +336,6 @@ func (e *entityTags) get(cardinality collectors.TagCardinality) ([]string, []str
 	e.cachedLow = e.cachedAll[:len(lowCardTags)]
 	e.cachedOrchestrator = e.cachedAll[:len(lowCardTags)+len(orchestratorCardTags)]
 	e.tagsHash = computeTagsHash(e.cachedAll)
-
-	if cardinality == collectors.HighCardinality {
-		return tags, sources, e.tagsHash
-	} else if cardinality == collectors.OrchestratorCardinality {
-		return e.cachedOrchestrator, sources, e.tagsHash
-	}
-	return lowCardTags, sources, e.tagsHash
 }
 
 func insertWithPriority(tagPrioMapper map[string][]tagPriority, tags []string, source string, cardinality collectors.TagCardinality) {
@@ -275,3 +354,12 @@ func insertWithPriority(tagPrioMapper map[string][]tagPriority, tags []string, s
 		})
 	}
 }
+
+func sortedCopy(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	copied := copyArray(tags)
+	sort.Strings(copied)
+	return copied
+}
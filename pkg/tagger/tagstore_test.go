@@ -6,6 +6,7 @@
 package tagger
 
 import (
+	"fmt"
 	"math/rand"
 	"testing"
 
@@ -207,6 +208,108 @@ func (s *StoreTestSuite) TestPrune() {
 
 }
 
+func (s *StoreTestSuite) TestSubscribe() {
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:               "source1",
+		Entity:               "test1",
+		LowCardTags:          []string{"low"},
+		OrchestratorCardTags: []string{"orchestrator"},
+		HighCardTags:         []string{"high"},
+		StandardTags:         []string{"env:prod"},
+	})
+
+	highCh := s.store.subscribe(collectors.HighCardinality)
+	lowCh := s.store.subscribe(collectors.LowCardinality)
+
+	// existing entities are sent first
+	events := <-highCh
+	assert.Len(s.T(), events, 1)
+	assert.Equal(s.T(), EventTypeAdded, events[0].EventType)
+	assert.Equal(s.T(), "test1", events[0].Entity.ID)
+	assert.Equal(s.T(), []string{"low"}, events[0].Entity.LowCardinalityTags)
+	assert.Equal(s.T(), []string{"orchestrator"}, events[0].Entity.OrchestratorCardinalityTags)
+	assert.Equal(s.T(), []string{"high"}, events[0].Entity.HighCardinalityTags)
+	assert.Equal(s.T(), []string{"env:prod"}, events[0].Entity.StandardTags)
+	assert.NotEmpty(s.T(), events[0].Entity.Hash)
+
+	events = <-lowCh
+	assert.Len(s.T(), events, 1)
+	assert.Equal(s.T(), []string{"low"}, events[0].Entity.LowCardinalityTags)
+	assert.Nil(s.T(), events[0].Entity.OrchestratorCardinalityTags)
+	assert.Nil(s.T(), events[0].Entity.HighCardinalityTags)
+
+	// new entity
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:      "source1",
+		Entity:      "test2",
+		LowCardTags: []string{"low"},
+	})
+	events = <-highCh
+	assert.Len(s.T(), events, 1)
+	assert.Equal(s.T(), EventTypeAdded, events[0].EventType)
+	assert.Equal(s.T(), "test2", events[0].Entity.ID)
+
+	// modified entity
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:      "source2",
+		Entity:      "test1",
+		LowCardTags: []string{"other"},
+	})
+	events = <-highCh
+	assert.Len(s.T(), events, 1)
+	assert.Equal(s.T(), EventTypeModified, events[0].EventType)
+	assert.Equal(s.T(), []string{"low", "other"}, events[0].Entity.LowCardinalityTags)
+
+	// updates not changing the tags are not sent
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:      "source2",
+		Entity:      "test1",
+		LowCardTags: []string{"other"},
+	})
+	assert.Len(s.T(), highCh, 0)
+
+	// deleted entity, once pruned
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:       "source1",
+		Entity:       "test2",
+		DeleteEntity: true,
+	})
+	assert.Len(s.T(), highCh, 0)
+	s.store.prune()
+	events = <-highCh
+	assert.Equal(s.T(), []EntityEvent{{EventType: EventTypeDeleted, Entity: Entity{ID: "test2"}}}, events)
+
+	s.store.unsubscribe(highCh)
+	_, ok := <-highCh
+	assert.False(s.T(), ok)
+
+	// unsubscribing twice is a noop
+	s.store.unsubscribe(highCh)
+	s.store.unsubscribe(lowCh)
+}
+
+func (s *StoreTestSuite) TestSubscriberLaggingBehind() {
+	ch := s.store.subscribe(collectors.LowCardinality)
+	// initial empty state
+	assert.Empty(s.T(), <-ch)
+
+	for i := 0; i <= subscriberBufferSize; i++ {
+		s.store.processTagInfo(&collectors.TagInfo{
+			Source:      "source1",
+			Entity:      fmt.Sprintf("test%d", i),
+			LowCardTags: []string{"low"},
+		})
+	}
+
+	// the buffer is drained, then the channel is closed
+	received := 0
+	for range ch {
+		received++
+	}
+	assert.Equal(s.T(), subscriberBufferSize, received)
+	assert.False(s.T(), s.store.subscriber.hasSubscribers())
+}
+
 func TestStoreSuite(t *testing.T) {
 	suite.Run(t, &StoreTestSuite{})
 }
@@ -8,6 +8,7 @@ package agent
 import (
 	"context"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -42,6 +43,7 @@ type Agent struct {
 	ErrorsScoreSampler *Sampler
 	ExceptionSampler   *sampler.ExceptionSampler
 	PrioritySampler    *Sampler
+	TailSampler        *sampler.TailSampler // nil if tail sampling is disabled
 	EventProcessor     *event.Processor
 	TraceWriter        *writer.TraceWriter
 	StatsWriter        *writer.StatsWriter
@@ -53,6 +55,14 @@ type Agent struct {
 	In  chan *api.Payload
 	Out chan *writer.SampledSpans
 
+	// tailOut receives the chunks of the traces kept by the TailSampler.
+	tailOut chan []pb.Trace
+	// tailDone is closed once all the chunks received on tailOut are sent to the writer.
+	tailDone chan struct{}
+
+	// workers is used to wait for the traces being processed on exit.
+	workers sync.WaitGroup
+
 	// config
 	conf *config.AgentConfig
 
@@ -67,6 +77,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 	in := make(chan *api.Payload, 1000)
 	out := make(chan *writer.SampledSpans, 1000)
 	statsChan := make(chan []stats.Bucket)
+	tailOut := make(chan []pb.Trace, 100)
 
 	return &Agent{
 		Receiver:           api.NewHTTPReceiver(conf, dynConf, in),
@@ -77,12 +88,15 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		ExceptionSampler:   sampler.NewExceptionSampler(),
 		ErrorsScoreSampler: NewErrorsSampler(conf),
 		PrioritySampler:    NewPrioritySampler(conf, dynConf),
+		TailSampler:        newTailSampler(conf, tailOut),
 		EventProcessor:     newEventProcessor(conf),
 		TraceWriter:        writer.NewTraceWriter(conf, out),
 		StatsWriter:        writer.NewStatsWriter(conf, statsChan),
 		obfuscator:         obfuscate.NewObfuscator(conf.Obfuscation),
 		In:                 in,
 		Out:                out,
+		tailOut:            tailOut,
+		tailDone:           make(chan struct{}),
 		conf:               conf,
 		ctx:                ctx,
 	}
@@ -100,11 +114,16 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+		go a.writeTailSampled()
+	}
 
 	go a.TraceWriter.Run()
 	go a.StatsWriter.Run()
 
 	for i := 0; i < runtime.NumCPU(); i++ {
+		a.workers.Add(1)
 		go a.work()
 	}
 
@@ -112,6 +131,7 @@ func (a *Agent) Run() {
 }
 
 func (a *Agent) work() {
+	defer a.workers.Done()
 	sublayerCalculator := stats.NewSublayerCalculator()
 	for {
 		select {
@@ -133,7 +153,15 @@ func (a *Agent) loop() {
 			if err := a.Receiver.Stop(); err != nil {
 				log.Error(err)
 			}
+			// the receiver closed the input, wait for the traces being processed
+			a.workers.Wait()
 			a.Concentrator.Stop()
+			if a.TailSampler != nil {
+				// flush the buffered traces before stopping the writer
+				a.TailSampler.Stop()
+				close(a.tailOut)
+				<-a.tailDone
+			}
 			a.TraceWriter.Stop()
 			a.StatsWriter.Stop()
 			a.ScoreSampler.Stop()
@@ -223,11 +251,18 @@ func (a *Agent) Process(p *api.Payload, sublayerCalculator *stats.SublayerCalcul
 
 		events, keep := a.sample(ts, pt)
 
+		// With tail sampling, the chunk is buffered until its trace is assembled, unless
+		// it was dropped or kept by the user, so it may be kept later on.
+		tail := a.TailSampler != nil && !keep
+		if priority, _ := sampler.GetSamplingPriority(root); priority < 0 {
+			tail = false
+		}
+
 		subtraces := stats.ExtractSubtraces(t, root)
 		for _, subtrace := range subtraces {
 			subtraceSublayers := sublayerCalculator.ComputeSublayers(subtrace.Trace)
 			pt.Sublayers[subtrace.Root] = subtraceSublayers
-			if keep || len(events) > 0 {
+			if keep || tail || len(events) > 0 {
 				stats.SetSublayersOnSpan(subtrace.Root, subtraceSublayers)
 			}
 		}
@@ -237,6 +272,11 @@ func (a *Agent) Process(p *api.Payload, sublayerCalculator *stats.SublayerCalcul
 			Env:       pt.Env,
 		})
 
+		if tail {
+			// the sublayers must be set before the chunk is handed to the tail sampler
+			keep, _ = a.TailSampler.Add(t)
+		}
+
 		if keep {
 			ss.Traces = append(ss.Traces, traceutil.APITrace(t))
 			ss.Size += t.Msgsize()
@@ -259,8 +299,29 @@ func (a *Agent) Process(p *api.Payload, sublayerCalculator *stats.SublayerCalcul
 	}
 }
 
+// writeTailSampled sends the chunks of the traces kept by the TailSampler to the writer.
+func (a *Agent) writeTailSampled() {
+	defer close(a.tailDone)
+	for chunks := range a.tailOut {
+		ss := new(writer.SampledSpans)
+		for _, t := range chunks {
+			ss.Traces = append(ss.Traces, traceutil.APITrace(t))
+			ss.Size += t.Msgsize()
+			ss.SpanCount += int64(len(t))
+			if ss.Size > writer.MaxPayloadSize {
+				a.Out <- ss
+				ss = new(writer.SampledSpans)
+			}
+		}
+		if ss.Size > 0 {
+			a.Out <- ss
+		}
+	}
+}
+
 // sample decides whether the trace will be kept and extracts any APM events
-// from it.
+// from it. With tail sampling, the decision is left to the TailSampler, except
+// for the traces kept by the user.
 func (a *Agent) sample(ts *info.TagStats, pt ProcessedTrace) (events []*pb.Span, keep bool) {
 	priority, hasPriority := sampler.GetSamplingPriority(pt.Root)
 
@@ -283,7 +344,17 @@ func (a *Agent) sample(ts *info.TagStats, pt ProcessedTrace) (events []*pb.Span,
 		return nil, false
 	}
 
-	sampled, rate := a.runSamplers(pt, hasPriority)
+	var (
+		sampled bool
+		rate    float64
+	)
+	if a.TailSampler == nil {
+		sampled, rate = a.runSamplers(pt, hasPriority)
+	} else if hasPriority {
+		// the priority sampler still computes the rates returned to the tracers
+		_, rate = a.PrioritySampler.Add(pt)
+		sampled = priority >= sampler.PriorityUserKeep
+	}
 	if sampled {
 		sampler.AddGlobalRate(pt.Root, rate)
 	}
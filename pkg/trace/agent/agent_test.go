@@ -525,6 +525,40 @@ func TestSampling(t *testing.T) {
 	}
 }
 
+func TestSampleTail(t *testing.T) {
+	for name, tt := range map[string]struct {
+		priority    sampler.SamplingPriority
+		hasPriority bool
+		wantSampled bool
+		wantAdded   uint64
+	}{
+		"no-priority":   {},
+		"auto-keep":     {priority: 1, hasPriority: true, wantAdded: 1},
+		"user-keep":     {priority: 2, hasPriority: true, wantSampled: true, wantAdded: 1},
+		"user-drop":     {priority: -1, hasPriority: true},
+		"auto-rejected": {priority: 0, hasPriority: true, wantAdded: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := &Agent{
+				PrioritySampler: newMockSampler(true, 0.5),
+				TailSampler:     sampler.NewTailSampler(sampler.TailSamplerConfig{}, nil),
+				EventProcessor:  newEventProcessor(config.New()),
+			}
+			root := &pb.Span{Service: "serv1", Metrics: map[string]float64{}}
+			if tt.hasPriority {
+				sampler.SetSamplingPriority(root, tt.priority)
+			}
+			pt := ProcessedTrace{Trace: pb.Trace{root}, Root: root}
+
+			_, sampled := a.sample(&info.TagStats{}, pt)
+			// the priority rates keep being computed, but the decision is left to the
+			// tail sampler unless the trace is kept by the user
+			assert.Equal(t, tt.wantSampled, sampled)
+			assert.Equal(t, tt.wantAdded, a.PrioritySampler.totalTraceCount)
+		})
+	}
+}
+
 func TestEventProcessorFromConf(t *testing.T) {
 	if _, ok := os.LookupEnv("INTEGRATION"); !ok {
 		t.Skip("set INTEGRATION environment variable to run")
@@ -124,7 +124,11 @@ func Run(ctx context.Context) {
 
 	rand.Seed(time.Now().UTC().UnixNano())
 
-	tagger.Init()
+	if coreconfig.Datadog.GetBool("apm_config.remote_tagger") {
+		tagger.InitRemote()
+	} else {
+		tagger.Init()
+	}
 	defer tagger.Stop()
 
 	agnt := NewAgent(ctx, cfg)
@@ -13,6 +13,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -58,6 +59,32 @@ func NewPrioritySampler(conf *config.AgentConfig, dynConf *sampler.DynamicConfig
 	}
 }
 
+// newTailSampler returns a tail sampler applying the tail sampling configuration, sending the
+// chunks of the traces it keeps to out, or nil if tail sampling is disabled.
+func newTailSampler(conf *config.AgentConfig, out chan<- []pb.Trace) *sampler.TailSampler {
+	ts := conf.TailSampling
+	if ts == nil || !ts.Enabled {
+		return nil
+	}
+	policies := make([]*sampler.TailPolicy, 0, len(ts.Policies))
+	for _, p := range ts.Policies {
+		policies = append(policies, &sampler.TailPolicy{
+			Name:    p.Name,
+			Type:    sampler.TailPolicyType(p.Type),
+			Rate:    *p.Rate,
+			Latency: time.Duration(p.LatencyThreshold) * time.Millisecond,
+			Key:     p.Key,
+			Values:  p.Values,
+		})
+	}
+	return sampler.NewTailSampler(sampler.TailSamplerConfig{
+		DecisionWait: time.Duration(ts.DecisionWait) * time.Second,
+		MaxTraces:    ts.NumTraces,
+		MaxSpans:     ts.MaxSpans,
+		Policies:     policies,
+	}, out)
+}
+
 // Start starts sampling traces
 func (s *Sampler) Start() {
 	go func() {
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/tinylib/msgp/msgp"
+	"google.golang.org/grpc"
 
 	mainconfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
@@ -38,6 +39,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/osutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb/otlp"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -54,6 +56,9 @@ type HTTPReceiver struct {
 	dynConf *sampler.DynamicConfig
 	server  *http.Server
 
+	// grpcServer receives OTLP traces over gRPC, it is nil when disabled.
+	grpcServer *grpc.Server
+
 	debug               bool
 	rateLimiterResponse int // HTTP status code when refusing
 
@@ -100,6 +105,9 @@ func (r *HTTPReceiver) Start() {
 	mux.HandleFunc("/v0.4/services", r.handleWithVersion(v04, r.handleServices))
 	mux.HandleFunc("/v0.5/traces", r.handleWithVersion(v05, r.handleTraces))
 	mux.Handle("/profiling/v1/input", r.profileProxyHandler())
+	mux.HandleFunc("/v1/traces", r.handleOTLPTraces)
+	mux.HandleFunc("/api/v2/spans", r.handleDecodedTraces(zipkinV2Endpoint, decodeZipkinTraces))
+	mux.HandleFunc("/api/traces", r.handleDecodedTraces(jaegerThriftEndpoint, decodeJaegerTraces))
 
 	timeout := 5 * time.Second
 	if r.conf.ReceiverTimeout > 0 {
@@ -136,6 +144,21 @@ func (r *HTTPReceiver) Start() {
 		log.Infof("Listening for traces at unix://%s", path)
 	}
 
+	if port := r.conf.OTLPGRPCPort; port > 0 {
+		addr := fmt.Sprintf("%s:%d", r.conf.ReceiverHost, port)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			killProcess("Error creating OTLP gRPC listener: %v", err)
+		}
+		r.grpcServer = grpc.NewServer(grpc.MaxRecvMsgSize(int(r.conf.MaxRequestBytes)))
+		otlp.RegisterTraceServiceServer(r.grpcServer, &otlpTraceService{r: r})
+		go func() {
+			defer watchdog.LogOnPanic()
+			r.grpcServer.Serve(ln)
+		}()
+		log.Infof("Listening for OTLP traces over gRPC at %s", addr)
+	}
+
 	go r.RateLimiter.Run()
 
 	go func() {
@@ -233,6 +256,9 @@ func (r *HTTPReceiver) Stop() error {
 	if err := r.server.Shutdown(ctx); err != nil {
 		return err
 	}
+	if r.grpcServer != nil {
+		r.grpcServer.GracefulStop()
+	}
 	r.wg.Wait()
 	close(r.out)
 	return nil
@@ -369,18 +395,7 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 	traces, err := decodeTraces(v, req)
 	if err != nil {
 		httpDecodingError(err, []string{"handler:traces", fmt.Sprintf("v:%s", v)}, w)
-		switch err {
-		case ErrLimitedReaderLimitReached:
-			atomic.AddInt64(&ts.TracesDropped.PayloadTooLarge, tracen)
-		case io.EOF, io.ErrUnexpectedEOF:
-			atomic.AddInt64(&ts.TracesDropped.EOF, tracen)
-		default:
-			if err, ok := err.(net.Error); ok && err.Timeout() {
-				atomic.AddInt64(&ts.TracesDropped.Timeout, tracen)
-			} else {
-				atomic.AddInt64(&ts.TracesDropped.DecodingError, tracen)
-			}
-		}
+		countDecodingError(ts, err, tracen)
 		log.Errorf("Cannot decode %s traces payload: %v", v, err)
 		return
 	}
@@ -390,11 +405,73 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 	atomic.AddInt64(&ts.TracesBytes, req.Body.(*LimitedReader).Count)
 	atomic.AddInt64(&ts.PayloadAccepted, 1)
 
-	payload := &Payload{
+	r.sendPayload(&Payload{
 		Source:        ts,
 		Traces:        traces,
 		ContainerTags: getContainerTags(req.Header.Get(headerContainerID)),
+	})
+}
+
+// traceDecoder decodes the traces of a request sent in the format of another tracing system,
+// along with the language of the tracer that sent them, when it is known.
+type traceDecoder func(req *http.Request) (traces pb.Traces, lang string, err error)
+
+// handleDecodedTraces returns a handler for the endpoints receiving traces in the format of
+// another tracing system: they are decoded with decode and then processed like the Datadog ones.
+func (r *HTTPReceiver) handleDecodedTraces(endpoint string, decode traceDecoder) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "only POST requests are supported", http.StatusMethodNotAllowed)
+			return
+		}
+		body := NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
+		req.Body = body
+		traces, lang, err := decode(req)
+		ts := r.Stats.GetTagStats(info.Tags{Lang: lang, EndpointVersion: endpoint})
+		if err != nil {
+			httpDecodingError(err, []string{"handler:traces", "v:" + endpoint}, w)
+			// the number of traces of a payload that can't be decoded is unknown
+			countDecodingError(ts, err, 1)
+			log.Errorf("Cannot decode %s traces payload: %v", endpoint, err)
+			return
+		}
+		if r.rateLimited(int64(len(traces))) {
+			w.WriteHeader(r.rateLimiterResponse)
+			atomic.AddInt64(&ts.PayloadRefused, 1)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+		atomic.AddInt64(&ts.TracesReceived, int64(len(traces)))
+		atomic.AddInt64(&ts.TracesBytes, body.Count)
+		atomic.AddInt64(&ts.PayloadAccepted, 1)
+
+		r.sendPayload(&Payload{
+			Source:        ts,
+			Traces:        traces,
+			ContainerTags: getContainerTags(req.Header.Get(headerContainerID)),
+		})
 	}
+}
+
+// countDecodingError records in ts the tracen traces of a payload which could not be decoded.
+func countDecodingError(ts *info.TagStats, err error, tracen int64) {
+	switch err {
+	case ErrLimitedReaderLimitReached:
+		atomic.AddInt64(&ts.TracesDropped.PayloadTooLarge, tracen)
+	case io.EOF, io.ErrUnexpectedEOF:
+		atomic.AddInt64(&ts.TracesDropped.EOF, tracen)
+	default:
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			atomic.AddInt64(&ts.TracesDropped.Timeout, tracen)
+		} else {
+			atomic.AddInt64(&ts.TracesDropped.DecodingError, tracen)
+		}
+	}
+}
+
+// sendPayload sends a payload to the receiver's output, without ever dropping it.
+func (r *HTTPReceiver) sendPayload(payload *Payload) {
 	select {
 	case r.out <- payload:
 		// ok
@@ -570,6 +647,22 @@ func decodeRequest(req *http.Request, dest msgp.Decodable) error {
 	}
 }
 
+// groupByTraceID groups spans into traces, keeping the order in which the traces are first seen.
+func groupByTraceID(spans []*pb.Span) pb.Traces {
+	byID := make(map[uint64]int)
+	var traces pb.Traces
+	for _, s := range spans {
+		i, ok := byID[s.TraceID]
+		if !ok {
+			i = len(traces)
+			byID[s.TraceID] = i
+			traces = append(traces, nil)
+		}
+		traces[i] = append(traces[i], s)
+	}
+	return traces
+}
+
 func tracesFromSpans(spans []pb.Span) pb.Traces {
 	traces := pb.Traces{}
 	byID := make(map[uint64][]*pb.Span)
@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb/jaeger"
+)
+
+// jaegerThriftEndpoint is the endpoint version reported in the stats of the traces received
+// on the Jaeger endpoint.
+const jaegerThriftEndpoint = "jaeger_thrift"
+
+// decodeJaegerTraces decodes the traces of a request holding a Jaeger batch of spans encoded
+// with the Thrift binary protocol, as sent by the Jaeger clients to the Jaeger collector.
+func decodeJaegerTraces(req *http.Request) (pb.Traces, string, error) {
+	switch mediaType := getMediaType(req); mediaType {
+	case "application/x-thrift", "application/vnd.apache.thrift.binary":
+	default:
+		return nil, "", fmt.Errorf("unsupported media type: %q", mediaType)
+	}
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	batch, err := jaeger.UnmarshalBatch(buf)
+	if err != nil {
+		return nil, "", err
+	}
+	traces, lang := convertJaegerBatch(batch)
+	return traces, lang, nil
+}
+
+// convertJaegerBatch converts the spans of a Jaeger batch into traces, and returns the language
+// of the client that sent them, as found in its "jaeger.version" tag (e.g. "Go-2.22.1").
+func convertJaegerBatch(batch *jaeger.Batch) (pb.Traces, string) {
+	var service, lang string
+	processTags := make(map[string]string)
+	if p := batch.Process; p != nil {
+		service = p.ServiceName
+		for _, t := range p.Tags {
+			processTags[t.Key] = jaegerTagString(t)
+		}
+		if v := processTags["jaeger.version"]; v != "" {
+			lang = strings.ToLower(strings.SplitN(v, "-", 2)[0])
+		}
+	}
+	spans := make([]*pb.Span, 0, len(batch.Spans))
+	for _, s := range batch.Spans {
+		spans = append(spans, convertJaegerSpan(s, service, processTags))
+	}
+	return groupByTraceID(spans), lang
+}
+
+// convertJaegerSpan converts a Jaeger span of the given service into a Datadog span. Its 128-bit
+// trace ID is truncated to its lower 64 bits, the tags of its process and its own tags being added
+// to its metrics when they are numbers, and to its meta otherwise.
+func convertJaegerSpan(in *jaeger.Span, service string, processTags map[string]string) *pb.Span {
+	span := &pb.Span{
+		Service:  service,
+		Resource: in.OperationName,
+		TraceID:  uint64(in.TraceIDLow),
+		SpanID:   uint64(in.SpanID),
+		ParentID: uint64(in.ParentSpanID),
+		Start:    in.StartTime * 1000,
+		Duration: in.Duration * 1000,
+		Meta:     make(map[string]string, len(processTags)+len(in.Tags)),
+		Metrics:  make(map[string]float64),
+	}
+	if span.ParentID == 0 {
+		// newer clients only set the parent in the references of the span
+		for _, ref := range in.References {
+			if ref.RefType == jaeger.SpanRefTypeChildOf && ref.TraceIDLow == in.TraceIDLow {
+				span.ParentID = uint64(ref.SpanID)
+				break
+			}
+		}
+	}
+	for k, v := range processTags {
+		span.Meta[k] = v
+	}
+	kind := "internal"
+	for _, t := range in.Tags {
+		switch t.Key {
+		case "error":
+			if t.VBool || t.VStr == "true" {
+				span.Error = 1
+			}
+		case "span.kind":
+			kind = strings.ToLower(t.VStr)
+			span.Meta["span.kind"] = kind
+		default:
+			setJaegerTag(span, t)
+		}
+	}
+	for _, l := range in.Logs {
+		// the fields of error logs follow the OpenTracing conventions
+		fields := make(map[string]string, len(l.Fields))
+		for _, f := range l.Fields {
+			fields[f.Key] = jaegerTagString(f)
+		}
+		if fields["event"] != "error" {
+			continue
+		}
+		for ddKey, otKey := range map[string]string{
+			"error.type":  "error.kind",
+			"error.msg":   "message",
+			"error.stack": "stack",
+		} {
+			if v := fields[otKey]; v != "" {
+				span.Meta[ddKey] = v
+			}
+		}
+		if v := fields["error.object"]; v != "" && span.Meta["error.msg"] == "" {
+			span.Meta["error.msg"] = v
+		}
+	}
+	span.Name = "jaeger." + kind
+	span.Type = spanKindType(kind, span.Meta)
+	return span
+}
+
+// setJaegerTag adds a tag to the span: numbers are added to its metrics, other values to its meta.
+func setJaegerTag(span *pb.Span, t *jaeger.Tag) {
+	switch t.VType {
+	case jaeger.TagTypeLong:
+		if t.Key == "http.status_code" {
+			span.Meta[t.Key] = strconv.FormatInt(t.VLong, 10)
+			return
+		}
+		span.Metrics[t.Key] = float64(t.VLong)
+	case jaeger.TagTypeDouble:
+		if math.IsNaN(t.VDouble) || math.IsInf(t.VDouble, 0) {
+			return
+		}
+		span.Metrics[t.Key] = t.VDouble
+	default:
+		span.Meta[t.Key] = jaegerTagString(t)
+	}
+}
+
+// jaegerTagString returns the value of a tag as a string.
+func jaegerTagString(t *jaeger.Tag) string {
+	switch t.VType {
+	case jaeger.TagTypeDouble:
+		return strconv.FormatFloat(t.VDouble, 'f', -1, 64)
+	case jaeger.TagTypeBool:
+		return strconv.FormatBool(t.VBool)
+	case jaeger.TagTypeLong:
+		return strconv.FormatInt(t.VLong, 10)
+	case jaeger.TagTypeBinary:
+		return base64.StdEncoding.EncodeToString(t.VBinary)
+	default:
+		return t.VStr
+	}
+}
@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb/jaeger"
+)
+
+func testJaegerBatch() *jaeger.Batch {
+	return &jaeger.Batch{
+		Process: &jaeger.Process{
+			ServiceName: "frontend",
+			Tags: []*jaeger.Tag{
+				{Key: "jaeger.version", VType: jaeger.TagTypeString, VStr: "Go-2.22.1"},
+				{Key: "hostname", VType: jaeger.TagTypeString, VStr: "host-1"},
+			},
+		},
+		Spans: []*jaeger.Span{
+			{
+				TraceIDLow:    42,
+				TraceIDHigh:   0x463ac35c9f6413ad,
+				SpanID:        1,
+				OperationName: "HTTP GET /api",
+				StartTime:     1556604172355737,
+				Duration:      1431,
+				Tags: []*jaeger.Tag{
+					{Key: "span.kind", VType: jaeger.TagTypeString, VStr: "server"},
+					{Key: "http.status_code", VType: jaeger.TagTypeLong, VLong: 500},
+					{Key: "error", VType: jaeger.TagTypeBool, VBool: true},
+					{Key: "retries", VType: jaeger.TagTypeLong, VLong: 2},
+					{Key: "ratio", VType: jaeger.TagTypeDouble, VDouble: 0.5},
+					{Key: "cached", VType: jaeger.TagTypeBool, VBool: false},
+				},
+				Logs: []*jaeger.Log{{
+					Timestamp: 1556604172355800,
+					Fields: []*jaeger.Tag{
+						{Key: "event", VType: jaeger.TagTypeString, VStr: "error"},
+						{Key: "error.kind", VType: jaeger.TagTypeString, VStr: "Timeout"},
+						{Key: "message", VType: jaeger.TagTypeString, VStr: "backend timed out"},
+					},
+				}},
+			},
+			{
+				TraceIDLow:    42,
+				TraceIDHigh:   0x463ac35c9f6413ad,
+				SpanID:        2,
+				OperationName: "SELECT",
+				References: []*jaeger.SpanRef{
+					{RefType: jaeger.SpanRefTypeChildOf, TraceIDLow: 42, TraceIDHigh: 0x463ac35c9f6413ad, SpanID: 1},
+				},
+				StartTime: 1556604172356000,
+				Duration:  1000,
+				Tags: []*jaeger.Tag{
+					{Key: "span.kind", VType: jaeger.TagTypeString, VStr: "client"},
+					{Key: "db.system", VType: jaeger.TagTypeString, VStr: "postgresql"},
+				},
+			},
+		},
+	}
+}
+
+func TestConvertJaegerBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	traces, lang := convertJaegerBatch(testJaegerBatch())
+	assert.Equal("go", lang)
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0], 2)
+
+	server, client := traces[0][0], traces[0][1]
+	assert.Equal(uint64(42), server.TraceID)
+	assert.Equal(uint64(1), server.SpanID)
+	assert.Equal(uint64(0), server.ParentID)
+	assert.Equal("frontend", server.Service)
+	assert.Equal("jaeger.server", server.Name)
+	assert.Equal("HTTP GET /api", server.Resource)
+	assert.Equal("web", server.Type)
+	assert.Equal(int64(1556604172355737000), server.Start)
+	assert.Equal(int64(1431000), server.Duration)
+	assert.Equal(int32(1), server.Error)
+	assert.Equal("Timeout", server.Meta["error.type"])
+	assert.Equal("backend timed out", server.Meta["error.msg"])
+	assert.Equal("500", server.Meta["http.status_code"])
+	assert.Equal("host-1", server.Meta["hostname"])
+	assert.Equal("false", server.Meta["cached"])
+	assert.Equal(2.0, server.Metrics["retries"])
+	assert.Equal(0.5, server.Metrics["ratio"])
+
+	assert.Equal(uint64(42), client.TraceID)
+	assert.Equal(uint64(1), client.ParentID)
+	assert.Equal("jaeger.client", client.Name)
+	assert.Equal("db", client.Type)
+	assert.Equal(int32(0), client.Error)
+}
+
+func TestHandleJaegerTraces(t *testing.T) {
+	assert := assert.New(t)
+	receiver := newTestReceiverFromConfig(newTestReceiverConfig())
+	handler := receiver.handleDecodedTraces(jaegerThriftEndpoint, decodeJaegerTraces)
+
+	// the batch returned by testJaegerBatch, encoded with the Thrift binary protocol
+	// like the Jaeger clients do
+	body, err := ioutil.ReadFile("testdata/jaeger_batch.thrift")
+	require.NoError(t, err)
+	batch, err := jaeger.UnmarshalBatch(body)
+	require.NoError(t, err)
+	require.Equal(t, testJaegerBatch(), batch)
+
+	req, err := http.NewRequest("POST", "/api/traces", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-thrift")
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	assert.Equal(http.StatusAccepted, rr.Code)
+	require.Len(t, receiver.out, 1)
+	payload := <-receiver.out
+	traces, _ := convertJaegerBatch(testJaegerBatch())
+	assert.Equal(traces, payload.Traces)
+	assert.Equal("go", payload.Source.Lang)
+	assert.Equal(jaegerThriftEndpoint, payload.Source.EndpointVersion)
+	assert.Equal(int64(1), payload.Source.TracesReceived)
+	assert.Equal(int64(len(body)), payload.Source.TracesBytes)
+
+	// truncated payloads are counted in the stats of the endpoint
+	req, err = http.NewRequest("POST", "/api/traces", bytes.NewReader(body[:len(body)/2]))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-thrift")
+	rr = httptest.NewRecorder()
+	handler(rr, req)
+	assert.Equal(http.StatusBadRequest, rr.Code)
+	assert.Len(receiver.out, 0)
+	ts := receiver.Stats.GetTagStats(info.Tags{EndpointVersion: jaegerThriftEndpoint})
+	assert.Equal(int64(1), ts.TracesDropped.EOF)
+}
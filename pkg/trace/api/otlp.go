@@ -0,0 +1,404 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb/otlp"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// otlpHTTPEndpoint and otlpGRPCEndpoint are the endpoint versions reported in the
+	// stats of the traces received over OTLP/HTTP and OTLP/gRPC.
+	otlpHTTPEndpoint = "otlp_http"
+	otlpGRPCEndpoint = "otlp_grpc"
+
+	// otlpProtobufContentType and otlpJSONContentType are the media types of the
+	// requests sent to the OTLP/HTTP endpoint.
+	otlpProtobufContentType = "application/x-protobuf"
+	otlpJSONContentType     = "application/json"
+)
+
+// handleOTLPTraces handles the requests sent to the OTLP/HTTP traces endpoint, whose body is an
+// ExportTraceServiceRequest encoded in protobuf or in JSON, depending on its Content-Type.
+func (r *HTTPReceiver) handleOTLPTraces(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "only POST requests are supported", http.StatusMethodNotAllowed)
+		return
+	}
+	mediaType := getMediaType(req)
+	if mediaType != otlpProtobufContentType && mediaType != otlpJSONContentType {
+		httpFormatError(w, otlpHTTPEndpoint, fmt.Errorf("unsupported media type: %q", mediaType))
+		return
+	}
+	body := NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
+	in, err := decodeOTLPRequest(mediaType, req.Header.Get("Content-Encoding"), body, r.conf.MaxRequestBytes)
+	if err != nil {
+		httpDecodingError(err, []string{"handler:traces", "v:" + otlpHTTPEndpoint}, w)
+		log.Errorf("Cannot decode %s traces payload: %v", otlpHTTPEndpoint, err)
+		return
+	}
+	if !r.receiveOTLPTraces(in, otlpHTTPEndpoint, body.Count, getContainerTags(req.Header.Get(headerContainerID))) {
+		w.WriteHeader(r.rateLimiterResponse)
+		return
+	}
+	// the response is an empty ExportTraceServiceResponse
+	w.Header().Set("Content-Type", mediaType)
+	if mediaType == otlpJSONContentType {
+		io.WriteString(w, "{}")
+	}
+}
+
+// decodeOTLPRequest decodes an ExportTraceServiceRequest of the given media type from the
+// body of a request, uncompressing it first if it is gzipped. The uncompressed body may
+// not be larger than limit bytes.
+func decodeOTLPRequest(mediaType, contentEncoding string, body io.Reader, limit int64) (*otlp.ExportTraceServiceRequest, error) {
+	if contentEncoding == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = NewLimitedReader(ioutil.NopCloser(gz), limit)
+	}
+	buf, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	var in otlp.ExportTraceServiceRequest
+	if mediaType == otlpJSONContentType {
+		err = json.Unmarshal(buf, &in)
+	} else {
+		err = proto.Unmarshal(buf, &in)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// otlpTraceService implements the OTLP/gRPC trace service on top of a receiver.
+type otlpTraceService struct {
+	r *HTTPReceiver
+}
+
+// Export implements otlp.TraceServiceServer.
+func (s *otlpTraceService) Export(ctx context.Context, in *otlp.ExportTraceServiceRequest) (*otlp.ExportTraceServiceResponse, error) {
+	var containerTags string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if id := md.Get(headerContainerID); len(id) > 0 {
+			containerTags = getContainerTags(id[0])
+		}
+	}
+	if !s.r.receiveOTLPTraces(in, otlpGRPCEndpoint, int64(proto.Size(in)), containerTags) && s.r.rateLimiterResponse == http.StatusTooManyRequests {
+		return nil, status.Error(codes.ResourceExhausted, "too many requests")
+	}
+	return &otlp.ExportTraceServiceResponse{}, nil
+}
+
+// receiveOTLPTraces converts the spans of an OTLP request into traces and sends them to the
+// receiver's output, one payload for each resource. It returns false when the request is
+// refused by the rate limiter.
+func (r *HTTPReceiver) receiveOTLPTraces(in *otlp.ExportTraceServiceRequest, endpoint string, size int64, containerTags string) bool {
+	payloads := make([]*Payload, 0, len(in.ResourceSpans))
+	var tracen int64
+	for _, rspans := range in.ResourceSpans {
+		if rspans == nil {
+			// null elements are valid in JSON requests
+			continue
+		}
+		attrs := otlpAttributesMap(rspans.GetResource().GetAttributes())
+		traces := convertOTLPResourceSpans(rspans, attrs)
+		if len(traces) == 0 {
+			continue
+		}
+		tracen += int64(len(traces))
+		payloads = append(payloads, &Payload{
+			Source: r.Stats.GetTagStats(info.Tags{
+				Lang:            attrs["telemetry.sdk.language"],
+				TracerVersion:   attrs["telemetry.sdk.version"],
+				EndpointVersion: endpoint,
+			}),
+			Traces:        traces,
+			ContainerTags: containerTags,
+		})
+	}
+	if r.rateLimited(tracen) {
+		for _, p := range payloads {
+			atomic.AddInt64(&p.Source.PayloadRefused, 1)
+		}
+		return false
+	}
+	for i, p := range payloads {
+		if i == 0 {
+			// the size of the request can't be split between its resources
+			atomic.AddInt64(&p.Source.TracesBytes, size)
+		}
+		atomic.AddInt64(&p.Source.TracesReceived, int64(len(p.Traces)))
+		atomic.AddInt64(&p.Source.PayloadAccepted, 1)
+		r.sendPayload(p)
+	}
+	return true
+}
+
+// convertOTLPResourceSpans converts the spans of a resource, whose attributes are given, into
+// traces grouped by trace ID.
+func convertOTLPResourceSpans(rspans *otlp.ResourceSpans, resourceAttrs map[string]string) pb.Traces {
+	var spans []*pb.Span
+	for _, libspans := range rspans.InstrumentationLibrarySpans {
+		if libspans == nil {
+			continue
+		}
+		for _, span := range libspans.Spans {
+			if span == nil {
+				continue
+			}
+			spans = append(spans, convertOTLPSpan(span, libspans.InstrumentationLibrary, resourceAttrs))
+		}
+	}
+	return groupByTraceID(spans)
+}
+
+// otlpSpanKindNames are the names of the OTLP span kinds, as found in the "span.kind" tag.
+var otlpSpanKindNames = map[otlp.SpanKind]string{
+	otlp.SpanKindUnspecified: "unspecified",
+	otlp.SpanKindInternal:    "internal",
+	otlp.SpanKindServer:      "server",
+	otlp.SpanKindClient:      "client",
+	otlp.SpanKindProducer:    "producer",
+	otlp.SpanKindConsumer:    "consumer",
+}
+
+// convertOTLPSpan converts an OTLP span produced by the given library into a Datadog span:
+//
+//   - its service, env and version come from the service.name, deployment.environment and
+//     service.version resource attributes, all the resource attributes being added to its tags,
+//   - its name is made of the name of the instrumentation library and of its kind, its resource
+//     being the name of the OTLP span,
+//   - its type is derived from its kind,
+//   - it is flagged as an error when its status is an error,
+//   - its 128-bit trace ID is truncated to its lower 64 bits, the full ID being kept in the
+//     "otel.trace_id" tag.
+func convertOTLPSpan(in *otlp.Span, lib *otlp.InstrumentationLibrary, resourceAttrs map[string]string) *pb.Span {
+	kind, ok := otlpSpanKindNames[in.Kind]
+	if !ok {
+		kind = otlpSpanKindNames[otlp.SpanKindUnspecified]
+	}
+	span := &pb.Span{
+		Service:  resourceAttrs["service.name"],
+		Name:     otlpSpanName(lib, kind),
+		Resource: in.Name,
+		TraceID:  otlpID(in.TraceID),
+		SpanID:   otlpID(in.SpanID),
+		ParentID: otlpID(in.ParentSpanID),
+		Start:    int64(in.StartTimeUnixNano),
+		Meta:     make(map[string]string, len(resourceAttrs)+len(in.Attributes)+3),
+		Metrics:  make(map[string]float64),
+	}
+	if in.EndTimeUnixNano > in.StartTimeUnixNano {
+		span.Duration = int64(in.EndTimeUnixNano - in.StartTimeUnixNano)
+	}
+	for k, v := range resourceAttrs {
+		span.Meta[k] = v
+	}
+	if env := resourceAttrs["deployment.environment"]; env != "" {
+		span.Meta["env"] = env
+	}
+	if version := resourceAttrs["service.version"]; version != "" {
+		span.Meta["version"] = version
+	}
+	for _, kv := range in.Attributes {
+		if kv != nil {
+			setOTLPAttribute(span, kv.Key, kv.Value)
+		}
+	}
+	span.Meta["span.kind"] = kind
+	if len(in.TraceID) > 0 {
+		span.Meta["otel.trace_id"] = hex.EncodeToString(in.TraceID)
+	}
+	if lib != nil && lib.Name != "" {
+		span.Meta["otel.library.name"] = lib.Name
+		if lib.Version != "" {
+			span.Meta["otel.library.version"] = lib.Version
+		}
+	}
+	span.Type = spanKindType(kind, span.Meta)
+	if method, route := span.Meta["http.method"], span.Meta["http.route"]; in.Kind == otlp.SpanKindServer && method != "" && route != "" {
+		span.Resource = method + " " + route
+	}
+	if in.Status.IsError() {
+		span.Error = 1
+		if msg := in.Status.Message; msg != "" {
+			span.Meta["error.msg"] = msg
+		}
+	}
+	for _, e := range in.Events {
+		if e == nil || e.Name != "exception" {
+			continue
+		}
+		// the attributes of exception events follow the semantic conventions
+		attrs := otlpAttributesMap(e.Attributes)
+		for ddKey, otelKey := range map[string]string{
+			"error.type":  "exception.type",
+			"error.msg":   "exception.message",
+			"error.stack": "exception.stacktrace",
+		} {
+			if v := attrs[otelKey]; v != "" {
+				span.Meta[ddKey] = v
+			}
+		}
+	}
+	return span
+}
+
+// otlpSpanName returns the name of a span of the given kind produced by the given library.
+func otlpSpanName(lib *otlp.InstrumentationLibrary, kind string) string {
+	if lib == nil || lib.Name == "" {
+		return "opentelemetry." + kind
+	}
+	return lib.Name + "." + kind
+}
+
+// spanKindType returns the type of a span of the given kind ("server", "client", "producer",
+// "consumer" or "internal") with the given tags.
+func spanKindType(kind string, meta map[string]string) string {
+	switch kind {
+	case "server":
+		return "web"
+	case "client":
+		if meta["db.system"] != "" {
+			return "db"
+		}
+		return "http"
+	case "producer", "consumer":
+		return "queue"
+	default:
+		return "custom"
+	}
+}
+
+// otlpID returns the lower 64 bits of an OTLP trace or span ID.
+func otlpID(id []byte) uint64 {
+	if len(id) < 8 {
+		var buf [8]byte
+		copy(buf[8-len(id):], id)
+		return binary.BigEndian.Uint64(buf[:])
+	}
+	return binary.BigEndian.Uint64(id[len(id)-8:])
+}
+
+// setOTLPAttribute adds an attribute to the span: numbers are added to its metrics,
+// other values to its tags.
+func setOTLPAttribute(span *pb.Span, key string, value *otlp.AnyValue) {
+	if value == nil {
+		return
+	}
+	switch v := value.Value.(type) {
+	case *otlp.AnyValueInt:
+		if key == "http.status_code" {
+			span.Meta[key] = strconv.FormatInt(v.IntValue, 10)
+			return
+		}
+		span.Metrics[key] = float64(v.IntValue)
+	case *otlp.AnyValueDouble:
+		if math.IsNaN(v.DoubleValue) || math.IsInf(v.DoubleValue, 0) {
+			return
+		}
+		span.Metrics[key] = v.DoubleValue
+	default:
+		span.Meta[key] = otlpValueString(value)
+	}
+}
+
+// otlpAttributesMap returns the given attributes as strings, keyed by their name.
+func otlpAttributesMap(attrs []*otlp.KeyValue) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		if kv != nil && kv.Value != nil {
+			m[kv.Key] = otlpValueString(kv.Value)
+		}
+	}
+	return m
+}
+
+// otlpValueString returns the string representation of an OTLP value, lists being
+// represented in JSON.
+func otlpValueString(value *otlp.AnyValue) string {
+	switch v := value.GetValue().(type) {
+	case *otlp.AnyValueString:
+		return v.StringValue
+	case *otlp.AnyValueBool:
+		return strconv.FormatBool(v.BoolValue)
+	case *otlp.AnyValueInt:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *otlp.AnyValueDouble:
+		return strconv.FormatFloat(v.DoubleValue, 'f', -1, 64)
+	case *otlp.AnyValueBytes:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case nil:
+		return ""
+	default:
+		b, err := json.Marshal(otlpValueInterface(value))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+// otlpValueInterface returns an OTLP value as a value that can be encoded in JSON.
+func otlpValueInterface(value *otlp.AnyValue) interface{} {
+	switch v := value.GetValue().(type) {
+	case *otlp.AnyValueArray:
+		var values []interface{}
+		for _, item := range v.ArrayValue.GetValues() {
+			values = append(values, otlpValueInterface(item))
+		}
+		return values
+	case *otlp.AnyValueKvlist:
+		values := make(map[string]interface{})
+		for _, kv := range v.KvlistValue.GetValues() {
+			if kv != nil {
+				values[kv.Key] = otlpValueInterface(kv.Value)
+			}
+		}
+		return values
+	case *otlp.AnyValueBool:
+		return v.BoolValue
+	case *otlp.AnyValueInt:
+		return v.IntValue
+	case *otlp.AnyValueDouble:
+		if math.IsNaN(v.DoubleValue) || math.IsInf(v.DoubleValue, 0) {
+			return nil
+		}
+		return v.DoubleValue
+	case nil:
+		return nil
+	default:
+		return otlpValueString(value)
+	}
+}
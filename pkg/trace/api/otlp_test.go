@@ -0,0 +1,292 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb/otlp"
+)
+
+func otlpString(key, value string) *otlp.KeyValue {
+	return &otlp.KeyValue{Key: key, Value: &otlp.AnyValue{Value: &otlp.AnyValueString{StringValue: value}}}
+}
+
+func otlpInt(key string, value int64) *otlp.KeyValue {
+	return &otlp.KeyValue{Key: key, Value: &otlp.AnyValue{Value: &otlp.AnyValueInt{IntValue: value}}}
+}
+
+// testOTLPRequest returns a request holding a server span and its client child span.
+func testOTLPRequest() *otlp.ExportTraceServiceRequest {
+	traceID := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0, 0, 0, 0, 0, 0, 0, 0x2a}
+	return &otlp.ExportTraceServiceRequest{
+		ResourceSpans: []*otlp.ResourceSpans{{
+			Resource: &otlp.Resource{Attributes: []*otlp.KeyValue{
+				otlpString("service.name", "checkout"),
+				otlpString("service.version", "1.2.3"),
+				otlpString("deployment.environment", "prod"),
+				otlpString("telemetry.sdk.language", "go"),
+			}},
+			InstrumentationLibrarySpans: []*otlp.InstrumentationLibrarySpans{{
+				InstrumentationLibrary: &otlp.InstrumentationLibrary{Name: "net/http", Version: "0.13.0"},
+				Spans: []*otlp.Span{
+					{
+						TraceID:           traceID,
+						SpanID:            []byte{0, 0, 0, 0, 0, 0, 0, 1},
+						Name:              "HTTP GET",
+						Kind:              otlp.SpanKindServer,
+						StartTimeUnixNano: 1600000000000000000,
+						EndTimeUnixNano:   1600000000500000000,
+						Attributes: []*otlp.KeyValue{
+							otlpString("http.method", "GET"),
+							otlpString("http.route", "/cart/{id}"),
+							otlpInt("http.status_code", 500),
+							otlpInt("retries", 2),
+						},
+						Status: &otlp.Status{Code: otlp.StatusCodeError, Message: "internal error"},
+						Events: []*otlp.SpanEvent{{
+							Name: "exception",
+							Attributes: []*otlp.KeyValue{
+								otlpString("exception.type", "*errors.errorString"),
+								otlpString("exception.message", "boom"),
+							},
+						}},
+					},
+					{
+						TraceID:           traceID,
+						SpanID:            []byte{0, 0, 0, 0, 0, 0, 0, 2},
+						ParentSpanID:      []byte{0, 0, 0, 0, 0, 0, 0, 1},
+						Name:              "SELECT",
+						Kind:              otlp.SpanKindClient,
+						StartTimeUnixNano: 1600000000100000000,
+						EndTimeUnixNano:   1600000000200000000,
+						Attributes:        []*otlp.KeyValue{otlpString("db.system", "postgresql")},
+						Status:            &otlp.Status{Code: otlp.StatusCodeOk},
+					},
+				},
+			}},
+		}},
+	}
+}
+
+func TestConvertOTLPResourceSpans(t *testing.T) {
+	assert := assert.New(t)
+
+	rspans := testOTLPRequest().ResourceSpans[0]
+	traces := convertOTLPResourceSpans(rspans, otlpAttributesMap(rspans.Resource.Attributes))
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+
+	server, client := traces[0][0], traces[0][1]
+	assert.Equal(uint64(42), server.TraceID)
+	assert.Equal(uint64(1), server.SpanID)
+	assert.Equal(uint64(0), server.ParentID)
+	assert.Equal("checkout", server.Service)
+	assert.Equal("net/http.server", server.Name)
+	assert.Equal("GET /cart/{id}", server.Resource)
+	assert.Equal("web", server.Type)
+	assert.Equal(int64(1600000000000000000), server.Start)
+	assert.Equal(int64(500000000), server.Duration)
+	assert.Equal(int32(1), server.Error)
+	assert.Equal("prod", server.Meta["env"])
+	assert.Equal("1.2.3", server.Meta["version"])
+	assert.Equal("server", server.Meta["span.kind"])
+	assert.Equal("500", server.Meta["http.status_code"])
+	assert.Equal("*errors.errorString", server.Meta["error.type"])
+	assert.Equal("boom", server.Meta["error.msg"])
+	assert.Equal("1122334455667788000000000000002a", server.Meta["otel.trace_id"])
+	assert.Equal(2.0, server.Metrics["retries"])
+
+	assert.Equal(uint64(42), client.TraceID)
+	assert.Equal(uint64(1), client.ParentID)
+	assert.Equal("net/http.client", client.Name)
+	assert.Equal("SELECT", client.Resource)
+	assert.Equal("db", client.Type)
+	assert.Equal(int32(0), client.Error)
+	assert.Equal("checkout", client.Service)
+}
+
+func TestConvertOTLPSpanKinds(t *testing.T) {
+	for kind, typ := range map[otlp.SpanKind]string{
+		otlp.SpanKindUnspecified: "custom",
+		otlp.SpanKindInternal:    "custom",
+		otlp.SpanKindServer:      "web",
+		otlp.SpanKindClient:      "http",
+		otlp.SpanKindProducer:    "queue",
+		otlp.SpanKindConsumer:    "queue",
+	} {
+		span := convertOTLPSpan(&otlp.Span{Kind: kind}, nil, map[string]string{})
+		assert.Equal(t, typ, span.Type, kind)
+		assert.Equal(t, "opentelemetry."+otlpSpanKindNames[kind], span.Name)
+	}
+}
+
+func TestConvertOTLPStatus(t *testing.T) {
+	for i, tt := range []struct {
+		status *otlp.Status
+		error  int32
+	}{
+		{nil, 0},
+		{&otlp.Status{Code: otlp.StatusCodeUnset}, 0},
+		{&otlp.Status{Code: otlp.StatusCodeOk}, 0},
+		{&otlp.Status{Code: otlp.StatusCodeError}, 1},
+		// older versions of the protocol
+		{&otlp.Status{DeprecatedCode: 2}, 1},
+		{&otlp.Status{DeprecatedCode: 2, Code: otlp.StatusCodeOk}, 0},
+	} {
+		span := convertOTLPSpan(&otlp.Span{Status: tt.status}, nil, map[string]string{})
+		assert.Equal(t, tt.error, span.Error, "case %d", i)
+	}
+}
+
+func TestHandleOTLPTraces(t *testing.T) {
+	req := testOTLPRequest()
+	protobuf, err := proto.Marshal(req)
+	require.NoError(t, err)
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(protobuf)
+	gz.Close()
+	json := `{"resourceSpans":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},
+		"instrumentationLibrarySpans":[{"spans":[{
+			"traceId":"1122334455667788000000000000002a",
+			"spanId":"0000000000000001",
+			"name":"HTTP GET",
+			"kind":"SPAN_KIND_SERVER",
+			"startTimeUnixNano":"1600000000000000000",
+			"endTimeUnixNano":"1600000000500000000",
+			"attributes":[{"key":"retries","value":{"intValue":"2"}}],
+			"status":{"code":2}
+		}]}]
+	}]}`
+	// null elements are skipped
+	nullJSON := `{"resourceSpans":[null,{
+		"resource":{"attributes":[null,{"key":"service.name","value":{"stringValue":"checkout"}}]},
+		"instrumentationLibrarySpans":[null,{"spans":[null,{
+			"traceId":"1122334455667788000000000000002a",
+			"spanId":"0000000000000001",
+			"name":"HTTP GET",
+			"kind":"SPAN_KIND_SERVER",
+			"startTimeUnixNano":"1600000000000000000",
+			"endTimeUnixNano":"1600000000500000000",
+			"attributes":[null,{"key":"retries","value":{"intValue":"2"}},{"key":"tags","value":{"kvlistValue":{"values":[null]}}}],
+			"events":[null],
+			"status":{"code":2}
+		}]}]
+	}]}`
+
+	for name, tt := range map[string]struct {
+		contentType     string
+		contentEncoding string
+		body            []byte
+		status          int
+		spans           int
+	}{
+		"protobuf":     {"application/x-protobuf", "", protobuf, http.StatusOK, 2},
+		"gzip":         {"application/x-protobuf", "gzip", gzipped.Bytes(), http.StatusOK, 2},
+		"json":         {"application/json", "", []byte(json), http.StatusOK, 1},
+		"null-json":    {"application/json", "", []byte(nullJSON), http.StatusOK, 1},
+		"invalid":      {"application/x-protobuf", "", []byte("invalid"), http.StatusBadRequest, 0},
+		"invalid-json": {"application/json", "", []byte(`{"resourceSpans":`), http.StatusBadRequest, 0},
+		"media-type":   {"application/msgpack", "", protobuf, http.StatusUnsupportedMediaType, 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			receiver := newTestReceiverFromConfig(newTestReceiverConfig())
+
+			httpReq, err := http.NewRequest("POST", "/v1/traces", bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			httpReq.Header.Set("Content-Type", tt.contentType)
+			httpReq.Header.Set("Content-Encoding", tt.contentEncoding)
+			rr := httptest.NewRecorder()
+			receiver.handleOTLPTraces(rr, httpReq)
+			assert.Equal(tt.status, rr.Code)
+			if tt.status != http.StatusOK {
+				assert.Len(receiver.out, 0)
+				return
+			}
+			assert.Equal(tt.contentType, rr.Header().Get("Content-Type"))
+
+			require.Len(t, receiver.out, 1)
+			payload := <-receiver.out
+			assert.Equal(otlpHTTPEndpoint, payload.Source.EndpointVersion)
+			assert.Equal(int64(1), payload.Source.TracesReceived)
+			assert.Equal(int64(1), payload.Source.PayloadAccepted)
+			require.Len(t, payload.Traces, 1)
+			require.Len(t, payload.Traces[0], tt.spans)
+			span := payload.Traces[0][0]
+			assert.Equal(uint64(42), span.TraceID)
+			assert.Equal(uint64(1), span.SpanID)
+			assert.Equal("checkout", span.Service)
+			assert.Equal("web", span.Type)
+			assert.Equal(int32(1), span.Error)
+			assert.Equal(2.0, span.Metrics["retries"])
+		})
+	}
+}
+
+func TestOTLPGRPC(t *testing.T) {
+	assert := assert.New(t)
+	receiver := newTestReceiverFromConfig(newTestReceiverConfig())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	otlp.RegisterTraceServiceServer(server, &otlpTraceService{r: receiver})
+	go server.Serve(ln)
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, ln.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var resp otlp.ExportTraceServiceResponse
+	err = conn.Invoke(ctx, "/opentelemetry.proto.collector.trace.v1.TraceService/Export", testOTLPRequest(), &resp)
+	require.NoError(t, err)
+
+	require.Len(t, receiver.out, 1)
+	payload := <-receiver.out
+	assert.Equal(otlpGRPCEndpoint, payload.Source.EndpointVersion)
+	assert.Equal("go", payload.Source.Lang)
+	assert.Equal(pb.Traces{testOTLPTrace(t)}, payload.Traces)
+}
+
+// testOTLPTrace returns the trace converted from testOTLPRequest.
+func testOTLPTrace(t *testing.T) pb.Trace {
+	rspans := testOTLPRequest().ResourceSpans[0]
+	traces := convertOTLPResourceSpans(rspans, otlpAttributesMap(rspans.Resource.Attributes))
+	require.Len(t, traces, 1)
+	return traces[0]
+}
+
+func TestOTLPValueString(t *testing.T) {
+	value := &otlp.AnyValue{Value: &otlp.AnyValueArray{ArrayValue: &otlp.ArrayValue{Values: []*otlp.AnyValue{
+		{Value: &otlp.AnyValueString{StringValue: "a"}},
+		{Value: &otlp.AnyValueInt{IntValue: 1}},
+		{Value: &otlp.AnyValueKvlist{KvlistValue: &otlp.KeyValueList{Values: []*otlp.KeyValue{otlpString("k", "v")}}}},
+	}}}}
+	assert.Equal(t, `["a",1,{"k":"v"}]`, otlpValueString(value))
+	assert.Equal(t, "true", otlpValueString(&otlp.AnyValue{Value: &otlp.AnyValueBool{BoolValue: true}}))
+	assert.Equal(t, "0.5", otlpValueString(&otlp.AnyValue{Value: &otlp.AnyValueDouble{DoubleValue: 0.5}}))
+	assert.Equal(t, "", otlpValueString(&otlp.AnyValue{}))
+	assert.Equal(t, "YWJj", otlpValueString(&otlp.AnyValue{Value: &otlp.AnyValueBytes{BytesValue: []byte("abc")}}))
+}
@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// zipkinV2Endpoint is the endpoint version reported in the stats of the traces received
+// on the Zipkin v2 endpoint.
+const zipkinV2Endpoint = "zipkin_v2"
+
+// zipkinSpan is a span of the Zipkin v2 API, its timestamp and duration are in microseconds.
+type zipkinSpan struct {
+	TraceID        string            `json:"traceId"`
+	ParentID       string            `json:"parentId"`
+	ID             string            `json:"id"`
+	Kind           string            `json:"kind"`
+	Name           string            `json:"name"`
+	Timestamp      uint64            `json:"timestamp"`
+	Duration       uint64            `json:"duration"`
+	LocalEndpoint  *zipkinEndpoint   `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint   `json:"remoteEndpoint"`
+	Tags           map[string]string `json:"tags"`
+}
+
+// zipkinEndpoint is the network context of a node in the service graph.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+// decodeZipkinTraces decodes the traces of a request holding a JSON list of Zipkin v2 spans.
+func decodeZipkinTraces(req *http.Request) (pb.Traces, string, error) {
+	if mediaType := getMediaType(req); mediaType != "application/json" {
+		return nil, "", fmt.Errorf("unsupported media type: %q", mediaType)
+	}
+	var in []*zipkinSpan
+	if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+		return nil, "", err
+	}
+	spans := make([]*pb.Span, 0, len(in))
+	for _, s := range in {
+		if s == nil {
+			// null elements are valid JSON, they hold no span
+			continue
+		}
+		span, err := convertZipkinSpan(s)
+		if err != nil {
+			return nil, "", err
+		}
+		spans = append(spans, span)
+	}
+	return groupByTraceID(spans), "", nil
+}
+
+// convertZipkinSpan converts a Zipkin span into a Datadog span. Its 128-bit trace ID is
+// truncated to its lower 64 bits, its tags are added to its metrics when they are numbers,
+// and to its meta otherwise.
+func convertZipkinSpan(in *zipkinSpan) (*pb.Span, error) {
+	traceID, err := parseZipkinID(in.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid traceId: %v", err)
+	}
+	spanID, err := parseZipkinID(in.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+	var parentID uint64
+	if in.ParentID != "" {
+		if parentID, err = parseZipkinID(in.ParentID); err != nil {
+			return nil, fmt.Errorf("invalid parentId: %v", err)
+		}
+	}
+	kind := strings.ToLower(in.Kind)
+	if kind == "" {
+		kind = "internal"
+	}
+	span := &pb.Span{
+		Name:     "zipkin." + kind,
+		Resource: in.Name,
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Start:    int64(in.Timestamp * 1000),
+		Duration: int64(in.Duration * 1000),
+		Meta:     make(map[string]string, len(in.Tags)+1),
+		Metrics:  make(map[string]float64),
+	}
+	if in.LocalEndpoint != nil {
+		span.Service = in.LocalEndpoint.ServiceName
+	}
+	if in.Kind != "" {
+		span.Meta["span.kind"] = kind
+	}
+	for k, v := range in.Tags {
+		if k == "error" {
+			// the value of the error tag is its message, if any
+			span.Error = 1
+			if v != "" && v != "true" {
+				span.Meta["error.msg"] = v
+			}
+			continue
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil && k != "http.status_code" && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			span.Metrics[k] = f
+			continue
+		}
+		span.Meta[k] = v
+	}
+	if remote := in.RemoteEndpoint; remote != nil {
+		if remote.ServiceName != "" {
+			span.Meta["peer.service"] = remote.ServiceName
+		}
+		if host := remote.IPv4; host != "" {
+			span.Meta["out.host"] = host
+		} else if host := remote.IPv6; host != "" {
+			span.Meta["out.host"] = host
+		}
+		if remote.Port != 0 {
+			span.Metrics["out.port"] = float64(remote.Port)
+		}
+	}
+	span.Type = spanKindType(kind, span.Meta)
+	return span, nil
+}
+
+// parseZipkinID parses a Zipkin ID, made of 16 or 32 hex characters, keeping its lower 64 bits.
+func parseZipkinID(id string) (uint64, error) {
+	if id == "" || len(id) > 32 {
+		return 0, fmt.Errorf("%q is not a 64 or 128-bit hex ID", id)
+	}
+	if len(id) > 16 {
+		if _, err := strconv.ParseUint(id[:len(id)-16], 16, 64); err != nil {
+			return 0, err
+		}
+		id = id[len(id)-16:]
+	}
+	return strconv.ParseUint(id, 16, 64)
+}
@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testZipkinSpans = `[
+	{
+		"traceId": "463ac35c9f6413ad48485a3953bb6124",
+		"id": "a2fb4a1d1a96d312",
+		"kind": "SERVER",
+		"name": "get /api",
+		"timestamp": 1556604172355737,
+		"duration": 1431,
+		"localEndpoint": {"serviceName": "frontend", "ipv4": "192.168.99.1"},
+		"tags": {"http.method": "GET", "http.path": "/api", "http.status_code": "500", "error": "internal error", "retries": "2"}
+	},
+	{
+		"traceId": "48485a3953bb6124",
+		"parentId": "a2fb4a1d1a96d312",
+		"id": "b2fb4a1d1a96d313",
+		"kind": "CLIENT",
+		"name": "get",
+		"timestamp": 1556604172356000,
+		"duration": 1000,
+		"localEndpoint": {"serviceName": "frontend"},
+		"remoteEndpoint": {"serviceName": "backend", "ipv4": "192.168.99.101", "port": 9000}
+	},
+	{
+		"traceId": "0000000000000001",
+		"id": "0000000000000001",
+		"name": "local",
+		"localEndpoint": {"serviceName": "frontend"}
+	}
+]`
+
+func TestDecodeZipkinTraces(t *testing.T) {
+	assert := assert.New(t)
+
+	req, err := http.NewRequest("POST", "/api/v2/spans", strings.NewReader(testZipkinSpans))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	traces, _, err := decodeZipkinTraces(req)
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	require.Len(t, traces[0], 2)
+
+	server, client := traces[0][0], traces[0][1]
+	// 128-bit IDs are truncated to their lower 64 bits, like the 64-bit ones
+	assert.Equal(uint64(0x48485a3953bb6124), server.TraceID)
+	assert.Equal(server.TraceID, client.TraceID)
+	assert.Equal(uint64(0xa2fb4a1d1a96d312), server.SpanID)
+	assert.Equal(uint64(0), server.ParentID)
+	assert.Equal("frontend", server.Service)
+	assert.Equal("zipkin.server", server.Name)
+	assert.Equal("get /api", server.Resource)
+	assert.Equal("web", server.Type)
+	assert.Equal(int64(1556604172355737000), server.Start)
+	assert.Equal(int64(1431000), server.Duration)
+	assert.Equal(int32(1), server.Error)
+	assert.Equal("internal error", server.Meta["error.msg"])
+	assert.Equal("GET", server.Meta["http.method"])
+	assert.Equal("500", server.Meta["http.status_code"])
+	assert.Equal("server", server.Meta["span.kind"])
+	assert.Equal(2.0, server.Metrics["retries"])
+
+	assert.Equal(server.SpanID, client.ParentID)
+	assert.Equal("zipkin.client", client.Name)
+	assert.Equal("http", client.Type)
+	assert.Equal(int32(0), client.Error)
+	assert.Equal("backend", client.Meta["peer.service"])
+	assert.Equal("192.168.99.101", client.Meta["out.host"])
+	assert.Equal(9000.0, client.Metrics["out.port"])
+
+	local := traces[1][0]
+	assert.Equal("zipkin.internal", local.Name)
+	assert.Equal("custom", local.Type)
+}
+
+func TestDecodeZipkinTracesNull(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v2/spans", strings.NewReader(`[null,{"traceId":"1","id":"2"},null]`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	traces, _, err := decodeZipkinTraces(req)
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0], 1)
+	assert.Equal(t, uint64(2), traces[0][0].SpanID)
+}
+
+func TestDecodeZipkinTracesErrors(t *testing.T) {
+	for name, tt := range map[string]struct {
+		contentType string
+		body        string
+	}{
+		"media-type": {"application/x-protobuf", `[]`},
+		"json":       {"application/json", `[{"traceId":`},
+		"trace-id":   {"application/json", `[{"traceId":"xyz","id":"1"}]`},
+		"long-id":    {"application/json", `[{"traceId":"463ac35c9f6413ad48485a3953bb61240","id":"1"}]`},
+		"no-id":      {"application/json", `[{"traceId":"1"}]`},
+		"parent-id":  {"application/json", `[{"traceId":"1","id":"1","parentId":"-1"}]`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/api/v2/spans", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tt.contentType)
+			_, _, err = decodeZipkinTraces(req)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHandleZipkinTraces(t *testing.T) {
+	assert := assert.New(t)
+	receiver := newTestReceiverFromConfig(newTestReceiverConfig())
+	handler := receiver.handleDecodedTraces(zipkinV2Endpoint, decodeZipkinTraces)
+
+	req, err := http.NewRequest("POST", "/api/v2/spans", strings.NewReader(testZipkinSpans))
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	assert.Equal(http.StatusAccepted, rr.Code)
+	require.Len(t, receiver.out, 1)
+	payload := <-receiver.out
+	assert.Len(payload.Traces, 2)
+	assert.Equal(zipkinV2Endpoint, payload.Source.EndpointVersion)
+	assert.Equal(int64(2), payload.Source.TracesReceived)
+	assert.Equal(int64(len(testZipkinSpans)), payload.Source.TracesBytes)
+	assert.Equal(int64(1), payload.Source.PayloadAccepted)
+
+	// decoding errors are counted in the stats of the endpoint
+	req, err = http.NewRequest("POST", "/api/v2/spans", strings.NewReader(`[{"traceId":"xyz"}]`))
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	handler(rr, req)
+	assert.Equal(http.StatusBadRequest, rr.Code)
+	assert.Len(receiver.out, 0)
+	assert.Equal(int64(1), payload.Source.TracesDropped.DecodingError)
+
+	req, err = http.NewRequest("GET", "/api/v2/spans", nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	handler(rr, req)
+	assert.Equal(http.StatusMethodNotAllowed, rr.Code)
+}
@@ -34,6 +34,18 @@ type ObfuscationConfig struct {
 	// HTTP holds the obfuscation settings for HTTP URLs.
 	HTTP HTTPObfuscationConfig `mapstructure:"http"`
 
+	// SQL holds the obfuscation settings for SQL queries.
+	SQL SQLObfuscationConfig `mapstructure:"sql"`
+
+	// GraphQL holds the obfuscation configuration for GraphQL queries.
+	GraphQL GraphQLObfuscationConfig `mapstructure:"graphql"`
+
+	// AWS holds the obfuscation configuration for the request parameters of AWS SDK spans.
+	AWS AWSObfuscationConfig `mapstructure:"aws"`
+
+	// Kafka holds the obfuscation configuration for the message keys of Kafka spans.
+	Kafka KafkaObfuscationConfig `mapstructure:"kafka"`
+
 	// RemoveStackTraces specifies whether stack traces should be removed.
 	// More specifically "error.stack" tag values will be cleared.
 	RemoveStackTraces bool `mapstructure:"remove_stack_traces"`
@@ -45,6 +57,10 @@ type ObfuscationConfig struct {
 	// Memcached holds the configuration for obfuscating the "memcached.command" tag
 	// for spans of type "memcached".
 	Memcached Enablable `mapstructure:"memcached"`
+
+	// SQLInHTTP holds the configuration for obfuscating the SQL queries found in the
+	// "sql.query" and "db.statement" tags of spans of type "web" and "http".
+	SQLInHTTP Enablable `mapstructure:"sql_in_http"`
 }
 
 // HTTPObfuscationConfig holds the configuration settings for HTTP obfuscation.
@@ -56,6 +72,17 @@ type HTTPObfuscationConfig struct {
 	RemovePathDigits bool `mapstructure:"remove_paths_with_digits"`
 }
 
+// SQLObfuscationConfig holds the configuration settings for SQL obfuscation.
+type SQLObfuscationConfig struct {
+	// Dialect specifies the dialect of the queries of "sql" spans which do not have
+	// a known "db.type" tag, e.g. "postgresql", "mysql" or "mssql".
+	Dialect string `mapstructure:"dialect"`
+
+	// CollectMetadata specifies whether the tables and the command of the queries
+	// should be added to spans in the "sql.tables" and "sql.command" tags.
+	CollectMetadata bool `mapstructure:"collect_metadata"`
+}
+
 // Enablable can represent any option that has an "enabled" boolean sub-field.
 type Enablable struct {
 	Enabled bool `mapstructure:"enabled"`
@@ -72,6 +99,124 @@ type JSONObfuscationConfig struct {
 	KeepValues []string `mapstructure:"keep_values"`
 }
 
+// GraphQLObfuscationConfig holds the obfuscation configuration for the literal
+// arguments of GraphQL queries.
+type GraphQLObfuscationConfig struct {
+	// Enabled will specify whether obfuscation should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// KeepValues will specify a set of arguments and input fields for which
+	// their values will not be obfuscated.
+	KeepValues []string `mapstructure:"keep_values"`
+}
+
+// AWSObfuscationConfig holds the obfuscation configuration for the request
+// parameters found in the tags of AWS SDK spans, e.g. DynamoDB keys.
+type AWSObfuscationConfig struct {
+	// Enabled will specify whether obfuscation should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// KeepValues will specify a set of parameters for which their values will
+	// not be obfuscated, e.g. "TableName".
+	KeepValues []string `mapstructure:"keep_values"`
+}
+
+// KafkaObfuscationConfig holds the obfuscation configuration for the message
+// keys found in the tags of Kafka spans.
+type KafkaObfuscationConfig struct {
+	// Enabled will specify whether obfuscation should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// KeepValues will specify a set of topics for which the message keys will
+	// not be obfuscated.
+	KeepValues []string `mapstructure:"keep_values"`
+}
+
+// TailSamplingConfig holds the configuration of the tail-based sampling, which buffers the
+// chunks of the traces by trace ID to apply policies to the assembled traces.
+type TailSamplingConfig struct {
+	// Enabled specifies whether traces are sampled once assembled, instead of the samplers
+	// deciding on each chunk of a trace when it is received.
+	Enabled bool `mapstructure:"enabled"`
+
+	// DecisionWait is the number of seconds during which the chunks of a trace are buffered,
+	// from the arrival of its first chunk, before deciding whether it is kept.
+	DecisionWait int `mapstructure:"decision_wait"`
+
+	// NumTraces is the maximum number of traces buffered, the oldest ones being decided early
+	// when it is reached.
+	NumTraces int `mapstructure:"num_traces"`
+
+	// MaxSpans is the maximum number of spans buffered across all traces, the oldest traces
+	// being decided early when it is reached.
+	MaxSpans int `mapstructure:"max_spans"`
+
+	// Policies are applied in order to the assembled traces, the first one matching a trace
+	// deciding whether it is kept. Traces matching no policy are dropped.
+	Policies []*TailSamplingPolicy `mapstructure:"policies"`
+}
+
+// TailSamplingPolicy specifies a policy of the tail-based sampling.
+type TailSamplingPolicy struct {
+	// Name is the name of the policy in the telemetry, it defaults to its type.
+	Name string `mapstructure:"name"`
+
+	// Type is one of "error", "latency", "attribute" or "probabilistic".
+	Type string `mapstructure:"type"`
+
+	// Rate is the rate at which the traces matched by the policy are kept, it defaults to 1.
+	Rate *float64 `mapstructure:"rate"`
+
+	// LatencyThreshold is the minimum duration in milliseconds of the traces matched by a
+	// latency policy.
+	LatencyThreshold int `mapstructure:"latency_threshold_ms"`
+
+	// Key is the tag looked up by an attribute policy.
+	Key string `mapstructure:"key"`
+
+	// Values are the values of the tag matched by an attribute policy. If empty, the tag
+	// matches whatever its value.
+	Values []string `mapstructure:"values"`
+}
+
+// validate validates the tail sampling configuration, setting the defaults of its policies.
+func (ts *TailSamplingConfig) validate() error {
+	if ts.DecisionWait <= 0 {
+		return fmt.Errorf("decision_wait must be positive, got %d", ts.DecisionWait)
+	}
+	if ts.NumTraces <= 0 || ts.MaxSpans <= 0 {
+		return errors.New("num_traces and max_spans must be positive")
+	}
+	if len(ts.Policies) == 0 {
+		return errors.New("no policies are configured")
+	}
+	for i, p := range ts.Policies {
+		switch p.Type {
+		case "error", "probabilistic":
+		case "latency":
+			if p.LatencyThreshold <= 0 {
+				return fmt.Errorf("policy %d: latency_threshold_ms must be positive", i)
+			}
+		case "attribute":
+			if p.Key == "" {
+				return fmt.Errorf("policy %d: key is required", i)
+			}
+		default:
+			return fmt.Errorf("policy %d: unknown type %q", i, p.Type)
+		}
+		if p.Rate == nil {
+			rate := 1.0
+			p.Rate = &rate
+		} else if *p.Rate < 0 || *p.Rate > 1 {
+			return fmt.Errorf("policy %d: rate must be between 0 and 1, got %f", i, *p.Rate)
+		}
+		if p.Name == "" {
+			p.Name = p.Type
+		}
+	}
+	return nil
+}
+
 // ReplaceRule specifies a replace rule.
 type ReplaceRule struct {
 	// Name specifies the name of the tag that the replace rule addresses. However,
@@ -195,6 +340,9 @@ func (c *AgentConfig) applyDatadogConfig() error {
 	if config.Datadog.IsSet("apm_config.receiver_socket") {
 		c.ReceiverSocket = config.Datadog.GetString("apm_config.receiver_socket")
 	}
+	if config.Datadog.IsSet("apm_config.otlp.grpc_port") {
+		c.OTLPGRPCPort = config.Datadog.GetInt("apm_config.otlp.grpc_port")
+	}
 	if config.Datadog.IsSet("apm_config.connection_limit") {
 		c.ConnectionLimit = config.Datadog.GetInt("apm_config.connection_limit")
 	}
@@ -248,6 +396,22 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if k := "apm_config.tail_sampling"; config.Datadog.IsSet(k) {
+		if err := config.Datadog.UnmarshalKey(k, c.TailSampling); err != nil {
+			log.Errorf("Error reading tail sampling config %q: %v", k, err)
+			c.TailSampling.Enabled = false
+		}
+	}
+	if k := "apm_config.tail_sampling.enabled"; config.Datadog.IsSet(k) {
+		c.TailSampling.Enabled = config.Datadog.GetBool(k)
+	}
+	if c.TailSampling.Enabled {
+		if err := c.TailSampling.validate(); err != nil {
+			log.Errorf("Tail sampling is disabled, its configuration is invalid: %v", err)
+			c.TailSampling.Enabled = false
+		}
+	}
+
 	// undocumented
 	if config.Datadog.IsSet("apm_config.max_cpu_percent") {
 		c.MaxCPU = config.Datadog.GetFloat64("apm_config.max_cpu_percent") / 100
@@ -27,3 +27,33 @@ func TestParseRepaceRules(t *testing.T) {
 		assert.Equal(r.Pattern, r.Re.String())
 	}
 }
+
+func TestTailSamplingValidate(t *testing.T) {
+	rate := func(f float64) *float64 { return &f }
+	newConfig := func(policies ...*TailSamplingPolicy) *TailSamplingConfig {
+		return &TailSamplingConfig{Enabled: true, DecisionWait: 10, NumTraces: 10, MaxSpans: 100, Policies: policies}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		ts := newConfig(&TailSamplingPolicy{Type: "error"}, &TailSamplingPolicy{Name: "all", Type: "probabilistic", Rate: rate(0.1)})
+		assert.NoError(t, ts.validate())
+		assert.Equal(t, "error", ts.Policies[0].Name)
+		assert.Equal(t, 1.0, *ts.Policies[0].Rate)
+		assert.Equal(t, "all", ts.Policies[1].Name)
+		assert.Equal(t, 0.1, *ts.Policies[1].Rate)
+	})
+
+	for name, ts := range map[string]*TailSamplingConfig{
+		"no-policies":   newConfig(),
+		"unknown-type":  newConfig(&TailSamplingPolicy{Type: "unknown"}),
+		"latency":       newConfig(&TailSamplingPolicy{Type: "latency"}),
+		"attribute":     newConfig(&TailSamplingPolicy{Type: "attribute"}),
+		"rate":          newConfig(&TailSamplingPolicy{Type: "error", Rate: rate(1.5)}),
+		"decision-wait": {DecisionWait: 0, NumTraces: 10, MaxSpans: 10, Policies: []*TailSamplingPolicy{{Type: "error"}}},
+		"num-traces":    {DecisionWait: 10, MaxSpans: 10, Policies: []*TailSamplingPolicy{{Type: "error"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, ts.validate())
+		})
+	}
+}
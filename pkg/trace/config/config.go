@@ -68,6 +68,7 @@ type AgentConfig struct {
 	ExtraSampleRate float64
 	MaxTPS          float64
 	MaxEPS          float64
+	TailSampling    *TailSamplingConfig
 
 	// Receiver
 	ReceiverHost    string
@@ -76,6 +77,7 @@ type AgentConfig struct {
 	ConnectionLimit int    // for rate-limiting, how many unique connections to allow in a lease period (30s)
 	ReceiverTimeout int
 	MaxRequestBytes int64 // specifies the maximum allowed request size for incoming trace payloads
+	OTLPGRPCPort    int   // if not 0, OTLP traces are received over gRPC on this port
 
 	// Writers
 	StatsWriter             *WriterConfig
@@ -131,6 +133,11 @@ func New() *AgentConfig {
 		ExtraSampleRate: 1.0,
 		MaxTPS:          10,
 		MaxEPS:          200,
+		TailSampling: &TailSamplingConfig{
+			DecisionWait: 10,
+			NumTraces:    50000,
+			MaxSpans:     500000,
+		},
 
 		ReceiverHost:    "localhost",
 		ReceiverPort:    8126,
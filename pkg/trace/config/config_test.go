@@ -158,9 +158,23 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.Equal("test", c.DefaultEnv)
 	assert.Equal(123, c.ConnectionLimit)
 	assert.Equal(18126, c.ReceiverPort)
+	assert.Equal(14317, c.OTLPGRPCPort)
 	assert.Equal(0.5, c.ExtraSampleRate)
 	assert.Equal(5.0, c.MaxTPS)
 	assert.Equal(50.0, c.MaxEPS)
+	assert.True(c.TailSampling.Enabled)
+	assert.Equal(5, c.TailSampling.DecisionWait)
+	assert.Equal(1000, c.TailSampling.NumTraces)
+	assert.Equal(500000, c.TailSampling.MaxSpans)
+	assert.Len(c.TailSampling.Policies, 3)
+	assert.Equal("error", c.TailSampling.Policies[0].Name)
+	assert.Equal(1.0, *c.TailSampling.Policies[0].Rate)
+	assert.Equal("slow", c.TailSampling.Policies[1].Name)
+	assert.Equal(500, c.TailSampling.Policies[1].LatencyThreshold)
+	assert.Equal(0.5, *c.TailSampling.Policies[1].Rate)
+	assert.Equal("http.url", c.TailSampling.Policies[2].Key)
+	assert.Equal([]string{"/health"}, c.TailSampling.Policies[2].Values)
+	assert.Equal(0.0, *c.TailSampling.Policies[2].Rate)
 	assert.Equal(0.5, c.MaxCPU)
 	assert.EqualValues(123.4, c.MaxMemory)
 	assert.Equal("0.0.0.0", c.ReceiverHost)
@@ -214,9 +228,18 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.EqualValues([]string{"uid", "cat_id"}, o.Mongo.KeepValues)
 	assert.True(o.HTTP.RemoveQueryString)
 	assert.True(o.HTTP.RemovePathDigits)
+	assert.Equal("postgresql", o.SQL.Dialect)
+	assert.True(o.SQL.CollectMetadata)
+	assert.True(o.GraphQL.Enabled)
+	assert.EqualValues([]string{"first"}, o.GraphQL.KeepValues)
+	assert.True(o.AWS.Enabled)
+	assert.EqualValues([]string{"TableName"}, o.AWS.KeepValues)
+	assert.True(o.Kafka.Enabled)
+	assert.EqualValues([]string{"metrics"}, o.Kafka.KeepValues)
 	assert.True(o.RemoveStackTraces)
 	assert.True(c.Obfuscation.Redis.Enabled)
 	assert.True(c.Obfuscation.Memcached.Enabled)
+	assert.True(c.Obfuscation.SQLInHTTP.Enabled)
 }
 
 func TestUndocumentedYamlConfig(t *testing.T) {
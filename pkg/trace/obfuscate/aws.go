@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// awsParamsPrefixes are the prefixes of the tags holding the parameters of AWS SDK
+// requests, e.g. "params.Key" or "aws.request.params.ExpressionAttributeValues".
+var awsParamsPrefixes = []string{"params.", "aws.request.params."}
+
+// awsSpanTags are the tags identifying the spans of AWS SDK requests.
+var awsSpanTags = []string{"aws.operation", "aws.service", "aws.agent"}
+
+// obfuscateAWS obfuscates the request parameters found in the tags of AWS SDK spans.
+// JSON parameters, such as DynamoDB keys and items, keep their structure.
+func (o *Obfuscator) obfuscateAWS(span *pb.Span) {
+	if o.aws == nil || !isAWSSpan(span) {
+		return
+	}
+	for k, v := range span.Meta {
+		for _, prefix := range awsParamsPrefixes {
+			if strings.HasPrefix(k, prefix) {
+				span.Meta[k] = o.aws.obfuscate(strings.TrimPrefix(k, prefix), v)
+				break
+			}
+		}
+	}
+}
+
+// isAWSSpan reports whether the given span is the span of an AWS SDK request.
+func isAWSSpan(span *pb.Span) bool {
+	if span.Type == "aws" {
+		return true
+	}
+	for _, k := range awsSpanTags {
+		if span.Meta[k] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+type awsObfuscator struct {
+	keepers map[string]bool // the values of these parameters will not be obfuscated
+	json    *jsonObfuscator // obfuscates JSON parameters
+}
+
+func newAWSObfuscator(cfg *config.AWSObfuscationConfig) *awsObfuscator {
+	keepValue := make(map[string]bool, len(cfg.KeepValues))
+	for _, v := range cfg.KeepValues {
+		keepValue[v] = true
+	}
+	return &awsObfuscator{
+		keepers: keepValue,
+		json:    newJSONObfuscator(&config.JSONObfuscationConfig{KeepValues: cfg.KeepValues}),
+	}
+}
+
+// obfuscate returns the obfuscated value of the given request parameter. The name of
+// nested parameters which are flattened in tags, e.g. "Key.id.S", starts with the name
+// of their top level parameter.
+func (p *awsObfuscator) obfuscate(param, value string) string {
+	if p.keepers[param] || p.keepers[strings.SplitN(param, ".", 2)[0]] {
+		return value
+	}
+	if trimmed := strings.TrimSpace(value); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if out, err := p.json.obfuscate([]byte(trimmed)); err == nil {
+			return out
+		}
+	}
+	return "?"
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateAWS(t *testing.T) {
+	newSpan := func() *pb.Span {
+		return &pb.Span{
+			Type: "http",
+			Meta: map[string]string{
+				"aws.operation":                          "PutItem",
+				"aws.service":                            "dynamodb",
+				"params.TableName":                       "users",
+				"params.Item":                            `{"id": {"S": "42"}, "email": {"S": "jane@example.com"}}`,
+				"params.Key.id.S":                        "42",
+				"aws.request.params.ConditionExpression": "attribute_not_exists(id)",
+				"aws.request.params.ExpressionAttributeValues": `[{"S": "secret"}]`,
+				"http.method": "POST",
+			},
+		}
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		span := newSpan()
+		o := NewObfuscator(&config.ObfuscationConfig{
+			AWS: config.AWSObfuscationConfig{Enabled: true, KeepValues: []string{"TableName", "ConditionExpression", "id"}},
+		})
+		o.Obfuscate(span)
+		assert.Equal(t, map[string]string{
+			"aws.operation":                          "PutItem",
+			"aws.service":                            "dynamodb",
+			"params.TableName":                       "users",
+			"params.Item":                            `{"id":{"S":"42"},"email":{"S":"?"}}`,
+			"params.Key.id.S":                        "?",
+			"aws.request.params.ConditionExpression": "attribute_not_exists(id)",
+			"aws.request.params.ExpressionAttributeValues": `[{"S":"?"}]`,
+			"http.method": "POST",
+		}, span.Meta)
+	})
+
+	t.Run("not-aws", func(t *testing.T) {
+		span := &pb.Span{Type: "web", Meta: map[string]string{"params.id": "42"}}
+		NewObfuscator(&config.ObfuscationConfig{AWS: config.AWSObfuscationConfig{Enabled: true}}).Obfuscate(span)
+		assert.Equal(t, "42", span.Meta["params.id"])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		span := newSpan()
+		NewObfuscator(nil).Obfuscate(span)
+		assert.Equal(t, newSpan().Meta, span.Meta)
+	})
+}
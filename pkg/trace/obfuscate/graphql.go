@@ -0,0 +1,234 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// graphQLQueryTags are the tags holding the GraphQL queries of spans of type "graphql".
+var graphQLQueryTags = []string{"graphql.query", "graphql.source"}
+
+// obfuscateGraphQL obfuscates the literal arguments of the GraphQL query found in the
+// resource and in the query tags of the span, keeping the shape of the operation.
+func (o *Obfuscator) obfuscateGraphQL(span *pb.Span) {
+	if o.graphql == nil {
+		return
+	}
+	span.Resource = o.graphql.obfuscate(span.Resource)
+	for _, k := range graphQLQueryTags {
+		if span.Meta == nil || span.Meta[k] == "" {
+			continue
+		}
+		span.Meta[k] = o.graphql.obfuscate(span.Meta[k])
+	}
+}
+
+type graphQLObfuscator struct {
+	keepers map[string]bool // the values of these arguments will not be obfuscated
+}
+
+func newGraphQLObfuscator(cfg *config.GraphQLObfuscationConfig) *graphQLObfuscator {
+	keepValue := make(map[string]bool, len(cfg.KeepValues))
+	for _, v := range cfg.KeepValues {
+		keepValue[v] = true
+	}
+	return &graphQLObfuscator{keepers: keepValue}
+}
+
+// obfuscate replaces the string, number, boolean and null literals of the given GraphQL
+// query with "?", removes its comments and compacts its whitespaces. Variables, enum values
+// and the values of the arguments in the keepers are left untouched. The whole query is
+// replaced with "?" when it can not be parsed, as it may otherwise expose literals.
+func (p *graphQLObfuscator) obfuscate(query string) string {
+	out, err := p.obfuscateQuery(query)
+	if err != nil {
+		return "?"
+	}
+	return out
+}
+
+func (p *graphQLObfuscator) obfuscateQuery(query string) (string, error) {
+	var (
+		out strings.Builder
+		tok = graphQLTokenizer{query: query}
+		// closures is the stack of the parentheses, lists and input objects enclosing the
+		// current token in arguments, true if the values they hold are kept.
+		closures []bool
+		// keep is true if the value currently scanned is kept.
+		keep bool
+		// name is the name of the last argument or input field seen.
+		name string
+	)
+	inherited := func() bool { return len(closures) > 0 && closures[len(closures)-1] }
+	for {
+		kind, text, err := tok.next()
+		if err != nil {
+			return "", err
+		}
+		if kind == graphQLEOF {
+			break
+		}
+		if kind == graphQLComment {
+			continue
+		}
+		switch {
+		case text == "(":
+			// arguments or variable definitions start, literals can only be found there
+			closures = append(closures, false)
+			keep = false
+		case len(closures) == 0:
+			// selection sets, fragments, etc.
+		case text == "[" || text == "{":
+			closures = append(closures, keep)
+		case text == ")" || text == "]" || text == "}":
+			closures = closures[:len(closures)-1]
+			keep = inherited()
+		case kind == graphQLName && tok.peek() == ':':
+			name = text
+		case text == ":" || text == "=":
+			// the value of the argument, variable or input field named before
+			keep = p.keepers[name] || inherited()
+		case kind == graphQLString || kind == graphQLNumber || kind == graphQLName && isGraphQLLiteralName(text):
+			if !keep {
+				text = "?"
+			}
+		}
+		if out.Len() > 0 && tok.spaceBefore {
+			out.WriteByte(' ')
+		}
+		out.WriteString(text)
+	}
+	return out.String(), nil
+}
+
+// isGraphQLLiteralName reports whether the given name is a literal value.
+func isGraphQLLiteralName(name string) bool {
+	return name == "true" || name == "false" || name == "null"
+}
+
+// graphQLTokenKind specifies the kind of a token scanned by a graphQLTokenizer.
+type graphQLTokenKind int
+
+const (
+	graphQLEOF graphQLTokenKind = iota
+	graphQLPunctuator
+	graphQLName
+	graphQLNumber
+	graphQLString
+	graphQLComment
+)
+
+var errGraphQLUnterminatedString = errors.New("unterminated string")
+
+// graphQLTokenizer scans the lexical tokens of a GraphQL document. Whitespaces are
+// skipped, while commas, which are not significant either, are kept as punctuators
+// so that the query can be written back as it was.
+type graphQLTokenizer struct {
+	query string
+	pos   int
+	// spaceBefore reports whether the last token scanned was preceded by whitespaces.
+	spaceBefore bool
+}
+
+// peek returns the next significant character, without consuming it.
+func (tkn *graphQLTokenizer) peek() byte {
+	for i := tkn.pos; i < len(tkn.query); i++ {
+		if !isGraphQLIgnored(tkn.query[i]) {
+			return tkn.query[i]
+		}
+	}
+	return 0
+}
+
+// next scans the next token and returns its kind and its text.
+func (tkn *graphQLTokenizer) next() (graphQLTokenKind, string, error) {
+	start := tkn.pos
+	for tkn.pos < len(tkn.query) && isGraphQLIgnored(tkn.query[tkn.pos]) && tkn.query[tkn.pos] != ',' {
+		tkn.pos++
+	}
+	tkn.spaceBefore = tkn.pos > start
+	if tkn.pos >= len(tkn.query) {
+		return graphQLEOF, "", nil
+	}
+	start = tkn.pos
+	switch ch := tkn.query[tkn.pos]; {
+	case ch == '#':
+		for tkn.pos < len(tkn.query) && tkn.query[tkn.pos] != '\n' && tkn.query[tkn.pos] != '\r' {
+			tkn.pos++
+		}
+		return graphQLComment, tkn.query[start:tkn.pos], nil
+	case ch == '"':
+		if strings.HasPrefix(tkn.query[tkn.pos:], `"""`) {
+			return tkn.scanBlockString()
+		}
+		return tkn.scanString()
+	case ch == '-' || isDigit(rune(ch)):
+		tkn.pos++
+		for tkn.pos < len(tkn.query) && isGraphQLNumberPart(tkn.query[tkn.pos]) {
+			tkn.pos++
+		}
+		return graphQLNumber, tkn.query[start:tkn.pos], nil
+	case ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z':
+		for tkn.pos < len(tkn.query) && isGraphQLNamePart(tkn.query[tkn.pos]) {
+			tkn.pos++
+		}
+		return graphQLName, tkn.query[start:tkn.pos], nil
+	case strings.HasPrefix(tkn.query[tkn.pos:], "..."):
+		tkn.pos += 3
+		return graphQLPunctuator, "...", nil
+	default:
+		tkn.pos++
+		return graphQLPunctuator, tkn.query[start:tkn.pos], nil
+	}
+}
+
+func (tkn *graphQLTokenizer) scanString() (graphQLTokenKind, string, error) {
+	start := tkn.pos
+	for tkn.pos++; tkn.pos < len(tkn.query); tkn.pos++ {
+		switch tkn.query[tkn.pos] {
+		case '\\':
+			tkn.pos++
+		case '"':
+			tkn.pos++
+			return graphQLString, tkn.query[start:tkn.pos], nil
+		case '\n', '\r':
+			return graphQLString, "", errGraphQLUnterminatedString
+		}
+	}
+	return graphQLString, "", errGraphQLUnterminatedString
+}
+
+func (tkn *graphQLTokenizer) scanBlockString() (graphQLTokenKind, string, error) {
+	start := tkn.pos
+	for tkn.pos += 3; tkn.pos < len(tkn.query); tkn.pos++ {
+		switch {
+		case strings.HasPrefix(tkn.query[tkn.pos:], `\"""`):
+			tkn.pos += 3
+		case strings.HasPrefix(tkn.query[tkn.pos:], `"""`):
+			tkn.pos += 3
+			return graphQLString, tkn.query[start:tkn.pos], nil
+		}
+	}
+	return graphQLString, "", errGraphQLUnterminatedString
+}
+
+// isGraphQLIgnored reports whether the given character is insignificant in GraphQL.
+func isGraphQLIgnored(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ','
+}
+
+func isGraphQLNamePart(ch byte) bool {
+	return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9'
+}
+
+func isGraphQLNumberPart(ch byte) bool {
+	return '0' <= ch && ch <= '9' || ch == '.' || ch == 'e' || ch == 'E' || ch == '+' || ch == '-'
+}
@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out    string
+		keepValues []string
+	}{
+		{
+			in:  `query { user(id: 42, email: "jane@example.com") { id name } }`,
+			out: `query { user(id: ?, email: ?) { id name } }`,
+		},
+		{
+			in: `query GetUsers($first: Int = 10, $after: String) {
+  # only active users
+  users(first: $first, after: $after, filter: {status: ACTIVE, tags: ["a", "b"], verified: true, deleted: null}) {
+    edges { node { id } }
+  }
+}`,
+			out: `query GetUsers($first: Int = ?, $after: String) { users(first: $first, after: $after, filter: {status: ACTIVE, tags: [?, ?], verified: ?, deleted: ?}) { edges { node { id } } } }`,
+		},
+		{
+			in:  `mutation { createPost(input: {title: """multi` + "\n" + `line \""" text""", score: -1.5e3}) { id ...PostFields @include(if: false) } }`,
+			out: `mutation { createPost(input: {title: ?, score: ?}) { id ...PostFields @include(if: ?) } }`,
+		},
+		{
+			in:         `query { users(first: 10, filter: {country: "FR", name: "jane"}, ids: [1, 2]) { id } }`,
+			out:        `query { users(first: 10, filter: {country: "FR", name: ?}, ids: [1, 2]) { id } }`,
+			keepValues: []string{"first", "country", "ids"},
+		},
+		{
+			in:  `query { user(name: "unterminated) { id } }`,
+			out: `?`,
+		},
+		{
+			in:  `GetUsers`,
+			out: `GetUsers`,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			o := NewObfuscator(&config.ObfuscationConfig{
+				GraphQL: config.GraphQLObfuscationConfig{Enabled: true, KeepValues: tt.keepValues},
+			})
+			span := &pb.Span{
+				Type:     "graphql",
+				Resource: tt.in,
+				Meta:     map[string]string{"graphql.query": tt.in},
+			}
+			o.Obfuscate(span)
+			assert.Equal(t, tt.out, span.Resource)
+			assert.Equal(t, tt.out, span.Meta["graphql.query"])
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		query := `query { user(id: 42) { id } }`
+		span := &pb.Span{Type: "graphql", Resource: query}
+		NewObfuscator(nil).Obfuscate(span)
+		assert.Equal(t, query, span.Resource)
+	})
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// kafkaKeyTags are the tags holding the key of the messages produced or consumed by spans.
+var kafkaKeyTags = []string{"kafka.message.key", "kafka.key", "messaging.kafka.message_key", "messaging.kafka.message.key"}
+
+// kafkaTopicTags are the tags holding the topic of the messages produced or consumed by spans.
+var kafkaTopicTags = []string{"kafka.topic", "messaging.destination", "messaging.destination.name"}
+
+// obfuscateKafka replaces the message keys found in the tags of the span with "?",
+// unless the messages belong to a topic whose keys are kept.
+func (o *Obfuscator) obfuscateKafka(span *pb.Span) {
+	if o.kafka == nil || span.Meta == nil {
+		return
+	}
+	for _, k := range kafkaTopicTags {
+		if topic := span.Meta[k]; topic != "" && o.kafka.keepers[topic] {
+			return
+		}
+	}
+	for _, k := range kafkaKeyTags {
+		if _, ok := span.Meta[k]; ok {
+			span.Meta[k] = "?"
+		}
+	}
+}
+
+type kafkaObfuscator struct {
+	keepers map[string]bool // the message keys of these topics will not be obfuscated
+}
+
+func newKafkaObfuscator(cfg *config.KafkaObfuscationConfig) *kafkaObfuscator {
+	keepValue := make(map[string]bool, len(cfg.KeepValues))
+	for _, v := range cfg.KeepValues {
+		keepValue[v] = true
+	}
+	return &kafkaObfuscator{keepers: keepValue}
+}
@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateKafka(t *testing.T) {
+	o := NewObfuscator(&config.ObfuscationConfig{
+		Kafka: config.KafkaObfuscationConfig{Enabled: true, KeepValues: []string{"metrics"}},
+	})
+	for _, tt := range []struct {
+		meta map[string]string
+		key  string
+		out  string
+	}{
+		{map[string]string{"kafka.topic": "orders", "kafka.message.key": "customer-42"}, "kafka.message.key", "?"},
+		{map[string]string{"messaging.destination": "orders", "messaging.kafka.message_key": "customer-42"}, "messaging.kafka.message_key", "?"},
+		{map[string]string{"kafka.topic": "metrics", "kafka.message.key": "host-1"}, "kafka.message.key", "host-1"},
+		{map[string]string{"kafka.topic": "orders", "kafka.partition": "3"}, "kafka.partition", "3"},
+	} {
+		span := &pb.Span{Type: "queue", Meta: tt.meta}
+		o.Obfuscate(span)
+		assert.Equal(t, tt.out, span.Meta[tt.key])
+	}
+
+	span := &pb.Span{Type: "queue", Meta: map[string]string{"kafka.message.key": "customer-42"}}
+	NewObfuscator(nil).Obfuscate(span)
+	assert.Equal(t, "customer-42", span.Meta["kafka.message.key"])
+}
@@ -13,14 +13,18 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Obfuscator quantizes and obfuscates spans. The obfuscator is not safe for
 // concurrent use.
 type Obfuscator struct {
-	opts  *config.ObfuscationConfig
-	es    *jsonObfuscator // nil if disabled
-	mongo *jsonObfuscator // nil if disabled
+	opts    *config.ObfuscationConfig
+	es      *jsonObfuscator    // nil if disabled
+	mongo   *jsonObfuscator    // nil if disabled
+	graphql *graphQLObfuscator // nil if disabled
+	aws     *awsObfuscator     // nil if disabled
+	kafka   *kafkaObfuscator   // nil if disabled
 	// sqlLiteralEscapes reports whether we should treat escape characters literally or as escape characters.
 	// A non-zero value means 'yes'. Different SQL engines behave in different ways and the tokenizer needs
 	// to be generic.
@@ -28,6 +32,8 @@ type Obfuscator struct {
 	sqlLiteralEscapes int32
 	// queryCache keeps a cache of already obfuscated queries.
 	queryCache *measuredCache
+	// defaultSQLDialect is the dialect of the SQL queries of spans without a known "db.type" tag.
+	defaultSQLDialect SQLDialect
 }
 
 // SetSQLLiteralEscapes sets whether or not escape characters should be treated literally by the SQL obfuscator.
@@ -59,6 +65,21 @@ func NewObfuscator(cfg *config.ObfuscationConfig) *Obfuscator {
 	if cfg.Mongo.Enabled {
 		o.mongo = newJSONObfuscator(&cfg.Mongo)
 	}
+	if cfg.GraphQL.Enabled {
+		o.graphql = newGraphQLObfuscator(&cfg.GraphQL)
+	}
+	if cfg.AWS.Enabled {
+		o.aws = newAWSObfuscator(&cfg.AWS)
+	}
+	if cfg.Kafka.Enabled {
+		o.kafka = newKafkaObfuscator(&cfg.Kafka)
+	}
+	if cfg.SQL.Dialect != "" {
+		o.defaultSQLDialect = SQLDialectFromDBType(cfg.SQL.Dialect)
+		if o.defaultSQLDialect == DialectGeneric {
+			log.Warnf("Unknown SQL dialect %q, SQL queries will be obfuscated without dialect specific rules", cfg.SQL.Dialect)
+		}
+	}
 	return &o
 }
 
@@ -82,11 +103,20 @@ func (o *Obfuscator) Obfuscate(span *pb.Span) {
 		}
 	case "web", "http":
 		o.obfuscateHTTP(span)
+		if o.opts.SQLInHTTP.Enabled {
+			o.obfuscateSQLTags(span)
+		}
 	case "mongodb":
 		o.obfuscateJSON(span, "mongodb.query", o.mongo)
 	case "elasticsearch":
 		o.obfuscateJSON(span, "elasticsearch.body", o.es)
+	case "graphql":
+		o.obfuscateGraphQL(span)
 	}
+	// AWS SDK and messaging spans are recognized by their tags, as their type
+	// depends on the tracer which created them.
+	o.obfuscateAWS(span)
+	o.obfuscateKafka(span)
 }
 
 // compactWhitespaces compacts all whitespaces in t.
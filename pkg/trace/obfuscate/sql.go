@@ -102,7 +102,7 @@ func (f *replaceFilter) Filter(token, lastToken TokenKind, buffer []byte) (token
 		}
 	}
 	switch token {
-	case String, Number, Null, Variable, PreparedStatement, BooleanLiteral, EscapeSequence:
+	case String, Number, Null, Variable, PreparedStatement, BooleanLiteral, EscapeSequence, DollarQuotedString:
 		return FilteredGroupable, []byte("?"), nil
 	default:
 		return token, buffer, nil
@@ -165,26 +165,56 @@ func (f *groupingFilter) Reset() {
 // some elements such as comments and aliases and obfuscation attempts to hide sensitive information
 // in strings and numbers by redacting them.
 func (o *Obfuscator) ObfuscateSQLString(in string) (*ObfuscatedQuery, error) {
-	if v, ok := o.queryCache.Get(in); ok {
+	return o.ObfuscateSQLStringForDialect(in, DialectGeneric)
+}
+
+// ObfuscateSQLStringForDialect quantizes and obfuscates the given input SQL query string like
+// ObfuscateSQLString, scanning it with the rules of the given SQL dialect.
+func (o *Obfuscator) ObfuscateSQLStringForDialect(in string, dialect SQLDialect) (*ObfuscatedQuery, error) {
+	key := in
+	if dialect != DialectGeneric {
+		// the same query may be obfuscated differently in each dialect
+		key = string(dialect) + "\x00" + in
+	}
+	if v, ok := o.queryCache.Get(key); ok {
 		return v.(*ObfuscatedQuery), nil
 	}
-	oq, err := o.obfuscateSQLString(in)
+	oq, err := o.obfuscateSQLStringForDialect(in, dialect)
 	if err != nil {
 		return oq, err
 	}
-	o.queryCache.Set(in, oq, oq.Cost())
+	o.queryCache.Set(key, oq, oq.Cost())
 	return oq, nil
 }
 
+func (o *Obfuscator) obfuscateSQLStringForDialect(in string, dialect SQLDialect) (*ObfuscatedQuery, error) {
+	if dialect == DialectGeneric {
+		return o.obfuscateSQLString(in)
+	}
+	// the escaping rules of the dialects are known, the opposite ones are only
+	// tried for servers which changed them (e.g. NO_BACKSLASH_ESCAPES in MySQL)
+	collectMetadata := o.opts.SQL.CollectMetadata
+	lesc := dialect.literalEscapes()
+	tok := NewSQLDialectTokenizer(in, lesc, dialect)
+	out, err := attemptObfuscation(tok, collectMetadata)
+	if err != nil && tok.SeenEscape() {
+		if out, err2 := attemptObfuscation(NewSQLDialectTokenizer(in, !lesc, dialect), collectMetadata); err2 == nil {
+			return out, nil
+		}
+	}
+	return out, err
+}
+
 func (o *Obfuscator) obfuscateSQLString(in string) (*ObfuscatedQuery, error) {
+	collectMetadata := o.opts.SQL.CollectMetadata
 	lesc := o.SQLLiteralEscapes()
 	tok := NewSQLTokenizer(in, lesc)
-	out, err := attemptObfuscation(tok)
+	out, err := attemptObfuscation(tok, collectMetadata)
 	if err != nil && tok.SeenEscape() {
 		// If the tokenizer failed, but saw an escape character in the process,
 		// try again treating escapes differently
 		tok = NewSQLTokenizer(in, !lesc)
-		if out, err2 := attemptObfuscation(tok); err2 == nil {
+		if out, err2 := attemptObfuscation(tok, collectMetadata); err2 == nil {
 			// If the second attempt succeeded, change the default behavior so that
 			// on the next run we get it right in the first run.
 			o.SetSQLLiteralEscapes(!lesc)
@@ -209,7 +239,7 @@ func (f *tableFinderFilter) Filter(token, lastToken TokenKind, buffer []byte) (T
 	case From:
 		// SELECT ... FROM [tableName]
 		// DELETE FROM [tableName]
-		if r, _ := utf8.DecodeRune(buffer); !unicode.IsLetter(r) {
+		if r, _ := utf8.DecodeRune(buffer); token != ID && !unicode.IsLetter(r) {
 			// first character in buffer is not a letter; we might have a nested
 			// query like SELECT * FROM (SELECT ...)
 			break
@@ -250,30 +280,70 @@ func (f *tableFinderFilter) Reset() {
 	f.csv.Reset()
 }
 
+// commandFinderFilter is a filter which identifies the command of a query, e.g. SELECT,
+// as the first keyword of the query.
+type commandFinderFilter struct {
+	// done reports whether the first keyword of the query was seen.
+	done    bool
+	command string
+}
+
+// Filter implements tokenFilter.
+func (f *commandFinderFilter) Filter(token, lastToken TokenKind, buffer []byte) (TokenKind, []byte, error) {
+	if f.done || buffer == nil || token == '(' {
+		// discarded tokens such as comments and opening parenthesis
+		// are skipped, e.g. in /* comment */ (SELECT ...)
+		return token, buffer, nil
+	}
+	f.done = true
+	switch token {
+	case ID, Update, Insert:
+		if isPlainIdentifier(buffer) {
+			f.command = strings.ToUpper(string(buffer))
+		}
+	}
+	return token, buffer, nil
+}
+
+// Command returns the command of the query, or an empty string if none was found.
+func (f *commandFinderFilter) Command() string { return f.command }
+
+// Reset implements tokenFilter.
+func (f *commandFinderFilter) Reset() {
+	f.done = false
+	f.command = ""
+}
+
 // ObfuscatedQuery specifies information about an obfuscated SQL query.
 type ObfuscatedQuery struct {
 	Query     string // the obfuscated SQL query
 	TablesCSV string // comma-separated list of tables that the query addresses
+	Command   string // the command of the query (e.g. SELECT), only set when collecting metadata
 }
 
 // Cost returns the number of bytes needed to store all the fields
 // of this ObfuscatedQuery.
 func (oq *ObfuscatedQuery) Cost() int64 {
-	return int64(len(oq.Query) + len(oq.TablesCSV))
+	return int64(len(oq.Query) + len(oq.TablesCSV) + len(oq.Command))
 }
 
 // attemptObfuscation attempts to obfuscate the SQL query loaded into the tokenizer, using the
-// given set of filters.
-func attemptObfuscation(tokenizer *SQLTokenizer) (*ObfuscatedQuery, error) {
+// given set of filters. When collectMetadata is true, the tables and the command of the query
+// are also extracted.
+func attemptObfuscation(tokenizer *SQLTokenizer, collectMetadata bool) (*ObfuscatedQuery, error) {
 	filters := []tokenFilter{
 		&discardFilter{},
 		&replaceFilter{},
 		&groupingFilter{},
 	}
 	tableFinder := &tableFinderFilter{}
-	if config.HasFeature("table_names") {
+	if collectMetadata || config.HasFeature("table_names") {
 		filters = append(filters, tableFinder)
 	}
+	commandFinder := &commandFinderFilter{}
+	if collectMetadata {
+		filters = append(filters, commandFinder)
+	}
 	var (
 		out       bytes.Buffer
 		err       error
@@ -320,14 +390,49 @@ func attemptObfuscation(tokenizer *SQLTokenizer) (*ObfuscatedQuery, error) {
 	return &ObfuscatedQuery{
 		Query:     out.String(),
 		TablesCSV: tableFinder.CSV(),
+		Command:   commandFinder.Command(),
 	}, nil
 }
 
+// sqlDialect returns the SQL dialect of the query of the given span, based on its "db.type" tag,
+// or the configured one for spans of type "sql".
+func (o *Obfuscator) sqlDialect(span *pb.Span) SQLDialect {
+	if dialect := SQLDialectFromDBType(span.Meta["db.type"]); dialect != DialectGeneric {
+		return dialect
+	}
+	if span.Type != "sql" {
+		// e.g. cassandra
+		return DialectGeneric
+	}
+	return o.defaultSQLDialect
+}
+
+// sqlTags are the tags holding the SQL queries sent by spans which are not of type "sql",
+// e.g. the HTTP requests sent to databases with an HTTP API.
+var sqlTags = []string{sqlQueryTag, "db.statement"}
+
+// obfuscateSQLTags obfuscates the SQL queries found in the tags of the span. Unlike
+// obfuscateSQL, it leaves the resource of the span untouched.
+func (o *Obfuscator) obfuscateSQLTags(span *pb.Span) {
+	for _, k := range sqlTags {
+		if span.Meta == nil || span.Meta[k] == "" {
+			continue
+		}
+		oq, err := o.ObfuscateSQLStringForDialect(span.Meta[k], o.sqlDialect(span))
+		if err != nil {
+			log.Debugf("Error parsing SQL query: %v. Tag %s: %q", err, k, span.Meta[k])
+			span.Meta[k] = nonParsableResource
+			continue
+		}
+		span.Meta[k] = oq.Query
+	}
+}
+
 func (o *Obfuscator) obfuscateSQL(span *pb.Span) {
 	if span.Resource == "" {
 		return
 	}
-	oq, err := o.ObfuscateSQLString(span.Resource)
+	oq, err := o.ObfuscateSQLStringForDialect(span.Resource, o.sqlDialect(span))
 	if err != nil {
 		// we have an error, discard the SQL to avoid polluting user resources.
 		log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
@@ -346,6 +451,9 @@ func (o *Obfuscator) obfuscateSQL(span *pb.Span) {
 	if len(oq.TablesCSV) > 0 {
 		traceutil.SetMeta(span, "sql.tables", oq.TablesCSV)
 	}
+	if len(oq.Command) > 0 {
+		traceutil.SetMeta(span, "sql.command", oq.Command)
+	}
 	if span.Meta != nil && span.Meta[sqlQueryTag] != "" {
 		// "sql.query" tag already set by user, do not change it.
 		return
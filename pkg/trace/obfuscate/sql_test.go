@@ -13,6 +13,7 @@ import (
 	"sync/atomic"
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/stretchr/testify/assert"
 )
@@ -1155,3 +1156,169 @@ func TestUnicodeDigit(t *testing.T) {
 	o := NewObfuscator(nil)
 	o.ObfuscateSQLString(hangStr)
 }
+
+func TestSQLDialects(t *testing.T) {
+	for dialect, cases := range map[SQLDialect][]sqlTestCase{
+		DialectPostgreSQL: {
+			{
+				`SELECT * FROM users WHERE id = $1 AND name = $2`,
+				`SELECT * FROM users WHERE id = $1 AND name = $2`,
+			},
+			{
+				`SELECT * FROM users WHERE id IN ($1, $2, 3)`,
+				`SELECT * FROM users WHERE id IN ( $1, $2, ? )`,
+			},
+			{
+				`SELECT $$it's a secret$$ FROM users`,
+				`SELECT ? FROM users`,
+			},
+			{
+				`SELECT $tag$nested $$ quotes$tag$ FROM users WHERE "user"."id" = 42`,
+				`SELECT ? FROM users WHERE user.id = ?`,
+			},
+			{
+				`SELECT id::text FROM public."Order Items" WHERE price > 10.5 LIMIT $1`,
+				`SELECT id :: text FROM public."Order Items" WHERE price > ? LIMIT $1`,
+			},
+			{
+				`SELECT * FROM users WHERE name = E'O\'Reilly' AND path = 'C:\temp\'`,
+				`SELECT * FROM users WHERE name = ? AND path = ?`,
+			},
+			{
+				`SELECT $$unterminated`,
+				`Non-parsable SQL query`,
+			},
+		},
+		DialectMySQL: {
+			{
+				"SELECT `u`.`id`, `order-items`.`qty` FROM `shop`.`order-items` JOIN `u` ON `u`.`id` = `order-items`.`user_id`",
+				"SELECT u.id, `order-items`.qty FROM shop.`order-items` JOIN u ON u.id = `order-items`.user_id",
+			},
+			{
+				"SELECT * FROM `we``ird` WHERE `my col` = 'it\\'s' AND name = \"john\"",
+				"SELECT * FROM `we``ird` WHERE `my col` = ? AND name = ?",
+			},
+			{
+				"INSERT INTO `users` (`id`, `name`) VALUES (1, \"a\"), (2, \"b\")",
+				"INSERT INTO users ( id, name ) VALUES ( ? )",
+			},
+			{
+				"SELECT * FROM `users",
+				"Non-parsable SQL query",
+			},
+		},
+		DialectMSSQL: {
+			{
+				`SELECT [b].[BlogId], [b].[Name] FROM [dbo].[Blogs] AS [b] WHERE [b].[Url] = N'http://example.com' ORDER BY [b].[Name]`,
+				`SELECT b.BlogId, b.Name FROM dbo.Blogs WHERE b.Url = ? ORDER BY b.Name`,
+			},
+			{
+				`SELECT TOP 10 * FROM dbo.[Order Details] WHERE [Unit Price] > 10 AND "Quantity" = @qty`,
+				`SELECT TOP ? * FROM dbo.[Order Details] WHERE [Unit Price] > ? AND Quantity = @qty`,
+			},
+			{
+				`UPDATE [dbo].[we]]ird] SET a = 'it''s'`,
+				`UPDATE dbo.[we]]ird] SET a = ?`,
+			},
+			{
+				`SELECT [b].[BlogId] FROM [Blogs] AS [b`,
+				`Non-parsable SQL query`,
+			},
+			{
+				`SELECT @x := 1`,
+				`SELECT @x := ?`,
+			},
+		},
+	} {
+		t.Run(string(dialect), func(t *testing.T) {
+			for _, c := range cases {
+				span := SQLSpan(c.query)
+				span.Meta["db.type"] = string(dialect)
+				NewObfuscator(nil).Obfuscate(span)
+				assert.Equal(t, c.expected, span.Resource)
+			}
+		})
+	}
+}
+
+func TestSQLDialectSelection(t *testing.T) {
+	assert := assert.New(t)
+	query := `SELECT * FROM users WHERE id = $1`
+	o := NewObfuscator(&config.ObfuscationConfig{SQL: config.SQLObfuscationConfig{Dialect: "postgresql"}})
+
+	// the dialect is taken from the "db.type" tag first
+	span := SQLSpan(query)
+	span.Meta["db.type"] = "mysql"
+	o.Obfuscate(span)
+	assert.Equal(`SELECT * FROM users WHERE id = ?`, span.Resource)
+
+	// then from the configuration
+	span = SQLSpan(query)
+	o.Obfuscate(span)
+	assert.Equal(`SELECT * FROM users WHERE id = $1`, span.Resource)
+
+	// which does not apply to cassandra queries
+	span = CassSpan(query)
+	o.Obfuscate(span)
+	assert.Equal(`SELECT * FROM users WHERE id = ?`, span.Resource)
+
+	assert.Equal(DialectMSSQL, SQLDialectFromDBType("SQLServer"))
+	assert.Equal(DialectGeneric, SQLDialectFromDBType("oracle"))
+}
+
+func TestSQLCollectMetadata(t *testing.T) {
+	t.Run("on", func(t *testing.T) {
+		o := NewObfuscator(&config.ObfuscationConfig{SQL: config.SQLObfuscationConfig{CollectMetadata: true}})
+		for _, tt := range []struct {
+			query, dbType, command, tables string
+		}{
+			{"SELECT * FROM users WHERE id = 42", "", "SELECT", "users"},
+			{"/* comment */ delete from sessions where user_id = 'abc'", "", "DELETE", "sessions"},
+			{"(SELECT 1) UNION (SELECT 2)", "", "SELECT", ""},
+			{"INSERT INTO `shop`.`orders` (id) VALUES (1)", "mysql", "INSERT", "shop.orders"},
+			{"UPDATE [dbo].[Order Details] SET qty = 2", "mssql", "UPDATE", "dbo.[Order Details]"},
+		} {
+			span := SQLSpan(tt.query)
+			span.Meta["db.type"] = tt.dbType
+			o.Obfuscate(span)
+			assert.Equal(t, tt.command, span.Meta["sql.command"], tt.query)
+			assert.Equal(t, tt.tables, span.Meta["sql.tables"], tt.query)
+			assert.NotContains(t, span.Resource, "42")
+		}
+	})
+
+	t.Run("off", func(t *testing.T) {
+		span := SQLSpan("SELECT * FROM users WHERE id = 42")
+		NewObfuscator(nil).Obfuscate(span)
+		assert.Empty(t, span.Meta["sql.command"])
+		assert.Empty(t, span.Meta["sql.tables"])
+	})
+}
+
+func TestSQLInHTTP(t *testing.T) {
+	newSpan := func() *pb.Span {
+		return &pb.Span{
+			Type:     "http",
+			Resource: "POST /query",
+			Meta: map[string]string{
+				"db.type":      "postgresql",
+				"db.statement": "SELECT * FROM users WHERE email = 'jane@example.com' AND id = $1",
+				"sql.query":    "SELECT * FROM users WHERE id = '1",
+			},
+		}
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		span := newSpan()
+		NewObfuscator(&config.ObfuscationConfig{SQLInHTTP: config.Enablable{Enabled: true}}).Obfuscate(span)
+		assert.Equal(t, "POST /query", span.Resource)
+		assert.Equal(t, "SELECT * FROM users WHERE email = ? AND id = $1", span.Meta["db.statement"])
+		assert.Equal(t, nonParsableResource, span.Meta["sql.query"])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		span := newSpan()
+		NewObfuscator(nil).Obfuscate(span)
+		assert.Equal(t, newSpan(), span)
+	})
+}
@@ -60,6 +60,14 @@ const (
 	Join
 	ColonCast
 
+	// DollarQuotedString specifies a PostgreSQL dollar-quoted string constant,
+	// e.g. $$text$$ or $tag$text$tag$.
+	DollarQuotedString
+
+	// PositionalParameter specifies a PostgreSQL positional parameter, e.g. $1.
+	// Unlike PreparedStatement, it is only scanned by the PostgreSQL dialect.
+	PositionalParameter
+
 	// FilteredGroupable specifies that the given token has been discarded by one of the
 	// token filters and that it is groupable together with consecutive FilteredGroupable
 	// tokens.
@@ -77,6 +85,52 @@ const (
 
 const escapeCharacter = '\\'
 
+// SQLDialect specifies the SQL dialect of the queries scanned by a SQLTokenizer.
+type SQLDialect string
+
+// List of supported SQL dialects.
+const (
+	// DialectGeneric scans queries without any dialect specific rule.
+	DialectGeneric SQLDialect = ""
+
+	// DialectPostgreSQL scans dollar-quoted strings ($$text$$), positional
+	// parameters ($1), double-quoted identifiers and E'' escape strings.
+	DialectPostgreSQL SQLDialect = "postgresql"
+
+	// DialectMySQL scans backtick quoted identifiers and double-quoted strings.
+	DialectMySQL SQLDialect = "mysql"
+
+	// DialectMSSQL scans bracketed and double-quoted identifiers and N'' strings.
+	DialectMSSQL SQLDialect = "mssql"
+)
+
+// sqlDialects maps the database types found in the "db.type" tag of spans, or
+// in the configuration, to the dialect of their queries.
+var sqlDialects = map[string]SQLDialect{
+	"postgres":   DialectPostgreSQL,
+	"postgresql": DialectPostgreSQL,
+	"pg":         DialectPostgreSQL,
+	"mysql":      DialectMySQL,
+	"mariadb":    DialectMySQL,
+	"mssql":      DialectMSSQL,
+	"sqlserver":  DialectMSSQL,
+	"sql server": DialectMSSQL,
+}
+
+// SQLDialectFromDBType returns the SQL dialect of the given database type, or
+// DialectGeneric if it is unknown.
+func SQLDialectFromDBType(dbType string) SQLDialect {
+	return sqlDialects[strings.ToLower(strings.TrimSpace(dbType))]
+}
+
+// literalEscapes reports whether backslashes are treated literally in the strings of the dialect.
+// It should not be called on DialectGeneric, for which this depends on the queries seen so far.
+func (d SQLDialect) literalEscapes() bool {
+	// MySQL is the only dialect treating backslashes as escape characters by
+	// default; PostgreSQL only does so in E'' strings.
+	return d != DialectMySQL
+}
+
 // SQLTokenizer is the struct used to generate SQL
 // tokens for the parser.
 type SQLTokenizer struct {
@@ -85,16 +139,24 @@ type SQLTokenizer struct {
 	lastChar rune            // last read rune
 	err      error           // any error occurred while reading
 
-	literalEscapes bool // indicates we should not treat backslashes as escape characters
-	seenEscape     bool // indicates whether this tokenizer has seen an escape character within a string
+	literalEscapes bool       // indicates we should not treat backslashes as escape characters
+	seenEscape     bool       // indicates whether this tokenizer has seen an escape character within a string
+	dialect        SQLDialect // the dialect of the scanned query
 }
 
 // NewSQLTokenizer creates a new SQLTokenizer for the given SQL string. The literalEscapes argument specifies
 // whether escape characters should be treated literally or as such.
 func NewSQLTokenizer(sql string, literalEscapes bool) *SQLTokenizer {
+	return NewSQLDialectTokenizer(sql, literalEscapes, DialectGeneric)
+}
+
+// NewSQLDialectTokenizer creates a new SQLTokenizer for the given SQL string, scanning it with the rules
+// of the given dialect.
+func NewSQLDialectTokenizer(sql string, literalEscapes bool, dialect SQLDialect) *SQLTokenizer {
 	return &SQLTokenizer{
 		rd:             strings.NewReader(sql),
 		literalEscapes: literalEscapes,
+		dialect:        dialect,
 	}
 }
 
@@ -159,6 +221,9 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			}
 			fallthrough
 		case '=', ',', ';', '(', ')', '+', '*', '&', '|', '^', '~', '[', ']', '?':
+			if ch == '[' && tkn.dialect == DialectMSSQL {
+				return tkn.scanQuotedIdentifier('[', ']')
+			}
 			return TokenKind(ch), runeBytes(ch)
 		case '.':
 			if isDigit(tkn.lastChar) {
@@ -218,8 +283,18 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 		case '\'':
 			return tkn.scanString(ch, String)
 		case '"':
+			switch tkn.dialect {
+			case DialectPostgreSQL, DialectMSSQL:
+				return tkn.scanQuotedIdentifier('"', '"')
+			case DialectMySQL:
+				// unless ANSI_QUOTES is enabled, double-quoted text is a string
+				return tkn.scanString(ch, String)
+			}
 			return tkn.scanString(ch, DoubleQuotedString)
 		case '`':
+			if tkn.dialect == DialectMySQL {
+				return tkn.scanQuotedIdentifier('`', '`')
+			}
 			return tkn.scanLiteralIdentifier('`')
 		case '%':
 			if tkn.lastChar == '(' {
@@ -232,6 +307,12 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			// modulo operator (e.g. 'id % 8')
 			return TokenKind(ch), runeBytes(ch)
 		case '$':
+			if tkn.dialect == DialectPostgreSQL {
+				if isDigit(tkn.lastChar) {
+					return tkn.scanPositionalParameter()
+				}
+				return tkn.scanDollarQuotedString()
+			}
 			return tkn.scanPreparedStatement('$')
 		case '{':
 			return tkn.scanEscapeSequence('{')
@@ -258,12 +339,113 @@ func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
 		tkn.next()
 	}
 	upper := bytes.ToUpper(buffer.Bytes())
+	if tkn.lastChar == '\'' {
+		switch {
+		case tkn.dialect == DialectPostgreSQL && string(upper) == "E":
+			// escape string constant, e.g. E'it\'s'
+			tkn.next()
+			literalEscapes := tkn.literalEscapes
+			tkn.literalEscapes = false
+			defer func() { tkn.literalEscapes = literalEscapes }()
+			return tkn.scanString('\'', String)
+		case tkn.dialect == DialectMSSQL && string(upper) == "N":
+			// unicode string constant, e.g. N'text'
+			tkn.next()
+			return tkn.scanString('\'', String)
+		}
+	}
 	if keywordID, found := keywords[string(upper)]; found {
 		return keywordID, buffer.Bytes()
 	}
+	if bytes.HasSuffix(buffer.Bytes(), []byte(".")) {
+		// a qualified name continued by a quoted identifier, e.g. dbo.[users]
+		if opening, closing, ok := tkn.identifierQuotes(); ok {
+			tkn.next()
+			return tkn.scanQualifiedIdentifier(buffer, opening, closing)
+		}
+	}
 	return ID, buffer.Bytes()
 }
 
+// identifierQuotes returns the quotes delimiting the identifier starting at the current character
+// in the dialect of the tokenizer, if any.
+func (tkn *SQLTokenizer) identifierQuotes() (opening, closing rune, ok bool) {
+	switch {
+	case tkn.dialect == DialectMySQL && tkn.lastChar == '`':
+		return '`', '`', true
+	case tkn.dialect == DialectMSSQL && tkn.lastChar == '[':
+		return '[', ']', true
+	case (tkn.dialect == DialectPostgreSQL || tkn.dialect == DialectMSSQL) && tkn.lastChar == '"':
+		return '"', '"', true
+	}
+	return 0, 0, false
+}
+
+// scanQuotedIdentifier scans an identifier enclosed in the given quotes, along with the rest of its
+// qualified name, e.g. `db`.`table` or [dbo].[table]. The opening quote has already been consumed.
+// Unlike scanLiteralIdentifier, it allows any character in the identifier.
+func (tkn *SQLTokenizer) scanQuotedIdentifier(opening, closing rune) (TokenKind, []byte) {
+	return tkn.scanQualifiedIdentifier(&bytes.Buffer{}, opening, closing)
+}
+
+// scanQualifiedIdentifier appends the quoted identifier starting after the opening quote, and the parts
+// of the qualified name following it, to buffer.
+func (tkn *SQLTokenizer) scanQualifiedIdentifier(buffer *bytes.Buffer, opening, closing rune) (TokenKind, []byte) {
+	for {
+		part := &bytes.Buffer{}
+		for {
+			ch := tkn.lastChar
+			if ch == EOFChar {
+				tkn.setErr(`unexpected EOF in quoted identifier, expected "%c"`, closing)
+				return LexError, buffer.Bytes()
+			}
+			tkn.next()
+			if ch == closing {
+				if tkn.lastChar != closing {
+					break
+				}
+				// doubling the closing quote embeds it within the identifier
+				tkn.next()
+			}
+			part.WriteRune(ch)
+		}
+		if part.Len() == 0 {
+			tkn.setErr("empty quoted identifier")
+			return LexError, buffer.Bytes()
+		}
+		if isPlainIdentifier(part.Bytes()) {
+			buffer.Write(part.Bytes())
+		} else {
+			// keep the quotes of identifiers which could not be told apart from
+			// the rest of the query without them
+			buffer.WriteRune(opening)
+			quote := runeBytes(closing)
+			buffer.Write(bytes.Replace(part.Bytes(), quote, append(quote, quote...), -1))
+			buffer.WriteRune(closing)
+		}
+		if tkn.lastChar != '.' {
+			return ID, buffer.Bytes()
+		}
+		buffer.WriteRune('.')
+		tkn.next()
+		for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || tkn.lastChar == '.' || tkn.lastChar == '*' {
+			tkn.consumeNext(buffer)
+		}
+		var ok bool
+		if opening, closing, ok = tkn.identifierQuotes(); !ok || !bytes.HasSuffix(buffer.Bytes(), []byte(".")) {
+			return ID, buffer.Bytes()
+		}
+		tkn.next()
+	}
+}
+
+// isPlainIdentifier reports whether the given identifier is only made of letters, digits and underscores.
+func isPlainIdentifier(ident []byte) bool {
+	return len(ident) > 0 && bytes.IndexFunc(ident, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) == -1
+}
+
 func (tkn *SQLTokenizer) scanLiteralIdentifier(quote rune) (TokenKind, []byte) {
 	buffer := &bytes.Buffer{}
 	buffer.WriteRune(tkn.lastChar)
@@ -333,6 +515,44 @@ func (tkn *SQLTokenizer) scanPreparedStatement(prefix rune) (TokenKind, []byte)
 	return PreparedStatement, buffer.Bytes()
 }
 
+// scanPositionalParameter scans a PostgreSQL positional parameter such as $1, the '$' has already
+// been consumed.
+func (tkn *SQLTokenizer) scanPositionalParameter() (TokenKind, []byte) {
+	buffer := bytes.NewBufferString("$")
+	for isDigit(tkn.lastChar) {
+		tkn.consumeNext(buffer)
+	}
+	return PositionalParameter, buffer.Bytes()
+}
+
+// scanDollarQuotedString scans a PostgreSQL dollar-quoted string such as $$text$$ or $tag$text$tag$,
+// the first '$' has already been consumed.
+func (tkn *SQLTokenizer) scanDollarQuotedString() (TokenKind, []byte) {
+	tag := bytes.NewBufferString("$")
+	for tkn.lastChar != '$' {
+		if !isLetter(tkn.lastChar) && !isDigit(tkn.lastChar) || tkn.lastChar == '#' || tkn.lastChar == '@' {
+			tkn.setErr(`unexpected character "%c" (%d) in dollar-quote tag`, tkn.lastChar, tkn.lastChar)
+			return LexError, tag.Bytes()
+		}
+		tkn.consumeNext(tag)
+	}
+	tkn.consumeNext(tag)
+	delim := tag.Bytes()
+
+	buffer := &bytes.Buffer{}
+	for {
+		if tkn.lastChar == EOFChar {
+			tkn.setErr("unexpected EOF in dollar-quoted string")
+			return LexError, buffer.Bytes()
+		}
+		tkn.consumeNext(buffer)
+		if bytes.HasSuffix(buffer.Bytes(), delim) {
+			buffer.Truncate(buffer.Len() - len(delim))
+			return DollarQuotedString, buffer.Bytes()
+		}
+	}
+}
+
 func (tkn *SQLTokenizer) scanEscapeSequence(braces rune) (TokenKind, []byte) {
 	buffer := &bytes.Buffer{}
 	buffer.WriteRune(braces)
@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// Package jaeger contains the Jaeger data model, as sent by the Jaeger clients to the
+// HTTP endpoint of the Jaeger collector: a Batch of spans encoded with the Thrift binary
+// protocol (see jaeger.thrift in the jaeger-idl repository). The structures keep the
+// field IDs of their Thrift definition and are decoded by hand (see thrift.go), to avoid
+// depending on the Thrift library.
+package jaeger
+
+// TagType is the type of the value of a tag.
+type TagType int32
+
+// Tag types.
+const (
+	TagTypeString TagType = 0
+	TagTypeDouble TagType = 1
+	TagTypeBool   TagType = 2
+	TagTypeLong   TagType = 3
+	TagTypeBinary TagType = 4
+)
+
+// Tag is a key-value pair, whose value is the field matching its type.
+type Tag struct {
+	Key     string  // 1
+	VType   TagType // 2
+	VStr    string  // 3
+	VDouble float64 // 4
+	VBool   bool    // 5
+	VLong   int64   // 6
+	VBinary []byte  // 7
+}
+
+// Log is a timed event of a span, made of a set of fields.
+type Log struct {
+	Timestamp int64  // 1, in microseconds
+	Fields    []*Tag // 2
+}
+
+// SpanRefType is the type of the relationship between two spans.
+type SpanRefType int32
+
+// Span reference types.
+const (
+	SpanRefTypeChildOf     SpanRefType = 0
+	SpanRefTypeFollowsFrom SpanRefType = 1
+)
+
+// SpanRef is a reference from a span to another span.
+type SpanRef struct {
+	RefType     SpanRefType // 1
+	TraceIDLow  int64       // 2
+	TraceIDHigh int64       // 3
+	SpanID      int64       // 4
+}
+
+// Span is a span, whose 128-bit trace ID is split in its lower and higher 64 bits.
+type Span struct {
+	TraceIDLow    int64      // 1
+	TraceIDHigh   int64      // 2
+	SpanID        int64      // 3
+	ParentSpanID  int64      // 4
+	OperationName string     // 5
+	References    []*SpanRef // 6
+	Flags         int32      // 7
+	StartTime     int64      // 8, in microseconds
+	Duration      int64      // 9, in microseconds
+	Tags          []*Tag     // 10
+	Logs          []*Log     // 11
+}
+
+// Process describes the traced process that emitted the spans.
+type Process struct {
+	ServiceName string // 1
+	Tags        []*Tag // 2
+}
+
+// Batch is a collection of spans reported by a process.
+type Batch struct {
+	Process *Process // 1
+	Spans   []*Span  // 2
+}
@@ -0,0 +1,404 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package jaeger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Thrift types, as encoded by the binary protocol.
+const (
+	thriftStop   byte = 0
+	thriftBool   byte = 2
+	thriftByte   byte = 3
+	thriftDouble byte = 4
+	thriftI16    byte = 6
+	thriftI32    byte = 8
+	thriftI64    byte = 10
+	thriftString byte = 11
+	thriftStruct byte = 12
+	thriftMap    byte = 13
+	thriftSet    byte = 14
+	thriftList   byte = 15
+)
+
+// maxThriftDepth is the maximum nesting of the structures and containers skipped.
+const maxThriftDepth = 64
+
+var errThriftDepth = errors.New("thrift: maximum depth exceeded")
+
+// UnmarshalBatch decodes a Batch encoded with the Thrift binary protocol.
+func UnmarshalBatch(data []byte) (*Batch, error) {
+	r := &thriftReader{buf: data}
+	var b Batch
+	err := r.readStruct(func(id int16, typ byte) (bool, error) {
+		switch {
+		case id == 1 && typ == thriftStruct:
+			b.Process = &Process{}
+			return true, r.readProcess(b.Process)
+		case id == 2 && typ == thriftList:
+			return true, r.readList(thriftStruct, func() error {
+				s := &Span{}
+				b.Spans = append(b.Spans, s)
+				return r.readSpan(s)
+			})
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *thriftReader) readProcess(p *Process) error {
+	return r.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftString:
+			p.ServiceName, err = r.readString()
+		case id == 2 && typ == thriftList:
+			p.Tags, err = r.readTags()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+func (r *thriftReader) readSpan(s *Span) error {
+	return r.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI64:
+			s.TraceIDLow, err = r.readI64()
+		case id == 2 && typ == thriftI64:
+			s.TraceIDHigh, err = r.readI64()
+		case id == 3 && typ == thriftI64:
+			s.SpanID, err = r.readI64()
+		case id == 4 && typ == thriftI64:
+			s.ParentSpanID, err = r.readI64()
+		case id == 5 && typ == thriftString:
+			s.OperationName, err = r.readString()
+		case id == 6 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				ref := &SpanRef{}
+				s.References = append(s.References, ref)
+				return r.readSpanRef(ref)
+			})
+		case id == 7 && typ == thriftI32:
+			s.Flags, err = r.readI32()
+		case id == 8 && typ == thriftI64:
+			s.StartTime, err = r.readI64()
+		case id == 9 && typ == thriftI64:
+			s.Duration, err = r.readI64()
+		case id == 10 && typ == thriftList:
+			s.Tags, err = r.readTags()
+		case id == 11 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				l := &Log{}
+				s.Logs = append(s.Logs, l)
+				return r.readLog(l)
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+func (r *thriftReader) readSpanRef(ref *SpanRef) error {
+	return r.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			var v int32
+			v, err = r.readI32()
+			ref.RefType = SpanRefType(v)
+		case id == 2 && typ == thriftI64:
+			ref.TraceIDLow, err = r.readI64()
+		case id == 3 && typ == thriftI64:
+			ref.TraceIDHigh, err = r.readI64()
+		case id == 4 && typ == thriftI64:
+			ref.SpanID, err = r.readI64()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+func (r *thriftReader) readLog(l *Log) error {
+	return r.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI64:
+			l.Timestamp, err = r.readI64()
+		case id == 2 && typ == thriftList:
+			l.Fields, err = r.readTags()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+func (r *thriftReader) readTags() ([]*Tag, error) {
+	var tags []*Tag
+	err := r.readList(thriftStruct, func() error {
+		t := &Tag{}
+		tags = append(tags, t)
+		return r.readTag(t)
+	})
+	return tags, err
+}
+
+func (r *thriftReader) readTag(t *Tag) error {
+	return r.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftString:
+			t.Key, err = r.readString()
+		case id == 2 && typ == thriftI32:
+			var v int32
+			v, err = r.readI32()
+			t.VType = TagType(v)
+		case id == 3 && typ == thriftString:
+			t.VStr, err = r.readString()
+		case id == 4 && typ == thriftDouble:
+			t.VDouble, err = r.readDouble()
+		case id == 5 && typ == thriftBool:
+			t.VBool, err = r.readBool()
+		case id == 6 && typ == thriftI64:
+			t.VLong, err = r.readI64()
+		case id == 7 && typ == thriftString:
+			t.VBinary, err = r.readBinary()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+// thriftReader reads values encoded with the Thrift binary protocol.
+type thriftReader struct {
+	buf   []byte
+	pos   int
+	depth int
+}
+
+// next returns the next n bytes, or io.ErrUnexpectedEOF if there are not enough left.
+func (r *thriftReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.buf)-r.pos {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) readBool() (bool, error) {
+	b, err := r.readByte()
+	return b != 0, err
+}
+
+func (r *thriftReader) readI16() (int16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) readI32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) readI64() (int64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readDouble() (float64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readBinary() ([]byte, error) {
+	n, err := r.readI32()
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.next(int(n))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func (r *thriftReader) readString() (string, error) {
+	n, err := r.readI32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.next(int(n))
+	return string(b), err
+}
+
+// readStruct reads the fields of a structure, calling field for each of them. The fields
+// which are not handled by field are skipped.
+func (r *thriftReader) readStruct(field func(id int16, typ byte) (bool, error)) error {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > maxThriftDepth {
+		return errThriftDepth
+	}
+	for {
+		typ, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		id, err := r.readI16()
+		if err != nil {
+			return err
+		}
+		ok, err := field(id, typ)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := r.skip(typ); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readList reads a list whose elements are of type typ, calling elem for each of them.
+func (r *thriftReader) readList(typ byte, elem func() error) error {
+	elemType, n, err := r.readListHeader()
+	if err != nil {
+		return err
+	}
+	if elemType != typ {
+		return fmt.Errorf("thrift: unexpected list element type %d, expected %d", elemType, typ)
+	}
+	for i := 0; i < n; i++ {
+		if err := elem(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *thriftReader) readListHeader() (byte, int, error) {
+	typ, err := r.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := r.readI32()
+	if err != nil {
+		return 0, 0, err
+	}
+	// every element is at least one byte long, this avoids looping over huge invalid sizes
+	if n < 0 || int(n) > len(r.buf)-r.pos {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	return typ, int(n), nil
+}
+
+// skip skips a value of the given type.
+func (r *thriftReader) skip(typ byte) error {
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.next(1)
+	case thriftI16:
+		_, err = r.next(2)
+	case thriftI32:
+		_, err = r.next(4)
+	case thriftI64, thriftDouble:
+		_, err = r.next(8)
+	case thriftString:
+		_, err = r.readBinary()
+	case thriftStruct:
+		err = r.readStruct(func(int16, byte) (bool, error) { return false, nil })
+	case thriftList, thriftSet:
+		err = r.skipContainer(func() error {
+			elemType, n, err := r.readListHeader()
+			if err != nil {
+				return err
+			}
+			for i := 0; i < n; i++ {
+				if err := r.skip(elemType); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case thriftMap:
+		err = r.skipContainer(func() error {
+			kv, err := r.next(2)
+			if err != nil {
+				return err
+			}
+			n, err := r.readI32()
+			if err != nil {
+				return err
+			}
+			if n < 0 || int(n) > len(r.buf)-r.pos {
+				return io.ErrUnexpectedEOF
+			}
+			for i := 0; i < int(n); i++ {
+				if err := r.skip(kv[0]); err != nil {
+					return err
+				}
+				if err := r.skip(kv[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	default:
+		err = fmt.Errorf("thrift: unknown type %d", typ)
+	}
+	return err
+}
+
+// skipContainer calls skip, limiting the nesting of the containers.
+func (r *thriftReader) skipContainer(skip func() error) error {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > maxThriftDepth {
+		return errThriftDepth
+	}
+	return skip()
+}
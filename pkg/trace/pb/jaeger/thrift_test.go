@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package jaeger
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBatch() *Batch {
+	return &Batch{
+		Process: &Process{
+			ServiceName: "frontend",
+			Tags:        []*Tag{{Key: "hostname", VType: TagTypeString, VStr: "host-1"}},
+		},
+		Spans: []*Span{{
+			TraceIDLow:    -1,
+			TraceIDHigh:   1,
+			SpanID:        2,
+			ParentSpanID:  3,
+			OperationName: "GET",
+			References:    []*SpanRef{{RefType: SpanRefTypeFollowsFrom, TraceIDLow: -1, TraceIDHigh: 1, SpanID: 4}},
+			Flags:         1,
+			StartTime:     1556604172355737,
+			Duration:      1431,
+			Tags: []*Tag{
+				{Key: "s", VType: TagTypeString, VStr: "v"},
+				{Key: "d", VType: TagTypeDouble, VDouble: 0.5},
+				{Key: "b", VType: TagTypeBool, VBool: true},
+				{Key: "l", VType: TagTypeLong, VLong: -12},
+				{Key: "bin", VType: TagTypeBinary, VBinary: []byte{0, 1}},
+			},
+			Logs: []*Log{{Timestamp: 1556604172355800, Fields: []*Tag{{Key: "event", VType: TagTypeString, VStr: "error"}}}},
+		}},
+	}
+}
+
+func TestUnmarshalBatch(t *testing.T) {
+	b, err := UnmarshalBatch(marshalBatch(testBatch()))
+	require.NoError(t, err)
+	assert.Equal(t, testBatch(), b)
+}
+
+func TestUnmarshalBatchUnknownFields(t *testing.T) {
+	var w thriftWriter
+	// seqNo
+	w.i64(3, 12)
+	// stats, with a map and a set
+	w.field(thriftStruct, 4)
+	w.field(thriftMap, 1)
+	w.buf = append(w.buf, thriftString, thriftI32, 0, 0, 0, 1)
+	w.buf = append(w.buf, 0, 0, 0, 1, 'k', 0, 0, 0, 7)
+	w.field(thriftSet, 2)
+	w.buf = append(w.buf, thriftDouble, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0)
+	w.stop()
+	body := marshalBatch(testBatch())
+	data := append(w.buf, body...)
+
+	b, err := UnmarshalBatch(data)
+	require.NoError(t, err)
+	assert.Equal(t, testBatch(), b)
+}
+
+func TestUnmarshalBatchErrors(t *testing.T) {
+	body := marshalBatch(testBatch())
+	for i := 0; i < len(body); i++ {
+		_, err := UnmarshalBatch(body[:i])
+		assert.Equal(t, io.ErrUnexpectedEOF, err, "truncated at %d", i)
+	}
+
+	// the spans are not a list of structures
+	var w thriftWriter
+	w.field(thriftList, 2)
+	w.buf = append(w.buf, thriftI64, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0)
+	w.stop()
+	_, err := UnmarshalBatch(w.buf)
+	assert.Error(t, err)
+
+	// the list size is larger than the payload
+	w = thriftWriter{}
+	w.list(2, 1<<30)
+	_, err = UnmarshalBatch(w.buf)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// unknown structures are too deeply nested
+	w = thriftWriter{}
+	for i := 0; i < maxThriftDepth+1; i++ {
+		w.field(thriftStruct, 5)
+	}
+	_, err = UnmarshalBatch(w.buf)
+	assert.Equal(t, errThriftDepth, err)
+}
+
+// marshalBatch encodes a Batch with the Thrift binary protocol, like the Jaeger clients do.
+func marshalBatch(b *Batch) []byte {
+	var w thriftWriter
+	if b.Process != nil {
+		w.field(thriftStruct, 1)
+		w.str(thriftString, 1, b.Process.ServiceName)
+		w.tags(2, b.Process.Tags)
+		w.stop()
+	}
+	w.list(2, len(b.Spans))
+	for _, s := range b.Spans {
+		w.i64(1, s.TraceIDLow)
+		w.i64(2, s.TraceIDHigh)
+		w.i64(3, s.SpanID)
+		w.i64(4, s.ParentSpanID)
+		w.str(thriftString, 5, s.OperationName)
+		if len(s.References) > 0 {
+			w.list(6, len(s.References))
+			for _, ref := range s.References {
+				w.i32(1, int32(ref.RefType))
+				w.i64(2, ref.TraceIDLow)
+				w.i64(3, ref.TraceIDHigh)
+				w.i64(4, ref.SpanID)
+				w.stop()
+			}
+		}
+		w.i32(7, s.Flags)
+		w.i64(8, s.StartTime)
+		w.i64(9, s.Duration)
+		w.tags(10, s.Tags)
+		if len(s.Logs) > 0 {
+			w.list(11, len(s.Logs))
+			for _, l := range s.Logs {
+				w.i64(1, l.Timestamp)
+				w.tags(2, l.Fields)
+				w.stop()
+			}
+		}
+		w.stop()
+	}
+	w.stop()
+	return w.buf
+}
+
+// thriftWriter writes the fields of structures with the Thrift binary protocol.
+type thriftWriter struct {
+	buf []byte
+}
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	w.buf = append(w.buf, typ, byte(id>>8), byte(id))
+}
+
+func (w *thriftWriter) stop() {
+	w.buf = append(w.buf, thriftStop)
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thriftI32, id)
+	w.buf = append(w.buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thriftI64, id)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *thriftWriter) str(typ byte, id int16, v string) {
+	w.field(typ, id)
+	n := len(v)
+	w.buf = append(w.buf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	w.buf = append(w.buf, v...)
+}
+
+// list writes the header of a list of n structures.
+func (w *thriftWriter) list(id int16, n int) {
+	w.field(thriftList, id)
+	w.buf = append(w.buf, thriftStruct, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func (w *thriftWriter) tags(id int16, tags []*Tag) {
+	if len(tags) == 0 {
+		return
+	}
+	w.list(id, len(tags))
+	for _, t := range tags {
+		w.str(thriftString, 1, t.Key)
+		w.i32(2, int32(t.VType))
+		switch t.VType {
+		case TagTypeString:
+			w.str(thriftString, 3, t.VStr)
+		case TagTypeDouble:
+			w.field(thriftDouble, 4)
+			var b [8]byte
+			binary.BigEndian.PutUint64(b[:], math.Float64bits(t.VDouble))
+			w.buf = append(w.buf, b[:]...)
+		case TagTypeBool:
+			w.field(thriftBool, 5)
+			if t.VBool {
+				w.buf = append(w.buf, 1)
+			} else {
+				w.buf = append(w.buf, 0)
+			}
+		case TagTypeLong:
+			w.i64(6, t.VLong)
+		case TagTypeBinary:
+			w.str(thriftString, 7, string(t.VBinary))
+		}
+		w.stop()
+	}
+}
@@ -0,0 +1,229 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// The OTLP/JSON encoding follows the Protobuf JSON mapping with a few exceptions:
+// trace and span IDs are hex strings rather than base64 ones, and enums may only be
+// their integer value. The decoders below also accept the base64 IDs sent by older
+// exporters and the names of the enums, as well as 64-bit integers as JSON numbers
+// rather than strings.
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *Span) UnmarshalJSON(data []byte) error {
+	var v struct {
+		TraceID                string       `json:"traceId"`
+		SpanID                 string       `json:"spanId"`
+		TraceState             string       `json:"traceState"`
+		ParentSpanID           string       `json:"parentSpanId"`
+		Name                   string       `json:"name"`
+		Kind                   jsonEnum     `json:"kind"`
+		StartTimeUnixNano      jsonUint64   `json:"startTimeUnixNano"`
+		EndTimeUnixNano        jsonUint64   `json:"endTimeUnixNano"`
+		Attributes             []*KeyValue  `json:"attributes"`
+		DroppedAttributesCount uint32       `json:"droppedAttributesCount"`
+		Events                 []*SpanEvent `json:"events"`
+		DroppedEventsCount     uint32       `json:"droppedEventsCount"`
+		Status                 *Status      `json:"status"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	kind, err := v.Kind.value(spanKindNames)
+	if err != nil {
+		return err
+	}
+	*m = Span{
+		TraceState:             v.TraceState,
+		Name:                   v.Name,
+		Kind:                   SpanKind(kind),
+		StartTimeUnixNano:      uint64(v.StartTimeUnixNano),
+		EndTimeUnixNano:        uint64(v.EndTimeUnixNano),
+		Attributes:             v.Attributes,
+		DroppedAttributesCount: v.DroppedAttributesCount,
+		Events:                 v.Events,
+		DroppedEventsCount:     v.DroppedEventsCount,
+		Status:                 v.Status,
+	}
+	if m.TraceID, err = decodeID(v.TraceID, 16); err != nil {
+		return fmt.Errorf("invalid traceId: %v", err)
+	}
+	if m.SpanID, err = decodeID(v.SpanID, 8); err != nil {
+		return fmt.Errorf("invalid spanId: %v", err)
+	}
+	if m.ParentSpanID, err = decodeID(v.ParentSpanID, 8); err != nil {
+		return fmt.Errorf("invalid parentSpanId: %v", err)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *SpanEvent) UnmarshalJSON(data []byte) error {
+	var v struct {
+		TimeUnixNano           jsonUint64  `json:"timeUnixNano"`
+		Name                   string      `json:"name"`
+		Attributes             []*KeyValue `json:"attributes"`
+		DroppedAttributesCount uint32      `json:"droppedAttributesCount"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = SpanEvent{
+		TimeUnixNano:           uint64(v.TimeUnixNano),
+		Name:                   v.Name,
+		Attributes:             v.Attributes,
+		DroppedAttributesCount: v.DroppedAttributesCount,
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *Status) UnmarshalJSON(data []byte) error {
+	var v struct {
+		DeprecatedCode int32    `json:"deprecatedCode"`
+		Message        string   `json:"message"`
+		Code           jsonEnum `json:"code"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	code, err := v.Code.value(statusCodeNames)
+	if err != nil {
+		return err
+	}
+	*m = Status{
+		DeprecatedCode: v.DeprecatedCode,
+		Message:        v.Message,
+		Code:           StatusCode(code),
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *AnyValue) UnmarshalJSON(data []byte) error {
+	var v struct {
+		StringValue *string       `json:"stringValue"`
+		BoolValue   *bool         `json:"boolValue"`
+		IntValue    *jsonInt64    `json:"intValue"`
+		DoubleValue *float64      `json:"doubleValue"`
+		ArrayValue  *ArrayValue   `json:"arrayValue"`
+		KvlistValue *KeyValueList `json:"kvlistValue"`
+		BytesValue  []byte        `json:"bytesValue"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch {
+	case v.StringValue != nil:
+		m.Value = &AnyValueString{StringValue: *v.StringValue}
+	case v.BoolValue != nil:
+		m.Value = &AnyValueBool{BoolValue: *v.BoolValue}
+	case v.IntValue != nil:
+		m.Value = &AnyValueInt{IntValue: int64(*v.IntValue)}
+	case v.DoubleValue != nil:
+		m.Value = &AnyValueDouble{DoubleValue: *v.DoubleValue}
+	case v.ArrayValue != nil:
+		m.Value = &AnyValueArray{ArrayValue: v.ArrayValue}
+	case v.KvlistValue != nil:
+		m.Value = &AnyValueKvlist{KvlistValue: v.KvlistValue}
+	case v.BytesValue != nil:
+		m.Value = &AnyValueBytes{BytesValue: v.BytesValue}
+	default:
+		m.Value = nil
+	}
+	return nil
+}
+
+// decodeID decodes a trace or span ID of the given length in bytes, encoded as a hex
+// or a base64 string. An empty string is decoded as an empty ID.
+func decodeID(s string, n int) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if len(s) == 2*n {
+		if id, err := hex.DecodeString(s); err == nil {
+			return id, nil
+		}
+	}
+	id, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(id) != n {
+		return nil, fmt.Errorf("%q is not %d bytes long", s, n)
+	}
+	return id, nil
+}
+
+// jsonEnum decodes an enum from either its integer value or its name.
+type jsonEnum struct {
+	number int32
+	name   string
+}
+
+func (e *jsonEnum) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &e.name)
+	}
+	return json.Unmarshal(data, &e.number)
+}
+
+// value returns the value of the enum, looking its name up in names when it was
+// decoded from a string.
+func (e jsonEnum) value(names map[string]int32) (int32, error) {
+	if e.name == "" {
+		return e.number, nil
+	}
+	if n, ok := names[e.name]; ok {
+		return n, nil
+	}
+	return 0, fmt.Errorf("unknown enum value %q", e.name)
+}
+
+// jsonUint64 decodes an unsigned 64-bit integer from either a JSON string or number.
+type jsonUint64 uint64
+
+func (n *jsonUint64) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	v, err := strconv.ParseUint(unquoteNumber(data), 10, 64)
+	if err != nil {
+		return err
+	}
+	*n = jsonUint64(v)
+	return nil
+}
+
+// jsonInt64 decodes a 64-bit integer from either a JSON string or number.
+type jsonInt64 int64
+
+func (n *jsonInt64) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	v, err := strconv.ParseInt(unquoteNumber(data), 10, 64)
+	if err != nil {
+		return err
+	}
+	*n = jsonInt64(v)
+	return nil
+}
+
+// unquoteNumber returns the number held by the given JSON string or number.
+func unquoteNumber(data []byte) string {
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		return string(data[1 : len(data)-1])
+	}
+	return string(data)
+}
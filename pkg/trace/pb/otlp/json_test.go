@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package otlp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanUnmarshalJSON(t *testing.T) {
+	for name, tt := range map[string]struct {
+		in  string
+		out *Span
+		err bool
+	}{
+		"hex": {
+			in: `{"traceId":"0102030405060708090a0b0c0d0e0f10","spanId":"0102030405060708","kind":2,"startTimeUnixNano":"10","endTimeUnixNano":20}`,
+			out: &Span{
+				TraceID:           []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				SpanID:            []byte{1, 2, 3, 4, 5, 6, 7, 8},
+				Kind:              SpanKindServer,
+				StartTimeUnixNano: 10,
+				EndTimeUnixNano:   20,
+			},
+		},
+		"base64": {
+			in: `{"traceId":"AQIDBAUGBwgJCgsMDQ4PEA==","spanId":"AQIDBAUGBwg=","parentSpanId":"","kind":"SPAN_KIND_CLIENT"}`,
+			out: &Span{
+				TraceID: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				SpanID:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+				Kind:    SpanKindClient,
+			},
+		},
+		"status": {
+			in:  `{"status":{"code":"STATUS_CODE_ERROR","message":"failed"}}`,
+			out: &Span{Status: &Status{Code: StatusCodeError, Message: "failed"}},
+		},
+		"attributes": {
+			in: `{"attributes":[{"key":"a","value":{"intValue":"12"}},{"key":"b","value":{"boolValue":false}},{"key":"c","value":{}}]}`,
+			out: &Span{Attributes: []*KeyValue{
+				{Key: "a", Value: &AnyValue{Value: &AnyValueInt{IntValue: 12}}},
+				{Key: "b", Value: &AnyValue{Value: &AnyValueBool{BoolValue: false}}},
+				{Key: "c", Value: &AnyValue{}},
+			}},
+		},
+		"invalid-id":   {in: `{"spanId":"0102"}`, err: true},
+		"invalid-kind": {in: `{"kind":"SERVER"}`, err: true},
+		"invalid-time": {in: `{"startTimeUnixNano":"now"}`, err: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var span Span
+			err := json.Unmarshal([]byte(tt.in), &span)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, &span)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// Package otlp contains the subset of the OpenTelemetry protocol (OTLP) messages
+// needed by the trace agent to receive traces from OpenTelemetry SDKs and collectors.
+//
+// The messages mirror the ones of the opentelemetry-proto repository (trace/v1,
+// common/v1, resource/v1 and collector/trace/v1), keeping their field numbers and
+// JSON names, but were written by hand to avoid depending on the generated code of
+// the OpenTelemetry collector. They are encoded and decoded by the proto packages
+// through their struct tags, and in JSON through encoding/json (see json.go).
+package otlp
+
+import (
+	proto "github.com/gogo/protobuf/proto"
+)
+
+// ExportTraceServiceRequest is the request of the Export method of the trace
+// service, and the body of the requests sent to the /v1/traces HTTP endpoint.
+type ExportTraceServiceRequest struct {
+	ResourceSpans []*ResourceSpans `protobuf:"bytes,1,rep,name=resource_spans,json=resourceSpans,proto3" json:"resourceSpans,omitempty"`
+}
+
+func (m *ExportTraceServiceRequest) Reset()         { *m = ExportTraceServiceRequest{} }
+func (m *ExportTraceServiceRequest) String() string { return proto.CompactTextString(m) }
+func (*ExportTraceServiceRequest) ProtoMessage()    {}
+
+// ExportTraceServiceResponse is the response of the Export method of the trace service.
+type ExportTraceServiceResponse struct{}
+
+func (m *ExportTraceServiceResponse) Reset()         { *m = ExportTraceServiceResponse{} }
+func (m *ExportTraceServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*ExportTraceServiceResponse) ProtoMessage()    {}
+
+// ResourceSpans is a collection of spans from a Resource.
+type ResourceSpans struct {
+	Resource                    *Resource                      `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	InstrumentationLibrarySpans []*InstrumentationLibrarySpans `protobuf:"bytes,2,rep,name=instrumentation_library_spans,json=instrumentationLibrarySpans,proto3" json:"instrumentationLibrarySpans,omitempty"`
+}
+
+func (m *ResourceSpans) Reset()         { *m = ResourceSpans{} }
+func (m *ResourceSpans) String() string { return proto.CompactTextString(m) }
+func (*ResourceSpans) ProtoMessage()    {}
+
+// GetResource returns the resource of the spans, or nil.
+func (m *ResourceSpans) GetResource() *Resource {
+	if m != nil {
+		return m.Resource
+	}
+	return nil
+}
+
+// Resource is the entity producing telemetry, e.g. a service or a host.
+type Resource struct {
+	Attributes             []*KeyValue `protobuf:"bytes,1,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32      `protobuf:"varint,2,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3" json:"droppedAttributesCount,omitempty"`
+}
+
+func (m *Resource) Reset()         { *m = Resource{} }
+func (m *Resource) String() string { return proto.CompactTextString(m) }
+func (*Resource) ProtoMessage()    {}
+
+// GetAttributes returns the attributes of the resource, or nil.
+func (m *Resource) GetAttributes() []*KeyValue {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+// InstrumentationLibrarySpans is a collection of spans produced by an instrumentation library.
+type InstrumentationLibrarySpans struct {
+	InstrumentationLibrary *InstrumentationLibrary `protobuf:"bytes,1,opt,name=instrumentation_library,json=instrumentationLibrary,proto3" json:"instrumentationLibrary,omitempty"`
+	Spans                  []*Span                 `protobuf:"bytes,2,rep,name=spans,proto3" json:"spans,omitempty"`
+}
+
+func (m *InstrumentationLibrarySpans) Reset()         { *m = InstrumentationLibrarySpans{} }
+func (m *InstrumentationLibrarySpans) String() string { return proto.CompactTextString(m) }
+func (*InstrumentationLibrarySpans) ProtoMessage()    {}
+
+// InstrumentationLibrary is the library that produced the spans.
+type InstrumentationLibrary struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (m *InstrumentationLibrary) Reset()         { *m = InstrumentationLibrary{} }
+func (m *InstrumentationLibrary) String() string { return proto.CompactTextString(m) }
+func (*InstrumentationLibrary) ProtoMessage()    {}
+
+// SpanKind is the type of a span, it describes the relationship between the span,
+// its parents and its children.
+type SpanKind int32
+
+// Span kinds.
+const (
+	SpanKindUnspecified SpanKind = 0
+	SpanKindInternal    SpanKind = 1
+	SpanKindServer      SpanKind = 2
+	SpanKindClient      SpanKind = 3
+	SpanKindProducer    SpanKind = 4
+	SpanKindConsumer    SpanKind = 5
+)
+
+// spanKindNames maps the names of the span kinds to their value.
+var spanKindNames = map[string]int32{
+	"SPAN_KIND_UNSPECIFIED": int32(SpanKindUnspecified),
+	"SPAN_KIND_INTERNAL":    int32(SpanKindInternal),
+	"SPAN_KIND_SERVER":      int32(SpanKindServer),
+	"SPAN_KIND_CLIENT":      int32(SpanKindClient),
+	"SPAN_KIND_PRODUCER":    int32(SpanKindProducer),
+	"SPAN_KIND_CONSUMER":    int32(SpanKindConsumer),
+}
+
+// Span represents a single operation within a trace. Its trace ID is 16 bytes
+// long, and its span and parent span IDs 8 bytes long.
+type Span struct {
+	TraceID                []byte       `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"traceId,omitempty"`
+	SpanID                 []byte       `protobuf:"bytes,2,opt,name=span_id,json=spanId,proto3" json:"spanId,omitempty"`
+	TraceState             string       `protobuf:"bytes,3,opt,name=trace_state,json=traceState,proto3" json:"traceState,omitempty"`
+	ParentSpanID           []byte       `protobuf:"bytes,4,opt,name=parent_span_id,json=parentSpanId,proto3" json:"parentSpanId,omitempty"`
+	Name                   string       `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	Kind                   SpanKind     `protobuf:"varint,6,opt,name=kind,proto3" json:"kind,omitempty"`
+	StartTimeUnixNano      uint64       `protobuf:"fixed64,7,opt,name=start_time_unix_nano,json=startTimeUnixNano,proto3" json:"startTimeUnixNano,omitempty"`
+	EndTimeUnixNano        uint64       `protobuf:"fixed64,8,opt,name=end_time_unix_nano,json=endTimeUnixNano,proto3" json:"endTimeUnixNano,omitempty"`
+	Attributes             []*KeyValue  `protobuf:"bytes,9,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32       `protobuf:"varint,10,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3" json:"droppedAttributesCount,omitempty"`
+	Events                 []*SpanEvent `protobuf:"bytes,11,rep,name=events,proto3" json:"events,omitempty"`
+	DroppedEventsCount     uint32       `protobuf:"varint,12,opt,name=dropped_events_count,json=droppedEventsCount,proto3" json:"droppedEventsCount,omitempty"`
+	Status                 *Status      `protobuf:"bytes,15,opt,name=status,proto3" json:"status,omitempty"`
+}
+
+func (m *Span) Reset()         { *m = Span{} }
+func (m *Span) String() string { return proto.CompactTextString(m) }
+func (*Span) ProtoMessage()    {}
+
+// SpanEvent is a time-stamped annotation of a span, e.g. an exception.
+type SpanEvent struct {
+	TimeUnixNano           uint64      `protobuf:"fixed64,1,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"timeUnixNano,omitempty"`
+	Name                   string      `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Attributes             []*KeyValue `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32      `protobuf:"varint,4,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3" json:"droppedAttributesCount,omitempty"`
+}
+
+func (m *SpanEvent) Reset()         { *m = SpanEvent{} }
+func (m *SpanEvent) String() string { return proto.CompactTextString(m) }
+func (*SpanEvent) ProtoMessage()    {}
+
+// StatusCode is the status of a span.
+type StatusCode int32
+
+// Status codes.
+const (
+	StatusCodeUnset StatusCode = 0
+	StatusCodeOk    StatusCode = 1
+	StatusCodeError StatusCode = 2
+)
+
+// statusCodeNames maps the names of the status codes to their value.
+var statusCodeNames = map[string]int32{
+	"STATUS_CODE_UNSET": int32(StatusCodeUnset),
+	"STATUS_CODE_OK":    int32(StatusCodeOk),
+	"STATUS_CODE_ERROR": int32(StatusCodeError),
+}
+
+// Status is the status of a span. DeprecatedCode holds the gRPC-like status code
+// used by the versions of the protocol older than 0.7, where any code but 0 (OK)
+// denotes an error.
+type Status struct {
+	DeprecatedCode int32      `protobuf:"varint,1,opt,name=deprecated_code,json=deprecatedCode,proto3" json:"deprecatedCode,omitempty"`
+	Message        string     `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Code           StatusCode `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+}
+
+func (m *Status) Reset()         { *m = Status{} }
+func (m *Status) String() string { return proto.CompactTextString(m) }
+func (*Status) ProtoMessage()    {}
+
+// IsError reports whether the status denotes an error.
+func (m *Status) IsError() bool {
+	if m == nil {
+		return false
+	}
+	if m.Code == StatusCodeUnset {
+		return m.DeprecatedCode != 0
+	}
+	return m.Code == StatusCodeError
+}
+
+// KeyValue is a key-value pair, used for the attributes of resources, spans and events.
+type KeyValue struct {
+	Key   string    `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value *AnyValue `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *KeyValue) Reset()         { *m = KeyValue{} }
+func (m *KeyValue) String() string { return proto.CompactTextString(m) }
+func (*KeyValue) ProtoMessage()    {}
+
+// AnyValue holds the value of an attribute, which is one of the AnyValue* types.
+type AnyValue struct {
+	Value isAnyValueValue `protobuf_oneof:"value"`
+}
+
+func (m *AnyValue) Reset()         { *m = AnyValue{} }
+func (m *AnyValue) String() string { return proto.CompactTextString(m) }
+func (*AnyValue) ProtoMessage()    {}
+
+// GetValue returns the value held, or nil.
+func (m *AnyValue) GetValue() isAnyValueValue {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is used by the proto packages to decode the value of the oneof field.
+func (*AnyValue) XXX_OneofWrappers() []interface{} { //nolint:golint
+	return []interface{}{
+		(*AnyValueString)(nil),
+		(*AnyValueBool)(nil),
+		(*AnyValueInt)(nil),
+		(*AnyValueDouble)(nil),
+		(*AnyValueArray)(nil),
+		(*AnyValueKvlist)(nil),
+		(*AnyValueBytes)(nil),
+	}
+}
+
+type isAnyValueValue interface {
+	isAnyValueValue()
+}
+
+// AnyValueString holds a string value.
+type AnyValueString struct {
+	StringValue string `protobuf:"bytes,1,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+// AnyValueBool holds a boolean value.
+type AnyValueBool struct {
+	BoolValue bool `protobuf:"varint,2,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+// AnyValueInt holds an integer value.
+type AnyValueInt struct {
+	IntValue int64 `protobuf:"varint,3,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+// AnyValueDouble holds a floating point value.
+type AnyValueDouble struct {
+	DoubleValue float64 `protobuf:"fixed64,4,opt,name=double_value,json=doubleValue,proto3,oneof"`
+}
+
+// AnyValueArray holds a list of values.
+type AnyValueArray struct {
+	ArrayValue *ArrayValue `protobuf:"bytes,5,opt,name=array_value,json=arrayValue,proto3,oneof"`
+}
+
+// AnyValueKvlist holds a list of key-value pairs.
+type AnyValueKvlist struct {
+	KvlistValue *KeyValueList `protobuf:"bytes,6,opt,name=kvlist_value,json=kvlistValue,proto3,oneof"`
+}
+
+// AnyValueBytes holds a bytes value.
+type AnyValueBytes struct {
+	BytesValue []byte `protobuf:"bytes,7,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+func (*AnyValueString) isAnyValueValue() {}
+func (*AnyValueBool) isAnyValueValue()   {}
+func (*AnyValueInt) isAnyValueValue()    {}
+func (*AnyValueDouble) isAnyValueValue() {}
+func (*AnyValueArray) isAnyValueValue()  {}
+func (*AnyValueKvlist) isAnyValueValue() {}
+func (*AnyValueBytes) isAnyValueValue()  {}
+
+// ArrayValue is a list of values.
+type ArrayValue struct {
+	Values []*AnyValue `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+}
+
+func (m *ArrayValue) Reset()         { *m = ArrayValue{} }
+func (m *ArrayValue) String() string { return proto.CompactTextString(m) }
+func (*ArrayValue) ProtoMessage()    {}
+
+// GetValues returns the values of the list, or nil.
+func (m *ArrayValue) GetValues() []*AnyValue {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+// KeyValueList is a list of key-value pairs.
+type KeyValueList struct {
+	Values []*KeyValue `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+}
+
+func (m *KeyValueList) Reset()         { *m = KeyValueList{} }
+func (m *KeyValueList) String() string { return proto.CompactTextString(m) }
+func (*KeyValueList) ProtoMessage()    {}
+
+// GetValues returns the key-value pairs of the list, or nil.
+func (m *KeyValueList) GetValues() []*KeyValue {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sampler
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+const (
+	// tailDecisionTick is the frequency at which the buffered traces are checked for a decision.
+	tailDecisionTick = time.Second
+	// tailNoPolicy is the name under which the traces matching no policy are reported.
+	tailNoPolicy = "none"
+	// tailPolicyKey is the key of the tag holding the policy that kept a trace, set on the root of its chunks.
+	tailPolicyKey = "_dd.tail_sampling.policy"
+)
+
+// TailPolicyType specifies the traces matched by a TailPolicy.
+type TailPolicyType string
+
+const (
+	// TailPolicyError matches the traces having at least one span with an error.
+	TailPolicyError TailPolicyType = "error"
+
+	// TailPolicyLatency matches the traces lasting at least the latency of the policy,
+	// from the start of their first span to the end of their last one.
+	TailPolicyLatency TailPolicyType = "latency"
+
+	// TailPolicyAttribute matches the traces having at least one span with the tag of
+	// the policy set to one of its values, or set to any value if the policy has none.
+	TailPolicyAttribute TailPolicyType = "attribute"
+
+	// TailPolicyProbabilistic matches all the traces.
+	TailPolicyProbabilistic TailPolicyType = "probabilistic"
+)
+
+// TailPolicy is a policy applied to the traces assembled by the TailSampler.
+type TailPolicy struct {
+	// Name is the name of the policy, it is reported in the telemetry of the sampler.
+	Name string
+	// Type specifies which traces are matched by the policy.
+	Type TailPolicyType
+	// Rate is the rate at which the traces matched by the policy are kept.
+	Rate float64
+	// Latency is the minimum duration of the traces matched by a latency policy.
+	Latency time.Duration
+	// Key is the tag looked up by an attribute policy.
+	Key string
+	// Values are the values of the tag matched by an attribute policy.
+	Values []string
+}
+
+// matches returns whether the spans of the chunks of a trace are matched by the policy.
+func (p *TailPolicy) matches(chunks []pb.Trace) bool {
+	switch p.Type {
+	case TailPolicyError:
+		for _, t := range chunks {
+			for _, s := range t {
+				if s.Error != 0 {
+					return true
+				}
+			}
+		}
+	case TailPolicyLatency:
+		var start, end int64
+		for _, t := range chunks {
+			for _, s := range t {
+				if start == 0 || s.Start < start {
+					start = s.Start
+				}
+				if s.Start+s.Duration > end {
+					end = s.Start + s.Duration
+				}
+			}
+		}
+		return time.Duration(end-start) >= p.Latency
+	case TailPolicyAttribute:
+		for _, t := range chunks {
+			for _, s := range t {
+				v, ok := s.Meta[p.Key]
+				if !ok {
+					continue
+				}
+				if len(p.Values) == 0 {
+					return true
+				}
+				for _, want := range p.Values {
+					if v == want {
+						return true
+					}
+				}
+			}
+		}
+	case TailPolicyProbabilistic:
+		return true
+	}
+	return false
+}
+
+// TailSamplerConfig holds the configuration of a TailSampler.
+type TailSamplerConfig struct {
+	// DecisionWait is the time during which the chunks of a trace are buffered, from the
+	// arrival of its first chunk, before a decision is made on the assembled trace.
+	DecisionWait time.Duration
+	// MaxTraces is the maximum number of traces buffered.
+	MaxTraces int
+	// MaxSpans is the maximum number of spans buffered, across all traces.
+	MaxSpans int
+	// Policies are the policies applied to the assembled traces, in order.
+	Policies []*TailPolicy
+}
+
+// TailSampler buffers the chunks of the traces it receives by trace ID, so that spans of
+// a same trace arriving in separate payloads are sampled together. Once the decision wait
+// has elapsed, the policies are applied in order to the assembled trace, and the first
+// one matching it decides whether it is kept, at its rate. Traces matching no policy are
+// dropped. When the buffer is full, the oldest traces are decided early on the chunks
+// received so far, and are reported as evicted.
+//
+// Chunks arriving after the decision on their trace are sampled the same way, as long as
+// the decision is remembered, which is for another decision wait.
+type TailSampler struct {
+	// Variables access through the 'atomic' package must be 64bits aligned.
+	evicted int64
+	late    int64
+
+	conf TailSamplerConfig
+	out  chan<- []pb.Trace
+
+	mu      sync.Mutex
+	traces  map[uint64]*tailTrace
+	queue   []*tailTrace // buffered traces, oldest first
+	spans   int
+	decided map[uint64]*tailDecision
+	expires []*tailDecision // remembered decisions, oldest first
+
+	// stats holds the number of traces kept and dropped by each policy, its keys
+	// are set at creation so it can be read without holding the lock.
+	stats map[string]*tailPolicyStats
+
+	tick *time.Ticker
+	exit chan struct{}
+	done chan struct{}
+}
+
+// tailTrace holds the chunks of a buffered trace.
+type tailTrace struct {
+	id     uint64
+	seen   time.Time
+	chunks []pb.Trace
+	spans  int
+}
+
+// tailDecision is a decision remembered for the late chunks of a trace.
+type tailDecision struct {
+	id     uint64
+	keep   bool
+	rate   float64
+	policy string
+	expire time.Time
+}
+
+// tailPolicyStats counts the traces decided by a policy.
+type tailPolicyStats struct {
+	kept    int64
+	dropped int64
+}
+
+// NewTailSampler returns a TailSampler applying the given configuration. The chunks of the
+// traces it keeps are sent to out once decided.
+func NewTailSampler(conf TailSamplerConfig, out chan<- []pb.Trace) *TailSampler {
+	stats := map[string]*tailPolicyStats{tailNoPolicy: {}}
+	for _, p := range conf.Policies {
+		stats[p.Name] = &tailPolicyStats{}
+	}
+	return &TailSampler{
+		conf:    conf,
+		out:     out,
+		traces:  make(map[uint64]*tailTrace),
+		decided: make(map[uint64]*tailDecision),
+		stats:   stats,
+		exit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start starts deciding on the buffered traces once their decision wait has elapsed.
+func (s *TailSampler) Start() {
+	s.tick = time.NewTicker(tailDecisionTick)
+	go func() {
+		defer close(s.done)
+		statsTick := time.NewTicker(10 * time.Second)
+		defer statsTick.Stop()
+		for {
+			select {
+			case now := <-s.tick.C:
+				s.flush(now, false)
+			case <-statsTick.C:
+				s.report()
+			case <-s.exit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the sampler, deciding on all the traces still buffered.
+func (s *TailSampler) Stop() {
+	s.tick.Stop()
+	close(s.exit)
+	<-s.done
+	s.flush(time.Now(), true)
+	s.report()
+}
+
+// Add adds a chunk of a trace to the sampler. If its trace was already decided, the chunk is
+// not buffered and the decision is returned with decided set to true. Otherwise, the chunk
+// will be sent to the output of the sampler if its trace is kept.
+func (s *TailSampler) Add(t pb.Trace) (keep, decided bool) {
+	return s.add(time.Now(), t)
+}
+
+func (s *TailSampler) add(now time.Time, t pb.Trace) (keep, decided bool) {
+	id := t[0].TraceID
+	s.mu.Lock()
+	if d, ok := s.decided[id]; ok {
+		s.mu.Unlock()
+		atomic.AddInt64(&s.late, 1)
+		if d.keep {
+			d.apply(t)
+		}
+		return d.keep, true
+	}
+	tt, ok := s.traces[id]
+	if !ok {
+		tt = &tailTrace{id: id, seen: now}
+		s.traces[id] = tt
+		s.queue = append(s.queue, tt)
+	}
+	tt.chunks = append(tt.chunks, t)
+	tt.spans += len(t)
+	s.spans += len(t)
+
+	var kept []pb.Trace
+	for len(s.queue) > 0 && (len(s.traces) > s.conf.MaxTraces || s.spans > s.conf.MaxSpans) {
+		atomic.AddInt64(&s.evicted, 1)
+		kept = append(kept, s.decideOldest(now)...)
+	}
+	s.mu.Unlock()
+
+	if len(kept) > 0 {
+		s.out <- kept
+	}
+	return false, false
+}
+
+// flush decides on the traces buffered for longer than the decision wait, or on all of
+// them if force is set, sending the chunks of the kept ones to the output.
+func (s *TailSampler) flush(now time.Time, force bool) {
+	var kept []pb.Trace
+	s.mu.Lock()
+	for len(s.queue) > 0 && (force || now.Sub(s.queue[0].seen) >= s.conf.DecisionWait) {
+		kept = append(kept, s.decideOldest(now)...)
+	}
+	for len(s.expires) > 0 && now.After(s.expires[0].expire) {
+		delete(s.decided, s.expires[0].id)
+		s.expires[0] = nil
+		s.expires = s.expires[1:]
+	}
+	s.mu.Unlock()
+
+	if len(kept) > 0 {
+		s.out <- kept
+	}
+}
+
+// decideOldest removes the oldest trace from the buffer and decides on it, returning its
+// chunks if it is kept. It must be called with the lock held.
+func (s *TailSampler) decideOldest(now time.Time) []pb.Trace {
+	tt := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	delete(s.traces, tt.id)
+	s.spans -= tt.spans
+
+	d := &tailDecision{id: tt.id, policy: tailNoPolicy, expire: now.Add(s.conf.DecisionWait)}
+	for _, p := range s.conf.Policies {
+		if p.matches(tt.chunks) {
+			d.keep, d.rate, d.policy = SampleByRate(tt.id, p.Rate), p.Rate, p.Name
+			break
+		}
+	}
+	if len(s.decided) < s.conf.MaxTraces {
+		s.decided[tt.id] = d
+		s.expires = append(s.expires, d)
+	}
+	if !d.keep {
+		atomic.AddInt64(&s.stats[d.policy].dropped, 1)
+		return nil
+	}
+	atomic.AddInt64(&s.stats[d.policy].kept, 1)
+	for _, t := range tt.chunks {
+		d.apply(t)
+	}
+	return tt.chunks
+}
+
+// apply sets the rate and the policy of a kept trace on the root of one of its chunks. The root
+// is replaced by a copy, since its spans may still be read by the concentrator or the writer.
+func (d *tailDecision) apply(t pb.Trace) {
+	root := traceutil.GetRoot(t)
+	cp := *root
+	cp.Meta = make(map[string]string, len(root.Meta)+1)
+	for k, v := range root.Meta {
+		cp.Meta[k] = v
+	}
+	cp.Metrics = make(map[string]float64, len(root.Metrics)+1)
+	for k, v := range root.Metrics {
+		cp.Metrics[k] = v
+	}
+	AddGlobalRate(&cp, d.rate)
+	cp.Meta[tailPolicyKey] = d.policy
+	for i, s := range t {
+		if s == root {
+			t[i] = &cp
+		}
+	}
+}
+
+func (s *TailSampler) report() {
+	s.mu.Lock()
+	traces, spans := len(s.traces), s.spans
+	s.mu.Unlock()
+	metrics.Gauge("datadog.trace_agent.sampler.tail.traces", float64(traces), nil, 1)
+	metrics.Gauge("datadog.trace_agent.sampler.tail.spans", float64(spans), nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.evicted", atomic.SwapInt64(&s.evicted, 0), nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.late", atomic.SwapInt64(&s.late, 0), nil, 1)
+	for name, st := range s.stats {
+		tags := []string{"policy:" + name}
+		metrics.Count("datadog.trace_agent.sampler.tail.kept", atomic.SwapInt64(&st.kept, 0), tags, 1)
+		metrics.Count("datadog.trace_agent.sampler.tail.dropped", atomic.SwapInt64(&st.dropped, 0), tags, 1)
+	}
+}
@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+func newTestTailSampler(policies ...*TailPolicy) (*TailSampler, chan []pb.Trace) {
+	out := make(chan []pb.Trace, 10)
+	return NewTailSampler(TailSamplerConfig{
+		DecisionWait: 10 * time.Second,
+		MaxTraces:    10,
+		MaxSpans:     100,
+		Policies:     policies,
+	}, out), out
+}
+
+func testChunk(traceID, spanID, parentID uint64, errors int32) pb.Trace {
+	return pb.Trace{{TraceID: traceID, SpanID: spanID, ParentID: parentID, Error: errors, Start: 1000, Duration: 100}}
+}
+
+func TestTailPolicyMatches(t *testing.T) {
+	chunks := []pb.Trace{
+		{{Start: 1000, Duration: 500, Meta: map[string]string{"http.url": "/users"}}},
+		{{Start: 1200, Duration: 2000, Error: 1}},
+	}
+	for _, tt := range []struct {
+		policy  TailPolicy
+		matches bool
+	}{
+		{TailPolicy{Type: TailPolicyError}, true},
+		{TailPolicy{Type: TailPolicyLatency, Latency: 2200}, true},
+		{TailPolicy{Type: TailPolicyLatency, Latency: 2201}, false},
+		{TailPolicy{Type: TailPolicyAttribute, Key: "http.url"}, true},
+		{TailPolicy{Type: TailPolicyAttribute, Key: "http.url", Values: []string{"/health", "/users"}}, true},
+		{TailPolicy{Type: TailPolicyAttribute, Key: "http.url", Values: []string{"/health"}}, false},
+		{TailPolicy{Type: TailPolicyAttribute, Key: "http.method"}, false},
+		{TailPolicy{Type: TailPolicyProbabilistic}, true},
+		{TailPolicy{Type: "unknown"}, false},
+	} {
+		assert.Equal(t, tt.matches, tt.policy.matches(chunks), "%+v", tt.policy)
+	}
+	assert.False(t, (&TailPolicy{Type: TailPolicyError}).matches(chunks[:1]))
+}
+
+func TestTailSamplerAssemble(t *testing.T) {
+	assert := assert.New(t)
+	s, out := newTestTailSampler(
+		&TailPolicy{Name: "errors", Type: TailPolicyError, Rate: 1},
+	)
+	now := time.Now()
+
+	// the error is only found in the second chunk of the trace
+	chunk := testChunk(1, 1, 0, 0)
+	root := chunk[0]
+	keep, decided := s.add(now, chunk)
+	assert.False(keep)
+	assert.False(decided)
+	s.add(now.Add(time.Second), testChunk(1, 2, 1, 1))
+	s.add(now, testChunk(2, 1, 0, 0))
+
+	s.flush(now.Add(5*time.Second), false)
+	assert.Len(out, 0)
+	s.flush(now.Add(10*time.Second), false)
+	require.Len(t, out, 1)
+	chunks := <-out
+	require.Len(t, chunks, 2)
+	assert.Equal(uint64(1), chunks[0][0].SpanID)
+	assert.Equal(uint64(2), chunks[1][0].SpanID)
+	assert.Equal("errors", chunks[0][0].Meta[tailPolicyKey])
+	assert.Equal(1.0, chunks[0][0].Metrics[KeySamplingRateGlobal])
+	// the root is copied before the decision is set on it
+	assert.Nil(root.Meta)
+	assert.NotEqual(root, chunks[0][0])
+	assert.Equal(0, s.spans)
+	assert.Len(s.traces, 0)
+	assert.Equal(int64(1), s.stats["errors"].kept)
+	assert.Equal(int64(1), s.stats[tailNoPolicy].dropped)
+
+	// late chunks follow the decision on their trace
+	late := testChunk(1, 3, 1, 0)
+	keep, decided = s.add(now.Add(11*time.Second), late)
+	assert.True(keep)
+	assert.True(decided)
+	assert.Equal("errors", late[0].Meta[tailPolicyKey])
+	keep, decided = s.add(now.Add(11*time.Second), testChunk(2, 3, 1, 0))
+	assert.False(keep)
+	assert.True(decided)
+	assert.Equal(int64(2), s.late)
+
+	// until the decision is forgotten
+	s.flush(now.Add(21*time.Second), false)
+	_, decided = s.add(now.Add(21*time.Second), testChunk(1, 4, 1, 0))
+	assert.False(decided)
+	assert.Len(s.traces, 1)
+}
+
+func TestTailSamplerPolicyOrder(t *testing.T) {
+	assert := assert.New(t)
+	s, out := newTestTailSampler(
+		&TailPolicy{Name: "health", Type: TailPolicyAttribute, Key: "http.url", Values: []string{"/health"}, Rate: 0},
+		&TailPolicy{Name: "all", Type: TailPolicyProbabilistic, Rate: 1},
+	)
+	now := time.Now()
+	health := testChunk(1, 1, 0, 0)
+	health[0].Meta = map[string]string{"http.url": "/health"}
+	s.add(now, health)
+	s.add(now, testChunk(2, 1, 0, 0))
+	s.flush(now.Add(10*time.Second), false)
+
+	require.Len(t, out, 1)
+	chunks := <-out
+	require.Len(t, chunks, 1)
+	assert.Equal(uint64(2), chunks[0][0].TraceID)
+	assert.Equal(int64(1), s.stats["health"].dropped)
+	assert.Equal(int64(1), s.stats["all"].kept)
+}
+
+func TestTailSamplerEviction(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+
+	t.Run("traces", func(t *testing.T) {
+		s, out := newTestTailSampler(&TailPolicy{Name: "all", Type: TailPolicyProbabilistic, Rate: 1})
+		s.conf.MaxTraces = 2
+		s.add(now, testChunk(1, 1, 0, 0))
+		s.add(now, testChunk(2, 1, 0, 0))
+		assert.Len(out, 0)
+		s.add(now, testChunk(3, 1, 0, 0))
+		require.Len(t, out, 1)
+		assert.Equal(uint64(1), (<-out)[0][0].TraceID)
+		assert.Equal(int64(1), s.evicted)
+		assert.Len(s.traces, 2)
+	})
+
+	t.Run("spans", func(t *testing.T) {
+		s, out := newTestTailSampler(&TailPolicy{Name: "all", Type: TailPolicyProbabilistic, Rate: 1})
+		s.conf.MaxSpans = 3
+		s.add(now, pb.Trace{{TraceID: 1, SpanID: 1}, {TraceID: 1, SpanID: 2, ParentID: 1}})
+		s.add(now, testChunk(2, 1, 0, 0))
+		assert.Len(out, 0)
+		s.add(now, testChunk(2, 2, 1, 0))
+		require.Len(t, out, 1)
+		assert.Equal(uint64(1), (<-out)[0][0].TraceID)
+		assert.Equal(int64(1), s.evicted)
+		assert.Equal(2, s.spans)
+	})
+}
+
+func TestTailSamplerStop(t *testing.T) {
+	s, out := newTestTailSampler(&TailPolicy{Name: "all", Type: TailPolicyProbabilistic, Rate: 1})
+	s.Start()
+	s.Add(testChunk(1, 1, 0, 0))
+	s.Stop()
+	require.Len(t, out, 1)
+	assert.Len(t, s.traces, 0)
+}